@@ -0,0 +1,172 @@
+// Package audit records who changed which identity, how, and whether it
+// worked. Recent events are kept in a store for the audit query endpoint and
+// every event is written to each configured sink.
+package audit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+
+	"gitea.slauson.io/slausonio/iam-ms/config"
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+	"gitea.slauson.io/slausonio/iam-ms/model"
+	"gitea.slauson.io/slausonio/iam-ms/utils"
+)
+
+// Auditor records events to its store and sinks.
+type Auditor struct {
+	store   Store
+	sinks   []Sink
+	now     func() time.Time
+	changes bool
+}
+
+var (
+	defaultOnce    sync.Once
+	defaultAuditor *Auditor
+)
+
+func New(store Store, sinks ...Sink) *Auditor {
+	return &Auditor{
+		store: store,
+		sinks: sinks,
+		now:   time.Now,
+	}
+}
+
+// FromConfig builds an Auditor over the configured store that logs events and
+// writes them to the file and webhook sinks when configured.
+func FromConfig(cfg *config.Config) (*Auditor, error) {
+	sinks := []Sink{LogSink{}}
+	if cfg.Audit.File != "" {
+		f, err := NewFileSink(cfg.Audit.File)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, f)
+	}
+	if cfg.Audit.WebhookURL != "" {
+		sinks = append(sinks, NewWebhookSink(
+			cfg.Audit.WebhookURL,
+			cfg.Audit.WebhookToken.Value(),
+			cfg.Audit.WebhookTimeout,
+		))
+	}
+	var store Store = NewMemoryStore(cfg.Audit.Retention)
+	if cfg.Audit.Store == constants.STORE_REDIS {
+		store = NewRedisStore(cfg)
+	}
+	a := New(store, sinks...)
+	if cfg.Audit.Changes {
+		a.WithChanges()
+	}
+	return a, nil
+}
+
+// WithChanges makes updates carry the fields they changed, which costs
+// looking the state up before every change. Created users carry their fields
+// either way.
+func (a *Auditor) WithChanges() *Auditor {
+	a.changes = true
+	return a
+}
+
+// Changes reports whether updates carry the fields they changed.
+func (a *Auditor) Changes() bool {
+	return a.changes
+}
+
+// Default is the Auditor shared by every controller, so the audit endpoint
+// sees the events they record.
+func Default(cfg *config.Config) *Auditor {
+	defaultOnce.Do(func() {
+		a, err := FromConfig(cfg)
+		if err != nil {
+			log.Fatalf("error: %v", err)
+		}
+		defaultAuditor = a
+	})
+	return defaultAuditor
+}
+
+// NewEvent starts the event for action on target, made by the request in c.
+// The actor is the caller resolved by the role middleware, or the calling
+// service on routes that do not resolve one.
+func NewEvent(c *gin.Context, action string, target string) *model.AuditEvent {
+	e := &model.AuditEvent{
+		Action:    action,
+		Actor:     model.AuditActor{Type: constants.AUDIT_ACTOR_SERVICE},
+		TargetID:  target,
+		ClientIP:  c.ClientIP(),
+		RequestID: c.GetString(constants.REQUEST_ID_CONTEXT_KEY),
+	}
+	if v, ok := c.Get(constants.CALLER_CONTEXT_KEY); ok {
+		if caller, ok := v.(*model.Caller); ok {
+			e.Actor = model.AuditActor{
+				Type:  constants.AUDIT_ACTOR_USER,
+				ID:    caller.ID,
+				Roles: caller.Roles,
+			}
+		}
+	}
+	return e
+}
+
+// Record completes e with the outcome of the change, err being what it
+// returned, and writes it out. The store or a sink failing is logged rather
+// than failing the request, whose change has already been made.
+func (a *Auditor) Record(e *model.AuditEvent, err error) {
+	id, idErr := utils.NewID()
+	if idErr != nil {
+		// The event is still worth writing without an ID.
+		log.Errorf("audit event ID not generated: %v", idErr)
+	}
+	e.ID = id
+	e.Time = a.now().UTC()
+	e.Outcome = constants.AUDIT_OUTCOME_SUCCESS
+	e.Status = http.StatusOK
+	if err != nil {
+		e.Outcome = constants.AUDIT_OUTCOME_FAILURE
+		e.Status = http.StatusInternalServerError
+		e.Error = constants.ERR_TYPE_UNKNOWN
+		e.Changes = nil
+
+		var ie *utils.IamError
+		if errors.As(err, &ie) {
+			e.Status = ie.Status
+			e.Error = ie.Type
+		}
+	}
+
+	if serr := a.store.Add(context.Background(), *e); serr != nil {
+		log.Errorf("audit event %s not stored: %v", e.ID, serr)
+	}
+	for _, s := range a.sinks {
+		if serr := s.Write(*e); serr != nil {
+			log.Errorf("audit event %s not written to %s: %v", e.ID, s.Name(), serr)
+		}
+	}
+}
+
+// Query returns the events the store still holds matching p, newest first.
+func (a *Auditor) Query(
+	ctx context.Context,
+	p *model.AuditQueryParams,
+) ([]model.AuditEvent, error) {
+	return a.store.Query(ctx, p)
+}
+
+// Close flushes and closes the sinks, giving up once ctx is done.
+func (a *Auditor) Close(ctx context.Context) error {
+	var errs []error
+	for _, s := range a.sinks {
+		errs = append(errs, s.Close(ctx))
+	}
+	return errors.Join(errs...)
+}
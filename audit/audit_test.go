@@ -0,0 +1,155 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"gitea.slauson.io/slausonio/iam-ms/config"
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+	"gitea.slauson.io/slausonio/iam-ms/model"
+	"gitea.slauson.io/slausonio/iam-ms/utils"
+)
+
+// memorySink keeps what it is written, failing every write while err is set.
+type memorySink struct {
+	events []model.AuditEvent
+	err    error
+	closed bool
+}
+
+func (s *memorySink) Name() string {
+	return "memory"
+}
+
+func (s *memorySink) Write(e model.AuditEvent) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.events = append(s.events, e)
+	return nil
+}
+
+func (s *memorySink) Close(context.Context) error {
+	s.closed = true
+	return nil
+}
+
+func requestContext(caller *model.Caller) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("PUT", "/api/iam/v1/user/u1/name", nil)
+	c.Request.RemoteAddr = "203.0.113.7:51234"
+	c.Set(constants.REQUEST_ID_CONTEXT_KEY, "req-1")
+	if caller != nil {
+		c.Set(constants.CALLER_CONTEXT_KEY, caller)
+	}
+	return c
+}
+
+func TestNewEvent(t *testing.T) {
+	caller := &model.Caller{ID: "admin1", Roles: []string{constants.ROLE_ADMIN}}
+	e := NewEvent(requestContext(caller), constants.AUDIT_USER_UPDATE_NAME, "u1")
+
+	assert.Equal(t, constants.AUDIT_USER_UPDATE_NAME, e.Action)
+	assert.Equal(t, "u1", e.TargetID)
+	assert.Equal(t, model.AuditActor{
+		Type:  constants.AUDIT_ACTOR_USER,
+		ID:    "admin1",
+		Roles: []string{constants.ROLE_ADMIN},
+	}, e.Actor)
+	assert.Equal(t, "203.0.113.7", e.ClientIP)
+	assert.Equal(t, "req-1", e.RequestID)
+
+	e = NewEvent(requestContext(nil), constants.AUDIT_USER_CREATE, "")
+	assert.Equal(t, model.AuditActor{Type: constants.AUDIT_ACTOR_SERVICE}, e.Actor)
+}
+
+func TestAuditor_Record(t *testing.T) {
+	failing := &memorySink{err: errors.New("disk full")}
+	sink := &memorySink{}
+	a := New(NewMemoryStore(10), failing, sink)
+	clock := time.Date(2023, 11, 14, 22, 13, 20, 0, time.UTC)
+	a.now = func() time.Time { return clock }
+
+	e := NewEvent(requestContext(nil), constants.AUDIT_USER_UPDATE_NAME, "u1")
+	e.Changes = map[string]model.AuditChange{"name": {Before: "a", After: "b"}}
+	a.Record(e, nil)
+
+	e = NewEvent(requestContext(nil), constants.AUDIT_USER_UPDATE_NAME, "u1")
+	e.Changes = map[string]model.AuditChange{"name": {Before: "a", After: "b"}}
+	a.Record(e, utils.NewIamError(http.StatusNotFound, constants.ERR_TYPE_USER_NOT_FOUND, "nope"))
+
+	e = NewEvent(requestContext(nil), constants.AUDIT_USER_DELETE, "u1")
+	a.Record(e, errors.New("boom"))
+
+	// A failing sink does not keep events from the others.
+	if !assert.Len(t, sink.events, 3) {
+		return
+	}
+	success, notFound, unknown := sink.events[0], sink.events[1], sink.events[2]
+
+	assert.Len(t, success.ID, 32)
+	assert.Equal(t, clock, success.Time)
+	assert.Equal(t, constants.AUDIT_OUTCOME_SUCCESS, success.Outcome)
+	assert.Equal(t, http.StatusOK, success.Status)
+	assert.Empty(t, success.Error)
+	assert.NotEmpty(t, success.Changes)
+
+	assert.Equal(t, constants.AUDIT_OUTCOME_FAILURE, notFound.Outcome)
+	assert.Equal(t, http.StatusNotFound, notFound.Status)
+	assert.Equal(t, constants.ERR_TYPE_USER_NOT_FOUND, notFound.Error)
+	assert.Nil(t, notFound.Changes, "a failed change changed nothing")
+
+	assert.Equal(t, http.StatusInternalServerError, unknown.Status)
+	assert.Equal(t, constants.ERR_TYPE_UNKNOWN, unknown.Error)
+
+	events, err := a.Query(context.Background(), &model.AuditQueryParams{})
+	assert.Nil(t, err)
+	assert.Equal(t, unknown.ID, events[0].ID)
+	assert.Len(t, events, 3)
+}
+
+func TestAuditor_Close(t *testing.T) {
+	sink := &memorySink{}
+	a := New(NewMemoryStore(1), LogSink{}, sink)
+
+	assert.Nil(t, a.Close(context.Background()))
+	assert.True(t, sink.closed)
+}
+
+func TestFromConfig(t *testing.T) {
+	cfg := config.Default()
+	a, err := FromConfig(cfg)
+	if assert.Nil(t, err) {
+		assert.Equal(t, []Sink{LogSink{}}, a.sinks)
+		assert.IsType(t, &MemoryStore{}, a.store)
+		assert.True(t, a.Changes())
+	}
+
+	cfg.Audit.Store = constants.STORE_REDIS
+	cfg.Redis.Addr = "localhost:6379"
+	a, err = FromConfig(cfg)
+	if assert.Nil(t, err) {
+		assert.IsType(t, &RedisStore{}, a.store)
+	}
+	cfg.Audit.Store = constants.STORE_MEMORY
+
+	cfg.Audit.File = filepath.Join(t.TempDir(), "audit.log")
+	cfg.Audit.WebhookURL = "https://hooks.example.com/audit"
+	a, err = FromConfig(cfg)
+	if assert.Nil(t, err) {
+		assert.Len(t, a.sinks, 3)
+		assert.Nil(t, a.Close(context.Background()))
+	}
+
+	cfg.Audit.File = filepath.Join(t.TempDir(), "missing", "audit.log")
+	_, err = FromConfig(cfg)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,64 @@
+package audit
+
+import (
+	"reflect"
+
+	"gitea.slauson.io/slausonio/iam-ms/model"
+)
+
+// DiffUsers is what changed between before and after, either of which may be
+// nil for a user that did not exist yet or no longer does. Only the fields
+// listed in userFields are compared; model.User holds no secrets, and
+// timestamps other than passwordUpdate are left out as noise.
+func DiffUsers(before *model.User, after *model.User) map[string]model.AuditChange {
+	return Diff(userFields(before), userFields(after))
+}
+
+// DiffPrefs is what changed between two versions of a user's prefs, keyed
+// prefs.<key> as in DiffUsers.
+func DiffPrefs(before model.Prefs, after model.Prefs) map[string]model.AuditChange {
+	return Diff(prefsFields(before), prefsFields(after))
+}
+
+// Diff compares two sets of fields keyed by name. A field missing on one side
+// is reported as nil there.
+func Diff(before map[string]any, after map[string]any) map[string]model.AuditChange {
+	changes := map[string]model.AuditChange{}
+	for k, b := range before {
+		if a := after[k]; !reflect.DeepEqual(a, b) {
+			changes[k] = model.AuditChange{Before: b, After: a}
+		}
+	}
+	for k, a := range after {
+		if _, ok := before[k]; !ok {
+			changes[k] = model.AuditChange{After: a}
+		}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	return changes
+}
+
+func userFields(u *model.User) map[string]any {
+	if u == nil {
+		return nil
+	}
+	fields := prefsFields(u.Prefs)
+	fields["name"] = u.Name
+	fields["email"] = u.Email
+	fields["phone"] = u.Phone
+	fields["status"] = u.Status
+	fields["emailVerification"] = u.EmailVerification
+	fields["phoneVerification"] = u.PhoneVerification
+	fields["passwordUpdate"] = u.PasswordUpdate
+	return fields
+}
+
+func prefsFields(p model.Prefs) map[string]any {
+	fields := map[string]any{}
+	for k, v := range p {
+		fields["prefs."+k] = v
+	}
+	return fields
+}
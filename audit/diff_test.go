@@ -0,0 +1,59 @@
+package audit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"gitea.slauson.io/slausonio/iam-ms/model"
+)
+
+func TestDiffUsers(t *testing.T) {
+	user := model.User{
+		ID:             "u1",
+		Name:           "Matt",
+		Email:          "m@slauson.io",
+		Status:         true,
+		PasswordUpdate: "2023-11-14T22:13:20Z",
+		UpdatedAt:      "2023-11-14T22:13:20Z",
+		Prefs:          model.Prefs{"theme": "dark"},
+	}
+
+	updated := user
+	updated.Email = "matt@slauson.io"
+	updated.PasswordUpdate = "2023-11-15T08:00:00Z"
+	updated.UpdatedAt = "2023-11-15T08:00:00Z"
+	updated.Prefs = model.Prefs{"theme": "light", "newsletter": true}
+
+	assert.Equal(t, map[string]model.AuditChange{
+		"email":            {Before: "m@slauson.io", After: "matt@slauson.io"},
+		"passwordUpdate":   {Before: "2023-11-14T22:13:20Z", After: "2023-11-15T08:00:00Z"},
+		"prefs.theme":      {Before: "dark", After: "light"},
+		"prefs.newsletter": {After: true},
+	}, DiffUsers(&user, &updated))
+
+	assert.Nil(t, DiffUsers(&user, &user))
+
+	created := DiffUsers(nil, &user)
+	assert.Equal(t, model.AuditChange{After: "Matt"}, created["name"])
+	assert.Equal(t, model.AuditChange{After: true}, created["status"])
+
+	deleted := DiffUsers(&user, nil)
+	assert.Equal(t, model.AuditChange{Before: "m@slauson.io"}, deleted["email"])
+	assert.Equal(t, model.AuditChange{Before: "dark"}, deleted["prefs.theme"])
+
+	for _, changes := range []map[string]model.AuditChange{created, deleted} {
+		assert.NotContains(t, changes, "updatedAt")
+		assert.NotContains(t, changes, "$id")
+	}
+}
+
+func TestDiffPrefs(t *testing.T) {
+	assert.Equal(t, map[string]model.AuditChange{
+		"prefs.theme": {Before: "dark"},
+		"prefs.lang":  {Before: "en", After: "de"},
+	}, DiffPrefs(
+		model.Prefs{"theme": "dark", "lang": "en", "tz": "UTC"},
+		model.Prefs{"lang": "de", "tz": "UTC"},
+	))
+}
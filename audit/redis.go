@@ -0,0 +1,72 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/redis/go-redis/v9"
+
+	"gitea.slauson.io/slausonio/iam-ms/config"
+	"gitea.slauson.io/slausonio/iam-ms/model"
+)
+
+const redisEventsKey = "iam:audit:events"
+
+// RedisStore keeps events in a Redis list shared by every replica, newest
+// first and trimmed to size, so any replica answers with all of them.
+type RedisStore struct {
+	client *redis.Client
+	size   int
+}
+
+func NewRedisStore(cfg *config.Config) *RedisStore {
+	return NewRedisStoreFor(redis.NewClient(&redis.Options{
+		Addr:     cfg.Redis.Addr,
+		Password: cfg.Redis.Password.Value(),
+		DB:       cfg.Redis.DB,
+	}), cfg.Audit.Retention)
+}
+
+func NewRedisStoreFor(client *redis.Client, size int) *RedisStore {
+	return &RedisStore{client: client, size: size}
+}
+
+func (s *RedisStore) Add(ctx context.Context, e model.AuditEvent) error {
+	value, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.LPush(ctx, redisEventsKey, value)
+		p.LTrim(ctx, redisEventsKey, 0, int64(s.size-1))
+		return nil
+	})
+	return err
+}
+
+func (s *RedisStore) Query(
+	ctx context.Context,
+	p *model.AuditQueryParams,
+) ([]model.AuditEvent, error) {
+	limit, since := queryBounds(p)
+
+	values, err := s.client.LRange(ctx, redisEventsKey, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	result := []model.AuditEvent{}
+	for _, value := range values {
+		if len(result) == limit {
+			break
+		}
+		var e model.AuditEvent
+		if err := json.Unmarshal([]byte(value), &e); err != nil {
+			return nil, err
+		}
+		if !e.Time.Before(since) && matches(&e, p) {
+			result = append(result, e)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,46 @@
+package audit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+
+	"gitea.slauson.io/slausonio/iam-ms/model"
+)
+
+func initRedisStoreTest(t *testing.T, size int) *RedisStore {
+	mr := miniredis.RunT(t)
+	return NewRedisStoreFor(redis.NewClient(&redis.Options{Addr: mr.Addr()}), size)
+}
+
+func TestRedisStore_Retention(t *testing.T) {
+	testStoreRetention(t, initRedisStoreTest(t, 3))
+}
+
+func TestRedisStore_Query(t *testing.T) {
+	testStoreQuery(t, initRedisStoreTest(t, 10))
+}
+
+func TestRedisStore_Shared(t *testing.T) {
+	mr := miniredis.RunT(t)
+	newStore := func() *RedisStore {
+		return NewRedisStoreFor(redis.NewClient(&redis.Options{Addr: mr.Addr()}), 10)
+	}
+	ctx := context.Background()
+
+	assert.Nil(t, newStore().Add(ctx, model.AuditEvent{ID: "1"}))
+	assert.Equal(t, []string{"1"}, queryIDs(t, newStore(), &model.AuditQueryParams{}))
+}
+
+func TestRedisStore_Down(t *testing.T) {
+	mr := miniredis.RunT(t)
+	s := NewRedisStoreFor(redis.NewClient(&redis.Options{Addr: mr.Addr()}), 10)
+	mr.Close()
+
+	assert.NotNil(t, s.Add(context.Background(), model.AuditEvent{ID: "1"}))
+	_, err := s.Query(context.Background(), &model.AuditQueryParams{})
+	assert.NotNil(t, err)
+}
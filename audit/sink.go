@@ -0,0 +1,188 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"gitea.slauson.io/slausonio/iam-ms/model"
+)
+
+// webhookQueueSize is how many events a webhook sink holds while deliveries
+// are slow, after which further events are dropped for it.
+const webhookQueueSize = 256
+
+var (
+	errQueueFull = errors.New("delivery queue is full, event dropped")
+	errClosed    = errors.New("sink is closed, event dropped")
+)
+
+// Sink is somewhere audit events are sent.
+type Sink interface {
+	Name() string
+	Write(e model.AuditEvent) error
+	// Close flushes what the sink still holds, giving up once ctx is done.
+	Close(ctx context.Context) error
+}
+
+// LogSink logs events, which ships them to Loki along with the other logs.
+type LogSink struct{}
+
+func (LogSink) Name() string {
+	return "log"
+}
+
+func (LogSink) Write(e model.AuditEvent) error {
+	log.WithField("audit", e).Info("audit " + e.Action)
+	return nil
+}
+
+func (LogSink) Close(context.Context) error {
+	return nil
+}
+
+// FileSink appends events to a file, one JSON object per line.
+type FileSink struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+func NewFileSink(path string) (*FileSink, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("audit file: %w", err)
+	}
+	return &FileSink{f: f}, nil
+}
+
+func (s *FileSink) Name() string {
+	return "file"
+}
+
+func (s *FileSink) Write(e model.AuditEvent) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.f.Write(append(line, '\n'))
+	return err
+}
+
+func (s *FileSink) Close(context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.f.Close()
+}
+
+// WebhookSink POSTs each event as JSON to a URL. Deliveries happen in the
+// background, in order, so a slow receiver does not hold up requests; a
+// failed delivery is logged and not retried.
+type WebhookSink struct {
+	url    string
+	token  string
+	client *http.Client
+	queue  chan model.AuditEvent
+	done   chan struct{}
+
+	// mu guards closed, so events written while closing are not sent on the
+	// closed queue.
+	mu     sync.Mutex
+	closed bool
+}
+
+// NewWebhookSink delivers to url, sending token as a bearer token when set,
+// with each delivery bounded by timeout.
+func NewWebhookSink(url string, token string, timeout time.Duration) *WebhookSink {
+	s := &WebhookSink{
+		url:    url,
+		token:  token,
+		client: &http.Client{Timeout: timeout},
+		queue:  make(chan model.AuditEvent, webhookQueueSize),
+		done:   make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+func (s *WebhookSink) Name() string {
+	return "webhook"
+}
+
+func (s *WebhookSink) Write(e model.AuditEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return errClosed
+	}
+
+	select {
+	case s.queue <- e:
+		return nil
+	default:
+		return errQueueFull
+	}
+}
+
+// Close delivers the queued events. Events written after it are dropped.
+func (s *WebhookSink) Close(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("audit webhook: %d events not delivered: %w", len(s.queue), ctx.Err())
+	}
+}
+
+func (s *WebhookSink) run() {
+	defer close(s.done)
+	for e := range s.queue {
+		if err := s.deliver(e); err != nil {
+			log.Errorf("audit event %s not delivered to webhook: %v", e.ID, err)
+		}
+	}
+}
+
+func (s *WebhookSink) deliver(e model.AuditEvent) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("webhook responded %s", res.Status)
+	}
+	return nil
+}
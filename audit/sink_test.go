@@ -0,0 +1,124 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"gitea.slauson.io/slausonio/iam-ms/model"
+)
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	s, err := NewFileSink(path)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Nil(t, s.Write(model.AuditEvent{ID: "e1", Action: "user.create"}))
+	assert.Nil(t, s.Write(model.AuditEvent{ID: "e2", Action: "user.delete"}))
+	assert.Nil(t, s.Close(context.Background()))
+
+	// Reopening appends rather than truncating.
+	s, err = NewFileSink(path)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Nil(t, s.Write(model.AuditEvent{ID: "e3"}))
+	assert.Nil(t, s.Close(context.Background()))
+
+	f, err := os.Open(path)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer f.Close()
+
+	var ids []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e model.AuditEvent
+		assert.Nil(t, json.Unmarshal(scanner.Bytes(), &e))
+		ids = append(ids, e.ID)
+	}
+	assert.Equal(t, []string{"e1", "e2", "e3"}, ids)
+}
+
+func TestWebhookSink(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		received []model.AuditEvent
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "Bearer hook-token", r.Header.Get("Authorization"))
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		var e model.AuditEvent
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&e))
+		mu.Lock()
+		received = append(received, e)
+		mu.Unlock()
+		if e.ID == "rejected" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	s := NewWebhookSink(srv.URL, "hook-token", time.Second)
+	for _, id := range []string{"e1", "rejected", "e2"} {
+		assert.Nil(t, s.Write(model.AuditEvent{ID: id}))
+	}
+	// Close waits for the queued deliveries, and a rejected one does not stop
+	// the rest.
+	assert.Nil(t, s.Close(context.Background()))
+
+	mu.Lock()
+	defer mu.Unlock()
+	var ids []string
+	for _, e := range received {
+		ids = append(ids, e.ID)
+	}
+	assert.Equal(t, []string{"e1", "rejected", "e2"}, ids)
+}
+
+func TestWebhookSink_WriteAfterClose(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer srv.Close()
+
+	s := NewWebhookSink(srv.URL, "", time.Second)
+	assert.Nil(t, s.Close(context.Background()))
+	assert.Equal(t, errClosed, s.Write(model.AuditEvent{ID: "late"}))
+	assert.Nil(t, s.Close(context.Background()))
+}
+
+func TestWebhookSink_Backlog(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	s := NewWebhookSink(srv.URL, "", time.Minute)
+
+	var err error
+	for i := 0; i <= webhookQueueSize+1 && err == nil; i++ {
+		err = s.Write(model.AuditEvent{})
+	}
+	assert.Equal(t, errQueueFull, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = s.Close(ctx)
+	if assert.NotNil(t, err) {
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	}
+}
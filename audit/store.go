@@ -0,0 +1,91 @@
+package audit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+	"gitea.slauson.io/slausonio/iam-ms/model"
+)
+
+// Store keeps the most recent events for the audit query endpoint, dropping
+// the oldest once full. The sinks are the complete record.
+type Store interface {
+	Add(ctx context.Context, e model.AuditEvent) error
+	// Query returns up to p.Limit events matching p, newest first. p is
+	// expected to be valid.
+	Query(ctx context.Context, p *model.AuditQueryParams) ([]model.AuditEvent, error)
+}
+
+// MemoryStore keeps events in this process, so each replica only holds the
+// events it recorded itself.
+type MemoryStore struct {
+	mu     sync.RWMutex
+	events []model.AuditEvent
+	// next is where the next event goes, which once full is the oldest.
+	next int
+	full bool
+}
+
+// NewMemoryStore keeps up to size events.
+func NewMemoryStore(size int) *MemoryStore {
+	return &MemoryStore{events: make([]model.AuditEvent, size)}
+}
+
+func (s *MemoryStore) Add(_ context.Context, e model.AuditEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.events[s.next] = e
+	s.next = (s.next + 1) % len(s.events)
+	if s.next == 0 {
+		s.full = true
+	}
+	return nil
+}
+
+func (s *MemoryStore) Query(
+	_ context.Context,
+	p *model.AuditQueryParams,
+) ([]model.AuditEvent, error) {
+	limit, since := queryBounds(p)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	count := s.next
+	if s.full {
+		count = len(s.events)
+	}
+
+	result := []model.AuditEvent{}
+	for i := 1; i <= count && len(result) < limit; i++ {
+		e := s.events[(s.next-i+len(s.events))%len(s.events)]
+		if !e.Time.Before(since) && matches(&e, p) {
+			result = append(result, e)
+		}
+	}
+	return result, nil
+}
+
+// queryBounds is the page size p asks for and the time events must not be
+// before.
+func queryBounds(p *model.AuditQueryParams) (int, time.Time) {
+	limit := p.Limit
+	if limit == 0 {
+		limit = constants.DEFAULT_AUDIT_LIST_LIMIT
+	}
+	var since time.Time
+	if p.Since != "" {
+		since, _ = time.Parse(time.RFC3339, p.Since)
+	}
+	return limit, since
+}
+
+func matches(e *model.AuditEvent, p *model.AuditQueryParams) bool {
+	return (p.Actor == "" || e.Actor.ID == p.Actor) &&
+		(p.Target == "" || e.TargetID == p.Target) &&
+		(p.Action == "" || e.Action == p.Action) &&
+		(p.Outcome == "" || e.Outcome == p.Outcome)
+}
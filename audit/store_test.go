@@ -0,0 +1,125 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+	"gitea.slauson.io/slausonio/iam-ms/model"
+)
+
+// queryIDs are the IDs of the events s returns for p.
+func queryIDs(t *testing.T, s Store, p *model.AuditQueryParams) []string {
+	t.Helper()
+	events, err := s.Query(context.Background(), p)
+	assert.Nil(t, err)
+	ids := []string{}
+	for _, e := range events {
+		ids = append(ids, e.ID)
+	}
+	return ids
+}
+
+// testStoreRetention checks a store of size 3.
+func testStoreRetention(t *testing.T, s Store) {
+	ctx := context.Background()
+	assert.Equal(t, []string{}, queryIDs(t, s, &model.AuditQueryParams{}))
+
+	for i := 1; i <= 5; i++ {
+		assert.Nil(t, s.Add(ctx, model.AuditEvent{ID: fmt.Sprint(i)}))
+	}
+	assert.Equal(
+		t,
+		[]string{"5", "4", "3"},
+		queryIDs(t, s, &model.AuditQueryParams{}),
+	)
+	assert.Equal(
+		t,
+		[]string{"5", "4"},
+		queryIDs(t, s, &model.AuditQueryParams{Limit: 2}),
+	)
+}
+
+// testStoreQuery checks a store of size 10.
+func testStoreQuery(t *testing.T, s Store) {
+	ctx := context.Background()
+	start := time.Date(2023, 11, 14, 22, 0, 0, 0, time.UTC)
+	for i, e := range []model.AuditEvent{
+		{
+			Action:   constants.AUDIT_USER_CREATE,
+			Actor:    model.AuditActor{Type: constants.AUDIT_ACTOR_SERVICE},
+			TargetID: "u1",
+			Outcome:  constants.AUDIT_OUTCOME_SUCCESS,
+		},
+		{
+			Action:   constants.AUDIT_USER_UPDATE_STATUS,
+			Actor:    model.AuditActor{Type: constants.AUDIT_ACTOR_USER, ID: "admin1"},
+			TargetID: "u1",
+			Outcome:  constants.AUDIT_OUTCOME_SUCCESS,
+		},
+		{
+			Action:   constants.AUDIT_USER_UPDATE_STATUS,
+			Actor:    model.AuditActor{Type: constants.AUDIT_ACTOR_USER, ID: "admin1"},
+			TargetID: "u2",
+			Outcome:  constants.AUDIT_OUTCOME_FAILURE,
+		},
+		{
+			Action:   constants.AUDIT_SESSION_DELETE,
+			Actor:    model.AuditActor{Type: constants.AUDIT_ACTOR_USER, ID: "u2"},
+			TargetID: "u2",
+			Outcome:  constants.AUDIT_OUTCOME_SUCCESS,
+		},
+	} {
+		e.ID = fmt.Sprint(i + 1)
+		e.Time = start.Add(time.Duration(i) * time.Minute)
+		assert.Nil(t, s.Add(ctx, e))
+	}
+
+	tests := []struct {
+		name   string
+		params model.AuditQueryParams
+		want   []string
+	}{
+		{name: "All", want: []string{"4", "3", "2", "1"}},
+		{name: "Actor", params: model.AuditQueryParams{Actor: "admin1"}, want: []string{"3", "2"}},
+		{name: "Target", params: model.AuditQueryParams{Target: "u1"}, want: []string{"2", "1"}},
+		{
+			name:   "Action",
+			params: model.AuditQueryParams{Action: constants.AUDIT_SESSION_DELETE},
+			want:   []string{"4"},
+		},
+		{
+			name:   "Outcome",
+			params: model.AuditQueryParams{Outcome: constants.AUDIT_OUTCOME_FAILURE},
+			want:   []string{"3"},
+		},
+		{
+			name:   "Since",
+			params: model.AuditQueryParams{Since: "2023-11-14T22:02:00Z"},
+			want:   []string{"4", "3"},
+		},
+		{
+			name:   "Combined",
+			params: model.AuditQueryParams{Actor: "admin1", Target: "u1", Limit: 1},
+			want:   []string{"2"},
+		},
+		{name: "None", params: model.AuditQueryParams{Actor: "nobody"}, want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, queryIDs(t, s, &tt.params))
+		})
+	}
+}
+
+func TestMemoryStore_Retention(t *testing.T) {
+	testStoreRetention(t, NewMemoryStore(3))
+}
+
+func TestMemoryStore_Query(t *testing.T) {
+	testStoreQuery(t, NewMemoryStore(10))
+}
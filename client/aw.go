@@ -1,56 +1,150 @@
 package client
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
-	"os"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"gitea.slauson.io/slausonio/go-types/siogeneric"
 	"gitea.slauson.io/slausonio/go-utils/sioUtils"
-	"gitea.slauson.io/slausonio/go-utils/sioerror"
+	"gitea.slauson.io/slausonio/iam-ms/config"
 	"gitea.slauson.io/slausonio/iam-ms/constants"
+	"gitea.slauson.io/slausonio/iam-ms/model"
+	"gitea.slauson.io/slausonio/iam-ms/utils"
 )
 
 type AwClient struct {
 	h              sioUtils.SioRestHelpers
-	defaultHeaders map[string][]string
+	defaultHeaders http.Header
 	host           string
 	key            string
+	recoveryURL    string
+	verifyURL      string
+	timeout        time.Duration
+	retry          retryPolicy
+	breaker        *breaker
 }
 
 //go:generate mockery --name AppwriteClient
 type AppwriteClient interface {
-	ListUsers() (*siogeneric.AwlistResponse, error)
-	GetUserByID(id string) (*siogeneric.AwUser, error)
-	CreateUser(r *siogeneric.AwCreateUserRequest) (*siogeneric.AwUser, error)
-	UpdateEmail(id string, r *siogeneric.UpdateEmailRequest) (*siogeneric.AwUser, error)
-	UpdatePhone(id string, r *siogeneric.UpdatePhoneRequest) (*siogeneric.AwUser, error)
-	UpdatePassword(id string, r *siogeneric.UpdatePasswordRequest) (*siogeneric.AwUser, error)
-	DeleteUser(id string) error
-	CreateEmailSession(r *siogeneric.AwEmailSessionRequest) (*siogeneric.AwSession, error)
-	DeleteSession(ID, sID string) error
-}
-
-func NewAwClient() *AwClient {
+	ListUsers(ctx context.Context, p *model.ListUsersParams) (*siogeneric.AwlistResponse, error)
+	GetUserByID(ctx context.Context, id string) (*siogeneric.AwUser, error)
+	CreateUser(ctx context.Context, r *siogeneric.AwCreateUserRequest) (*siogeneric.AwUser, error)
+	UpdateEmail(
+		ctx context.Context,
+		id string,
+		r *siogeneric.UpdateEmailRequest,
+	) (*siogeneric.AwUser, error)
+	UpdatePhone(
+		ctx context.Context,
+		id string,
+		r *siogeneric.UpdatePhoneRequest,
+	) (*siogeneric.AwUser, error)
+	UpdatePassword(
+		ctx context.Context,
+		id string,
+		r *siogeneric.UpdatePasswordRequest,
+	) (*siogeneric.AwUser, error)
+	UpdateName(
+		ctx context.Context,
+		id string,
+		r *model.UpdateNameRequest,
+	) (*siogeneric.AwUser, error)
+	UpdateStatus(ctx context.Context, id string, status bool) (*siogeneric.AwUser, error)
+	GetPrefs(ctx context.Context, id string) (model.Prefs, error)
+	GetLabels(ctx context.Context, id string) (*model.AwUserLabels, error)
+	UpdateLabels(ctx context.Context, id string, labels []string) (*model.AwUserLabels, error)
+	GetAccount(ctx context.Context, jwt string) (*model.AwUserLabels, error)
+	UpdatePrefs(ctx context.Context, id string, prefs model.Prefs) (model.Prefs, error)
+	DeleteUser(ctx context.Context, id string) error
+	CreateRecovery(ctx context.Context, r *model.AwRecoveryRequest) (*model.AwToken, error)
+	UpdateRecovery(ctx context.Context, r *model.AwRecoveryConfirmRequest) (*model.AwToken, error)
+	CreateVerification(ctx context.Context, jwt string) (*model.AwToken, error)
+	ConfirmVerification(
+		ctx context.Context,
+		r *model.VerificationConfirmRequest,
+	) (*model.AwToken, error)
+	CreatePhoneVerification(ctx context.Context, jwt string) (*model.AwToken, error)
+	ConfirmPhoneVerification(
+		ctx context.Context,
+		r *model.VerificationConfirmRequest,
+	) (*model.AwToken, error)
+	UpdateEmailVerification(
+		ctx context.Context,
+		id string,
+		verified bool,
+	) (*siogeneric.AwUser, error)
+	UpdatePhoneVerification(
+		ctx context.Context,
+		id string,
+		verified bool,
+	) (*siogeneric.AwUser, error)
+	CreateEmailSession(
+		ctx context.Context,
+		r *siogeneric.AwEmailSessionRequest,
+	) (*siogeneric.AwSession, error)
+	ListSessions(ctx context.Context, id string) (*model.AwSessionList, error)
+	DeleteSession(ctx context.Context, ID, sID string) error
+	DeleteSessions(ctx context.Context, id string) error
+	Health(ctx context.Context) error
+}
+
+// NewAwClient builds a client for the Appwrite API configured in cfg.
+func NewAwClient(cfg *config.Config) *AwClient {
 	return &AwClient{
 		h: sioUtils.NewRestHelpers(),
-		defaultHeaders: map[string][]string{
+		defaultHeaders: http.Header{
 			"Content-Type":                 {"application/json"},
-			constants.AW_HEADER_PROJECT_ID: {os.Getenv("IAM_PROJECT")},
+			constants.AW_HEADER_PROJECT_ID: {cfg.Appwrite.Project},
+		},
+		host:        strings.TrimSuffix(cfg.Appwrite.Host, "/"),
+		key:         cfg.Appwrite.Key.Value(),
+		recoveryURL: cfg.IAM.RecoveryURL,
+		verifyURL:   cfg.IAM.VerificationURL,
+		timeout:     cfg.Upstream.Timeout,
+		retry: retryPolicy{
+			Retries:    cfg.Upstream.RetryMax,
+			Backoff:    cfg.Upstream.RetryBackoff,
+			MaxBackoff: cfg.Upstream.RetryMaxBackoff,
 		},
-		host: os.Getenv("IAM_HOST"),
-		key:  os.Getenv("IAM_KEY"),
+		breaker: newBreaker(
+			constants.PROVIDER_APPWRITE,
+			cfg.Upstream.BreakerFailures,
+			cfg.Upstream.BreakerCooldown,
+		),
 	}
 }
 
-func (c *AwClient) ListUsers() (*siogeneric.AwlistResponse, error) {
-	url := fmt.Sprintf("%s/users", c.host)
-	req, _ := http.NewRequest("GET", url, nil)
+// NewAwClientFor builds a client with the default settings for the Appwrite
+// API at host, e.g. https://appwrite.example.com/v1 or the Host of an
+// awtest.Server.
+func NewAwClientFor(host string, project string, key string) *AwClient {
+	cfg := config.Default()
+	cfg.Appwrite = config.Appwrite{Host: host, Project: project, Key: config.Secret(key)}
+	return NewAwClient(cfg)
+}
+
+func (c *AwClient) ListUsers(
+	ctx context.Context,
+	p *model.ListUsersParams,
+) (*siogeneric.AwlistResponse, error) {
+	req, err := c.adminRequest(
+		ctx,
+		"GET",
+		fmt.Sprintf("/users?%s", listUsersQuery(p).Encode()),
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
 
-	req.Header = c.defaultHeaders
-	req.Header.Add(constants.AW_HEADER_KEY, c.key)
 	response := new(siogeneric.AwlistResponse)
 	if err := c.executeAndParseResponse(req, response); err != nil {
 		return nil, err
@@ -58,11 +152,11 @@ func (c *AwClient) ListUsers() (*siogeneric.AwlistResponse, error) {
 	return response, nil
 }
 
-func (c *AwClient) GetUserByID(id string) (*siogeneric.AwUser, error) {
-	url := fmt.Sprintf("%s/users/%s", c.host, id)
-	req, _ := http.NewRequest("GET", url, nil)
-	req.Header = c.defaultHeaders
-	req.Header.Add(constants.AW_HEADER_KEY, c.key)
+func (c *AwClient) GetUserByID(ctx context.Context, id string) (*siogeneric.AwUser, error) {
+	req, err := c.adminRequest(ctx, "GET", fmt.Sprintf("/users/%s", id), nil)
+	if err != nil {
+		return nil, err
+	}
 
 	response := new(siogeneric.AwUser)
 	if err := c.executeAndParseResponse(req, response); err != nil {
@@ -71,20 +165,15 @@ func (c *AwClient) GetUserByID(id string) (*siogeneric.AwUser, error) {
 	return response, nil
 }
 
-func (c *AwClient) CreateUser(r *siogeneric.AwCreateUserRequest) (*siogeneric.AwUser, error) {
-	url := fmt.Sprintf("%s/users", c.host)
-	r.Phone = fmt.Sprintf("+1%s", r.Phone)
-	rJSON, err := json.Marshal(r)
+func (c *AwClient) CreateUser(
+	ctx context.Context,
+	r *siogeneric.AwCreateUserRequest,
+) (*siogeneric.AwUser, error) {
+	req, err := c.adminRequest(ctx, "POST", "/users", r)
 	if err != nil {
 		return nil, err
 	}
 
-	sr := strings.NewReader(string(rJSON))
-	req, _ := http.NewRequest("POST", url, sr)
-
-	req.Header = c.defaultHeaders
-	req.Header.Add(constants.AW_HEADER_KEY, c.key)
-
 	response := new(siogeneric.AwUser)
 	if err := c.executeAndParseResponse(req, response); err != nil {
 		return nil, err
@@ -93,21 +182,15 @@ func (c *AwClient) CreateUser(r *siogeneric.AwCreateUserRequest) (*siogeneric.Aw
 }
 
 func (c *AwClient) UpdateEmail(
+	ctx context.Context,
 	id string,
 	r *siogeneric.UpdateEmailRequest,
 ) (*siogeneric.AwUser, error) {
-	url := fmt.Sprintf("%s/users/%s/email", c.host, id)
-	rJSON, err := json.Marshal(r)
+	req, err := c.adminRequest(ctx, "PATCH", fmt.Sprintf("/users/%s/email", id), r)
 	if err != nil {
 		return nil, err
 	}
 
-	sr := strings.NewReader(string(rJSON))
-	req, _ := http.NewRequest("PATCH", url, sr)
-
-	req.Header = c.defaultHeaders
-	req.Header.Add(constants.AW_HEADER_KEY, c.key)
-
 	response := new(siogeneric.AwUser)
 	if err := c.executeAndParseResponse(req, response); err != nil {
 		return nil, err
@@ -116,21 +199,15 @@ func (c *AwClient) UpdateEmail(
 }
 
 func (c *AwClient) UpdatePassword(
+	ctx context.Context,
 	id string,
 	r *siogeneric.UpdatePasswordRequest,
 ) (*siogeneric.AwUser, error) {
-	url := fmt.Sprintf("%s/users/%s/password", c.host, id)
-	rJSON, err := json.Marshal(r)
+	req, err := c.adminRequest(ctx, "PATCH", fmt.Sprintf("/users/%s/password", id), r)
 	if err != nil {
 		return nil, err
 	}
 
-	sr := strings.NewReader(string(rJSON))
-	req, _ := http.NewRequest("PATCH", url, sr)
-
-	req.Header = c.defaultHeaders
-	req.Header.Add(constants.AW_HEADER_KEY, c.key)
-
 	response := new(siogeneric.AwUser)
 	if err := c.executeAndParseResponse(req, response); err != nil {
 		return nil, err
@@ -139,20 +216,31 @@ func (c *AwClient) UpdatePassword(
 }
 
 func (c *AwClient) UpdatePhone(
+	ctx context.Context,
 	id string,
 	r *siogeneric.UpdatePhoneRequest,
 ) (*siogeneric.AwUser, error) {
-	url := fmt.Sprintf("%s/users/%s/phone", c.host, id)
-	rJSON, err := json.Marshal(r)
+	req, err := c.adminRequest(ctx, "PATCH", fmt.Sprintf("/users/%s/phone", id), r)
 	if err != nil {
 		return nil, err
 	}
 
-	sr := strings.NewReader(string(rJSON))
-	req, _ := http.NewRequest("PATCH", url, sr)
+	response := new(siogeneric.AwUser)
+	if err := c.executeAndParseResponse(req, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
 
-	req.Header = c.defaultHeaders
-	req.Header.Add(constants.AW_HEADER_KEY, c.key)
+func (c *AwClient) UpdateName(
+	ctx context.Context,
+	id string,
+	r *model.UpdateNameRequest,
+) (*siogeneric.AwUser, error) {
+	req, err := c.adminRequest(ctx, "PATCH", fmt.Sprintf("/users/%s/name", id), r)
+	if err != nil {
+		return nil, err
+	}
 
 	response := new(siogeneric.AwUser)
 	if err := c.executeAndParseResponse(req, response); err != nil {
@@ -161,62 +249,483 @@ func (c *AwClient) UpdatePhone(
 	return response, nil
 }
 
-func (c *AwClient) DeleteUser(id string) error {
-	url := fmt.Sprintf("%s/users/%s", c.host, id)
-	req, _ := http.NewRequest("DELETE", url, nil)
-	req.Header = c.defaultHeaders
-	req.Header.Add(constants.AW_HEADER_KEY, c.key)
+func (c *AwClient) UpdateStatus(
+	ctx context.Context,
+	id string,
+	status bool,
+) (*siogeneric.AwUser, error) {
+	req, err := c.adminRequest(
+		ctx,
+		"PATCH",
+		fmt.Sprintf("/users/%s/status", id),
+		&model.AwUpdateStatusRequest{Status: status},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	response := new(siogeneric.AwUser)
+	if err := c.executeAndParseResponse(req, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (c *AwClient) GetPrefs(ctx context.Context, id string) (model.Prefs, error) {
+	req, err := c.adminRequest(ctx, "GET", fmt.Sprintf("/users/%s/prefs", id), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response := model.Prefs{}
+	if err := c.executeAndParseResponse(req, &response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// UpdatePrefs replaces all of the user's prefs with prefs.
+func (c *AwClient) UpdatePrefs(
+	ctx context.Context,
+	id string,
+	prefs model.Prefs,
+) (model.Prefs, error) {
+	req, err := c.adminRequest(
+		ctx,
+		"PATCH",
+		fmt.Sprintf("/users/%s/prefs", id),
+		&model.AwUpdatePrefsRequest{Prefs: prefs},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	response := model.Prefs{}
+	if err := c.executeAndParseResponse(req, &response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (c *AwClient) GetLabels(ctx context.Context, id string) (*model.AwUserLabels, error) {
+	req, err := c.adminRequest(ctx, "GET", fmt.Sprintf("/users/%s", id), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response := new(model.AwUserLabels)
+	if err := c.executeAndParseResponse(req, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// UpdateLabels replaces all of the user's labels with labels.
+func (c *AwClient) UpdateLabels(
+	ctx context.Context,
+	id string,
+	labels []string,
+) (*model.AwUserLabels, error) {
+	req, err := c.adminRequest(
+		ctx,
+		"PUT",
+		fmt.Sprintf("/users/%s/labels", id),
+		&model.AwUpdateLabelsRequest{Labels: labels},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	response := new(model.AwUserLabels)
+	if err := c.executeAndParseResponse(req, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// GetAccount returns the user owning the session JWT.
+func (c *AwClient) GetAccount(ctx context.Context, jwt string) (*model.AwUserLabels, error) {
+	req, err := c.sessionRequest(ctx, "GET", "/account", jwt, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response := new(model.AwUserLabels)
+	if err := c.executeAndParseResponse(req, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (c *AwClient) DeleteUser(ctx context.Context, id string) error {
+	req, err := c.adminRequest(ctx, "DELETE", fmt.Sprintf("/users/%s", id), nil)
+	if err != nil {
+		return err
+	}
 
 	return c.executeAndParseResponse(req, nil)
 }
 
+// CreateRecovery emails the user a link to recoveryURL carrying the userId and
+// secret needed by UpdateRecovery.
+func (c *AwClient) CreateRecovery(
+	ctx context.Context,
+	r *model.AwRecoveryRequest,
+) (*model.AwToken, error) {
+	r.URL = c.recoveryURL
+	req, err := c.publicRequest(ctx, "POST", "/account/recovery", r)
+	if err != nil {
+		return nil, err
+	}
+
+	response := new(model.AwToken)
+	if err := c.executeAndParseResponse(req, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (c *AwClient) UpdateRecovery(
+	ctx context.Context,
+	r *model.AwRecoveryConfirmRequest,
+) (*model.AwToken, error) {
+	req, err := c.publicRequest(ctx, "PUT", "/account/recovery", r)
+	if err != nil {
+		return nil, err
+	}
+
+	response := new(model.AwToken)
+	if err := c.executeAndParseResponse(req, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// CreateVerification emails the user owning the session JWT a link to
+// verifyURL carrying the userId and secret needed by ConfirmVerification.
+func (c *AwClient) CreateVerification(ctx context.Context, jwt string) (*model.AwToken, error) {
+	req, err := c.sessionRequest(
+		ctx,
+		"POST",
+		"/account/verification",
+		jwt,
+		&model.AwVerificationRequest{URL: c.verifyURL},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	response := new(model.AwToken)
+	if err := c.executeAndParseResponse(req, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (c *AwClient) ConfirmVerification(
+	ctx context.Context,
+	r *model.VerificationConfirmRequest,
+) (*model.AwToken, error) {
+	req, err := c.publicRequest(ctx, "PUT", "/account/verification", r)
+	if err != nil {
+		return nil, err
+	}
+
+	response := new(model.AwToken)
+	if err := c.executeAndParseResponse(req, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// CreatePhoneVerification texts a verification code to the phone number of the
+// user owning the session JWT.
+func (c *AwClient) CreatePhoneVerification(
+	ctx context.Context,
+	jwt string,
+) (*model.AwToken, error) {
+	req, err := c.sessionRequest(ctx, "POST", "/account/verification/phone", jwt, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response := new(model.AwToken)
+	if err := c.executeAndParseResponse(req, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (c *AwClient) ConfirmPhoneVerification(
+	ctx context.Context,
+	r *model.VerificationConfirmRequest,
+) (*model.AwToken, error) {
+	req, err := c.publicRequest(ctx, "PUT", "/account/verification/phone", r)
+	if err != nil {
+		return nil, err
+	}
+
+	response := new(model.AwToken)
+	if err := c.executeAndParseResponse(req, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (c *AwClient) UpdateEmailVerification(
+	ctx context.Context,
+	id string,
+	verified bool,
+) (*siogeneric.AwUser, error) {
+	req, err := c.adminRequest(
+		ctx,
+		"PATCH",
+		fmt.Sprintf("/users/%s/verification", id),
+		&model.AwEmailVerificationStatusRequest{EmailVerification: verified},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	response := new(siogeneric.AwUser)
+	if err := c.executeAndParseResponse(req, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (c *AwClient) UpdatePhoneVerification(
+	ctx context.Context,
+	id string,
+	verified bool,
+) (*siogeneric.AwUser, error) {
+	req, err := c.adminRequest(
+		ctx,
+		"PATCH",
+		fmt.Sprintf("/users/%s/verification/phone", id),
+		&model.AwPhoneVerificationStatusRequest{PhoneVerification: verified},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	response := new(siogeneric.AwUser)
+	if err := c.executeAndParseResponse(req, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
 func (c *AwClient) CreateEmailSession(
+	ctx context.Context,
 	r *siogeneric.AwEmailSessionRequest,
 ) (*siogeneric.AwSession, error) {
-	url := fmt.Sprintf("%s/account/sessions/email", c.host)
-	rJSON, err := json.Marshal(r)
+	req, err := c.publicRequest(ctx, "POST", "/account/sessions/email", r)
 	if err != nil {
 		return nil, err
 	}
 
-	sr := strings.NewReader(string(rJSON))
-	req, _ := http.NewRequest("POST", url, sr)
+	response := new(siogeneric.AwSession)
+	if err := c.executeAndParseResponse(req, response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
 
-	req.Header = c.defaultHeaders
+func (c *AwClient) ListSessions(ctx context.Context, id string) (*model.AwSessionList, error) {
+	req, err := c.adminRequest(ctx, "GET", fmt.Sprintf("/users/%s/sessions", id), nil)
+	if err != nil {
+		return nil, err
+	}
 
-	response := new(siogeneric.AwSession)
+	response := new(model.AwSessionList)
 	if err := c.executeAndParseResponse(req, response); err != nil {
 		return nil, err
 	}
 	return response, nil
 }
 
-func (c *AwClient) DeleteSession(ID, sID string) error {
-	url := fmt.Sprintf("%s/users/%s/sessions/%s", c.host, ID, sID)
-	req, _ := http.NewRequest("DELETE", url, nil)
+func (c *AwClient) DeleteSession(ctx context.Context, ID, sID string) error {
+	req, err := c.adminRequest(ctx, "DELETE", fmt.Sprintf("/users/%s/sessions/%s", ID, sID), nil)
+	if err != nil {
+		return err
+	}
+
+	return c.executeAndParseResponse(req, nil)
+}
 
-	req.Header = c.defaultHeaders
-	req.Header.Add(constants.AW_HEADER_KEY, c.key)
+func (c *AwClient) DeleteSessions(ctx context.Context, id string) error {
+	req, err := c.adminRequest(ctx, "DELETE", fmt.Sprintf("/users/%s/sessions", id), nil)
+	if err != nil {
+		return err
+	}
 
 	return c.executeAndParseResponse(req, nil)
 }
 
+// Health checks Appwrite's HTTP server with the project and API key, so a
+// wrong IAM_KEY fails it too.
+func (c *AwClient) Health(ctx context.Context) error {
+	req, err := c.adminRequest(ctx, "GET", "/health", nil)
+	if err != nil {
+		return err
+	}
+
+	response := new(model.AwHealthStatus)
+	if err := c.executeAndParseResponse(req, response); err != nil {
+		return err
+	}
+	if response.Status != constants.AW_HEALTH_PASS {
+		return fmt.Errorf("appwrite health status %q", response.Status)
+	}
+	return nil
+}
+
+// adminRequest builds a request authenticated with the API key, for the
+// server side users and health APIs, which may act on any user.
+func (c *AwClient) adminRequest(
+	ctx context.Context,
+	method string,
+	path string,
+	body any,
+) (*http.Request, error) {
+	req, err := c.newRequest(ctx, method, path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(constants.AW_HEADER_KEY, c.key)
+	return req, nil
+}
+
+// sessionRequest builds a request for the account API that acts as the user
+// owning the session JWT. It never carries the API key, which would make
+// Appwrite ignore the JWT.
+func (c *AwClient) sessionRequest(
+	ctx context.Context,
+	method string,
+	path string,
+	jwt string,
+	body any,
+) (*http.Request, error) {
+	req, err := c.newRequest(ctx, method, path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(constants.AW_HEADER_JWT, jwt)
+	return req, nil
+}
+
+// publicRequest builds an unauthenticated request for the account API, e.g.
+// to sign in or to confirm a secret a user received.
+func (c *AwClient) publicRequest(
+	ctx context.Context,
+	method string,
+	path string,
+	body any,
+) (*http.Request, error) {
+	return c.newRequest(ctx, method, path, body)
+}
+
+// newRequest builds a request for path, relative to the host, with its own
+// copy of the default headers, so requests can add credentials without
+// affecting each other. A non-nil body is sent as JSON and can be replayed
+// for retries.
+func (c *AwClient) newRequest(
+	ctx context.Context,
+	method string,
+	path string,
+	body any,
+) (*http.Request, error) {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		r = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.host+path, r)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = c.defaultHeaders.Clone()
+	return req, nil
+}
+
+// listUsersQuery translates the list params into Appwrite's search param and
+// queries[] filters.
+func listUsersQuery(p *model.ListUsersParams) url.Values {
+	v := url.Values{}
+	if p == nil {
+		return v
+	}
+
+	if p.Search != "" {
+		v.Set("search", p.Search)
+	}
+
+	queries := []string{}
+	if p.Limit > 0 {
+		queries = append(queries, fmt.Sprintf("limit(%d)", p.Limit))
+	}
+	if p.Offset > 0 {
+		queries = append(queries, fmt.Sprintf("offset(%d)", p.Offset))
+	}
+	if p.Cursor != "" {
+		queries = append(queries, fmt.Sprintf("cursorAfter(%s)", strconv.Quote(p.Cursor)))
+	}
+	if p.Email != "" {
+		queries = append(queries, equalQuery("email", strconv.Quote(p.Email)))
+	}
+	if p.Phone != "" {
+		queries = append(queries, equalQuery("phone", strconv.Quote(p.Phone)))
+	}
+	if p.Name != "" {
+		queries = append(queries, equalQuery("name", strconv.Quote(p.Name)))
+	}
+	if p.Status != nil {
+		queries = append(queries, equalQuery("status", strconv.FormatBool(*p.Status)))
+	}
+	// Appwrite only accepts user attributes in list queries, and registration
+	// is when the user was created.
+	if p.CreatedAfter != "" {
+		queries = append(
+			queries,
+			fmt.Sprintf("greaterThan(\"registration\", [%s])", strconv.Quote(p.CreatedAfter)),
+		)
+	}
+
+	for _, q := range queries {
+		v.Add("queries[]", q)
+	}
+	return v
+}
+
+func equalQuery(attr, value string) string {
+	return fmt.Sprintf("equal(%s, [%s])", strconv.Quote(attr), value)
+}
+
 func (c *AwClient) executeAndParseResponse(
 	req *http.Request,
 	response any,
 ) error {
-	res, err := c.h.ExecuteRequest(req)
+	req, cancel := withCallTimeout(req, c.timeout)
+	defer cancel()
+
+	res, err := c.execute(req)
 	if err != nil {
 		return err
 	}
 
 	if !(res.StatusCode >= 200 && res.StatusCode <= 300) {
+		// The status is authoritative; the body adds Appwrite's error type.
 		errRes := new(siogeneric.AppwriteError)
-		if err := c.h.ParseResponse(res, errRes); err != nil {
-			return err
+		if err := c.h.ParseResponse(res, errRes); err != nil || errRes.Message == "" {
+			errRes.Message = http.StatusText(res.StatusCode)
 		}
 
-		return sioerror.NewSioIamError(errRes)
+		return utils.NewIamError(res.StatusCode, errRes.Type, errRes.Message)
 	} else if response != nil {
 		if err := c.h.ParseResponse(res, response); err != nil {
 			return err
@@ -225,3 +734,60 @@ func (c *AwClient) executeAndParseResponse(
 
 	return nil
 }
+
+// execute sends req through the circuit breaker, retrying transient failures
+// of idempotent requests as c.retry allows.
+func (c *AwClient) execute(req *http.Request) (*http.Response, error) {
+	attempts := c.retry.attempts(req)
+	for attempt := 1; ; attempt++ {
+		if !c.breaker.allow() {
+			return nil, utils.NewIamError(
+				http.StatusServiceUnavailable,
+				constants.ERR_TYPE_PROVIDER_DOWN,
+				constants.ProviderDown,
+			)
+		}
+
+		res, err := c.h.ExecuteRequest(req)
+		if err != nil {
+			if cerr := utils.ContextError(req.Context()); cerr != nil {
+				c.breaker.abandon()
+				return nil, cerr
+			}
+		}
+		failed := transient(res, err)
+		c.breaker.record(!failed)
+		if !failed && attempt > 1 && deletedEarlier(req, res) {
+			discard(res)
+			res = &http.Response{
+				StatusCode: http.StatusNoContent,
+				Status:     http.StatusText(http.StatusNoContent),
+				Body:       http.NoBody,
+				Request:    req,
+			}
+		}
+		if !failed || attempt >= attempts {
+			return res, err
+		}
+
+		next, ok := rewind(req)
+		if !ok {
+			return res, err
+		}
+		discard(res)
+		if err := sleep(req.Context(), c.retry.delay(attempt-1)); err != nil {
+			return nil, utils.ContextError(req.Context())
+		}
+		req = next
+	}
+}
+
+// withCallTimeout bounds a single upstream call by d on top of any deadline
+// the request context already carries. A zero d adds none.
+func withCallTimeout(req *http.Request, d time.Duration) (*http.Request, context.CancelFunc) {
+	if d <= 0 {
+		return req, func() {}
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), d)
+	return req.WithContext(ctx), cancel
+}
@@ -0,0 +1,291 @@
+package client
+
+import (
+	"context"
+
+	"gitea.slauson.io/slausonio/go-types/siogeneric"
+	"gitea.slauson.io/slausonio/iam-ms/config"
+	"gitea.slauson.io/slausonio/iam-ms/model"
+)
+
+// AwProvider is the Appwrite identity provider. It adapts AppwriteClient and
+// its Appwrite types to the provider-neutral model types.
+type AwProvider struct {
+	c AppwriteClient
+}
+
+func NewAwProvider(cfg *config.Config) *AwProvider {
+	return NewAwProviderFor(NewAwClient(cfg))
+}
+
+// NewAwProviderFor adapts c, e.g. a client built with NewAwClientFor.
+func NewAwProviderFor(c AppwriteClient) *AwProvider {
+	return &AwProvider{
+		c: c,
+	}
+}
+
+func (p *AwProvider) ListUsers(
+	ctx context.Context,
+	params *model.ListUsersParams,
+) (*model.UserList, error) {
+	response, err := p.c.ListUsers(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &model.UserList{
+		Total: response.Total,
+		Users: make([]model.User, 0, len(response.Users)),
+	}
+	for i := range response.Users {
+		result.Users = append(result.Users, *toUser(&response.Users[i]))
+	}
+	return result, nil
+}
+
+func (p *AwProvider) GetUserByID(ctx context.Context, id string) (*model.User, error) {
+	return userOrErr(p.c.GetUserByID(ctx, id))
+}
+
+func (p *AwProvider) CreateUser(ctx context.Context, u *model.NewUser) (*model.User, error) {
+	return userOrErr(p.c.CreateUser(ctx, &siogeneric.AwCreateUserRequest{
+		UserID:   u.ID,
+		Email:    u.Email,
+		Phone:    u.Phone,
+		Password: u.Password,
+		Name:     u.Name,
+	}))
+}
+
+func (p *AwProvider) UpdateEmail(
+	ctx context.Context,
+	id string,
+	email string,
+) (*model.User, error) {
+	return userOrErr(p.c.UpdateEmail(ctx, id, &siogeneric.UpdateEmailRequest{Email: email}))
+}
+
+func (p *AwProvider) UpdatePhone(
+	ctx context.Context,
+	id string,
+	number string,
+) (*model.User, error) {
+	return userOrErr(p.c.UpdatePhone(ctx, id, &siogeneric.UpdatePhoneRequest{Number: number}))
+}
+
+func (p *AwProvider) UpdatePassword(
+	ctx context.Context,
+	id string,
+	password string,
+) (*model.User, error) {
+	return userOrErr(
+		p.c.UpdatePassword(ctx, id, &siogeneric.UpdatePasswordRequest{Password: password}),
+	)
+}
+
+func (p *AwProvider) UpdateName(ctx context.Context, id string, name string) (*model.User, error) {
+	return userOrErr(p.c.UpdateName(ctx, id, &model.UpdateNameRequest{Name: name}))
+}
+
+func (p *AwProvider) UpdateStatus(
+	ctx context.Context,
+	id string,
+	status bool,
+) (*model.User, error) {
+	return userOrErr(p.c.UpdateStatus(ctx, id, status))
+}
+
+func (p *AwProvider) UpdateEmailVerification(
+	ctx context.Context,
+	id string,
+	verified bool,
+) (*model.User, error) {
+	return userOrErr(p.c.UpdateEmailVerification(ctx, id, verified))
+}
+
+func (p *AwProvider) UpdatePhoneVerification(
+	ctx context.Context,
+	id string,
+	verified bool,
+) (*model.User, error) {
+	return userOrErr(p.c.UpdatePhoneVerification(ctx, id, verified))
+}
+
+func (p *AwProvider) GetPrefs(ctx context.Context, id string) (model.Prefs, error) {
+	return p.c.GetPrefs(ctx, id)
+}
+
+func (p *AwProvider) UpdatePrefs(
+	ctx context.Context,
+	id string,
+	prefs model.Prefs,
+) (model.Prefs, error) {
+	return p.c.UpdatePrefs(ctx, id, prefs)
+}
+
+func (p *AwProvider) GetLabels(ctx context.Context, id string) (*model.UserLabels, error) {
+	return labelsOrErr(p.c.GetLabels(ctx, id))
+}
+
+func (p *AwProvider) UpdateLabels(
+	ctx context.Context,
+	id string,
+	labels []string,
+) (*model.UserLabels, error) {
+	return labelsOrErr(p.c.UpdateLabels(ctx, id, labels))
+}
+
+func (p *AwProvider) GetSessionUser(ctx context.Context, token string) (*model.UserLabels, error) {
+	return labelsOrErr(p.c.GetAccount(ctx, token))
+}
+
+func (p *AwProvider) DeleteUser(ctx context.Context, id string) error {
+	return p.c.DeleteUser(ctx, id)
+}
+
+func (p *AwProvider) CreateRecovery(ctx context.Context, email string) error {
+	_, err := p.c.CreateRecovery(ctx, &model.AwRecoveryRequest{Email: email})
+	return err
+}
+
+func (p *AwProvider) ConfirmRecovery(
+	ctx context.Context,
+	userID string,
+	secret string,
+	password string,
+) error {
+	_, err := p.c.UpdateRecovery(ctx, &model.AwRecoveryConfirmRequest{
+		UserID:        userID,
+		Secret:        secret,
+		Password:      password,
+		PasswordAgain: password,
+	})
+	return err
+}
+
+func (p *AwProvider) SendEmailVerification(ctx context.Context, token string) error {
+	_, err := p.c.CreateVerification(ctx, token)
+	return err
+}
+
+func (p *AwProvider) ConfirmEmailVerification(
+	ctx context.Context,
+	userID string,
+	secret string,
+) error {
+	_, err := p.c.ConfirmVerification(
+		ctx,
+		&model.VerificationConfirmRequest{UserID: userID, Secret: secret},
+	)
+	return err
+}
+
+func (p *AwProvider) SendPhoneVerification(ctx context.Context, token string) error {
+	_, err := p.c.CreatePhoneVerification(ctx, token)
+	return err
+}
+
+func (p *AwProvider) ConfirmPhoneVerification(
+	ctx context.Context,
+	userID string,
+	secret string,
+) error {
+	_, err := p.c.ConfirmPhoneVerification(
+		ctx,
+		&model.VerificationConfirmRequest{UserID: userID, Secret: secret},
+	)
+	return err
+}
+
+func (p *AwProvider) CreateEmailSession(
+	ctx context.Context,
+	email string,
+	password string,
+) (*model.Session, error) {
+	response, err := p.c.CreateEmailSession(
+		ctx,
+		&siogeneric.AwEmailSessionRequest{Email: email, Password: password},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return toSession(response), nil
+}
+
+func (p *AwProvider) ListSessions(ctx context.Context, id string) ([]model.Session, error) {
+	response, err := p.c.ListSessions(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]model.Session, 0, len(response.Sessions))
+	for i := range response.Sessions {
+		sessions = append(sessions, *toSession(&response.Sessions[i]))
+	}
+	return sessions, nil
+}
+
+func (p *AwProvider) DeleteSession(ctx context.Context, id string, sessionID string) error {
+	return p.c.DeleteSession(ctx, id, sessionID)
+}
+
+func (p *AwProvider) DeleteSessions(ctx context.Context, id string) error {
+	return p.c.DeleteSessions(ctx, id)
+}
+
+func (p *AwProvider) Ping(ctx context.Context) error {
+	return p.c.Health(ctx)
+}
+
+func userOrErr(u *siogeneric.AwUser, err error) (*model.User, error) {
+	if err != nil {
+		return nil, err
+	}
+	return toUser(u), nil
+}
+
+func labelsOrErr(l *model.AwUserLabels, err error) (*model.UserLabels, error) {
+	if err != nil {
+		return nil, err
+	}
+	return &model.UserLabels{ID: l.ID, Labels: l.Labels}, nil
+}
+
+func toUser(u *siogeneric.AwUser) *model.User {
+	return &model.User{
+		ID:                u.ID,
+		CreatedAt:         u.CreatedAt,
+		UpdatedAt:         u.UpdatedAt,
+		Name:              u.Name,
+		Email:             u.Email,
+		Phone:             u.Phone,
+		Status:            u.Status,
+		Registration:      u.Registration,
+		PasswordUpdate:    u.PasswordUpdate,
+		EmailVerification: u.EmailVerification,
+		PhoneVerification: u.PhoneVerification,
+		Prefs:             u.Prefs,
+	}
+}
+
+func toSession(s *siogeneric.AwSession) *model.Session {
+	return &model.Session{
+		ID:            s.ID,
+		CreatedAt:     s.CreatedAt,
+		UserID:        s.UserId,
+		Expire:        s.Expire,
+		Current:       s.Current,
+		Provider:      s.Provider,
+		ClientName:    s.AwClientName,
+		ClientVersion: s.AwClientVersion,
+		DeviceName:    s.DeviceName,
+		DeviceBrand:   s.DeviceBrand,
+		DeviceModel:   s.DeviceModel,
+		OsName:        s.OsName,
+		OsVersion:     s.OsVersion,
+		Ip:            s.Ip,
+		CountryCode:   s.CountryCode,
+		CountryName:   s.CountryName,
+	}
+}
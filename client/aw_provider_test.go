@@ -0,0 +1,244 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"gitea.slauson.io/slausonio/go-types/siogeneric"
+	"gitea.slauson.io/slausonio/iam-ms/client/mocks"
+	"gitea.slauson.io/slausonio/iam-ms/config"
+	"gitea.slauson.io/slausonio/iam-ms/model"
+)
+
+func initProviderForTests(t *testing.T) (*AwProvider, *mocks.AppwriteClient) {
+	c := mocks.NewAppwriteClient(t)
+	return &AwProvider{c: c}, c
+}
+
+func TestNewAwProvider(t *testing.T) {
+	assert.NotNil(t, NewAwProvider(config.Default()))
+}
+
+func TestAwProvider_GetUserByID(t *testing.T) {
+	p, c := initProviderForTests(t)
+
+	c.On("GetUserByID", mock.Anything, "a").Return(&siogeneric.AwUser{
+		ID:    "a",
+		Email: "t@t.com",
+		Hash:  "argon2",
+	}, nil)
+	actual, err := p.GetUserByID(context.Background(), "a")
+	assert.Nil(t, err)
+	assert.Equal(t, &model.User{ID: "a", Email: "t@t.com"}, actual)
+}
+
+func TestAwProvider_GetUserByID_Error(t *testing.T) {
+	p, c := initProviderForTests(t)
+
+	c.On("GetUserByID", mock.Anything, "a").Return(nil, fmt.Errorf("test error"))
+	actual, err := p.GetUserByID(context.Background(), "a")
+	assert.Nil(t, actual)
+	assert.NotNil(t, err)
+}
+
+func TestAwProvider_ListUsers(t *testing.T) {
+	p, c := initProviderForTests(t)
+
+	params := &model.ListUsersParams{Limit: 2}
+	c.On("ListUsers", mock.Anything, params).Return(&siogeneric.AwlistResponse{
+		Total: 2,
+		Users: []siogeneric.AwUser{{ID: "a"}, {ID: "b"}},
+	}, nil)
+	actual, err := p.ListUsers(context.Background(), params)
+	assert.Nil(t, err)
+	assert.Equal(t, &model.UserList{Total: 2, Users: []model.User{{ID: "a"}, {ID: "b"}}}, actual)
+}
+
+func TestAwProvider_CreateUser(t *testing.T) {
+	p, c := initProviderForTests(t)
+
+	c.On("CreateUser", mock.Anything, &siogeneric.AwCreateUserRequest{
+		UserID:   "a",
+		Email:    "t@t.com",
+		Phone:    "+15555555555",
+		Password: "Password123!",
+		Name:     "matt",
+	}).Return(&siogeneric.AwUser{ID: "a"}, nil)
+	actual, err := p.CreateUser(context.Background(), &model.NewUser{
+		ID:       "a",
+		Email:    "t@t.com",
+		Phone:    "+15555555555",
+		Password: "Password123!",
+		Name:     "matt",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "a", actual.ID)
+}
+
+func TestAwProvider_Updates(t *testing.T) {
+	p, c := initProviderForTests(t)
+
+	u := &siogeneric.AwUser{ID: "a"}
+	c.On("UpdateEmail", mock.Anything, "a", &siogeneric.UpdateEmailRequest{Email: "t@t.com"}).
+		Return(u, nil)
+	c.On("UpdatePhone", mock.Anything, "a", &siogeneric.UpdatePhoneRequest{Number: "+1"}).
+		Return(u, nil)
+	c.On("UpdatePassword", mock.Anything, "a", &siogeneric.UpdatePasswordRequest{Password: "p"}).
+		Return(u, nil)
+	c.On("UpdateName", mock.Anything, "a", &model.UpdateNameRequest{Name: "n"}).Return(u, nil)
+	c.On("UpdateStatus", mock.Anything, "a", false).Return(u, nil)
+	c.On("UpdateEmailVerification", mock.Anything, "a", true).Return(u, nil)
+	c.On("UpdatePhoneVerification", mock.Anything, "a", true).Return(nil, fmt.Errorf("test error"))
+
+	ctx := context.Background()
+	calls := map[string]func() (*model.User, error){
+		"UpdateEmail": func() (*model.User, error) {
+			return p.UpdateEmail(ctx, "a", "t@t.com")
+		},
+		"UpdatePhone": func() (*model.User, error) {
+			return p.UpdatePhone(ctx, "a", "+1")
+		},
+		"UpdatePassword": func() (*model.User, error) {
+			return p.UpdatePassword(ctx, "a", "p")
+		},
+		"UpdateName": func() (*model.User, error) {
+			return p.UpdateName(ctx, "a", "n")
+		},
+		"UpdateStatus": func() (*model.User, error) {
+			return p.UpdateStatus(ctx, "a", false)
+		},
+		"UpdateEmailVerification": func() (*model.User, error) {
+			return p.UpdateEmailVerification(ctx, "a", true)
+		},
+	}
+	for name, call := range calls {
+		t.Run(name, func(t *testing.T) {
+			actual, err := call()
+			assert.Nil(t, err)
+			assert.Equal(t, "a", actual.ID)
+		})
+	}
+
+	actual, err := p.UpdatePhoneVerification(context.Background(), "a", true)
+	assert.Nil(t, actual)
+	assert.NotNil(t, err)
+}
+
+func TestAwProvider_Labels(t *testing.T) {
+	p, c := initProviderForTests(t)
+
+	l := &model.AwUserLabels{ID: "a", Labels: []string{"admin"}}
+	c.On("GetLabels", mock.Anything, "a").Return(l, nil)
+	c.On("UpdateLabels", mock.Anything, "a", []string{"admin"}).Return(l, nil)
+	c.On("GetAccount", mock.Anything, "jwt").Return(nil, fmt.Errorf("test error"))
+
+	want := &model.UserLabels{ID: "a", Labels: []string{"admin"}}
+	actual, err := p.GetLabels(context.Background(), "a")
+	assert.Nil(t, err)
+	assert.Equal(t, want, actual)
+
+	actual, err = p.UpdateLabels(context.Background(), "a", []string{"admin"})
+	assert.Nil(t, err)
+	assert.Equal(t, want, actual)
+
+	actual, err = p.GetSessionUser(context.Background(), "jwt")
+	assert.Nil(t, actual)
+	assert.NotNil(t, err)
+}
+
+func TestAwProvider_RecoveryAndVerification(t *testing.T) {
+	p, c := initProviderForTests(t)
+
+	c.On("CreateRecovery", mock.Anything, &model.AwRecoveryRequest{Email: "t@t.com"}).
+		Return(&model.AwToken{}, nil)
+	c.On("UpdateRecovery", mock.Anything, &model.AwRecoveryConfirmRequest{
+		UserID:        "a",
+		Secret:        "s",
+		Password:      "p",
+		PasswordAgain: "p",
+	}).Return(&model.AwToken{}, nil)
+	c.On("CreateVerification", mock.Anything, "jwt").Return(&model.AwToken{}, nil)
+	c.On(
+		"ConfirmVerification",
+		mock.Anything,
+		&model.VerificationConfirmRequest{UserID: "a", Secret: "s"},
+	).
+		Return(&model.AwToken{}, nil)
+	c.On("CreatePhoneVerification", mock.Anything, "jwt").Return(nil, fmt.Errorf("test error"))
+	c.On(
+		"ConfirmPhoneVerification",
+		mock.Anything,
+		&model.VerificationConfirmRequest{UserID: "a", Secret: "s"},
+	).
+		Return(&model.AwToken{}, nil)
+
+	assert.Nil(t, p.CreateRecovery(context.Background(), "t@t.com"))
+	assert.Nil(t, p.ConfirmRecovery(context.Background(), "a", "s", "p"))
+	assert.Nil(t, p.SendEmailVerification(context.Background(), "jwt"))
+	assert.Nil(t, p.ConfirmEmailVerification(context.Background(), "a", "s"))
+	assert.NotNil(t, p.SendPhoneVerification(context.Background(), "jwt"))
+	assert.Nil(t, p.ConfirmPhoneVerification(context.Background(), "a", "s"))
+}
+
+func TestAwProvider_Sessions(t *testing.T) {
+	p, c := initProviderForTests(t)
+
+	aws := siogeneric.AwSession{
+		ID:                  "s",
+		UserId:              "a",
+		AwClientName:        "Chrome",
+		ProviderAccessToken: "secret",
+	}
+	c.On(
+		"CreateEmailSession",
+		mock.Anything,
+		&siogeneric.AwEmailSessionRequest{Email: "t@t.com", Password: "p"},
+	).
+		Return(&aws, nil)
+	c.On("ListSessions", mock.Anything, "a").
+		Return(&model.AwSessionList{Total: 1, Sessions: []siogeneric.AwSession{aws}}, nil)
+	c.On("DeleteSession", mock.Anything, "a", "s").Return(nil)
+	c.On("DeleteSessions", mock.Anything, "a").Return(nil)
+
+	want := model.Session{ID: "s", UserID: "a", ClientName: "Chrome"}
+	session, err := p.CreateEmailSession(context.Background(), "t@t.com", "p")
+	assert.Nil(t, err)
+	assert.Equal(t, &want, session)
+
+	sessions, err := p.ListSessions(context.Background(), "a")
+	assert.Nil(t, err)
+	assert.Equal(t, []model.Session{want}, sessions)
+
+	assert.Nil(t, p.DeleteSession(context.Background(), "a", "s"))
+	assert.Nil(t, p.DeleteSessions(context.Background(), "a"))
+}
+
+func TestAwProvider_Prefs(t *testing.T) {
+	p, c := initProviderForTests(t)
+
+	prefs := model.Prefs{"theme": "dark"}
+	c.On("GetPrefs", mock.Anything, "a").Return(prefs, nil)
+	c.On("UpdatePrefs", mock.Anything, "a", prefs).Return(prefs, nil)
+	c.On("DeleteUser", mock.Anything, "a").Return(nil)
+
+	actual, err := p.GetPrefs(context.Background(), "a")
+	assert.Nil(t, err)
+	assert.Equal(t, prefs, actual)
+
+	actual, err = p.UpdatePrefs(context.Background(), "a", prefs)
+	assert.Nil(t, err)
+	assert.Equal(t, prefs, actual)
+
+	assert.Nil(t, p.DeleteUser(context.Background(), "a"))
+}
+
+func TestAwProvider_Ping(t *testing.T) {
+	p, c := initProviderForTests(t)
+
+	c.On("Health", mock.Anything).Return(fmt.Errorf("down"))
+	assert.NotNil(t, p.Ping(context.Background()))
+}
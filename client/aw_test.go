@@ -2,18 +2,29 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
 	"gitea.slauson.io/slausonio/go-types/siogeneric"
 	"gitea.slauson.io/slausonio/go-utils/sioUtils"
+	"gitea.slauson.io/slausonio/iam-ms/config"
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+	"gitea.slauson.io/slausonio/iam-ms/model"
+	"gitea.slauson.io/slausonio/iam-ms/utils"
 )
 
 func initForTests(t *testing.T) (*AwClient, *sioUtils.MockSioRestHelpers) {
@@ -52,8 +63,17 @@ var (
 )
 
 func TestNewAwClient(t *testing.T) {
-	ac := NewAwClient()
-	assert.NotNilf(t, ac, "expected non-nil AwClient")
+	cfg := config.Default()
+	cfg.Appwrite = config.Appwrite{Host: "https://aw/v1/", Project: "p", Key: "k"}
+	cfg.Upstream.BreakerFailures = 0
+
+	ac := NewAwClient(cfg)
+	assert.Equal(t, "https://aw/v1", ac.host)
+	assert.Equal(t, "k", ac.key)
+	assert.Equal(t, []string{"p"}, ac.defaultHeaders[constants.AW_HEADER_PROJECT_ID])
+	assert.Equal(t, cfg.Upstream.Timeout, ac.timeout)
+	assert.Equal(t, cfg.Upstream.RetryMax, ac.retry.Retries)
+	assert.Nil(t, ac.breaker)
 }
 
 func TestAwClient_ListUsers(t *testing.T) {
@@ -99,11 +119,15 @@ func TestAwClient_ListUsers(t *testing.T) {
 				Return(mockRes, tt.execErr)
 
 			if tt.execErr == nil {
-				h.On("ParseResponse", mock.AnythingOfType("*http.Response"), mock.AnythingOfType("*siogeneric.AwlistResponse")).
+				h.On(
+					"ParseResponse",
+					mock.AnythingOfType("*http.Response"),
+					mock.AnythingOfType("*siogeneric.AwlistResponse"),
+				).
 					Return(tt.ParseErr)
 			}
 
-			result, err := ac.ListUsers()
+			result, err := ac.ListUsers(context.Background(), &model.ListUsersParams{Limit: 10})
 			if tt.happy && result == nil {
 				t.Errorf("expected result but got nil")
 				return
@@ -120,6 +144,48 @@ func TestAwClient_ListUsers(t *testing.T) {
 	}
 }
 
+func TestListUsersQuery(t *testing.T) {
+	status := false
+	tests := []struct {
+		name   string
+		params *model.ListUsersParams
+		want   url.Values
+	}{
+		{name: "nil params", params: nil, want: url.Values{}},
+		{name: "empty params", params: &model.ListUsersParams{}, want: url.Values{}},
+		{
+			name:   "paging and search",
+			params: &model.ListUsersParams{Limit: 10, Offset: 20, Search: "matt"},
+			want: url.Values{
+				"search":    {"matt"},
+				"queries[]": {"limit(10)", "offset(20)"},
+			},
+		},
+		{
+			name: "cursor and filters",
+			params: &model.ListUsersParams{
+				Cursor:       "abc",
+				Email:        "t@t.com",
+				Status:       &status,
+				CreatedAfter: "2023-01-01T00:00:00Z",
+			},
+			want: url.Values{
+				"queries[]": {
+					`cursorAfter("abc")`,
+					`equal("email", ["t@t.com"])`,
+					`equal("status", [false])`,
+					`greaterThan("registration", ["2023-01-01T00:00:00Z"])`,
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, listUsersQuery(tt.params))
+		})
+	}
+}
+
 func TestAwClient_GetUserByID(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -153,11 +219,15 @@ func TestAwClient_GetUserByID(t *testing.T) {
 				Return(mockRes, tt.execErr)
 
 			if tt.execErr == nil {
-				h.On("ParseResponse", mock.AnythingOfType("*http.Response"), mock.AnythingOfType("*siogeneric.AwUser")).
+				h.On(
+					"ParseResponse",
+					mock.AnythingOfType("*http.Response"),
+					mock.AnythingOfType("*siogeneric.AwUser"),
+				).
 					Return(tt.parseErr)
 			}
 
-			result, err := ac.GetUserByID("test")
+			result, err := ac.GetUserByID(context.Background(), "test")
 			if tt.happy && result == nil {
 				t.Errorf("expected result but got nil")
 				return
@@ -174,6 +244,41 @@ func TestAwClient_GetUserByID(t *testing.T) {
 	}
 }
 
+func TestAwClient_GetUserByID_Timeout(t *testing.T) {
+	ac, h := initForTests(t)
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now())
+	defer cancel()
+
+	h.On("ExecuteRequest", mock.AnythingOfType("*http.Request")).
+		Return(nil, context.DeadlineExceeded)
+
+	result, err := ac.GetUserByID(ctx, "test")
+	assert.Nil(t, result)
+	assert.Equal(t, constants.RequestTimeout, err.Error())
+}
+
+func TestAwClient_GetUserByID_AppwriteError(t *testing.T) {
+	ac, h := initForTests(t)
+
+	h.On("ExecuteRequest", mock.AnythingOfType("*http.Request")).
+		Return(mockHttpResponse(t, nil, http.StatusNotFound), nil)
+	h.On(
+		"ParseResponse",
+		mock.AnythingOfType("*http.Response"),
+		mock.AnythingOfType("*siogeneric.AppwriteError"),
+	).Run(func(args mock.Arguments) {
+		*args.Get(1).(*siogeneric.AppwriteError) = siogeneric.AppwriteError{
+			Message: "User with the requested ID could not be found.",
+			Code:    http.StatusNotFound,
+			Type:    "user_not_found",
+		}
+	}).Return(nil)
+
+	_, err := ac.GetUserByID(context.Background(), "a")
+	assertIamError(t, err, http.StatusNotFound, constants.ERR_TYPE_USER_NOT_FOUND)
+	assert.Equal(t, constants.NoUserFound, err.Error())
+}
+
 func TestAwClient_CreateUser(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -207,11 +312,15 @@ func TestAwClient_CreateUser(t *testing.T) {
 				Return(mockRes, tt.execErr)
 
 			if tt.execErr == nil {
-				h.On("ParseResponse", mock.AnythingOfType("*http.Response"), mock.AnythingOfType("*siogeneric.AwUser")).
+				h.On(
+					"ParseResponse",
+					mock.AnythingOfType("*http.Response"),
+					mock.AnythingOfType("*siogeneric.AwUser"),
+				).
 					Return(tt.parseErr)
 			}
 
-			result, err := ac.CreateUser(mCr)
+			result, err := ac.CreateUser(context.Background(), mCr)
 			if tt.happy && result == nil {
 				t.Errorf("expected result but got nil")
 				return
@@ -261,11 +370,16 @@ func TestAwClient_UpdatePassword(t *testing.T) {
 				Return(mockRes, tt.execErr)
 
 			if tt.execErr == nil {
-				h.On("ParseResponse", mock.AnythingOfType("*http.Response"), mock.AnythingOfType("*siogeneric.AwUser")).
+				h.On(
+					"ParseResponse",
+					mock.AnythingOfType("*http.Response"),
+					mock.AnythingOfType("*siogeneric.AwUser"),
+				).
 					Return(tt.parseErr)
 			}
 
 			result, err := ac.UpdatePassword(
+				context.Background(),
 				"test",
 				&siogeneric.UpdatePasswordRequest{Password: "test"},
 			)
@@ -318,10 +432,79 @@ func TestAwClient_UpdatePhone(t *testing.T) {
 				Return(mockRes, tt.execErr)
 
 			if tt.execErr == nil {
-				h.On("ParseResponse", mock.AnythingOfType("*http.Response"), mock.AnythingOfType("*siogeneric.AwUser")).
+				h.On(
+					"ParseResponse",
+					mock.AnythingOfType("*http.Response"),
+					mock.AnythingOfType("*siogeneric.AwUser"),
+				).
+					Return(tt.parseErr)
+			}
+			result, err := ac.UpdatePhone(
+				context.Background(),
+				"123",
+				&siogeneric.UpdatePhoneRequest{Number: "123"},
+			)
+			if tt.happy && result == nil {
+				t.Errorf("expected result but got nil")
+				return
+			} else if err == nil && !tt.happy {
+				if tt.happy && err != nil {
+					t.Errorf("error during create() error = %v", err)
+					return
+				} else if err == nil && !tt.happy {
+					t.Errorf("expected error but got nil")
+					return
+				}
+			}
+		})
+	}
+}
+
+func TestAwClient_UpdateName(t *testing.T) {
+	tests := []struct {
+		name     string
+		happy    bool
+		execErr  error
+		parseErr error
+		code     int
+	}{
+		{name: "Happy Path", happy: true, execErr: nil, parseErr: nil, code: http.StatusOK},
+		{
+			name:     "ExecErr",
+			happy:    false,
+			execErr:  fmt.Errorf("test error"),
+			parseErr: nil,
+			code:     http.StatusInternalServerError,
+		},
+		{
+			name:     "ParseErr",
+			happy:    false,
+			execErr:  fmt.Errorf("test error"),
+			parseErr: nil,
+			code:     http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ac, h := initForTests(t)
+
+			mockRes := mockHttpResponse(t, mAwUser, tt.code)
+			h.On("ExecuteRequest", mock.AnythingOfType("*http.Request")).
+				Return(mockRes, tt.execErr)
+
+			if tt.execErr == nil {
+				h.On(
+					"ParseResponse",
+					mock.AnythingOfType("*http.Response"),
+					mock.AnythingOfType("*siogeneric.AwUser"),
+				).
 					Return(tt.parseErr)
 			}
-			result, err := ac.UpdatePhone("123", &siogeneric.UpdatePhoneRequest{Number: "123"})
+			result, err := ac.UpdateName(
+				context.Background(),
+				"123",
+				&model.UpdateNameRequest{Name: "test"},
+			)
 			if tt.happy && result == nil {
 				t.Errorf("expected result but got nil")
 				return
@@ -371,10 +554,18 @@ func TestAwClient_UpdateEmail(t *testing.T) {
 				Return(mockRes, tt.execErr)
 
 			if tt.execErr == nil {
-				h.On("ParseResponse", mock.AnythingOfType("*http.Response"), mock.AnythingOfType("*siogeneric.AwUser")).
+				h.On(
+					"ParseResponse",
+					mock.AnythingOfType("*http.Response"),
+					mock.AnythingOfType("*siogeneric.AwUser"),
+				).
 					Return(tt.parseErr)
 			}
-			result, err := ac.UpdateEmail("123", &siogeneric.UpdateEmailRequest{Email: "a@a.com"})
+			result, err := ac.UpdateEmail(
+				context.Background(),
+				"123",
+				&siogeneric.UpdateEmailRequest{Email: "a@a.com"},
+			)
 			if tt.happy && result == nil {
 				t.Errorf("expected result but got nil")
 				return
@@ -425,7 +616,7 @@ func TestAwClient_DeleteUser(t *testing.T) {
 			h.On("ExecuteRequest", mock.AnythingOfType("*http.Request")).
 				Return(mockRes, tt.execErr)
 
-			err := ac.DeleteUser("a")
+			err := ac.DeleteUser(context.Background(), "a")
 			if tt.result != nil && err != nil {
 				t.Errorf("expected request to resolve but got error %v", err)
 				return
@@ -470,10 +661,14 @@ func TestAwClient_CreateEmailSession(t *testing.T) {
 				Return(mockRes, tt.execErr)
 
 			if tt.execErr == nil {
-				h.On("ParseResponse", mock.AnythingOfType("*http.Response"), mock.AnythingOfType("*siogeneric.AwSession")).
+				h.On(
+					"ParseResponse",
+					mock.AnythingOfType("*http.Response"),
+					mock.AnythingOfType("*siogeneric.AwSession"),
+				).
 					Return(tt.parseErr)
 			}
-			result, err := ac.CreateEmailSession(sessionReq)
+			result, err := ac.CreateEmailSession(context.Background(), sessionReq)
 			if tt.happy && result == nil {
 				t.Errorf("expected result but got nil")
 				return
@@ -521,7 +716,7 @@ func TestAwClient_DeleteUserSession(t *testing.T) {
 			h.On("ExecuteRequest", mock.AnythingOfType("*http.Request")).
 				Return(mockRes, tt.execErr)
 
-			err := ac.DeleteSession("1", "a")
+			err := ac.DeleteSession(context.Background(), "1", "a")
 			if tt.result != nil && err != nil {
 				t.Errorf("expected request to resolve but got error %v", err)
 				return
@@ -533,15 +728,665 @@ func TestAwClient_DeleteUserSession(t *testing.T) {
 	}
 }
 
-func mockHttpResponse(t *testing.T, v any, code int) *http.Response {
-	jsonData, err := json.Marshal(v)
-	if err != nil {
-		t.Fatalf("Error marshaling struct: %v\n", err)
+func TestAwClient_ListSessions(t *testing.T) {
+	tests := []struct {
+		name     string
+		happy    bool
+		execErr  error
+		parseErr error
+		code     int
+	}{
+		{name: "Happy Path", happy: true, execErr: nil, parseErr: nil, code: http.StatusOK},
+		{
+			name:     "Exec Error",
+			happy:    false,
+			execErr:  fmt.Errorf("test error"),
+			parseErr: nil,
+			code:     http.StatusInternalServerError,
+		},
+		{
+			name:     "Parse Error",
+			happy:    false,
+			execErr:  nil,
+			parseErr: fmt.Errorf("test error"),
+			code:     http.StatusOK,
+		},
 	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ac, h := initForTests(t)
 
-	response := &http.Response{
-		StatusCode: code,
-		Body:       io.NopCloser(bytes.NewBuffer(jsonData)),
+			mockRes := mockHttpResponse(t, model.AwSessionList{}, tt.code)
+			h.On("ExecuteRequest", mock.AnythingOfType("*http.Request")).
+				Return(mockRes, tt.execErr)
+
+			if tt.execErr == nil {
+				h.On(
+					"ParseResponse",
+					mock.AnythingOfType("*http.Response"),
+					mock.AnythingOfType("*model.AwSessionList"),
+				).
+					Return(tt.parseErr)
+			}
+
+			result, err := ac.ListSessions(context.Background(), "test")
+			if tt.happy && result == nil {
+				t.Errorf("expected result but got nil")
+				return
+			} else if err == nil && !tt.happy {
+				t.Errorf("expected error but got nil")
+				return
+			}
+		})
+	}
+}
+
+func TestAwClient_DeleteSessions(t *testing.T) {
+	tests := []struct {
+		name    string
+		happy   bool
+		execErr error
+		code    int
+	}{
+		{name: "Happy Path", happy: true, execErr: nil, code: http.StatusNoContent},
+		{
+			name:    "ExecErr",
+			happy:   false,
+			execErr: fmt.Errorf("test error"),
+			code:    http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ac, h := initForTests(t)
+
+			mockRes := mockHttpResponse(t, mAwUser, tt.code)
+			h.On("ExecuteRequest", mock.AnythingOfType("*http.Request")).
+				Return(mockRes, tt.execErr)
+
+			err := ac.DeleteSessions(context.Background(), "1")
+			if tt.happy && err != nil {
+				t.Errorf("expected request to resolve but got error %v", err)
+				return
+			} else if !tt.happy && err == nil {
+				t.Error("expected error to resolve but got result")
+				return
+			}
+		})
+	}
+}
+
+func TestAwClient_CreateRecovery(t *testing.T) {
+	tests := []struct {
+		name     string
+		happy    bool
+		execErr  error
+		parseErr error
+		code     int
+	}{
+		{name: "Happy Path", happy: true, execErr: nil, parseErr: nil, code: http.StatusCreated},
+		{
+			name:     "ExecErr",
+			happy:    false,
+			execErr:  fmt.Errorf("test error"),
+			parseErr: nil,
+			code:     http.StatusInternalServerError,
+		},
+		{
+			name:     "ParseErr",
+			happy:    false,
+			execErr:  nil,
+			parseErr: fmt.Errorf("test error"),
+			code:     http.StatusCreated,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ac, h := initForTests(t)
+			ac.recoveryURL = "https://blog.slauson.io/recovery"
+
+			mockRes := mockHttpResponse(t, model.AwToken{}, tt.code)
+			h.On("ExecuteRequest", mock.AnythingOfType("*http.Request")).
+				Return(mockRes, tt.execErr)
+
+			if tt.execErr == nil {
+				h.On(
+					"ParseResponse",
+					mock.AnythingOfType("*http.Response"),
+					mock.AnythingOfType("*model.AwToken"),
+				).
+					Return(tt.parseErr)
+			}
+
+			r := &model.AwRecoveryRequest{Email: "t@t.com"}
+			result, err := ac.CreateRecovery(context.Background(), r)
+			assert.Equal(t, ac.recoveryURL, r.URL)
+			if tt.happy && result == nil {
+				t.Errorf("expected result but got nil")
+				return
+			} else if err == nil && !tt.happy {
+				t.Errorf("expected error but got nil")
+				return
+			}
+		})
+	}
+}
+
+func TestAwClient_UpdateRecovery(t *testing.T) {
+	tests := []struct {
+		name     string
+		happy    bool
+		execErr  error
+		parseErr error
+		code     int
+	}{
+		{name: "Happy Path", happy: true, execErr: nil, parseErr: nil, code: http.StatusOK},
+		{
+			name:     "ExecErr",
+			happy:    false,
+			execErr:  fmt.Errorf("test error"),
+			parseErr: nil,
+			code:     http.StatusInternalServerError,
+		},
+		{
+			name:     "ParseErr",
+			happy:    false,
+			execErr:  nil,
+			parseErr: fmt.Errorf("test error"),
+			code:     http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ac, h := initForTests(t)
+
+			mockRes := mockHttpResponse(t, model.AwToken{}, tt.code)
+			h.On("ExecuteRequest", mock.AnythingOfType("*http.Request")).
+				Return(mockRes, tt.execErr)
+
+			if tt.execErr == nil {
+				h.On(
+					"ParseResponse",
+					mock.AnythingOfType("*http.Response"),
+					mock.AnythingOfType("*model.AwToken"),
+				).
+					Return(tt.parseErr)
+			}
+
+			result, err := ac.UpdateRecovery(context.Background(), &model.AwRecoveryConfirmRequest{
+				UserID:        "a",
+				Secret:        "b",
+				Password:      "Fake@123",
+				PasswordAgain: "Fake@123",
+			})
+			if tt.happy && result == nil {
+				t.Errorf("expected result but got nil")
+				return
+			} else if err == nil && !tt.happy {
+				t.Errorf("expected error but got nil")
+				return
+			}
+		})
+	}
+}
+
+func TestAwClient_CreateVerification(t *testing.T) {
+	tests := []struct {
+		name     string
+		happy    bool
+		execErr  error
+		parseErr error
+		code     int
+	}{
+		{name: "Happy Path", happy: true, execErr: nil, parseErr: nil, code: http.StatusCreated},
+		{
+			name:     "ExecErr",
+			happy:    false,
+			execErr:  fmt.Errorf("test error"),
+			parseErr: nil,
+			code:     http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ac, h := initForTests(t)
+
+			mockRes := mockHttpResponse(t, model.AwToken{}, tt.code)
+			h.On("ExecuteRequest", mock.MatchedBy(func(r *http.Request) bool {
+				return r.Header.Get("X-Appwrite-JWT") == "jwt"
+			})).Return(mockRes, tt.execErr)
+
+			if tt.execErr == nil {
+				h.On(
+					"ParseResponse",
+					mock.AnythingOfType("*http.Response"),
+					mock.AnythingOfType("*model.AwToken"),
+				).
+					Return(tt.parseErr)
+			}
+
+			result, err := ac.CreateVerification(context.Background(), "jwt")
+			if tt.happy && result == nil {
+				t.Errorf("expected result but got nil")
+				return
+			} else if err == nil && !tt.happy {
+				t.Errorf("expected error but got nil")
+				return
+			}
+		})
+	}
+}
+
+func TestAwClient_UpdateEmailVerification(t *testing.T) {
+	tests := []struct {
+		name     string
+		happy    bool
+		execErr  error
+		parseErr error
+		code     int
+	}{
+		{name: "Happy Path", happy: true, execErr: nil, parseErr: nil, code: http.StatusOK},
+		{
+			name:     "ExecErr",
+			happy:    false,
+			execErr:  fmt.Errorf("test error"),
+			parseErr: nil,
+			code:     http.StatusInternalServerError,
+		},
+		{
+			name:     "ParseErr",
+			happy:    false,
+			execErr:  nil,
+			parseErr: fmt.Errorf("test error"),
+			code:     http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ac, h := initForTests(t)
+
+			mockRes := mockHttpResponse(t, mAwUser, tt.code)
+			h.On("ExecuteRequest", mock.AnythingOfType("*http.Request")).
+				Return(mockRes, tt.execErr)
+
+			if tt.execErr == nil {
+				h.On(
+					"ParseResponse",
+					mock.AnythingOfType("*http.Response"),
+					mock.AnythingOfType("*siogeneric.AwUser"),
+				).
+					Return(tt.parseErr)
+			}
+
+			result, err := ac.UpdateEmailVerification(context.Background(), "test", true)
+			if tt.happy && result == nil {
+				t.Errorf("expected result but got nil")
+				return
+			} else if err == nil && !tt.happy {
+				t.Errorf("expected error but got nil")
+				return
+			}
+		})
+	}
+}
+
+func TestAwClient_UpdateStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		happy    bool
+		execErr  error
+		parseErr error
+		code     int
+	}{
+		{name: "Happy Path", happy: true, execErr: nil, parseErr: nil, code: http.StatusOK},
+		{
+			name:     "ExecErr",
+			happy:    false,
+			execErr:  fmt.Errorf("test error"),
+			parseErr: nil,
+			code:     http.StatusInternalServerError,
+		},
+		{
+			name:     "ParseErr",
+			happy:    false,
+			execErr:  nil,
+			parseErr: fmt.Errorf("test error"),
+			code:     http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ac, h := initForTests(t)
+
+			mockRes := mockHttpResponse(t, mAwUser, tt.code)
+			h.On("ExecuteRequest", mock.AnythingOfType("*http.Request")).
+				Return(mockRes, tt.execErr)
+
+			if tt.execErr == nil {
+				h.On(
+					"ParseResponse",
+					mock.AnythingOfType("*http.Response"),
+					mock.AnythingOfType("*siogeneric.AwUser"),
+				).
+					Return(tt.parseErr)
+			}
+
+			result, err := ac.UpdateStatus(context.Background(), "test", false)
+			if tt.happy && result == nil {
+				t.Errorf("expected result but got nil")
+				return
+			} else if err == nil && !tt.happy {
+				t.Errorf("expected error but got nil")
+				return
+			}
+		})
+	}
+}
+
+func TestAwClient_GetPrefs(t *testing.T) {
+	tests := []struct {
+		name     string
+		happy    bool
+		execErr  error
+		parseErr error
+		code     int
+	}{
+		{name: "Happy Path", happy: true, execErr: nil, parseErr: nil, code: http.StatusOK},
+		{
+			name:     "ExecErr",
+			happy:    false,
+			execErr:  fmt.Errorf("test error"),
+			parseErr: nil,
+			code:     http.StatusInternalServerError,
+		},
+		{
+			name:     "ParseErr",
+			happy:    false,
+			execErr:  nil,
+			parseErr: fmt.Errorf("test error"),
+			code:     http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ac, h := initForTests(t)
+
+			mockRes := mockHttpResponse(t, model.Prefs{"theme": "dark"}, tt.code)
+			h.On("ExecuteRequest", mock.AnythingOfType("*http.Request")).
+				Return(mockRes, tt.execErr)
+
+			if tt.execErr == nil {
+				h.On(
+					"ParseResponse",
+					mock.AnythingOfType("*http.Response"),
+					mock.AnythingOfType("*model.Prefs"),
+				).
+					Return(tt.parseErr)
+			}
+
+			result, err := ac.GetPrefs(context.Background(), "test")
+			if tt.happy && result == nil {
+				t.Errorf("expected result but got nil")
+				return
+			} else if err == nil && !tt.happy {
+				t.Errorf("expected error but got nil")
+				return
+			}
+		})
+	}
+}
+
+func TestAwClient_UpdatePrefs(t *testing.T) {
+	tests := []struct {
+		name     string
+		happy    bool
+		execErr  error
+		parseErr error
+		code     int
+	}{
+		{name: "Happy Path", happy: true, execErr: nil, parseErr: nil, code: http.StatusOK},
+		{
+			name:     "ExecErr",
+			happy:    false,
+			execErr:  fmt.Errorf("test error"),
+			parseErr: nil,
+			code:     http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ac, h := initForTests(t)
+
+			mockRes := mockHttpResponse(t, model.Prefs{"theme": "dark"}, tt.code)
+			h.On("ExecuteRequest", mock.AnythingOfType("*http.Request")).
+				Return(mockRes, tt.execErr)
+
+			if tt.execErr == nil {
+				h.On(
+					"ParseResponse",
+					mock.AnythingOfType("*http.Response"),
+					mock.AnythingOfType("*model.Prefs"),
+				).
+					Return(tt.parseErr)
+			}
+
+			result, err := ac.UpdatePrefs(
+				context.Background(),
+				"test",
+				model.Prefs{"theme": "dark"},
+			)
+			if tt.happy && result == nil {
+				t.Errorf("expected result but got nil")
+				return
+			} else if err == nil && !tt.happy {
+				t.Errorf("expected error but got nil")
+				return
+			}
+		})
+	}
+}
+
+func TestAwClient_Labels(t *testing.T) {
+	tests := []struct {
+		name     string
+		happy    bool
+		execErr  error
+		parseErr error
+		code     int
+	}{
+		{name: "Happy Path", happy: true, execErr: nil, parseErr: nil, code: http.StatusOK},
+		{
+			name:     "ExecErr",
+			happy:    false,
+			execErr:  fmt.Errorf("test error"),
+			parseErr: nil,
+			code:     http.StatusInternalServerError,
+		},
+		{
+			name:     "ParseErr",
+			happy:    false,
+			execErr:  nil,
+			parseErr: fmt.Errorf("test error"),
+			code:     http.StatusOK,
+		},
+	}
+	calls := map[string]func(ac *AwClient) (*model.AwUserLabels, error){
+		"GetLabels": func(ac *AwClient) (*model.AwUserLabels, error) {
+			return ac.GetLabels(context.Background(), "test")
+		},
+		"UpdateLabels": func(ac *AwClient) (*model.AwUserLabels, error) {
+			return ac.UpdateLabels(context.Background(), "test", []string{"admin"})
+		},
+		"GetAccount": func(ac *AwClient) (*model.AwUserLabels, error) {
+			return ac.GetAccount(context.Background(), "jwt")
+		},
+	}
+	for method, call := range calls {
+		for _, tt := range tests {
+			t.Run(method+" "+tt.name, func(t *testing.T) {
+				ac, h := initForTests(t)
+
+				mockRes := mockHttpResponse(t, model.AwUserLabels{ID: "test"}, tt.code)
+				h.On("ExecuteRequest", mock.AnythingOfType("*http.Request")).
+					Return(mockRes, tt.execErr)
+
+				if tt.execErr == nil {
+					h.On(
+						"ParseResponse",
+						mock.AnythingOfType("*http.Response"),
+						mock.AnythingOfType("*model.AwUserLabels"),
+					).
+						Return(tt.parseErr)
+				}
+
+				result, err := call(ac)
+				if tt.happy && result == nil {
+					t.Errorf("expected result but got nil")
+					return
+				} else if err == nil && !tt.happy {
+					t.Errorf("expected error but got nil")
+					return
+				}
+			})
+		}
+	}
+}
+
+func mockHttpResponse(t *testing.T, v any, code int) *http.Response {
+	jsonData, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Error marshaling struct: %v\n", err)
+	}
+
+	response := &http.Response{
+		StatusCode: code,
+		Body:       io.NopCloser(bytes.NewBuffer(jsonData)),
+	}
+	return response
+}
+
+func TestAwClient_Health(t *testing.T) {
+	tests := []struct {
+		name   string
+		status string
+		code   int
+		want   bool
+	}{
+		{name: "Pass", status: constants.AW_HEALTH_PASS, code: http.StatusOK, want: true},
+		{name: "Fail", status: "fail", code: http.StatusOK},
+		{name: "Unauthorized", code: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ac, h := initForTests(t)
+
+			var got *http.Request
+			h.On("ExecuteRequest", mock.AnythingOfType("*http.Request")).
+				Run(func(args mock.Arguments) { got = args.Get(0).(*http.Request) }).
+				Return(mockHttpResponse(t, nil, tt.code), nil)
+			h.On("ParseResponse", mock.AnythingOfType("*http.Response"), mock.Anything).
+				Run(func(args mock.Arguments) {
+					if s, ok := args.Get(1).(*model.AwHealthStatus); ok {
+						s.Status = tt.status
+					}
+				}).Return(nil)
+
+			err := ac.Health(context.Background())
+			assert.Equal(t, tt.want, err == nil, "error: %v", err)
+			assert.Equal(t, "/v1/health", got.URL.Path)
+			assert.NotEmpty(t, got.Header.Get(constants.AW_HEADER_KEY))
+		})
+	}
+}
+
+func TestAwClient_RequestAuth(t *testing.T) {
+	ac, _ := initForTests(t)
+	ctx := context.Background()
+
+	admin, err := ac.adminRequest(ctx, "GET", "/users", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"test"}, admin.Header.Values(constants.AW_HEADER_KEY))
+	assert.Empty(t, admin.Header.Get(constants.AW_HEADER_JWT))
+
+	session, err := ac.sessionRequest(ctx, "GET", "/account", "jwt", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "jwt", session.Header.Get(constants.AW_HEADER_JWT))
+	assert.Empty(t, session.Header.Values(constants.AW_HEADER_KEY))
+
+	public, err := ac.publicRequest(ctx, "POST", "/account/sessions/email", sessionReq)
+	assert.Nil(t, err)
+	assert.Empty(t, public.Header.Values(constants.AW_HEADER_KEY))
+	assert.Equal(t, "fake", public.Header.Get(constants.AW_HEADER_PROJECT_ID))
+	assert.Equal(t, "http://localhost:8080/v1/account/sessions/email", public.URL.String())
+	b, _ := public.GetBody()
+	body, _ := io.ReadAll(b)
+	assert.JSONEq(t, `{"email":"test","password":"test"}`, string(body))
+
+	assert.Len(t, ac.defaultHeaders, 2, "the default headers are left alone")
+	assert.Empty(t, ac.defaultHeaders.Get(constants.AW_HEADER_KEY))
+}
+
+func TestAwClient_ConcurrentRequests(t *testing.T) {
+	var violations atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys := r.Header.Values(constants.AW_HEADER_KEY)
+		jwt := r.Header.Get(constants.AW_HEADER_JWT)
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/v1/users"):
+			if len(keys) != 1 || keys[0] != "key" || jwt != "" {
+				violations.Add(1)
+			}
+			_ = json.NewEncoder(w).Encode(mAwUser)
+		case r.URL.Path == "/v1/account":
+			if len(keys) != 0 || jwt != "jwt" {
+				violations.Add(1)
+			}
+			_ = json.NewEncoder(w).Encode(model.AwUserLabels{ID: "a"})
+		default:
+			if len(keys) != 0 || jwt != "" {
+				violations.Add(1)
+			}
+			_ = json.NewEncoder(w).Encode(siogeneric.AwSession{ID: "s"})
+		}
+	}))
+	defer srv.Close()
+
+	ac := NewAwClientFor(srv.URL+"/v1", "p", "key")
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			_, err := ac.GetUserByID(ctx, "a")
+			assert.Nil(t, err)
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := ac.GetAccount(ctx, "jwt")
+			assert.Nil(t, err)
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := ac.CreateEmailSession(ctx, &siogeneric.AwEmailSessionRequest{
+				Email:    "t@t.com",
+				Password: "Password123!",
+			})
+			assert.Nil(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(0), violations.Load())
+	assert.Empty(t, ac.defaultHeaders.Values(constants.AW_HEADER_KEY))
+}
+
+func assertIamError(t *testing.T, err error, status int, errType string) {
+	t.Helper()
+	var ie *utils.IamError
+	if assert.ErrorAs(t, err, &ie) {
+		assert.Equal(t, status, ie.Status)
+		assert.Equal(t, errType, ie.Type)
 	}
-	return response
 }
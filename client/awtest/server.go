@@ -0,0 +1,655 @@
+// Package awtest provides an in-process fake of the parts of the Appwrite API
+// used by client.AwClient, so tests can run offline and deterministically.
+package awtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gitea.slauson.io/slausonio/go-types/siogeneric"
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+	"gitea.slauson.io/slausonio/iam-ms/model"
+)
+
+const (
+	// Project and Key are the credentials the fake accepts.
+	Project = "awtest"
+	Key     = "awtest-key"
+
+	version    = "1.4.13"
+	timeLayout = "2006-01-02T15:04:05.000-07:00"
+)
+
+var queryPattern = regexp.MustCompile(`^(\w+)\((.*)\)$`)
+
+type user struct {
+	siogeneric.AwUser
+	Labels []string `json:"labels"`
+}
+
+type token struct {
+	userID string
+	kind   string
+}
+
+// Server is a fake Appwrite API. Users, sessions, JWTs and recovery or
+// verification secrets live in memory and IDs are handed out in sequence.
+type Server struct {
+	*httptest.Server
+
+	// Now is the fake's clock. It defaults to a fixed instant that advances
+	// one second per call.
+	Now func() time.Time
+
+	mu        sync.Mutex
+	seq       int
+	users     []*user
+	passwords map[string]string
+	sessions  []*siogeneric.AwSession
+	jwts      map[string]string
+	secrets   map[string]token
+	last      string
+}
+
+// NewServer starts a fake. Point client.NewAwClientFor at Host with Project
+// and Key, and call Close when done.
+func NewServer() *Server {
+	s := &Server{
+		passwords: map[string]string{},
+		jwts:      map[string]string{},
+		secrets:   map[string]token{},
+	}
+	clock := time.Date(2023, 11, 14, 22, 13, 20, 0, time.UTC)
+	s.Now = func() time.Time {
+		clock = clock.Add(time.Second)
+		return clock
+	}
+	s.Server = httptest.NewServer(s)
+	return s
+}
+
+// Host is the API endpoint, the equivalent of IAM_HOST.
+func (s *Server) Host() string {
+	return s.URL + "/v1"
+}
+
+// JWT returns a session JWT for userID, as the Appwrite web SDK would create.
+func (s *Server) JWT(userID string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jwt := "jwt-" + s.nextID()
+	s.jwts[jwt] = userID
+	return jwt
+}
+
+// LastSecret returns the most recent recovery or verification secret, which
+// Appwrite would have emailed or texted.
+func (s *Server) LastSecret() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.last
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.Header.Get(constants.AW_HEADER_PROJECT_ID) != Project {
+		writeError(w, http.StatusNotFound, "project_not_found",
+			"Project with the requested ID could not be found.")
+		return
+	}
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1"), "/"), "/")
+	switch parts[0] {
+	case "users":
+		if r.Header.Get(constants.AW_HEADER_KEY) != Key {
+			writeError(w, http.StatusUnauthorized, "general_unauthorized_scope",
+				"The current user is not authorized to perform the requested action.")
+			return
+		}
+		s.serveUsers(w, r, parts[1:])
+	case "account":
+		s.serveAccount(w, r, strings.Join(parts[1:], "/"))
+	case "health":
+		if r.Header.Get(constants.AW_HEADER_KEY) != Key {
+			writeError(w, http.StatusUnauthorized, "general_unauthorized_scope",
+				"The current user is not authorized to perform the requested action.")
+			return
+		}
+		writeJSON(w, http.StatusOK, model.AwHealthStatus{
+			Name:   "http",
+			Status: constants.AW_HEALTH_PASS,
+		})
+	default:
+		writeError(w, http.StatusNotFound, "general_route_not_found",
+			"The requested route was not found.")
+	}
+}
+
+func (s *Server) serveUsers(w http.ResponseWriter, r *http.Request, parts []string) {
+	if len(parts) == 0 {
+		switch r.Method {
+		case "GET":
+			s.listUsers(w, r)
+		case "POST":
+			s.createUser(w, r)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "general_route_not_found",
+				"The requested route was not found.")
+		}
+		return
+	}
+
+	u := s.user(parts[0])
+	if u == nil {
+		writeError(w, http.StatusNotFound, "user_not_found",
+			"User with the requested ID could not be found.")
+		return
+	}
+
+	route := r.Method + " " + strings.Join(parts[1:], "/")
+	switch {
+	case route == "GET ":
+		writeJSON(w, http.StatusOK, u)
+	case route == "DELETE ":
+		s.deleteUser(u.ID)
+		w.WriteHeader(http.StatusNoContent)
+	case route == "GET prefs":
+		writeJSON(w, http.StatusOK, u.Prefs)
+	case route == "GET sessions":
+		sessions := s.userSessions(u.ID)
+		writeJSON(w, http.StatusOK, map[string]any{"total": len(sessions), "sessions": sessions})
+	case route == "DELETE sessions":
+		s.deleteSessions(u.ID, "")
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == "DELETE" && len(parts) == 3 && parts[1] == "sessions":
+		if !s.deleteSessions(u.ID, parts[2]) {
+			writeError(w, http.StatusNotFound, "user_session_not_found",
+				"The current user session could not be found.")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		s.updateUser(w, r, u, route)
+	}
+}
+
+func (s *Server) updateUser(w http.ResponseWriter, r *http.Request, u *user, route string) {
+	body := struct {
+		Email             *string     `json:"email"`
+		Number            *string     `json:"number"`
+		Password          *string     `json:"password"`
+		Name              *string     `json:"name"`
+		Status            *bool       `json:"status"`
+		Prefs             model.Prefs `json:"prefs"`
+		Labels            []string    `json:"labels"`
+		EmailVerification *bool       `json:"emailVerification"`
+		PhoneVerification *bool       `json:"phoneVerification"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "general_argument_invalid", err.Error())
+		return
+	}
+
+	switch route {
+	case "PATCH email":
+		if body.Email == nil || *body.Email == "" {
+			writeError(w, http.StatusBadRequest, "general_argument_invalid",
+				"Invalid `email` param: Value must be a valid email address")
+			return
+		}
+		if other := s.userByEmail(*body.Email); other != nil && other.ID != u.ID {
+			writeError(w, http.StatusConflict, "user_email_already_exists",
+				"A user with the same email already exists in the current project.")
+			return
+		}
+		u.Email = *body.Email
+		u.EmailVerification = false
+	case "PATCH phone":
+		if body.Number == nil || !strings.HasPrefix(*body.Number, "+") {
+			writeError(w, http.StatusBadRequest, "general_argument_invalid",
+				"Invalid `number` param: Phone number must start with a '+' can have a maximum of fifteen digits.")
+			return
+		}
+		u.Phone = *body.Number
+		u.PhoneVerification = false
+	case "PATCH password":
+		if body.Password == nil || len(*body.Password) < 8 {
+			writeError(w, http.StatusBadRequest, "general_argument_invalid",
+				"Invalid `password` param: Password must be at least 8 characters")
+			return
+		}
+		s.passwords[u.ID] = *body.Password
+		u.PasswordUpdate = s.now()
+	case "PATCH name":
+		if body.Name == nil {
+			writeError(w, http.StatusBadRequest, "general_argument_invalid",
+				"Param \"name\" is not optional.")
+			return
+		}
+		u.Name = *body.Name
+	case "PATCH status":
+		if body.Status != nil {
+			u.Status = *body.Status
+		}
+	case "PATCH prefs":
+		u.Prefs = body.Prefs
+		if u.Prefs == nil {
+			u.Prefs = model.Prefs{}
+		}
+		u.UpdatedAt = s.now()
+		writeJSON(w, http.StatusOK, u.Prefs)
+		return
+	case "PUT labels":
+		u.Labels = append([]string{}, body.Labels...)
+	case "PATCH verification":
+		if body.EmailVerification != nil {
+			u.EmailVerification = *body.EmailVerification
+		}
+	case "PATCH verification/phone":
+		if body.PhoneVerification != nil {
+			u.PhoneVerification = *body.PhoneVerification
+		}
+	default:
+		writeError(w, http.StatusNotFound, "general_route_not_found",
+			"The requested route was not found.")
+		return
+	}
+
+	u.UpdatedAt = s.now()
+	writeJSON(w, http.StatusOK, u)
+}
+
+func (s *Server) listUsers(w http.ResponseWriter, r *http.Request) {
+	matched := []*user{}
+	limit, offset, cursor := 25, 0, ""
+	filters := []func(u *user) bool{}
+
+	if search := strings.ToLower(r.URL.Query().Get("search")); search != "" {
+		filters = append(filters, func(u *user) bool {
+			return strings.Contains(strings.ToLower(u.ID+" "+u.Name+" "+u.Email+" "+u.Phone), search)
+		})
+	}
+
+	for _, q := range r.URL.Query()["queries[]"] {
+		m := queryPattern.FindStringSubmatch(q)
+		if m == nil {
+			writeError(w, http.StatusBadRequest, "general_query_invalid", "Invalid query: "+q)
+			return
+		}
+
+		switch m[1] {
+		case "limit":
+			limit, _ = strconv.Atoi(m[2])
+		case "offset":
+			offset, _ = strconv.Atoi(m[2])
+		case "cursorAfter":
+			cursor, _ = strconv.Unquote(m[2])
+		case "equal", "greaterThan":
+			attr, value := queryArgs(m[2])
+			if !userQueryAttributes[attr] {
+				writeError(
+					w,
+					http.StatusBadRequest,
+					"general_query_invalid",
+					"Invalid query: Attribute not found in schema: "+attr,
+				)
+				return
+			}
+			op := m[1]
+			filters = append(filters, func(u *user) bool {
+				if op == "equal" {
+					return attribute(u, attr) == value
+				}
+				return after(attribute(u, attr), value)
+			})
+		default:
+			writeError(w, http.StatusBadRequest, "general_query_invalid", "Invalid query: "+q)
+			return
+		}
+	}
+
+	for _, u := range s.users {
+		keep := true
+		for _, f := range filters {
+			keep = keep && f(u)
+		}
+		if keep {
+			matched = append(matched, u)
+		}
+	}
+
+	start := offset
+	if cursor != "" {
+		i := 0
+		for i < len(matched) && matched[i].ID != cursor {
+			i++
+		}
+		if i == len(matched) {
+			writeError(w, http.StatusBadRequest, "general_cursor_not_found",
+				"Invalid cursor: Document with the requested ID could not be found.")
+			return
+		}
+		start += i + 1
+	}
+
+	page := []*user{}
+	for i := start; i < len(matched) && len(page) < limit; i++ {
+		page = append(page, matched[i])
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"total": len(matched), "users": page})
+}
+
+func (s *Server) createUser(w http.ResponseWriter, r *http.Request) {
+	body := siogeneric.AwCreateUserRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "general_argument_invalid", err.Error())
+		return
+	}
+
+	if body.UserID == "" {
+		writeError(w, http.StatusBadRequest, "general_argument_invalid",
+			"Param \"userId\" is not optional.")
+		return
+	}
+	if body.Phone != "" && !strings.HasPrefix(body.Phone, "+") {
+		writeError(w, http.StatusBadRequest, "general_argument_invalid",
+			"Invalid `phone` param: Phone number must start with a '+' can have a maximum of fifteen digits.")
+		return
+	}
+	if body.UserID == "unique()" {
+		body.UserID = s.nextID()
+	}
+	if s.user(body.UserID) != nil || (body.Email != "" && s.userByEmail(body.Email) != nil) {
+		writeError(w, http.StatusConflict, "user_already_exists",
+			"A user with the same email already exists in your project.")
+		return
+	}
+
+	now := s.now()
+	u := &user{
+		AwUser: siogeneric.AwUser{
+			ID:             body.UserID,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+			Name:           body.Name,
+			Registration:   now,
+			Status:         true,
+			PasswordUpdate: now,
+			Email:          body.Email,
+			Phone:          body.Phone,
+			Prefs:          model.Prefs{},
+		},
+		Labels: []string{},
+	}
+	s.users = append(s.users, u)
+	s.passwords[u.ID] = body.Password
+	writeJSON(w, http.StatusCreated, u)
+}
+
+func (s *Server) serveAccount(w http.ResponseWriter, r *http.Request, route string) {
+	switch r.Method + " " + route {
+	case "POST sessions/email":
+		s.createEmailSession(w, r)
+	case "GET ":
+		if u := s.jwtUser(w, r); u != nil {
+			writeJSON(w, http.StatusOK, u)
+		}
+	case "POST recovery":
+		body := model.AwRecoveryRequest{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		u := s.userByEmail(body.Email)
+		if u == nil {
+			writeError(w, http.StatusNotFound, "user_not_found",
+				"User with the requested ID could not be found.")
+			return
+		}
+		writeJSON(w, http.StatusCreated, s.issueSecret(u.ID, "recovery"))
+	case "PUT recovery":
+		body := model.AwRecoveryConfirmRequest{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body.Password != body.PasswordAgain {
+			writeError(w, http.StatusBadRequest, "user_password_mismatch",
+				"Passwords do not match. Please check the password and confirm password.")
+			return
+		}
+		if t := s.consumeSecret(w, body.UserID, body.Secret, "recovery"); t != nil {
+			s.passwords[body.UserID] = body.Password
+			writeJSON(w, http.StatusOK, t)
+		}
+	case "POST verification", "POST verification/phone":
+		u := s.jwtUser(w, r)
+		if u == nil {
+			return
+		}
+		if route == "verification/phone" && u.Phone == "" {
+			writeError(w, http.StatusBadRequest, "user_phone_not_found",
+				"The current user does not have a phone number.")
+			return
+		}
+		writeJSON(w, http.StatusCreated, s.issueSecret(u.ID, route))
+	case "PUT verification", "PUT verification/phone":
+		body := model.VerificationConfirmRequest{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		t := s.consumeSecret(w, body.UserID, body.Secret, route)
+		if t == nil {
+			return
+		}
+		if u := s.user(body.UserID); u != nil {
+			if route == "verification" {
+				u.EmailVerification = true
+			} else {
+				u.PhoneVerification = true
+			}
+		}
+		writeJSON(w, http.StatusOK, t)
+	default:
+		writeError(w, http.StatusNotFound, "general_route_not_found",
+			"The requested route was not found.")
+	}
+}
+
+func (s *Server) createEmailSession(w http.ResponseWriter, r *http.Request) {
+	body := siogeneric.AwEmailSessionRequest{}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+
+	u := s.userByEmail(body.Email)
+	if u == nil || s.passwords[u.ID] != body.Password {
+		writeError(w, http.StatusUnauthorized, "user_invalid_credentials",
+			"Invalid credentials. Please check the email and password.")
+		return
+	}
+	if !u.Status {
+		writeError(w, http.StatusUnauthorized, "user_blocked",
+			"The current user has been blocked.")
+		return
+	}
+
+	now := s.now()
+	session := &siogeneric.AwSession{
+		ID:        s.nextID(),
+		CreatedAt: now,
+		UserId:    u.ID,
+		Expire:    s.Now().AddDate(1, 0, 0).Format(timeLayout),
+		Provider:  "email",
+		Ip:        "127.0.0.1",
+		Current:   true,
+	}
+	s.sessions = append(s.sessions, session)
+	writeJSON(w, http.StatusCreated, session)
+}
+
+func (s *Server) jwtUser(w http.ResponseWriter, r *http.Request) *user {
+	u := s.user(s.jwts[r.Header.Get(constants.AW_HEADER_JWT)])
+	if u == nil {
+		writeError(w, http.StatusUnauthorized, "user_jwt_invalid",
+			"Invalid token passed in the request.")
+	}
+	return u
+}
+
+func (s *Server) issueSecret(userID string, kind string) *model.AwToken {
+	t := &model.AwToken{
+		ID:        s.nextID(),
+		CreatedAt: s.now(),
+		UserID:    userID,
+		Secret:    "secret-" + s.nextID(),
+	}
+	s.secrets[t.Secret] = token{userID: userID, kind: kind}
+	s.last = t.Secret
+	// Appwrite only hands the secret out through the email or SMS.
+	t.Secret = ""
+	return t
+}
+
+func (s *Server) consumeSecret(
+	w http.ResponseWriter,
+	userID string,
+	secret string,
+	kind string,
+) *model.AwToken {
+	t, ok := s.secrets[secret]
+	if !ok || t.userID != userID || t.kind != kind {
+		writeError(w, http.StatusUnauthorized, "user_invalid_token",
+			"Invalid token passed in the request.")
+		return nil
+	}
+	delete(s.secrets, secret)
+	return &model.AwToken{ID: s.nextID(), CreatedAt: s.now(), UserID: userID}
+}
+
+func (s *Server) user(id string) *user {
+	for _, u := range s.users {
+		if u.ID == id {
+			return u
+		}
+	}
+	return nil
+}
+
+func (s *Server) userByEmail(email string) *user {
+	for _, u := range s.users {
+		if strings.EqualFold(u.Email, email) {
+			return u
+		}
+	}
+	return nil
+}
+
+func (s *Server) userSessions(userID string) []*siogeneric.AwSession {
+	sessions := []*siogeneric.AwSession{}
+	for _, session := range s.sessions {
+		if session.UserId == userID {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions
+}
+
+// deleteSessions removes userID's session sessionID, or all of their sessions
+// when sessionID is empty, and reports whether any were removed.
+func (s *Server) deleteSessions(userID string, sessionID string) bool {
+	kept := s.sessions[:0]
+	removed := false
+	for _, session := range s.sessions {
+		if session.UserId == userID && (sessionID == "" || session.ID == sessionID) {
+			removed = true
+			continue
+		}
+		kept = append(kept, session)
+	}
+	s.sessions = kept
+	return removed
+}
+
+func (s *Server) deleteUser(id string) {
+	kept := s.users[:0]
+	for _, u := range s.users {
+		if u.ID != id {
+			kept = append(kept, u)
+		}
+	}
+	s.users = kept
+	delete(s.passwords, id)
+	s.deleteSessions(id, "")
+}
+
+func (s *Server) nextID() string {
+	s.seq++
+	return fmt.Sprintf("%020d", s.seq)
+}
+
+func (s *Server) now() string {
+	return s.Now().Format(timeLayout)
+}
+
+// queryArgs splits the `"attr", [value]` arguments of an Appwrite query.
+func queryArgs(args string) (string, string) {
+	attr, value, _ := strings.Cut(args, ", ")
+	attr, _ = strconv.Unquote(attr)
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+	if unquoted, err := strconv.Unquote(value); err == nil {
+		value = unquoted
+	}
+	return attr, value
+}
+
+// userQueryAttributes are the attributes Appwrite lets users be listed by.
+var userQueryAttributes = map[string]bool{
+	"name":              true,
+	"email":             true,
+	"phone":             true,
+	"status":            true,
+	"passwordUpdate":    true,
+	"registration":      true,
+	"emailVerification": true,
+	"phoneVerification": true,
+	"labels":            true,
+}
+
+func attribute(u *user, attr string) string {
+	switch attr {
+	case "email":
+		return u.Email
+	case "phone":
+		return u.Phone
+	case "name":
+		return u.Name
+	case "status":
+		return strconv.FormatBool(u.Status)
+	case "registration":
+		return u.Registration
+	}
+	return ""
+}
+
+// after compares Appwrite timestamps, which may differ in precision.
+func after(a string, b string) bool {
+	ta, errA := time.Parse(time.RFC3339, a)
+	tb, errB := time.Parse(time.RFC3339, b)
+	return errA == nil && errB == nil && ta.After(tb)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, errType string, message string) {
+	writeJSON(w, code, &siogeneric.AppwriteError{
+		Message: message,
+		Code:    code,
+		Type:    errType,
+		Version: version,
+	})
+}
@@ -0,0 +1,170 @@
+package awtest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"gitea.slauson.io/slausonio/iam-ms/client"
+	"gitea.slauson.io/slausonio/iam-ms/model"
+)
+
+func initServerForTests(t *testing.T) (*Server, *client.AwProvider) {
+	s := NewServer()
+	t.Cleanup(s.Close)
+	return s, client.NewAwProviderFor(client.NewAwClientFor(s.Host(), Project, Key))
+}
+
+func TestServer_Users(t *testing.T) {
+	_, p := initServerForTests(t)
+
+	u, err := p.CreateUser(context.Background(), &model.NewUser{
+		ID:       "10000069",
+		Email:    "t@t.com",
+		Phone:    "+15555555555",
+		Password: "Password123!",
+		Name:     "Matt Slauson",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "10000069", u.ID)
+	assert.Equal(t, "+15555555555", u.Phone)
+	assert.Equal(t, "2023-11-14T22:13:21.000+00:00", u.CreatedAt)
+
+	_, err = p.CreateUser(
+		context.Background(),
+		&model.NewUser{ID: "unique()", Email: "t@t.com", Phone: "+15555555555"},
+	)
+	assert.NotNil(t, err)
+	other, err := p.CreateUser(
+		context.Background(),
+		&model.NewUser{ID: "unique()", Email: "o@t.com", Phone: "+15555555555"},
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, "00000000000000000002", other.ID)
+
+	list, err := p.ListUsers(context.Background(), &model.ListUsersParams{Limit: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, list.Total)
+	assert.Equal(t, "10000069", list.Users[0].ID)
+
+	list, err = p.ListUsers(
+		context.Background(),
+		&model.ListUsersParams{Limit: 25, Cursor: "10000069"},
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, other.ID, list.Users[0].ID)
+
+	list, err = p.ListUsers(
+		context.Background(),
+		&model.ListUsersParams{Limit: 25, Email: "o@t.com"},
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, list.Total)
+
+	list, err = p.ListUsers(context.Background(), &model.ListUsersParams{
+		Limit:        25,
+		CreatedAfter: "2023-11-14T22:13:21Z",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, list.Total)
+
+	u, err = p.UpdateName(context.Background(), u.ID, "Matthew Slauson")
+	assert.Nil(t, err)
+	assert.Equal(t, "Matthew Slauson", u.Name)
+
+	labels, err := p.UpdateLabels(context.Background(), u.ID, []string{"admin"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"admin"}, labels.Labels)
+
+	prefs, err := p.UpdatePrefs(context.Background(), u.ID, model.Prefs{"theme": "dark"})
+	assert.Nil(t, err)
+	assert.Equal(t, model.Prefs{"theme": "dark"}, prefs)
+
+	assert.Nil(t, p.DeleteUser(context.Background(), u.ID))
+	_, err = p.GetUserByID(context.Background(), u.ID)
+	assert.NotNil(t, err)
+}
+
+func TestServer_Errors(t *testing.T) {
+	s, _ := initServerForTests(t)
+	p := client.NewAwProviderFor(client.NewAwClientFor(s.Host(), Project, "wrong"))
+
+	_, err := p.GetUserByID(context.Background(), "a")
+	assert.Equal(
+		t,
+		"The current user is not authorized to perform the requested action.",
+		err.Error(),
+	)
+
+	p = client.NewAwProviderFor(client.NewAwClientFor(s.Host(), "other", Key))
+	_, err = p.GetUserByID(context.Background(), "a")
+	assert.Equal(t, "Project with the requested ID could not be found.", err.Error())
+}
+
+func TestServer_Health(t *testing.T) {
+	s, p := initServerForTests(t)
+	assert.Nil(t, p.Ping(context.Background()))
+
+	p = client.NewAwProviderFor(client.NewAwClientFor(s.Host(), Project, "wrong"))
+	assert.NotNil(t, p.Ping(context.Background()))
+}
+
+func TestServer_Sessions(t *testing.T) {
+	s, p := initServerForTests(t)
+	u, err := p.CreateUser(context.Background(), &model.NewUser{
+		ID:       "a",
+		Email:    "t@t.com",
+		Phone:    "+15555555555",
+		Password: "Password123!",
+	})
+	assert.Nil(t, err)
+
+	_, err = p.CreateEmailSession(context.Background(), "t@t.com", "wrong")
+	assert.NotNil(t, err)
+	session, err := p.CreateEmailSession(context.Background(), "t@t.com", "Password123!")
+	assert.Nil(t, err)
+	assert.Equal(t, u.ID, session.UserID)
+
+	sessions, err := p.ListSessions(context.Background(), u.ID)
+	assert.Nil(t, err)
+	assert.Len(t, sessions, 1)
+
+	labels, err := p.GetSessionUser(context.Background(), s.JWT(u.ID))
+	assert.Nil(t, err)
+	assert.Equal(t, u.ID, labels.ID)
+	_, err = p.GetSessionUser(context.Background(), "bogus")
+	assert.NotNil(t, err)
+
+	assert.Nil(t, p.DeleteSession(context.Background(), u.ID, session.ID))
+	assert.NotNil(t, p.DeleteSession(context.Background(), u.ID, session.ID))
+	assert.Nil(t, p.DeleteSessions(context.Background(), u.ID))
+}
+
+func TestServer_RecoveryAndVerification(t *testing.T) {
+	s, p := initServerForTests(t)
+	u, err := p.CreateUser(context.Background(), &model.NewUser{
+		ID:       "a",
+		Email:    "t@t.com",
+		Phone:    "+15555555555",
+		Password: "Password123!",
+	})
+	assert.Nil(t, err)
+
+	assert.Nil(t, p.CreateRecovery(context.Background(), "t@t.com"))
+	assert.NotNil(t, p.ConfirmRecovery(context.Background(), u.ID, "wrong", "NewPassword123!"))
+	assert.Nil(t, p.ConfirmRecovery(context.Background(), u.ID, s.LastSecret(), "NewPassword123!"))
+	_, err = p.CreateEmailSession(context.Background(), "t@t.com", "NewPassword123!")
+	assert.Nil(t, err)
+
+	jwt := s.JWT(u.ID)
+	assert.Nil(t, p.SendEmailVerification(context.Background(), jwt))
+	assert.Nil(t, p.ConfirmEmailVerification(context.Background(), u.ID, s.LastSecret()))
+	assert.Nil(t, p.SendPhoneVerification(context.Background(), jwt))
+	assert.Nil(t, p.ConfirmPhoneVerification(context.Background(), u.ID, s.LastSecret()))
+
+	actual, err := p.GetUserByID(context.Background(), u.ID)
+	assert.Nil(t, err)
+	assert.True(t, actual.EmailVerification)
+	assert.True(t, actual.PhoneVerification)
+}
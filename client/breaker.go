@@ -0,0 +1,125 @@
+package client
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerHalfOpen
+	breakerOpen
+)
+
+var breakerStateGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "iam_upstream_circuit_breaker_state",
+	Help: "State of the identity provider circuit breaker: 0 closed, 1 half-open, 2 open.",
+}, []string{"upstream"})
+
+// The gauge goes in the default Prometheus registry, the one sioprom and the
+// metrics endpoint serve on /metrics.
+func init() {
+	prometheus.MustRegister(breakerStateGauge)
+}
+
+// breaker is a consecutive failure circuit breaker. After threshold transient
+// failures in a row it opens and rejects calls for cooldown, then lets a single
+// probe through: a success closes it again, a failure reopens it.
+//
+// A nil breaker never opens.
+type breaker struct {
+	mu         sync.Mutex
+	threshold  int
+	cooldown   time.Duration
+	state      breakerState
+	streak     int
+	openedAt   time.Time
+	probing    bool
+	now        func() time.Time
+	stateGauge prometheus.Gauge
+}
+
+func newBreaker(upstream string, threshold int, cooldown time.Duration) *breaker {
+	if threshold <= 0 {
+		return nil
+	}
+	b := &breaker{
+		threshold:  threshold,
+		cooldown:   cooldown,
+		now:        time.Now,
+		stateGauge: breakerStateGauge.WithLabelValues(upstream),
+	}
+	b.stateGauge.Set(float64(breakerClosed))
+	return b
+}
+
+// allow reports whether a call may go upstream. A true result must be followed
+// by record or abandon.
+func (b *breaker) allow() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.setState(breakerHalfOpen)
+		b.probing = true
+		return true
+	case breakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	}
+	return true
+}
+
+// record reports the outcome of an allowed call. Only transient failures,
+// i.e. the upstream being unreachable or overloaded, count against it.
+func (b *breaker) record(ok bool) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if ok {
+		b.streak = 0
+		b.setState(breakerClosed)
+		return
+	}
+
+	b.streak++
+	if b.state == breakerHalfOpen || b.streak >= b.threshold {
+		b.openedAt = b.now()
+		b.setState(breakerOpen)
+	}
+}
+
+// abandon releases an allowed call that ended without an upstream verdict,
+// e.g. because the caller went away.
+func (b *breaker) abandon() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+func (b *breaker) setState(s breakerState) {
+	b.state = s
+	b.stateGauge.Set(float64(s))
+}
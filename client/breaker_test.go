@@ -0,0 +1,53 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreaker(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	b := newBreaker("test", 2, time.Minute)
+	b.now = func() time.Time { return now }
+
+	assert.True(t, b.allow())
+	b.record(false)
+	assert.True(t, b.allow())
+	b.record(true)
+
+	// Only consecutive failures open it.
+	for i := 0; i < 2; i++ {
+		assert.True(t, b.allow())
+		b.record(false)
+	}
+	assert.False(t, b.allow())
+	assert.Equal(t, float64(breakerOpen), testutil.ToFloat64(b.stateGauge))
+
+	// After the cooldown a single probe goes through.
+	now = now.Add(time.Minute)
+	assert.True(t, b.allow())
+	assert.False(t, b.allow())
+	assert.Equal(t, float64(breakerHalfOpen), testutil.ToFloat64(b.stateGauge))
+	b.record(false)
+	assert.False(t, b.allow())
+
+	now = now.Add(time.Minute)
+	assert.True(t, b.allow())
+	b.abandon()
+	assert.True(t, b.allow())
+	b.record(true)
+	assert.True(t, b.allow())
+	assert.Equal(t, float64(breakerClosed), testutil.ToFloat64(b.stateGauge))
+}
+
+func TestBreaker_Disabled(t *testing.T) {
+	b := newBreaker("test", 0, time.Minute)
+	assert.Nil(t, b)
+	for i := 0; i < 10; i++ {
+		assert.True(t, b.allow())
+		b.record(false)
+	}
+}
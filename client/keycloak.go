@@ -0,0 +1,744 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gitea.slauson.io/slausonio/go-utils/sioUtils"
+	"gitea.slauson.io/slausonio/iam-ms/config"
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+	"gitea.slauson.io/slausonio/iam-ms/model"
+	"gitea.slauson.io/slausonio/iam-ms/utils"
+)
+
+// kcTokenLeeway is how long before expiry a cached admin token is refreshed.
+const kcTokenLeeway = 30 * time.Second
+
+// KcClient is the Keycloak identity provider. It talks to the realm admin REST
+// API at keycloak.adminBase (https://host/admin/realms/<realm>) and to the
+// OpenID Connect endpoints under keycloak.issuerBase
+// (https://host/realms/<realm>).
+//
+// The keycloak.clientId client needs a service account with the realm-management
+// manage-users role, and direct access grants enabled for email sessions.
+// Realm roles stand in for Appwrite labels and phone numbers and prefs are kept
+// in user attributes.
+type KcClient struct {
+	h            sioUtils.SioRestHelpers
+	adminBase    string
+	issuerBase   string
+	clientID     string
+	clientSecret string
+	recoveryURL  string
+	verifyURL    string
+	timeout      time.Duration
+
+	tokenMu     sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+func NewKcClient(cfg *config.Config) *KcClient {
+	return &KcClient{
+		h:            sioUtils.NewRestHelpers(),
+		adminBase:    strings.TrimSuffix(cfg.Keycloak.AdminBase, "/"),
+		issuerBase:   strings.TrimSuffix(cfg.Keycloak.IssuerBase, "/"),
+		clientID:     cfg.Keycloak.ClientID,
+		clientSecret: cfg.Keycloak.ClientSecret.Value(),
+		recoveryURL:  cfg.IAM.RecoveryURL,
+		verifyURL:    cfg.IAM.VerificationURL,
+		timeout:      cfg.Upstream.Timeout,
+	}
+}
+
+func (c *KcClient) ListUsers(
+	ctx context.Context,
+	p *model.ListUsersParams,
+) (*model.UserList, error) {
+	if p.Cursor != "" || p.CreatedAfter != "" {
+		return nil, utils.NewIamError(
+			http.StatusBadRequest,
+			constants.ERR_TYPE_ARGUMENT_INVALID,
+			"cursor and createdAfter are not supported by this identity provider",
+		)
+	}
+
+	q := url.Values{}
+	if p.Search != "" {
+		q.Set("search", p.Search)
+	}
+	if p.Email != "" {
+		q.Set("email", p.Email)
+		q.Set("exact", "true")
+	}
+	if p.Name != "" {
+		first, last := splitName(p.Name)
+		q.Set("firstName", first)
+		if last != "" {
+			q.Set("lastName", last)
+		}
+	}
+	if p.Phone != "" {
+		q.Set("q", constants.KC_ATTR_PHONE+":"+p.Phone)
+	}
+	if p.Status != nil {
+		q.Set("enabled", strconv.FormatBool(*p.Status))
+	}
+
+	total := 0
+	if err := c.admin(ctx, "GET", "/users/count?"+q.Encode(), nil, &total); err != nil {
+		return nil, err
+	}
+
+	q.Set("first", strconv.Itoa(p.Offset))
+	q.Set("max", strconv.Itoa(p.Limit))
+	var users []model.KcUser
+	if err := c.admin(ctx, "GET", "/users?"+q.Encode(), nil, &users); err != nil {
+		return nil, err
+	}
+
+	result := &model.UserList{Total: total, Users: make([]model.User, 0, len(users))}
+	for i := range users {
+		result.Users = append(result.Users, *kcToUser(&users[i]))
+	}
+	return result, nil
+}
+
+func (c *KcClient) GetUserByID(ctx context.Context, id string) (*model.User, error) {
+	u, err := c.getKcUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return kcToUser(u), nil
+}
+
+func (c *KcClient) CreateUser(ctx context.Context, u *model.NewUser) (*model.User, error) {
+	first, last := splitName(u.Name)
+	rep := &model.KcUser{
+		Username:   u.Email,
+		Email:      u.Email,
+		Enabled:    true,
+		FirstName:  first,
+		LastName:   last,
+		Attributes: map[string][]string{constants.KC_ATTR_PHONE: {u.Phone}},
+		Credentials: []model.KcCredential{
+			{Type: "password", Value: u.Password, Temporary: false},
+		},
+	}
+
+	req, err := c.adminRequest(ctx, "POST", "/users", rep)
+	if err != nil {
+		return nil, err
+	}
+	req, cancel := withCallTimeout(req, c.timeout)
+	defer cancel()
+	res, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+	_ = res.Body.Close()
+
+	// Keycloak picks the ID and only returns it in the Location header.
+	return c.GetUserByID(ctx, path.Base(res.Header.Get("Location")))
+}
+
+func (c *KcClient) UpdateEmail(ctx context.Context, id string, email string) (*model.User, error) {
+	return c.updateUser(ctx, id, func(u *model.KcUser) {
+		u.Email = email
+		u.Username = email
+	})
+}
+
+func (c *KcClient) UpdatePhone(ctx context.Context, id string, number string) (*model.User, error) {
+	return c.updateUser(ctx, id, func(u *model.KcUser) {
+		setAttr(u, constants.KC_ATTR_PHONE, number)
+	})
+}
+
+func (c *KcClient) UpdatePassword(
+	ctx context.Context,
+	id string,
+	password string,
+) (*model.User, error) {
+	cred := &model.KcCredential{Type: "password", Value: password, Temporary: false}
+	if err := c.admin(ctx, "PUT", "/users/"+id+"/reset-password", cred, nil); err != nil {
+		return nil, err
+	}
+	return c.GetUserByID(ctx, id)
+}
+
+func (c *KcClient) UpdateName(ctx context.Context, id string, name string) (*model.User, error) {
+	return c.updateUser(ctx, id, func(u *model.KcUser) {
+		u.FirstName, u.LastName = splitName(name)
+	})
+}
+
+func (c *KcClient) UpdateStatus(ctx context.Context, id string, status bool) (*model.User, error) {
+	return c.updateUser(ctx, id, func(u *model.KcUser) {
+		u.Enabled = status
+	})
+}
+
+func (c *KcClient) UpdateEmailVerification(
+	ctx context.Context,
+	id string,
+	verified bool,
+) (*model.User, error) {
+	return c.updateUser(ctx, id, func(u *model.KcUser) {
+		u.EmailVerified = verified
+	})
+}
+
+func (c *KcClient) UpdatePhoneVerification(
+	ctx context.Context,
+	id string,
+	verified bool,
+) (*model.User, error) {
+	return c.updateUser(ctx, id, func(u *model.KcUser) {
+		setAttr(u, constants.KC_ATTR_PHONE_VERIFIED, strconv.FormatBool(verified))
+	})
+}
+
+func (c *KcClient) GetPrefs(ctx context.Context, id string) (model.Prefs, error) {
+	u, err := c.getKcUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return kcPrefs(u), nil
+}
+
+func (c *KcClient) UpdatePrefs(
+	ctx context.Context,
+	id string,
+	prefs model.Prefs,
+) (model.Prefs, error) {
+	raw, err := json.Marshal(prefs)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := c.updateUser(ctx, id, func(u *model.KcUser) {
+		setAttr(u, constants.KC_ATTR_PREFS, string(raw))
+	}); err != nil {
+		return nil, err
+	}
+	return prefs, nil
+}
+
+func (c *KcClient) GetLabels(ctx context.Context, id string) (*model.UserLabels, error) {
+	roles, err := c.realmRoles(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &model.UserLabels{ID: id, Labels: make([]string, 0, len(roles))}
+	for _, r := range roles {
+		result.Labels = append(result.Labels, r.Name)
+	}
+	return result, nil
+}
+
+// UpdateLabels makes the user's realm role mappings match labels.
+func (c *KcClient) UpdateLabels(
+	ctx context.Context,
+	id string,
+	labels []string,
+) (*model.UserLabels, error) {
+	current, err := c.realmRoles(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	want := map[string]bool{}
+	for _, l := range labels {
+		want[l] = true
+	}
+
+	var remove []model.KcRole
+	for _, r := range current {
+		if want[r.Name] {
+			delete(want, r.Name)
+		} else {
+			remove = append(remove, r)
+		}
+	}
+
+	var add []model.KcRole
+	for _, l := range labels {
+		if !want[l] {
+			continue
+		}
+		role := new(model.KcRole)
+		if err := c.admin(ctx, "GET", "/roles/"+url.PathEscape(l), nil, role); err != nil {
+			return nil, err
+		}
+		add = append(add, *role)
+		delete(want, l)
+	}
+
+	mappings := "/users/" + id + "/role-mappings/realm"
+	if len(remove) > 0 {
+		if err := c.admin(ctx, "DELETE", mappings, remove, nil); err != nil {
+			return nil, err
+		}
+	}
+	if len(add) > 0 {
+		if err := c.admin(ctx, "POST", mappings, add, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return c.GetLabels(ctx, id)
+}
+
+func (c *KcClient) GetSessionUser(ctx context.Context, token string) (*model.UserLabels, error) {
+	info, err := c.userInfo(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	return c.GetLabels(ctx, info.Sub)
+}
+
+func (c *KcClient) DeleteUser(ctx context.Context, id string) error {
+	return c.admin(ctx, "DELETE", "/users/"+id, nil, nil)
+}
+
+// CreateRecovery has Keycloak email the user an update password action link.
+func (c *KcClient) CreateRecovery(ctx context.Context, email string) error {
+	q := url.Values{"email": {email}, "exact": {"true"}}
+	var users []model.KcUser
+	if err := c.admin(ctx, "GET", "/users?"+q.Encode(), nil, &users); err != nil {
+		return err
+	}
+	if len(users) == 0 {
+		return utils.NewIamError(
+			http.StatusNotFound,
+			constants.ERR_TYPE_USER_NOT_FOUND,
+			constants.NoUserFound,
+		)
+	}
+
+	return c.admin(
+		ctx,
+		"PUT",
+		"/users/"+users[0].ID+"/execute-actions-email?"+c.redirectQuery(c.recoveryURL),
+		[]string{"UPDATE_PASSWORD"},
+		nil,
+	)
+}
+
+// ConfirmRecovery is handled by Keycloak's own update password page.
+func (c *KcClient) ConfirmRecovery(
+	ctx context.Context,
+	userID string,
+	secret string,
+	password string,
+) error {
+	return unsupported()
+}
+
+func (c *KcClient) SendEmailVerification(ctx context.Context, token string) error {
+	info, err := c.userInfo(ctx, token)
+	if err != nil {
+		return err
+	}
+
+	return c.admin(
+		ctx,
+		"PUT",
+		"/users/"+info.Sub+"/send-verify-email?"+c.redirectQuery(c.verifyURL),
+		nil,
+		nil,
+	)
+}
+
+// ConfirmEmailVerification is handled by Keycloak's own verification link.
+func (c *KcClient) ConfirmEmailVerification(
+	ctx context.Context,
+	userID string,
+	secret string,
+) error {
+	return unsupported()
+}
+
+func (c *KcClient) SendPhoneVerification(ctx context.Context, token string) error {
+	return unsupported()
+}
+
+func (c *KcClient) ConfirmPhoneVerification(
+	ctx context.Context,
+	userID string,
+	secret string,
+) error {
+	return unsupported()
+}
+
+// CreateEmailSession signs the user in with the password grant. The access
+// token is returned as the session secret.
+func (c *KcClient) CreateEmailSession(
+	ctx context.Context,
+	email string,
+	password string,
+) (*model.Session, error) {
+	token, err := c.tokenRequest(ctx, url.Values{
+		"grant_type": {"password"},
+		"username":   {email},
+		"password":   {password},
+		"scope":      {"openid"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := c.userInfo(ctx, token.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	return &model.Session{
+		ID:        token.SessionState,
+		CreatedAt: now.Format(time.RFC3339),
+		UserID:    info.Sub,
+		Expire:    now.Add(time.Duration(token.ExpiresIn) * time.Second).Format(time.RFC3339),
+		Current:   true,
+		Provider:  "email",
+		Secret:    token.AccessToken,
+	}, nil
+}
+
+func (c *KcClient) ListSessions(ctx context.Context, id string) ([]model.Session, error) {
+	var sessions []model.KcSession
+	if err := c.admin(ctx, "GET", "/users/"+id+"/sessions", nil, &sessions); err != nil {
+		return nil, err
+	}
+
+	result := make([]model.Session, 0, len(sessions))
+	for _, s := range sessions {
+		session := model.Session{
+			ID:        s.ID,
+			CreatedAt: millisToTime(s.Start),
+			UserID:    s.UserID,
+			Provider:  "email",
+			Ip:        s.IPAddress,
+		}
+		for _, name := range s.Clients {
+			session.ClientName = name
+			break
+		}
+		result = append(result, session)
+	}
+	return result, nil
+}
+
+// DeleteSession revokes one of the user's sessions. Keycloak deletes sessions
+// by ID alone, so the session is first looked up among the user's own.
+func (c *KcClient) DeleteSession(ctx context.Context, id string, sessionID string) error {
+	sessions, err := c.ListSessions(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range sessions {
+		if s.ID == sessionID {
+			return c.admin(ctx, "DELETE", "/sessions/"+sessionID, nil, nil)
+		}
+	}
+	return utils.NewIamError(
+		http.StatusNotFound,
+		constants.ERR_TYPE_SESSION_NOT_FOUND,
+		constants.NoSessionFound,
+	)
+}
+
+func (c *KcClient) DeleteSessions(ctx context.Context, id string) error {
+	return c.admin(ctx, "POST", "/users/"+id+"/logout", nil, nil)
+}
+
+// Ping counts users through the admin API, which needs both Keycloak and the
+// service account's credentials to work.
+func (c *KcClient) Ping(ctx context.Context) error {
+	var total int
+	return c.admin(ctx, "GET", "/users/count", nil, &total)
+}
+
+func (c *KcClient) getKcUser(ctx context.Context, id string) (*model.KcUser, error) {
+	u := new(model.KcUser)
+	if err := c.admin(ctx, "GET", "/users/"+id, nil, u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// updateUser applies mutate to the full user representation and writes it
+// back, since Keycloak replaces attributes wholesale.
+func (c *KcClient) updateUser(
+	ctx context.Context,
+	id string,
+	mutate func(u *model.KcUser),
+) (*model.User, error) {
+	u, err := c.getKcUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	mutate(u)
+	if err := c.admin(ctx, "PUT", "/users/"+id, u, nil); err != nil {
+		return nil, err
+	}
+	return kcToUser(u), nil
+}
+
+func (c *KcClient) realmRoles(ctx context.Context, id string) ([]model.KcRole, error) {
+	var roles []model.KcRole
+	if err := c.admin(ctx, "GET", "/users/"+id+"/role-mappings/realm", nil, &roles); err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+func (c *KcClient) userInfo(ctx context.Context, token string) (*model.KcUserInfo, error) {
+	req, _ := http.NewRequestWithContext(
+		ctx,
+		"GET",
+		c.issuerBase+"/protocol/openid-connect/userinfo",
+		nil,
+	)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	info := new(model.KcUserInfo)
+	if err := c.executeAndParseResponse(req, info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func (c *KcClient) redirectQuery(redirect string) string {
+	q := url.Values{"client_id": {c.clientID}}
+	if redirect != "" {
+		q.Set("redirect_uri", redirect)
+	}
+	return q.Encode()
+}
+
+// adminToken returns a cached client credentials token, fetching a new one
+// when it is missing or about to expire.
+func (c *KcClient) adminToken(ctx context.Context) (string, error) {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+
+	if c.token != "" && time.Now().Add(kcTokenLeeway).Before(c.tokenExpiry) {
+		return c.token, nil
+	}
+
+	token, err := c.tokenRequest(ctx, url.Values{"grant_type": {"client_credentials"}})
+	if err != nil {
+		return "", err
+	}
+
+	c.token = token.AccessToken
+	c.tokenExpiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	return c.token, nil
+}
+
+func (c *KcClient) tokenRequest(ctx context.Context, form url.Values) (*model.KcToken, error) {
+	form.Set("client_id", c.clientID)
+	form.Set("client_secret", c.clientSecret)
+
+	req, _ := http.NewRequestWithContext(
+		ctx,
+		"POST",
+		c.issuerBase+"/protocol/openid-connect/token",
+		strings.NewReader(form.Encode()),
+	)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	token := new(model.KcToken)
+	if err := c.executeAndParseResponse(req, token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func (c *KcClient) adminRequest(
+	ctx context.Context,
+	method string,
+	p string,
+	body any,
+) (*http.Request, error) {
+	token, err := c.adminToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var reader io.Reader
+	if body != nil {
+		rJSON, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(rJSON)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.adminBase+p, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
+
+func (c *KcClient) admin(
+	ctx context.Context,
+	method string,
+	p string,
+	body any,
+	response any,
+) error {
+	req, err := c.adminRequest(ctx, method, p, body)
+	if err != nil {
+		return err
+	}
+	return c.executeAndParseResponse(req, response)
+}
+
+func (c *KcClient) executeAndParseResponse(req *http.Request, response any) error {
+	req, cancel := withCallTimeout(req, c.timeout)
+	defer cancel()
+
+	res, err := c.send(req)
+	if err != nil {
+		return err
+	}
+
+	if response == nil {
+		return res.Body.Close()
+	}
+	return c.h.ParseResponse(res, response)
+}
+
+// send executes req and turns non 2xx responses into IamErrors.
+func (c *KcClient) send(req *http.Request) (*http.Response, error) {
+	res, err := c.h.ExecuteRequest(req)
+	if err != nil {
+		if cerr := utils.ContextError(req.Context()); cerr != nil {
+			return nil, cerr
+		}
+		return nil, err
+	}
+
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return res, nil
+	}
+
+	errRes := new(model.KcError)
+	_ = c.h.ParseResponse(res, errRes)
+
+	message := errRes.ErrorMessage
+	if message == "" {
+		message = errRes.ErrorDescription
+	}
+	if message == "" {
+		message = errRes.Error
+	}
+	if message == "" {
+		message = http.StatusText(res.StatusCode)
+	}
+
+	status, errType := kcErrorType(res.StatusCode, errRes)
+	return nil, utils.NewIamError(status, errType, message)
+}
+
+// kcErrorType translates a Keycloak error into the status and Appwrite style
+// type the other identity providers report for the same failure.
+func kcErrorType(status int, e *model.KcError) (int, string) {
+	switch {
+	case e.Error == "invalid_grant" && strings.Contains(e.ErrorDescription, "disabled"):
+		return http.StatusUnauthorized, constants.ERR_TYPE_USER_BLOCKED
+	case e.Error == "invalid_grant":
+		return http.StatusUnauthorized, constants.ERR_TYPE_INVALID_CREDENTIALS
+	case e.Error == "invalid_token":
+		return http.StatusUnauthorized, constants.ERR_TYPE_INVALID_TOKEN
+	case e.Error == "invalid_client", e.Error == "unauthorized_client":
+		return status, constants.ERR_TYPE_UNAUTHORIZED_SCOPE
+	case status == http.StatusConflict:
+		return status, constants.ERR_TYPE_USER_ALREADY_EXISTS
+	case status == http.StatusNotFound:
+		return status, constants.ERR_TYPE_USER_NOT_FOUND
+	case status == http.StatusTooManyRequests:
+		return status, constants.ERR_TYPE_RATE_LIMITED
+	}
+	return status, ""
+}
+
+func unsupported() error {
+	return utils.NewIamError(
+		http.StatusNotImplemented,
+		constants.ERR_TYPE_UNSUPPORTED,
+		constants.Unsupported,
+	)
+}
+
+func kcToUser(u *model.KcUser) *model.User {
+	created := millisToTime(u.CreatedTimestamp)
+	verified, _ := strconv.ParseBool(attr(u, constants.KC_ATTR_PHONE_VERIFIED))
+	return &model.User{
+		ID:                u.ID,
+		CreatedAt:         created,
+		UpdatedAt:         created,
+		Name:              strings.TrimSpace(u.FirstName + " " + u.LastName),
+		Email:             u.Email,
+		Phone:             attr(u, constants.KC_ATTR_PHONE),
+		Status:            u.Enabled,
+		Registration:      created,
+		EmailVerification: u.EmailVerified,
+		PhoneVerification: verified,
+		Prefs:             kcPrefs(u),
+	}
+}
+
+func kcPrefs(u *model.KcUser) model.Prefs {
+	prefs := model.Prefs{}
+	if raw := attr(u, constants.KC_ATTR_PREFS); raw != "" {
+		_ = json.Unmarshal([]byte(raw), &prefs)
+	}
+	return prefs
+}
+
+func attr(u *model.KcUser, key string) string {
+	if v := u.Attributes[key]; len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func setAttr(u *model.KcUser, key string, value string) {
+	if u.Attributes == nil {
+		u.Attributes = map[string][]string{}
+	}
+	u.Attributes[key] = []string{value}
+}
+
+// splitName maps a display name onto Keycloak's first and last name fields.
+func splitName(name string) (string, string) {
+	first, last, _ := strings.Cut(strings.TrimSpace(name), " ")
+	return first, strings.TrimSpace(last)
+}
+
+func millisToTime(ms int64) string {
+	if ms == 0 {
+		return ""
+	}
+	return time.UnixMilli(ms).UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,364 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"gitea.slauson.io/slausonio/go-utils/sioUtils"
+	"gitea.slauson.io/slausonio/iam-ms/config"
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+	"gitea.slauson.io/slausonio/iam-ms/model"
+)
+
+// fakeKeycloak serves the handful of admin and OpenID Connect endpoints the
+// KcClient uses for a single user "a".
+type fakeKeycloak struct {
+	tokens  atomic.Int32
+	user    model.KcUser
+	roles   []model.KcRole
+	removed []model.KcRole
+	added   []model.KcRole
+}
+
+func (f *fakeKeycloak) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	write := func(v any) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(v)
+	}
+
+	if strings.HasPrefix(r.URL.Path, "/realms/test/protocol/openid-connect/") {
+		f.serveOIDC(w, r, write)
+		return
+	}
+
+	if r.Header.Get("Authorization") != "Bearer admin-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		write(model.KcError{Error: "HTTP 401 Unauthorized"})
+		return
+	}
+
+	switch p := strings.TrimPrefix(r.URL.Path, "/admin/realms/test"); {
+	case p == "/users/count":
+		write(1)
+	case p == "/users" && r.Method == "GET":
+		write([]model.KcUser{f.user})
+	case p == "/users" && r.Method == "POST":
+		_ = json.NewDecoder(r.Body).Decode(&f.user)
+		f.user.ID = "a"
+		w.Header().Set("Location", "http://kc/admin/realms/test/users/a")
+		w.WriteHeader(http.StatusCreated)
+	case p == "/users/a" && r.Method == "GET":
+		write(f.user)
+	case p == "/users/a" && r.Method == "PUT":
+		_ = json.NewDecoder(r.Body).Decode(&f.user)
+		w.WriteHeader(http.StatusNoContent)
+	case p == "/users/missing":
+		w.WriteHeader(http.StatusNotFound)
+		write(model.KcError{ErrorMessage: "User not found"})
+	case p == "/users/a/role-mappings/realm":
+		switch r.Method {
+		case "GET":
+			write(f.roles)
+		case "DELETE":
+			_ = json.NewDecoder(r.Body).Decode(&f.removed)
+			w.WriteHeader(http.StatusNoContent)
+		case "POST":
+			_ = json.NewDecoder(r.Body).Decode(&f.added)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	case strings.HasPrefix(p, "/roles/"):
+		name := strings.TrimPrefix(p, "/roles/")
+		write(model.KcRole{ID: name + "-id", Name: name})
+	case p == "/users/a/sessions":
+		write([]model.KcSession{{
+			ID:        "s",
+			UserID:    "a",
+			IPAddress: "127.0.0.1",
+			Start:     1700000000000,
+			Clients:   map[string]string{"c": "blog"},
+		}})
+	case p == "/users/a/logout", p == "/sessions/s", p == "/users/a/reset-password",
+		p == "/users/a/execute-actions-email", p == "/users/a/send-verify-email":
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeKeycloak) serveOIDC(w http.ResponseWriter, r *http.Request, write func(v any)) {
+	switch strings.TrimPrefix(r.URL.Path, "/realms/test/protocol/openid-connect") {
+	case "/token":
+		_ = r.ParseForm()
+		switch r.PostForm.Get("grant_type") {
+		case "client_credentials":
+			f.tokens.Add(1)
+			write(model.KcToken{AccessToken: "admin-token", ExpiresIn: 300})
+		case "password":
+			if r.PostForm.Get("password") != "Password123!" {
+				w.WriteHeader(http.StatusUnauthorized)
+				write(model.KcError{
+					Error:            "invalid_grant",
+					ErrorDescription: "Invalid user credentials",
+				})
+				return
+			}
+			write(model.KcToken{AccessToken: "user-token", ExpiresIn: 300, SessionState: "s"})
+		}
+	case "/userinfo":
+		if r.Header.Get("Authorization") != "Bearer user-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		write(model.KcUserInfo{Sub: "a"})
+	}
+}
+
+func initKcForTests(t *testing.T) (*KcClient, *fakeKeycloak) {
+	f := &fakeKeycloak{
+		user: model.KcUser{
+			ID:               "a",
+			CreatedTimestamp: 1700000000000,
+			Username:         "t@t.com",
+			Email:            "t@t.com",
+			Enabled:          true,
+			FirstName:        "Matt",
+			LastName:         "Slauson",
+			Attributes: map[string][]string{
+				"phoneNumber": {"+15555555555"},
+				"prefs":       {`{"theme":"dark"}`},
+			},
+		},
+		roles: []model.KcRole{
+			{ID: "reader-id", Name: "reader"},
+			{ID: "default-id", Name: "default-roles-test"},
+		},
+	}
+	s := httptest.NewServer(f)
+	t.Cleanup(s.Close)
+
+	return &KcClient{
+		h:          sioUtils.NewRestHelpers(),
+		adminBase:  s.URL + "/admin/realms/test",
+		issuerBase: s.URL + "/realms/test",
+		clientID:   "iam-ms",
+	}, f
+}
+
+func TestNewKcClient(t *testing.T) {
+	cfg := config.Default()
+	cfg.Keycloak.AdminBase = "https://kc/admin/realms/blog/"
+	cfg.Keycloak.ClientSecret = "s"
+	c := NewKcClient(cfg)
+	assert.Equal(t, "https://kc/admin/realms/blog", c.adminBase)
+	assert.Equal(t, "s", c.clientSecret)
+}
+
+func TestKcClient_AdminTokenCached(t *testing.T) {
+	c, f := initKcForTests(t)
+
+	for i := 0; i < 3; i++ {
+		_, err := c.GetUserByID(context.Background(), "a")
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, int32(1), f.tokens.Load())
+
+	// A token inside the refresh leeway is replaced.
+	c.tokenExpiry = c.tokenExpiry.Add(-290 * time.Second)
+	_, err := c.GetUserByID(context.Background(), "a")
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), f.tokens.Load())
+}
+
+func TestKcClient_GetUserByID(t *testing.T) {
+	c, _ := initKcForTests(t)
+
+	actual, err := c.GetUserByID(context.Background(), "a")
+	assert.Nil(t, err)
+	assert.Equal(t, &model.User{
+		ID:           "a",
+		CreatedAt:    "2023-11-14T22:13:20Z",
+		UpdatedAt:    "2023-11-14T22:13:20Z",
+		Name:         "Matt Slauson",
+		Email:        "t@t.com",
+		Phone:        "+15555555555",
+		Status:       true,
+		Registration: "2023-11-14T22:13:20Z",
+		Prefs:        model.Prefs{"theme": "dark"},
+	}, actual)
+}
+
+func TestKcClient_GetUserByID_Error(t *testing.T) {
+	c, _ := initKcForTests(t)
+
+	actual, err := c.GetUserByID(context.Background(), "missing")
+	assert.Nil(t, actual)
+	assertIamError(t, err, http.StatusNotFound, constants.ERR_TYPE_USER_NOT_FOUND)
+}
+
+func TestKcClient_ListUsers(t *testing.T) {
+	c, _ := initKcForTests(t)
+
+	actual, err := c.ListUsers(
+		context.Background(),
+		&model.ListUsersParams{Limit: 25, Email: "t@t.com"},
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, actual.Total)
+	assert.Equal(t, "a", actual.Users[0].ID)
+
+	_, err = c.ListUsers(context.Background(), &model.ListUsersParams{Limit: 25, Cursor: "a"})
+	assert.NotNil(t, err)
+}
+
+func TestKcClient_CreateUser(t *testing.T) {
+	c, f := initKcForTests(t)
+
+	actual, err := c.CreateUser(context.Background(), &model.NewUser{
+		Email:    "n@t.com",
+		Phone:    "+15555555555",
+		Password: "Password123!",
+		Name:     "New User",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "a", actual.ID)
+	assert.Equal(t, "New User", actual.Name)
+	assert.Equal(t, "Password123!", f.user.Credentials[0].Value)
+}
+
+func TestKcClient_Updates(t *testing.T) {
+	c, f := initKcForTests(t)
+
+	_, err := c.UpdateName(context.Background(), "a", "Matthew J Slauson")
+	assert.Nil(t, err)
+	assert.Equal(t, "Matthew", f.user.FirstName)
+	assert.Equal(t, "J Slauson", f.user.LastName)
+
+	_, err = c.UpdateEmail(context.Background(), "a", "n@t.com")
+	assert.Nil(t, err)
+	assert.Equal(t, "n@t.com", f.user.Username)
+
+	u, err := c.UpdatePhoneVerification(context.Background(), "a", true)
+	assert.Nil(t, err)
+	assert.True(t, u.PhoneVerification)
+	assert.Equal(t, "+15555555555", u.Phone)
+
+	u, err = c.UpdateStatus(context.Background(), "a", false)
+	assert.Nil(t, err)
+	assert.False(t, u.Status)
+
+	_, err = c.UpdatePassword(context.Background(), "a", "Password123!")
+	assert.Nil(t, err)
+
+	prefs, err := c.UpdatePrefs(context.Background(), "a", model.Prefs{"theme": "light"})
+	assert.Nil(t, err)
+	assert.Equal(t, model.Prefs{"theme": "light"}, prefs)
+	assert.Equal(t, []string{`{"theme":"light"}`}, f.user.Attributes["prefs"])
+}
+
+func TestKcClient_UpdateLabels(t *testing.T) {
+	c, f := initKcForTests(t)
+
+	_, err := c.UpdateLabels(context.Background(), "a", []string{"default-roles-test", "admin"})
+	assert.Nil(t, err)
+	assert.Equal(t, []model.KcRole{{ID: "reader-id", Name: "reader"}}, f.removed)
+	assert.Equal(t, []model.KcRole{{ID: "admin-id", Name: "admin"}}, f.added)
+}
+
+func TestKcClient_Sessions(t *testing.T) {
+	c, _ := initKcForTests(t)
+
+	session, err := c.CreateEmailSession(context.Background(), "t@t.com", "Password123!")
+	assert.Nil(t, err)
+	assert.Equal(t, "s", session.ID)
+	assert.Equal(t, "a", session.UserID)
+	assert.Equal(t, "user-token", session.Secret)
+
+	_, err = c.CreateEmailSession(context.Background(), "t@t.com", "wrong")
+	assertIamError(t, err, http.StatusUnauthorized, constants.ERR_TYPE_INVALID_CREDENTIALS)
+
+	labels, err := c.GetSessionUser(context.Background(), "user-token")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"reader", "default-roles-test"}, labels.Labels)
+
+	sessions, err := c.ListSessions(context.Background(), "a")
+	assert.Nil(t, err)
+	assert.Equal(t, []model.Session{{
+		ID:         "s",
+		CreatedAt:  "2023-11-14T22:13:20Z",
+		UserID:     "a",
+		Provider:   "email",
+		ClientName: "blog",
+		Ip:         "127.0.0.1",
+	}}, sessions)
+
+	assert.Nil(t, c.DeleteSession(context.Background(), "a", "s"))
+	// Another user's session is not the caller's to revoke.
+	err = c.DeleteSession(context.Background(), "a", "other")
+	assertIamError(t, err, http.StatusNotFound, constants.ERR_TYPE_SESSION_NOT_FOUND)
+	assert.Nil(t, c.DeleteSessions(context.Background(), "a"))
+}
+
+func TestKcClient_RecoveryAndVerification(t *testing.T) {
+	c, _ := initKcForTests(t)
+
+	assert.Nil(t, c.CreateRecovery(context.Background(), "t@t.com"))
+	assert.Nil(t, c.SendEmailVerification(context.Background(), "user-token"))
+	assert.NotNil(t, c.ConfirmRecovery(context.Background(), "a", "s", "p"))
+	assert.NotNil(t, c.ConfirmEmailVerification(context.Background(), "a", "s"))
+	assert.NotNil(t, c.SendPhoneVerification(context.Background(), "user-token"))
+	assert.NotNil(t, c.ConfirmPhoneVerification(context.Background(), "a", "s"))
+}
+
+func TestKcErrorType(t *testing.T) {
+	tests := []struct {
+		status  int
+		err     model.KcError
+		want    int
+		errType string
+	}{
+		{
+			status:  http.StatusBadRequest,
+			err:     model.KcError{Error: "invalid_grant", ErrorDescription: "Account disabled"},
+			want:    http.StatusUnauthorized,
+			errType: constants.ERR_TYPE_USER_BLOCKED,
+		},
+		{
+			status:  http.StatusUnauthorized,
+			err:     model.KcError{Error: "invalid_client"},
+			want:    http.StatusUnauthorized,
+			errType: constants.ERR_TYPE_UNAUTHORIZED_SCOPE,
+		},
+		{
+			status:  http.StatusConflict,
+			err:     model.KcError{ErrorMessage: "User exists with same username"},
+			want:    http.StatusConflict,
+			errType: constants.ERR_TYPE_USER_ALREADY_EXISTS,
+		},
+		{
+			status: http.StatusInternalServerError,
+			want:   http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		status, errType := kcErrorType(tt.status, &tt.err)
+		assert.Equal(t, tt.want, status)
+		assert.Equal(t, tt.errType, errType)
+	}
+}
+
+func TestKcClient_Ping(t *testing.T) {
+	c, _ := initKcForTests(t)
+	assert.Nil(t, c.Ping(context.Background()))
+
+	// A token Keycloak no longer accepts.
+	c.token = "revoked"
+	assert.NotNil(t, c.Ping(context.Background()))
+}
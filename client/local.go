@@ -0,0 +1,976 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+	"golang.org/x/crypto/bcrypt"
+
+	"gitea.slauson.io/slausonio/iam-ms/config"
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+	"gitea.slauson.io/slausonio/iam-ms/model"
+	"gitea.slauson.io/slausonio/iam-ms/utils"
+)
+
+const (
+	// localSessionTTL matches Appwrite's default session length.
+	localSessionTTL = 365 * 24 * time.Hour
+	localSecretTTL  = time.Hour
+)
+
+const (
+	localSecretRecovery = "recovery"
+	localSecretEmail    = "email"
+	localSecretPhone    = "phone"
+)
+
+// localDummyHash is compared against when there is no password to check, so
+// unknown emails take as long to reject as wrong passwords.
+const localDummyHash = "$2a$10$2EBdw8RjRa5CG88ArkyhMOn2BbFpIbGOrLdsvDbPTzDu0HsgTddZ2"
+
+// localIDPattern is Appwrite's rule for custom IDs. Keeping '/' out also keeps
+// one user's session keys from prefixing another's.
+var localIDPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]{0,35}$`)
+
+var (
+	localUsersBucket    = []byte("users")
+	localEmailsBucket   = []byte("emails")
+	localSessionsBucket = []byte("sessions")
+	localTokensBucket   = []byte("tokens")
+	localSecretsBucket  = []byte("secrets")
+)
+
+// LocalStore is an identity provider that keeps users and sessions in an
+// embedded BoltDB file at IAM_LOCAL_DB, for local development and small
+// deployments that run without an external identity provider.
+//
+// Passwords are bcrypt hashed. Session tokens are random values handed out as
+// the session secret and are sent back in the X-Appwrite-JWT header in place
+// of an Appwrite JWT. Only hashes of session tokens and one time secrets are
+// stored. There is no mailer: recovery and verification links are logged in
+// full when IAM_LOCAL_LOG_LINKS is set, for development, and otherwise only
+// noted, which leaves those flows unusable.
+// Calls never leave the process, so the context arguments go unused.
+type LocalStore struct {
+	db          *bolt.DB
+	cost        int
+	recoveryURL string
+	verifyURL   string
+	adminEmail  string
+	notify      func(u *model.User, kind string, link string)
+}
+
+func NewLocalStore(cfg *config.Config) (*LocalStore, error) {
+	db, err := bolt.Open(cfg.Local.DB, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{
+			localUsersBucket,
+			localEmailsBucket,
+			localSessionsBucket,
+			localTokensBucket,
+			localSecretsBucket,
+		} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return grantLocalAdmin(tx, cfg.Local.AdminEmail)
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return &LocalStore{
+		db:          db,
+		cost:        bcrypt.DefaultCost,
+		recoveryURL: cfg.IAM.RecoveryURL,
+		verifyURL:   cfg.IAM.VerificationURL,
+		adminEmail:  cfg.Local.AdminEmail,
+		notify:      localNotifier(cfg.Local.LogLinks),
+	}, nil
+}
+
+// Close releases the database file lock.
+func (s *LocalStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *LocalStore) ListUsers(
+	ctx context.Context,
+	p *model.ListUsersParams,
+) (*model.UserList, error) {
+	var createdAfter time.Time
+	if p.CreatedAfter != "" {
+		t, err := time.Parse(time.RFC3339, p.CreatedAfter)
+		if err != nil {
+			return nil, utils.NewIamError(
+				http.StatusBadRequest,
+				constants.ERR_TYPE_ARGUMENT_INVALID,
+				"createdAfter must be an RFC3339 timestamp",
+			)
+		}
+		createdAfter = t
+	}
+
+	var users []model.LocalUser
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(localUsersBucket).ForEach(func(_, v []byte) error {
+			u := model.LocalUser{}
+			if err := json.Unmarshal(v, &u); err != nil {
+				return err
+			}
+			if localUserMatches(&u, p, createdAfter) {
+				users = append(users, u)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Seq < users[j].Seq })
+
+	start := 0
+	if p.Cursor != "" {
+		start = -1
+		for i := range users {
+			if users[i].ID == p.Cursor {
+				start = i + 1
+				break
+			}
+		}
+		if start < 0 {
+			return nil, utils.NewIamError(
+				http.StatusBadRequest,
+				constants.ERR_TYPE_ARGUMENT_INVALID,
+				"cursor does not match a user",
+			)
+		}
+	}
+	start += p.Offset
+
+	result := &model.UserList{Total: len(users), Users: []model.User{}}
+	for i := start; i < len(users); i++ {
+		if p.Limit > 0 && len(result.Users) == p.Limit {
+			break
+		}
+		result.Users = append(result.Users, users[i].User)
+	}
+	return result, nil
+}
+
+func (s *LocalStore) GetUserByID(ctx context.Context, id string) (*model.User, error) {
+	var u *model.LocalUser
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		u, err = getLocalUser(tx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &u.User, nil
+}
+
+func (s *LocalStore) CreateUser(ctx context.Context, nu *model.NewUser) (*model.User, error) {
+	id := nu.ID
+	if id == "" || id == "unique()" {
+		b, err := utils.RandomBytes(10)
+		if err != nil {
+			return nil, err
+		}
+		// Ten bytes hex encoded are IDs shaped like Appwrite's.
+		id = hex.EncodeToString(b)
+	} else if !localIDPattern.MatchString(id) {
+		return nil, utils.NewIamError(
+			http.StatusBadRequest,
+			constants.ERR_TYPE_ARGUMENT_INVALID,
+			constants.InvalidUserID,
+		)
+	}
+
+	hash := ""
+	if nu.Password != "" {
+		h, err := bcrypt.GenerateFromPassword([]byte(nu.Password), s.cost)
+		if err != nil {
+			return nil, err
+		}
+		hash = string(h)
+	}
+
+	now := localNow()
+	u := &model.LocalUser{
+		User: model.User{
+			ID:             id,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+			Name:           nu.Name,
+			Email:          nu.Email,
+			Phone:          nu.Phone,
+			Status:         true,
+			Registration:   now,
+			PasswordUpdate: now,
+			Prefs:          model.Prefs{},
+		},
+		PasswordHash: hash,
+		Labels:       []string{},
+	}
+	if s.adminEmail != "" && strings.EqualFold(u.Email, s.adminEmail) {
+		u.Labels = []string{constants.ROLE_ADMIN}
+	}
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		users := tx.Bucket(localUsersBucket)
+		if users.Get([]byte(id)) != nil {
+			return userAlreadyExists()
+		}
+		if err := claimLocalEmail(tx, u.Email, id); err != nil {
+			return err
+		}
+
+		seq, err := users.NextSequence()
+		if err != nil {
+			return err
+		}
+		u.Seq = seq
+		return putLocalUser(tx, u)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &u.User, nil
+}
+
+func (s *LocalStore) UpdateEmail(
+	ctx context.Context,
+	id string,
+	email string,
+) (*model.User, error) {
+	return s.updateUser(id, func(tx *bolt.Tx, u *model.LocalUser) error {
+		if strings.EqualFold(u.Email, email) {
+			u.Email = email
+			return nil
+		}
+		if err := claimLocalEmail(tx, email, id); err != nil {
+			return err
+		}
+		if err := releaseLocalEmail(tx, u.Email); err != nil {
+			return err
+		}
+		u.Email = email
+		u.EmailVerification = false
+		return nil
+	})
+}
+
+func (s *LocalStore) UpdatePhone(
+	ctx context.Context,
+	id string,
+	number string,
+) (*model.User, error) {
+	return s.updateUser(id, func(_ *bolt.Tx, u *model.LocalUser) error {
+		if u.Phone != number {
+			u.Phone = number
+			u.PhoneVerification = false
+		}
+		return nil
+	})
+}
+
+func (s *LocalStore) UpdatePassword(
+	ctx context.Context,
+	id string,
+	password string,
+) (*model.User, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), s.cost)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.updateUser(id, func(_ *bolt.Tx, u *model.LocalUser) error {
+		u.PasswordHash = string(hash)
+		u.PasswordUpdate = localNow()
+		return nil
+	})
+}
+
+func (s *LocalStore) UpdateName(ctx context.Context, id string, name string) (*model.User, error) {
+	return s.updateUser(id, func(_ *bolt.Tx, u *model.LocalUser) error {
+		u.Name = name
+		return nil
+	})
+}
+
+func (s *LocalStore) UpdateStatus(
+	ctx context.Context,
+	id string,
+	status bool,
+) (*model.User, error) {
+	return s.updateUser(id, func(_ *bolt.Tx, u *model.LocalUser) error {
+		u.Status = status
+		return nil
+	})
+}
+
+func (s *LocalStore) UpdateEmailVerification(
+	ctx context.Context,
+	id string,
+	verified bool,
+) (*model.User, error) {
+	return s.updateUser(id, func(_ *bolt.Tx, u *model.LocalUser) error {
+		u.EmailVerification = verified
+		return nil
+	})
+}
+
+func (s *LocalStore) UpdatePhoneVerification(
+	ctx context.Context,
+	id string,
+	verified bool,
+) (*model.User, error) {
+	return s.updateUser(id, func(_ *bolt.Tx, u *model.LocalUser) error {
+		u.PhoneVerification = verified
+		return nil
+	})
+}
+
+func (s *LocalStore) GetPrefs(ctx context.Context, id string) (model.Prefs, error) {
+	u, err := s.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return u.Prefs, nil
+}
+
+func (s *LocalStore) UpdatePrefs(
+	ctx context.Context,
+	id string,
+	prefs model.Prefs,
+) (model.Prefs, error) {
+	u, err := s.updateUser(id, func(_ *bolt.Tx, u *model.LocalUser) error {
+		u.Prefs = prefs
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return u.Prefs, nil
+}
+
+func (s *LocalStore) GetLabels(ctx context.Context, id string) (*model.UserLabels, error) {
+	var u *model.LocalUser
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		u, err = getLocalUser(tx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &model.UserLabels{ID: u.ID, Labels: u.Labels}, nil
+}
+
+func (s *LocalStore) UpdateLabels(
+	ctx context.Context,
+	id string,
+	labels []string,
+) (*model.UserLabels, error) {
+	_, err := s.updateUser(id, func(_ *bolt.Tx, u *model.LocalUser) error {
+		u.Labels = labels
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &model.UserLabels{ID: id, Labels: labels}, nil
+}
+
+// GetSessionUser resolves a session token handed out by CreateEmailSession.
+func (s *LocalStore) GetSessionUser(ctx context.Context, token string) (*model.UserLabels, error) {
+	var u *model.LocalUser
+	err := s.db.View(func(tx *bolt.Tx) error {
+		session, err := localSessionForToken(tx, token)
+		if err != nil {
+			return err
+		}
+		if u, err = getLocalUser(tx, session.UserID); err != nil {
+			return invalidToken()
+		}
+		if !u.Status {
+			return userBlocked()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &model.UserLabels{ID: u.ID, Labels: u.Labels}, nil
+}
+
+func (s *LocalStore) DeleteUser(ctx context.Context, id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		u, err := getLocalUser(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := releaseLocalEmail(tx, u.Email); err != nil {
+			return err
+		}
+		if err := deleteLocalSessions(tx, id); err != nil {
+			return err
+		}
+		return tx.Bucket(localUsersBucket).Delete([]byte(id))
+	})
+}
+
+// CreateRecovery issues a recovery secret for the user with email and hands
+// the recovery link to the notifier.
+func (s *LocalStore) CreateRecovery(ctx context.Context, email string) error {
+	var u *model.LocalUser
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		u, err = getLocalUserByEmail(tx, email)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return s.issueSecret(&u.User, localSecretRecovery, s.recoveryURL)
+}
+
+func (s *LocalStore) ConfirmRecovery(
+	ctx context.Context,
+	userID string,
+	secret string,
+	password string,
+) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), s.cost)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		u, err := consumeLocalSecret(tx, userID, secret, localSecretRecovery)
+		if err != nil {
+			return err
+		}
+		u.PasswordHash = string(hash)
+		u.PasswordUpdate = localNow()
+		u.UpdatedAt = u.PasswordUpdate
+		return putLocalUser(tx, u)
+	})
+}
+
+func (s *LocalStore) SendEmailVerification(ctx context.Context, token string) error {
+	u, err := s.sessionUser(ctx, token)
+	if err != nil {
+		return err
+	}
+	return s.issueSecret(u, localSecretEmail, s.verifyURL)
+}
+
+func (s *LocalStore) ConfirmEmailVerification(
+	ctx context.Context,
+	userID string,
+	secret string,
+) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		u, err := consumeLocalSecret(tx, userID, secret, localSecretEmail)
+		if err != nil {
+			return err
+		}
+		u.EmailVerification = true
+		u.UpdatedAt = localNow()
+		return putLocalUser(tx, u)
+	})
+}
+
+func (s *LocalStore) SendPhoneVerification(ctx context.Context, token string) error {
+	u, err := s.sessionUser(ctx, token)
+	if err != nil {
+		return err
+	}
+	if u.Phone == "" {
+		return utils.NewIamError(
+			http.StatusBadRequest,
+			constants.ERR_TYPE_ARGUMENT_INVALID,
+			"The user does not have a phone number.",
+		)
+	}
+	return s.issueSecret(u, localSecretPhone, "")
+}
+
+func (s *LocalStore) ConfirmPhoneVerification(
+	ctx context.Context,
+	userID string,
+	secret string,
+) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		u, err := consumeLocalSecret(tx, userID, secret, localSecretPhone)
+		if err != nil {
+			return err
+		}
+		u.PhoneVerification = true
+		u.UpdatedAt = localNow()
+		return putLocalUser(tx, u)
+	})
+}
+
+// CreateEmailSession checks the password and returns a new session whose
+// secret is the session token.
+func (s *LocalStore) CreateEmailSession(
+	ctx context.Context,
+	email string,
+	password string,
+) (*model.Session, error) {
+	var u *model.LocalUser
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		u, err = getLocalUserByEmail(tx, email)
+		return err
+	})
+	hash := localDummyHash
+	if err == nil && u.PasswordHash != "" {
+		hash = u.PasswordHash
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil ||
+		hash == localDummyHash {
+		return nil, utils.NewIamError(
+			http.StatusUnauthorized,
+			constants.ERR_TYPE_INVALID_CREDENTIALS,
+			constants.InvalidCredentials,
+		)
+	}
+	if !u.Status {
+		return nil, userBlocked()
+	}
+
+	token, err := localRandomToken()
+	if err != nil {
+		return nil, err
+	}
+	sessionID, err := utils.RandomBytes(10)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	session := &model.LocalSession{
+		Session: model.Session{
+			ID:        hex.EncodeToString(sessionID),
+			CreatedAt: now.Format(time.RFC3339),
+			UserID:    u.ID,
+			Expire:    now.Add(localSessionTTL).Format(time.RFC3339),
+			Provider:  "email",
+		},
+		TokenHash: localHash(token),
+	}
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		key := localSessionKey(u.ID, session.ID)
+		raw, err := json.Marshal(session)
+		if err != nil {
+			return err
+		}
+		if err := tx.Bucket(localSessionsBucket).Put(key, raw); err != nil {
+			return err
+		}
+		return tx.Bucket(localTokensBucket).Put([]byte(session.TokenHash), key)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := session.Session
+	result.Current = true
+	result.Secret = token
+	return &result, nil
+}
+
+func (s *LocalStore) ListSessions(ctx context.Context, id string) ([]model.Session, error) {
+	result := []model.Session{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if _, err := getLocalUser(tx, id); err != nil {
+			return err
+		}
+
+		prefix := localSessionKey(id, "")
+		c := tx.Bucket(localSessionsBucket).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			session := model.LocalSession{}
+			if err := json.Unmarshal(v, &session); err != nil {
+				return err
+			}
+			result = append(result, session.Session)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *LocalStore) DeleteSession(ctx context.Context, id string, sessionID string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		sessions := tx.Bucket(localSessionsBucket)
+		key := localSessionKey(id, sessionID)
+		raw := sessions.Get(key)
+		if raw == nil {
+			return utils.NewIamError(
+				http.StatusNotFound,
+				constants.ERR_TYPE_SESSION_NOT_FOUND,
+				constants.NoSessionFound,
+			)
+		}
+		return deleteLocalSession(tx, key, raw)
+	})
+}
+
+func (s *LocalStore) DeleteSessions(ctx context.Context, id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return deleteLocalSessions(tx, id)
+	})
+}
+
+// Ping checks the database is still open.
+func (s *LocalStore) Ping(ctx context.Context) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		return nil
+	})
+}
+
+// updateUser applies mutate to the stored user inside a single write
+// transaction.
+func (s *LocalStore) updateUser(
+	id string,
+	mutate func(tx *bolt.Tx, u *model.LocalUser) error,
+) (*model.User, error) {
+	var u *model.LocalUser
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		if u, err = getLocalUser(tx, id); err != nil {
+			return err
+		}
+		if err := mutate(tx, u); err != nil {
+			return err
+		}
+		u.UpdatedAt = localNow()
+		return putLocalUser(tx, u)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &u.User, nil
+}
+
+func (s *LocalStore) sessionUser(ctx context.Context, token string) (*model.User, error) {
+	labels, err := s.GetSessionUser(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetUserByID(ctx, labels.ID)
+}
+
+// issueSecret stores a one time secret of kind for u and passes the link that
+// confirms it to the notifier.
+func (s *LocalStore) issueSecret(u *model.User, kind string, base string) error {
+	secret, err := localRandomToken()
+	if err != nil {
+		return err
+	}
+	raw, err := json.Marshal(&model.LocalSecret{
+		UserID: u.ID,
+		Kind:   kind,
+		Expire: time.Now().UTC().Add(localSecretTTL).Format(time.RFC3339),
+	})
+	if err != nil {
+		return err
+	}
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(localSecretsBucket).Put([]byte(localHash(secret)), raw)
+	})
+	if err != nil {
+		return err
+	}
+
+	q := url.Values{"userId": {u.ID}, "secret": {secret}}
+	s.notify(u, kind, base+"?"+q.Encode())
+	return nil
+}
+
+// localNotifier logs issued links. Unless full is set the link itself is left
+// out: its secret would let anyone reading the logs act on it.
+func localNotifier(full bool) func(u *model.User, kind string, link string) {
+	if full {
+		return func(u *model.User, kind string, link string) {
+			log.Infof("local %s link for user %s: %s", kind, u.ID, link)
+		}
+	}
+	return func(u *model.User, kind string, _ string) {
+		log.Infof("local %s link issued for user %s", kind, u.ID)
+	}
+}
+
+func getLocalUser(tx *bolt.Tx, id string) (*model.LocalUser, error) {
+	raw := tx.Bucket(localUsersBucket).Get([]byte(id))
+	if raw == nil {
+		return nil, utils.NewIamError(
+			http.StatusNotFound,
+			constants.ERR_TYPE_USER_NOT_FOUND,
+			constants.NoUserFound,
+		)
+	}
+
+	u := new(model.LocalUser)
+	if err := json.Unmarshal(raw, u); err != nil {
+		return nil, err
+	}
+	if u.Prefs == nil {
+		u.Prefs = model.Prefs{}
+	}
+	return u, nil
+}
+
+func getLocalUserByEmail(tx *bolt.Tx, email string) (*model.LocalUser, error) {
+	id := tx.Bucket(localEmailsBucket).Get([]byte(strings.ToLower(email)))
+	if id == nil {
+		return nil, utils.NewIamError(
+			http.StatusNotFound,
+			constants.ERR_TYPE_USER_NOT_FOUND,
+			constants.NoUserFound,
+		)
+	}
+	return getLocalUser(tx, string(id))
+}
+
+// grantLocalAdmin gives the user with email the admin role, if there is one
+// and it does not have the role yet.
+func grantLocalAdmin(tx *bolt.Tx, email string) error {
+	id := tx.Bucket(localEmailsBucket).Get([]byte(strings.ToLower(email)))
+	if id == nil {
+		return nil
+	}
+	u, err := getLocalUser(tx, string(id))
+	if err != nil {
+		return err
+	}
+	for _, label := range u.Labels {
+		if label == constants.ROLE_ADMIN {
+			return nil
+		}
+	}
+
+	u.Labels = append(u.Labels, constants.ROLE_ADMIN)
+	u.UpdatedAt = localNow()
+	log.Infof("local user %s granted the %s role", u.ID, constants.ROLE_ADMIN)
+	return putLocalUser(tx, u)
+}
+
+func putLocalUser(tx *bolt.Tx, u *model.LocalUser) error {
+	raw, err := json.Marshal(u)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(localUsersBucket).Put([]byte(u.ID), raw)
+}
+
+// claimLocalEmail indexes email for id, failing if another user has it.
+func claimLocalEmail(tx *bolt.Tx, email string, id string) error {
+	if email == "" {
+		return nil
+	}
+
+	emails := tx.Bucket(localEmailsBucket)
+	key := []byte(strings.ToLower(email))
+	if owner := emails.Get(key); owner != nil && string(owner) != id {
+		return userAlreadyExists()
+	}
+	return emails.Put(key, []byte(id))
+}
+
+func releaseLocalEmail(tx *bolt.Tx, email string) error {
+	if email == "" {
+		return nil
+	}
+	return tx.Bucket(localEmailsBucket).Delete([]byte(strings.ToLower(email)))
+}
+
+func localSessionForToken(tx *bolt.Tx, token string) (*model.LocalSession, error) {
+	key := tx.Bucket(localTokensBucket).Get([]byte(localHash(token)))
+	if key == nil {
+		return nil, invalidToken()
+	}
+	raw := tx.Bucket(localSessionsBucket).Get(key)
+	if raw == nil {
+		return nil, invalidToken()
+	}
+
+	session := new(model.LocalSession)
+	if err := json.Unmarshal(raw, session); err != nil {
+		return nil, err
+	}
+	expire, err := time.Parse(time.RFC3339, session.Expire)
+	if err != nil || time.Now().After(expire) {
+		return nil, invalidToken()
+	}
+	return session, nil
+}
+
+func deleteLocalSession(tx *bolt.Tx, key []byte, raw []byte) error {
+	session := model.LocalSession{}
+	if err := json.Unmarshal(raw, &session); err != nil {
+		return err
+	}
+	if err := tx.Bucket(localTokensBucket).Delete([]byte(session.TokenHash)); err != nil {
+		return err
+	}
+	return tx.Bucket(localSessionsBucket).Delete(key)
+}
+
+func deleteLocalSessions(tx *bolt.Tx, id string) error {
+	// Collect first, since deleting while iterating a cursor skips keys.
+	var keys, values [][]byte
+	prefix := localSessionKey(id, "")
+	c := tx.Bucket(localSessionsBucket).Cursor()
+	for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+		keys = append(keys, append([]byte(nil), k...))
+		values = append(values, append([]byte(nil), v...))
+	}
+
+	for i := range keys {
+		if err := deleteLocalSession(tx, keys[i], values[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// consumeLocalSecret deletes a matching, unexpired secret and returns the user
+// it was issued to.
+func consumeLocalSecret(
+	tx *bolt.Tx,
+	userID string,
+	secret string,
+	kind string,
+) (*model.LocalUser, error) {
+	secrets := tx.Bucket(localSecretsBucket)
+	key := []byte(localHash(secret))
+	raw := secrets.Get(key)
+	if raw == nil {
+		return nil, invalidToken()
+	}
+
+	stored := model.LocalSecret{}
+	if err := json.Unmarshal(raw, &stored); err != nil {
+		return nil, err
+	}
+	expire, err := time.Parse(time.RFC3339, stored.Expire)
+	if err != nil || stored.UserID != userID || stored.Kind != kind || time.Now().After(expire) {
+		return nil, invalidToken()
+	}
+
+	if err := secrets.Delete(key); err != nil {
+		return nil, err
+	}
+	return getLocalUser(tx, userID)
+}
+
+func localUserMatches(u *model.LocalUser, p *model.ListUsersParams, createdAfter time.Time) bool {
+	if p.Search != "" {
+		search := strings.ToLower(p.Search)
+		found := false
+		for _, field := range []string{u.ID, u.Name, u.Email, u.Phone} {
+			if strings.Contains(strings.ToLower(field), search) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if p.Email != "" && !strings.EqualFold(p.Email, u.Email) {
+		return false
+	}
+	if p.Phone != "" && p.Phone != u.Phone {
+		return false
+	}
+	if p.Name != "" && p.Name != u.Name {
+		return false
+	}
+	if p.Status != nil && *p.Status != u.Status {
+		return false
+	}
+	if !createdAfter.IsZero() {
+		created, err := time.Parse(time.RFC3339, u.CreatedAt)
+		if err != nil || !created.After(createdAfter) {
+			return false
+		}
+	}
+	return true
+}
+
+// localSessionKey is the key of a session, under its user's ID so the user's
+// sessions can be found by prefix.
+func localSessionKey(userID string, sessionID string) []byte {
+	return []byte(userID + "/" + sessionID)
+}
+
+func localNow() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
+
+func localRandomToken() (string, error) {
+	b, err := utils.RandomBytes(32)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func localHash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func userAlreadyExists() error {
+	return utils.NewIamError(
+		http.StatusConflict,
+		constants.ERR_TYPE_USER_ALREADY_EXISTS,
+		constants.UserAlreadyExists,
+	)
+}
+
+func userBlocked() error {
+	return utils.NewIamError(
+		http.StatusUnauthorized,
+		constants.ERR_TYPE_USER_BLOCKED,
+		constants.UserBlocked,
+	)
+}
+
+func invalidToken() error {
+	return utils.NewIamError(
+		http.StatusUnauthorized,
+		constants.ERR_TYPE_INVALID_TOKEN,
+		constants.InvalidToken,
+	)
+}
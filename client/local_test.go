@@ -0,0 +1,344 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+
+	"gitea.slauson.io/slausonio/iam-ms/config"
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+	"gitea.slauson.io/slausonio/iam-ms/model"
+)
+
+// localLink is the last link handed to the notifier.
+type localLink struct {
+	kind   string
+	userID string
+	secret string
+}
+
+func initLocalForTests(t *testing.T) (*LocalStore, *localLink) {
+	cfg := config.Default()
+	cfg.Local.DB = filepath.Join(t.TempDir(), "iam.db")
+	s, err := NewLocalStore(cfg)
+	assert.Nil(t, err)
+	t.Cleanup(func() { _ = s.Close() })
+
+	last := &localLink{}
+	s.cost = bcrypt.MinCost
+	s.notify = func(u *model.User, kind string, link string) {
+		parsed, _ := url.Parse(link)
+		*last = localLink{
+			kind:   kind,
+			userID: parsed.Query().Get("userId"),
+			secret: parsed.Query().Get("secret"),
+		}
+	}
+	return s, last
+}
+
+func createLocalUser(t *testing.T, s *LocalStore, email string) *model.User {
+	u, err := s.CreateUser(context.Background(), &model.NewUser{
+		ID:       "unique()",
+		Email:    email,
+		Phone:    "+15555555555",
+		Password: "Password123!",
+		Name:     "Matt Slauson",
+	})
+	assert.Nil(t, err)
+	return u
+}
+
+func TestLocalStore_CreateUser(t *testing.T) {
+	s, _ := initLocalForTests(t)
+
+	u := createLocalUser(t, s, "t@t.com")
+	assert.Len(t, u.ID, 20)
+	assert.True(t, u.Status)
+	assert.Equal(t, model.Prefs{}, u.Prefs)
+
+	actual, err := s.GetUserByID(context.Background(), u.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, u, actual)
+
+	_, err = s.CreateUser(context.Background(), &model.NewUser{ID: "unique()", Email: "T@t.com"})
+	assertIamError(t, err, http.StatusConflict, constants.ERR_TYPE_USER_ALREADY_EXISTS)
+	_, err = s.CreateUser(context.Background(), &model.NewUser{ID: u.ID, Email: "n@t.com"})
+	assert.NotNil(t, err)
+}
+
+func TestLocalStore_CreateUser_ID(t *testing.T) {
+	s, _ := initLocalForTests(t)
+	ctx := context.Background()
+
+	u, err := s.CreateUser(ctx, &model.NewUser{ID: "matt.slauson_1", Email: "m@t.com"})
+	if assert.Nil(t, err) {
+		assert.Equal(t, "matt.slauson_1", u.ID)
+	}
+
+	for _, id := range []string{"a/b", "_a", "a b", strings.Repeat("a", 37)} {
+		_, err := s.CreateUser(ctx, &model.NewUser{ID: id, Email: id + "@t.com"})
+		assertIamError(t, err, http.StatusBadRequest, constants.ERR_TYPE_ARGUMENT_INVALID)
+	}
+}
+
+func TestLocalStore_AdminEmail(t *testing.T) {
+	cfg := config.Default()
+	cfg.Local.DB = filepath.Join(t.TempDir(), "iam.db")
+	cfg.Local.AdminEmail = "Admin@t.com"
+	s, err := NewLocalStore(cfg)
+	assert.Nil(t, err)
+	s.cost = bcrypt.MinCost
+	ctx := context.Background()
+
+	admin := createLocalUser(t, s, "admin@t.com")
+	other := createLocalUser(t, s, "other@t.com")
+	labels, _ := s.GetLabels(ctx, admin.ID)
+	assert.Equal(t, []string{constants.ROLE_ADMIN}, labels.Labels)
+	labels, _ = s.GetLabels(ctx, other.ID)
+	assert.Empty(t, labels.Labels)
+
+	// An existing account is granted the role on start.
+	_, err = s.UpdateLabels(ctx, admin.ID, []string{constants.ROLE_READER})
+	assert.Nil(t, err)
+	assert.Nil(t, s.Close())
+	s, err = NewLocalStore(cfg)
+	assert.Nil(t, err)
+	t.Cleanup(func() { _ = s.Close() })
+
+	labels, _ = s.GetLabels(ctx, admin.ID)
+	assert.Equal(t, []string{constants.ROLE_READER, constants.ROLE_ADMIN}, labels.Labels)
+}
+
+func TestLocalStore_GetUserByID_Error(t *testing.T) {
+	s, _ := initLocalForTests(t)
+
+	actual, err := s.GetUserByID(context.Background(), "missing")
+	assert.Nil(t, actual)
+	assertIamError(t, err, http.StatusNotFound, constants.ERR_TYPE_USER_NOT_FOUND)
+}
+
+func TestLocalStore_ListUsers(t *testing.T) {
+	s, _ := initLocalForTests(t)
+
+	a := createLocalUser(t, s, "a@t.com")
+	b := createLocalUser(t, s, "b@t.com")
+	c := createLocalUser(t, s, "c@t.com")
+	_, err := s.UpdateStatus(context.Background(), c.ID, false)
+	assert.Nil(t, err)
+
+	actual, err := s.ListUsers(context.Background(), &model.ListUsersParams{Limit: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, actual.Total)
+	assert.Equal(t, []string{a.ID, b.ID}, localIDs(actual.Users))
+
+	actual, err = s.ListUsers(context.Background(), &model.ListUsersParams{Limit: 2, Cursor: b.ID})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{c.ID}, localIDs(actual.Users))
+
+	active := true
+	actual, err = s.ListUsers(
+		context.Background(),
+		&model.ListUsersParams{Limit: 25, Offset: 1, Status: &active},
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, actual.Total)
+	assert.Equal(t, []string{b.ID}, localIDs(actual.Users))
+
+	actual, err = s.ListUsers(
+		context.Background(),
+		&model.ListUsersParams{Limit: 25, Search: "B@T"},
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{b.ID}, localIDs(actual.Users))
+
+	_, err = s.ListUsers(context.Background(), &model.ListUsersParams{Limit: 25, Cursor: "missing"})
+	assert.NotNil(t, err)
+}
+
+func TestLocalStore_Updates(t *testing.T) {
+	s, _ := initLocalForTests(t)
+	u := createLocalUser(t, s, "t@t.com")
+	createLocalUser(t, s, "taken@t.com")
+
+	_, err := s.UpdateEmailVerification(context.Background(), u.ID, true)
+	assert.Nil(t, err)
+	actual, err := s.UpdateEmail(context.Background(), u.ID, "n@t.com")
+	assert.Nil(t, err)
+	assert.Equal(t, "n@t.com", actual.Email)
+	assert.False(t, actual.EmailVerification)
+
+	_, err = s.UpdateEmail(context.Background(), u.ID, "taken@t.com")
+	assert.NotNil(t, err)
+
+	actual, err = s.UpdateName(context.Background(), u.ID, "Matthew Slauson")
+	assert.Nil(t, err)
+	assert.Equal(t, "Matthew Slauson", actual.Name)
+
+	actual, err = s.UpdatePhoneVerification(context.Background(), u.ID, true)
+	assert.Nil(t, err)
+	assert.True(t, actual.PhoneVerification)
+
+	prefs, err := s.UpdatePrefs(context.Background(), u.ID, model.Prefs{"theme": "dark"})
+	assert.Nil(t, err)
+	assert.Equal(t, model.Prefs{"theme": "dark"}, prefs)
+	prefs, err = s.GetPrefs(context.Background(), u.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, model.Prefs{"theme": "dark"}, prefs)
+
+	labels, err := s.UpdateLabels(context.Background(), u.ID, []string{"admin"})
+	assert.Nil(t, err)
+	assert.Equal(t, &model.UserLabels{ID: u.ID, Labels: []string{"admin"}}, labels)
+
+	_, err = s.UpdatePassword(context.Background(), u.ID, "NewPassword123!")
+	assert.Nil(t, err)
+	_, err = s.CreateEmailSession(context.Background(), "n@t.com", "Password123!")
+	assert.NotNil(t, err)
+	_, err = s.CreateEmailSession(context.Background(), "n@t.com", "NewPassword123!")
+	assert.Nil(t, err)
+
+	_, err = s.UpdateName(context.Background(), "missing", "x")
+	assert.NotNil(t, err)
+}
+
+func TestLocalStore_Sessions(t *testing.T) {
+	s, _ := initLocalForTests(t)
+	u := createLocalUser(t, s, "t@t.com")
+
+	_, err := s.CreateEmailSession(context.Background(), "t@t.com", "wrong")
+	assert.NotNil(t, err)
+	_, err = s.CreateEmailSession(context.Background(), "missing@t.com", "Password123!")
+	assert.NotNil(t, err)
+	// Matching the dummy hash signs no one in.
+	_, err = s.CreateEmailSession(context.Background(), "missing@t.com", "local-dummy-password")
+	assertIamError(t, err, http.StatusUnauthorized, constants.ERR_TYPE_INVALID_CREDENTIALS)
+
+	first, err := s.CreateEmailSession(context.Background(), "T@t.com", "Password123!")
+	assert.Nil(t, err)
+	assert.Equal(t, u.ID, first.UserID)
+	assert.NotEmpty(t, first.Secret)
+	second, err := s.CreateEmailSession(context.Background(), "t@t.com", "Password123!")
+	assert.Nil(t, err)
+
+	labels, err := s.GetSessionUser(context.Background(), first.Secret)
+	assert.Nil(t, err)
+	assert.Equal(t, u.ID, labels.ID)
+	_, err = s.GetSessionUser(context.Background(), "bogus")
+	assert.NotNil(t, err)
+
+	sessions, err := s.ListSessions(context.Background(), u.ID)
+	assert.Nil(t, err)
+	assert.Len(t, sessions, 2)
+	for _, session := range sessions {
+		assert.Empty(t, session.Secret)
+	}
+
+	assert.Nil(t, s.DeleteSession(context.Background(), u.ID, first.ID))
+	assert.NotNil(t, s.DeleteSession(context.Background(), u.ID, first.ID))
+	_, err = s.GetSessionUser(context.Background(), first.Secret)
+	assert.NotNil(t, err)
+
+	_, err = s.UpdateStatus(context.Background(), u.ID, false)
+	assert.Nil(t, err)
+	_, err = s.GetSessionUser(context.Background(), second.Secret)
+	assert.NotNil(t, err)
+	_, err = s.CreateEmailSession(context.Background(), "t@t.com", "Password123!")
+	assert.NotNil(t, err)
+
+	assert.Nil(t, s.DeleteSessions(context.Background(), u.ID))
+	sessions, err = s.ListSessions(context.Background(), u.ID)
+	assert.Nil(t, err)
+	assert.Empty(t, sessions)
+}
+
+func TestLocalStore_DeleteUser(t *testing.T) {
+	s, _ := initLocalForTests(t)
+	u := createLocalUser(t, s, "t@t.com")
+	session, err := s.CreateEmailSession(context.Background(), "t@t.com", "Password123!")
+	assert.Nil(t, err)
+
+	assert.Nil(t, s.DeleteUser(context.Background(), u.ID))
+	assert.NotNil(t, s.DeleteUser(context.Background(), u.ID))
+	_, err = s.GetSessionUser(context.Background(), session.Secret)
+	assert.NotNil(t, err)
+
+	// The email is free again.
+	createLocalUser(t, s, "t@t.com")
+}
+
+func TestLocalStore_Recovery(t *testing.T) {
+	s, last := initLocalForTests(t)
+	u := createLocalUser(t, s, "t@t.com")
+
+	assert.NotNil(t, s.CreateRecovery(context.Background(), "missing@t.com"))
+	assert.Nil(t, s.CreateRecovery(context.Background(), "t@t.com"))
+	assert.Equal(t, localSecretRecovery, last.kind)
+	assert.Equal(t, u.ID, last.userID)
+
+	assert.NotNil(t, s.ConfirmRecovery(context.Background(), u.ID, "wrong", "NewPassword123!"))
+	assert.Nil(t, s.ConfirmRecovery(context.Background(), u.ID, last.secret, "NewPassword123!"))
+	assert.NotNil(t, s.ConfirmRecovery(context.Background(), u.ID, last.secret, "NewPassword123!"))
+
+	_, err := s.CreateEmailSession(context.Background(), "t@t.com", "NewPassword123!")
+	assert.Nil(t, err)
+}
+
+func TestLocalNotifier(t *testing.T) {
+	hook := test.NewGlobal()
+	t.Cleanup(hook.Reset)
+	u := &model.User{ID: "a"}
+	link := "https://t.com/recover?secret=s3cret&userId=a"
+
+	localNotifier(false)(u, localSecretRecovery, link)
+	assert.NotContains(t, hook.LastEntry().Message, "s3cret")
+
+	localNotifier(true)(u, localSecretRecovery, link)
+	assert.Contains(t, hook.LastEntry().Message, link)
+}
+
+func TestLocalStore_Verification(t *testing.T) {
+	s, last := initLocalForTests(t)
+	u := createLocalUser(t, s, "t@t.com")
+	session, err := s.CreateEmailSession(context.Background(), "t@t.com", "Password123!")
+	assert.Nil(t, err)
+
+	assert.Nil(t, s.SendEmailVerification(context.Background(), session.Secret))
+	emailSecret := last.secret
+	assert.Nil(t, s.SendPhoneVerification(context.Background(), session.Secret))
+	assert.Equal(t, localSecretPhone, last.kind)
+
+	// Secrets only confirm the kind they were issued for.
+	assert.NotNil(t, s.ConfirmEmailVerification(context.Background(), u.ID, last.secret))
+	assert.Nil(t, s.ConfirmPhoneVerification(context.Background(), u.ID, last.secret))
+	assert.Nil(t, s.ConfirmEmailVerification(context.Background(), u.ID, emailSecret))
+
+	actual, err := s.GetUserByID(context.Background(), u.ID)
+	assert.Nil(t, err)
+	assert.True(t, actual.EmailVerification)
+	assert.True(t, actual.PhoneVerification)
+
+	assert.NotNil(t, s.SendEmailVerification(context.Background(), "bogus"))
+}
+
+func localIDs(users []model.User) []string {
+	ids := make([]string, 0, len(users))
+	for _, u := range users {
+		ids = append(ids, u.ID)
+	}
+	return ids
+}
+
+func TestLocalStore_Ping(t *testing.T) {
+	s, _ := initLocalForTests(t)
+	assert.Nil(t, s.Ping(context.Background()))
+
+	assert.Nil(t, s.Close())
+	assert.NotNil(t, s.Ping(context.Background()))
+}
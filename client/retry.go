@@ -0,0 +1,102 @@
+package client
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// retryPolicy retries transient failures of idempotent requests with full
+// jitter exponential backoff. The zero value makes a single attempt.
+type retryPolicy struct {
+	// Retries is the number of attempts after the first.
+	Retries    int
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// attempts is how often req may be sent. Only GET, DELETE and PATCH are
+// retried: Appwrite's PATCH endpoints set absolute values, while its POST and
+// PUT endpoints create resources or consume one time secrets.
+func (p retryPolicy) attempts(req *http.Request) int {
+	switch req.Method {
+	case http.MethodGet, http.MethodDelete, http.MethodPatch:
+		return 1 + p.Retries
+	}
+	return 1
+}
+
+// deletedEarlier reports whether res, the answer to a retried req, is a
+// DELETE finding nothing to delete. An earlier attempt that got no answer may
+// well have deleted it, so the call is taken as done.
+func deletedEarlier(req *http.Request, res *http.Response) bool {
+	return req.Method == http.MethodDelete && res != nil && res.StatusCode == http.StatusNotFound
+}
+
+// delay is a random wait in [0, min(MaxBackoff, Backoff*2^retry)).
+func (p retryPolicy) delay(retry int) time.Duration {
+	d := p.Backoff << retry
+	if d <= 0 || (p.MaxBackoff > 0 && d > p.MaxBackoff) {
+		d = p.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d)))
+}
+
+// transient reports whether an attempt failed in a way another attempt might
+// not: the request never got an answer or the upstream is down or overloaded.
+func transient(res *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch res.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// rewind prepares req for another attempt. Requests whose body cannot be
+// replayed are not retried.
+func rewind(req *http.Request) (*http.Request, bool) {
+	next := req.Clone(req.Context())
+	if req.Body == nil || req.Body == http.NoBody {
+		return next, true
+	}
+	if req.GetBody == nil {
+		return nil, false
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, false
+	}
+	next.Body = body
+	return next, true
+}
+
+// discard drains and closes a response that will not be parsed, so its
+// connection can be reused.
+func discard(res *http.Response) {
+	if res == nil || res.Body == nil {
+		return
+	}
+	_, _ = io.Copy(io.Discard, res.Body)
+	_ = res.Body.Close()
+}
+
+// sleep waits for d or until ctx is done, whichever comes first.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,125 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"gitea.slauson.io/slausonio/go-types/siogeneric"
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+)
+
+func initRetryForTests(t *testing.T) (*AwClient, *mock.Mock) {
+	ac, h := initForTests(t)
+	ac.retry = retryPolicy{Retries: 2}
+	ac.breaker = newBreaker("test", 3, time.Minute)
+	return ac, &h.Mock
+}
+
+func TestAwClient_Retry(t *testing.T) {
+	ac, h := initRetryForTests(t)
+
+	var bodies []string
+	record := func(args mock.Arguments) {
+		req := args.Get(0).(*http.Request)
+		if req.Body != nil {
+			b, _ := io.ReadAll(req.Body)
+			bodies = append(bodies, string(b))
+		}
+	}
+	h.On("ExecuteRequest", mock.AnythingOfType("*http.Request")).
+		Run(record).Return(nil, fmt.Errorf("connection reset by peer")).Once()
+	h.On("ExecuteRequest", mock.AnythingOfType("*http.Request")).
+		Run(record).Return(mockHttpResponse(t, nil, http.StatusBadGateway), nil).Once()
+	h.On("ExecuteRequest", mock.AnythingOfType("*http.Request")).
+		Run(record).Return(mockHttpResponse(t, mAwUser, http.StatusOK), nil).Once()
+	h.On(
+		"ParseResponse",
+		mock.AnythingOfType("*http.Response"),
+		mock.AnythingOfType("*siogeneric.AwUser"),
+	).Return(nil).Once()
+
+	_, err := ac.UpdateName(context.Background(), "a", nil)
+	assert.Nil(t, err)
+	h.AssertNumberOfCalls(t, "ExecuteRequest", 3)
+	assert.Equal(t, []string{"null", "null", "null"}, bodies, "the body is replayed")
+}
+
+func TestAwClient_Retry_NotIdempotent(t *testing.T) {
+	ac, h := initRetryForTests(t)
+
+	h.On("ExecuteRequest", mock.AnythingOfType("*http.Request")).
+		Return(nil, fmt.Errorf("connection reset by peer")).Once()
+
+	_, err := ac.CreateUser(context.Background(), mCr)
+	assert.NotNil(t, err)
+	h.AssertNumberOfCalls(t, "ExecuteRequest", 1)
+}
+
+func TestAwClient_Retry_DeleteNotFound(t *testing.T) {
+	ac, h := initRetryForTests(t)
+
+	h.On("ExecuteRequest", mock.AnythingOfType("*http.Request")).
+		Return(nil, fmt.Errorf("connection reset by peer")).Once()
+	h.On("ExecuteRequest", mock.AnythingOfType("*http.Request")).
+		Return(mockHttpResponse(t, nil, http.StatusNotFound), nil).Once()
+
+	// The first attempt may have deleted the user before its answer was lost.
+	assert.Nil(t, ac.DeleteUser(context.Background(), "a"))
+	h.AssertNumberOfCalls(t, "ExecuteRequest", 2)
+}
+
+func TestAwClient_Retry_Exhausted(t *testing.T) {
+	ac, h := initRetryForTests(t)
+
+	h.On("ExecuteRequest", mock.AnythingOfType("*http.Request")).
+		Return(mockHttpResponse(t, nil, http.StatusServiceUnavailable), nil).Times(3)
+	h.On(
+		"ParseResponse",
+		mock.AnythingOfType("*http.Response"),
+		mock.AnythingOfType("*siogeneric.AppwriteError"),
+	).Run(func(args mock.Arguments) {
+		*args.Get(1).(*siogeneric.AppwriteError) = siogeneric.AppwriteError{
+			Message: "Service unavailable",
+			Code:    http.StatusServiceUnavailable,
+		}
+	}).Return(nil).Once()
+
+	_, err := ac.GetUserByID(context.Background(), "a")
+	assert.Equal(t, "Service unavailable", err.Error())
+	h.AssertNumberOfCalls(t, "ExecuteRequest", 3)
+
+	// Three transient failures in a row opened the breaker.
+	_, err = ac.GetUserByID(context.Background(), "a")
+	assert.Equal(t, constants.ProviderDown, err.Error())
+	h.AssertNumberOfCalls(t, "ExecuteRequest", 3)
+}
+
+func TestAwClient_Retry_Canceled(t *testing.T) {
+	ac, h := initRetryForTests(t)
+	ac.retry.Backoff = time.Hour
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	h.On("ExecuteRequest", mock.AnythingOfType("*http.Request")).
+		Return(mockHttpResponse(t, nil, http.StatusBadGateway), nil).Once()
+
+	_, err := ac.GetUserByID(ctx, "a")
+	assert.Equal(t, constants.RequestTimeout, err.Error())
+	h.AssertNumberOfCalls(t, "ExecuteRequest", 1)
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	p := retryPolicy{Backoff: 100 * time.Millisecond, MaxBackoff: 250 * time.Millisecond}
+	for i := 0; i < 100; i++ {
+		assert.Less(t, p.delay(0), 100*time.Millisecond)
+		assert.Less(t, p.delay(5), 250*time.Millisecond)
+	}
+	assert.Zero(t, retryPolicy{}.delay(3))
+}
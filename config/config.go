@@ -0,0 +1,625 @@
+// Package config holds the service's settings. Load builds them once at
+// startup from, in increasing order of precedence, the defaults, an optional
+// YAML file, the environment and command line flags, and rejects invalid
+// settings before anything is served. Each setting's YAML key and environment
+// variable are given by its struct tags.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+)
+
+type Config struct {
+	// Env labels the deployment, e.g. dev or prod, in shipped logs.
+	Env       string    `yaml:"env" env:"ENV"`
+	Server    Server    `yaml:"server"`
+	Log       Log       `yaml:"log"`
+	IAM       IAM       `yaml:"iam"`
+	Upstream  Upstream  `yaml:"upstream"`
+	Appwrite  Appwrite  `yaml:"appwrite"`
+	Keycloak  Keycloak  `yaml:"keycloak"`
+	Local     Local     `yaml:"local"`
+	Audit     Audit     `yaml:"audit"`
+	Lockout   Lockout   `yaml:"lockout"`
+	RateLimit RateLimit `yaml:"rateLimit"`
+	Webhooks  Webhooks  `yaml:"webhooks"`
+	Redis     Redis     `yaml:"redis"`
+}
+
+type Server struct {
+	Addr string `yaml:"addr" env:"IAM_ADDR"`
+	// MetricsAddr serves Prometheus metrics on /metrics.
+	MetricsAddr string `yaml:"metricsAddr" env:"METRICS_ADDR"`
+	// RequestTimeout bounds each API call, including every identity provider
+	// call made while serving it.
+	RequestTimeout time.Duration `yaml:"requestTimeout" env:"REQUEST_TIMEOUT"`
+	// ReadTimeout, WriteTimeout and IdleTimeout are the http.Server timeouts.
+	// WriteTimeout must leave room for RequestTimeout.
+	ReadTimeout  time.Duration `yaml:"readTimeout" env:"READ_TIMEOUT"`
+	WriteTimeout time.Duration `yaml:"writeTimeout" env:"WRITE_TIMEOUT"`
+	IdleTimeout  time.Duration `yaml:"idleTimeout" env:"IDLE_TIMEOUT"`
+	// DrainDelay is how long the server keeps accepting requests after a
+	// shutdown signal, with readiness failing, so load balancers can stop
+	// routing to it. ShutdownTimeout then bounds waiting for in-flight
+	// requests and flushing audit events and webhook deliveries, together.
+	DrainDelay      time.Duration `yaml:"drainDelay" env:"DRAIN_DELAY"`
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout" env:"SHUTDOWN_TIMEOUT"`
+	// ReadyCacheTTL is how long /readyz reuses a dependency check's result.
+	// ReadyCheckTimeout bounds each check.
+	ReadyCacheTTL     time.Duration `yaml:"readyCacheTtl" env:"READY_CACHE_TTL"`
+	ReadyCheckTimeout time.Duration `yaml:"readyCheckTimeout" env:"READY_CHECK_TIMEOUT"`
+	// TrustedProxies are the IPs and CIDRs, such as the ingress's, whose
+	// X-Forwarded-For is believed when working out the client IP that rate
+	// limits and lockouts count by. The environment takes them comma
+	// separated. With none the client IP is the remote address.
+	TrustedProxies []string `yaml:"trustedProxies" env:"TRUSTED_PROXIES"`
+}
+
+type Log struct {
+	Level string `yaml:"level" env:"LOG_LEVEL"`
+	// LokiBatchSize and LokiBatchWait, in seconds, are handed to the Loki hook.
+	LokiBatchSize int `yaml:"lokiBatchSize" env:"LOKI_BATCH_SIZE"`
+	LokiBatchWait int `yaml:"lokiBatchWait" env:"LOKI_BATCH_WAIT"`
+}
+
+type IAM struct {
+	// Provider selects the identity provider: appwrite, keycloak or local.
+	Provider        string `yaml:"provider" env:"IAM_PROVIDER"`
+	RecoveryURL     string `yaml:"recoveryUrl" env:"IAM_RECOVERY_URL"`
+	VerificationURL string `yaml:"verificationUrl" env:"IAM_VERIFICATION_URL"`
+}
+
+// Upstream tunes calls to the identity provider.
+type Upstream struct {
+	// Timeout bounds a single call, so one slow call cannot use up the whole
+	// Server.RequestTimeout.
+	Timeout time.Duration `yaml:"timeout" env:"UPSTREAM_TIMEOUT"`
+	// RetryMax is the number of retries of a transient failure. Appwrite only.
+	RetryMax        int           `yaml:"retryMax" env:"IAM_RETRY_MAX"`
+	RetryBackoff    time.Duration `yaml:"retryBackoff" env:"IAM_RETRY_BACKOFF"`
+	RetryMaxBackoff time.Duration `yaml:"retryMaxBackoff" env:"IAM_RETRY_MAX_BACKOFF"`
+	// BreakerFailures transient failures in a row open the circuit breaker for
+	// BreakerCooldown. 0 disables it. Appwrite only.
+	BreakerFailures int           `yaml:"breakerFailures" env:"IAM_BREAKER_FAILURES"`
+	BreakerCooldown time.Duration `yaml:"breakerCooldown" env:"IAM_BREAKER_COOLDOWN"`
+}
+
+type Appwrite struct {
+	// Host is the API endpoint, e.g. https://appwrite.example.com/v1.
+	Host    string `yaml:"host" env:"IAM_HOST"`
+	Project string `yaml:"project" env:"IAM_PROJECT"`
+	Key     Secret `yaml:"key" env:"IAM_KEY"`
+}
+
+type Keycloak struct {
+	AdminBase    string `yaml:"adminBase" env:"OAUTH_ADMIN_BASE"`
+	IssuerBase   string `yaml:"issuerBase" env:"OAUTH_ISSUER_BASE"`
+	ClientID     string `yaml:"clientId" env:"OAUTH_CLIENT_ID"`
+	ClientSecret Secret `yaml:"clientSecret" env:"OAUTH_CLIENT_SECRET"`
+}
+
+type Local struct {
+	// DB is the path of the BoltDB file.
+	DB string `yaml:"db" env:"IAM_LOCAL_DB"`
+	// LogLinks logs recovery and verification links in full, secret and all,
+	// so they can be followed during development. Otherwise only their
+	// issuing is logged and the flows cannot be completed.
+	LogLinks bool `yaml:"logLinks" env:"IAM_LOCAL_LOG_LINKS"`
+	// AdminEmail is granted the admin role, on start if its account exists
+	// and otherwise when it is created, so the first admin can be set up.
+	// Create that account before exposing the service.
+	AdminEmail string `yaml:"adminEmail" env:"IAM_LOCAL_ADMIN_EMAIL"`
+}
+
+// Audit configures where identity change events go. They are always logged,
+// which ships them to Loki; File and WebhookURL each add a sink.
+type Audit struct {
+	// File is appended one JSON event per line.
+	File string `yaml:"file" env:"AUDIT_FILE"`
+	// WebhookURL is POSTed each event, with WebhookToken as a bearer token
+	// when set. WebhookTimeout bounds each delivery.
+	WebhookURL     string        `yaml:"webhookUrl" env:"AUDIT_WEBHOOK_URL"`
+	WebhookToken   Secret        `yaml:"webhookToken" env:"AUDIT_WEBHOOK_TOKEN"`
+	WebhookTimeout time.Duration `yaml:"webhookTimeout" env:"AUDIT_WEBHOOK_TIMEOUT"`
+	// Retention is how many recent events are kept to answer
+	// GET /api/iam/v1/audit.
+	Retention int `yaml:"retention" env:"AUDIT_RETENTION"`
+	// Store keeps them: memory, where each replica answers with the events it
+	// recorded itself, or redis to share them between replicas.
+	Store string `yaml:"store" env:"AUDIT_STORE"`
+	// Changes adds the fields each update changed to its event, looking the
+	// user up at the identity provider before every update to do so. Turn it
+	// off to save that lookup when only who changed what is needed.
+	Changes bool `yaml:"changes" env:"AUDIT_CHANGES"`
+}
+
+// Lockout throttles failed logins. An email that fails to log in Threshold
+// times within Window, or a client IP that fails IPThreshold times, is locked
+// out for Duration, doubling with each lockout in a row up to MaxDuration. A
+// threshold of 0 disables that half.
+type Lockout struct {
+	Threshold   int           `yaml:"threshold" env:"LOGIN_LOCKOUT_THRESHOLD"`
+	IPThreshold int           `yaml:"ipThreshold" env:"LOGIN_LOCKOUT_IP_THRESHOLD"`
+	Window      time.Duration `yaml:"window" env:"LOGIN_LOCKOUT_WINDOW"`
+	Duration    time.Duration `yaml:"duration" env:"LOGIN_LOCKOUT_DURATION"`
+	MaxDuration time.Duration `yaml:"maxDuration" env:"LOGIN_LOCKOUT_MAX_DURATION"`
+	// Store keeps the counts: memory, or redis to share them between
+	// replicas.
+	Store string `yaml:"store" env:"LOGIN_LOCKOUT_STORE"`
+}
+
+// RateLimit throttles API calls per client and route group. Each replica
+// keeps its own buckets, so a client gets the limit once per replica.
+type RateLimit struct {
+	// Default covers every /api/iam/v1 call, per client IP.
+	Default Limit `yaml:"default" env:"RATE_LIMIT"`
+	// Signup covers creating users, per client IP.
+	Signup Limit `yaml:"signup" env:"RATE_LIMIT_SIGNUP"`
+	// Password covers changing and recovering passwords, and changing your
+	// email, which checks yours, per caller, or per client IP when the caller
+	// is anonymous.
+	Password Limit `yaml:"password" env:"RATE_LIMIT_PASSWORD"`
+	// Login covers creating sessions, per client IP.
+	Login Limit `yaml:"login" env:"RATE_LIMIT_LOGIN"`
+}
+
+// Limit is a token bucket holding Burst requests and refilled with Requests
+// every Period. Its environment variables are its section's followed by
+// _REQUESTS, _PERIOD and _BURST. Requests of 0 lifts the limit.
+type Limit struct {
+	Requests int           `yaml:"requests" env:"REQUESTS"`
+	Period   time.Duration `yaml:"period" env:"PERIOD"`
+	Burst    int           `yaml:"burst" env:"BURST"`
+}
+
+// Webhooks tells subscribers about identity changes. Store keeps the
+// subscriptions and the outbox of deliveries: bolt, in the DB file, which
+// suits a single replica with a persistent volume, or redis, shared by every
+// replica. Webhooks are off while Store is empty.
+type Webhooks struct {
+	Store string `yaml:"store" env:"WEBHOOK_STORE"`
+	// DB is the path of the BoltDB file of the bolt store.
+	DB string `yaml:"db" env:"WEBHOOK_DB"`
+	// Timeout bounds each delivery attempt.
+	Timeout time.Duration `yaml:"timeout" env:"WEBHOOK_TIMEOUT"`
+	// A failed attempt is retried after Backoff, doubling with each failure up
+	// to MaxBackoff, until MaxAttempts attempts have failed.
+	MaxAttempts int           `yaml:"maxAttempts" env:"WEBHOOK_MAX_ATTEMPTS"`
+	Backoff     time.Duration `yaml:"backoff" env:"WEBHOOK_BACKOFF"`
+	MaxBackoff  time.Duration `yaml:"maxBackoff" env:"WEBHOOK_MAX_BACKOFF"`
+	// PollInterval is how often the outbox is checked for deliveries due.
+	PollInterval time.Duration `yaml:"pollInterval" env:"WEBHOOK_POLL_INTERVAL"`
+	// History is how long finished deliveries are kept for inspection.
+	History time.Duration `yaml:"history" env:"WEBHOOK_HISTORY"`
+	// AllowedNetworks are CIDRs subscribers may be in although they are
+	// private, such as the cluster's service network; every other private,
+	// loopback or link-local address is refused. The environment takes them
+	// comma separated.
+	AllowedNetworks []string `yaml:"allowedNetworks" env:"WEBHOOK_ALLOWED_NETWORKS"`
+}
+
+type Redis struct {
+	// Addr is host:port.
+	Addr     string `yaml:"addr" env:"REDIS_ADDR"`
+	Password Secret `yaml:"password" env:"REDIS_PASSWORD"`
+	DB       int    `yaml:"db" env:"REDIS_DB"`
+}
+
+// Secret is a setting that must not end up in logs. It formats as [REDACTED];
+// Value returns the real thing.
+type Secret string
+
+const redacted = "[REDACTED]"
+
+func (s Secret) Value() string {
+	return string(s)
+}
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Default is the configuration before any file, environment or flags are
+// applied. It is not valid on its own: the identity provider settings have no
+// defaults.
+func Default() *Config {
+	return &Config{
+		Server: Server{
+			Addr:              ":8080",
+			MetricsAddr:       ":2112",
+			RequestTimeout:    10 * time.Second,
+			ReadTimeout:       15 * time.Second,
+			WriteTimeout:      15 * time.Second,
+			IdleTimeout:       60 * time.Second,
+			DrainDelay:        5 * time.Second,
+			ShutdownTimeout:   15 * time.Second,
+			ReadyCacheTTL:     5 * time.Second,
+			ReadyCheckTimeout: 2 * time.Second,
+		},
+		Log: Log{
+			Level:         log.InfoLevel.String(),
+			LokiBatchSize: 10,
+			LokiBatchWait: 5,
+		},
+		IAM: IAM{
+			Provider: constants.PROVIDER_APPWRITE,
+		},
+		Upstream: Upstream{
+			Timeout:         5 * time.Second,
+			RetryMax:        2,
+			RetryBackoff:    100 * time.Millisecond,
+			RetryMaxBackoff: time.Second,
+			BreakerFailures: 5,
+			BreakerCooldown: 30 * time.Second,
+		},
+		Local: Local{
+			DB: "iam.db",
+		},
+		Audit: Audit{
+			WebhookTimeout: 5 * time.Second,
+			Retention:      1000,
+			Store:          constants.STORE_MEMORY,
+			Changes:        true,
+		},
+		Lockout: Lockout{
+			Threshold:   5,
+			IPThreshold: 20,
+			Window:      15 * time.Minute,
+			Duration:    time.Minute,
+			MaxDuration: time.Hour,
+			Store:       constants.STORE_MEMORY,
+		},
+		RateLimit: RateLimit{
+			Default:  Limit{Requests: 600, Period: time.Minute, Burst: 100},
+			Signup:   Limit{Requests: 20, Period: time.Hour, Burst: 5},
+			Password: Limit{Requests: 20, Period: time.Hour, Burst: 5},
+			Login:    Limit{Requests: 30, Period: time.Minute, Burst: 10},
+		},
+		Webhooks: Webhooks{
+			DB:           "iam-webhooks.db",
+			Timeout:      5 * time.Second,
+			MaxAttempts:  8,
+			Backoff:      10 * time.Second,
+			MaxBackoff:   time.Hour,
+			PollInterval: time.Second,
+			History:      7 * 24 * time.Hour,
+		},
+	}
+}
+
+// Load builds the configuration from args, which exclude the program name, and
+// the environment. The YAML file is named by the -config flag or IAM_CONFIG.
+func Load(args []string) (*Config, error) {
+	fs := flag.NewFlagSet("iam-ms", flag.ContinueOnError)
+	file := fs.String("config", os.Getenv("IAM_CONFIG"), "path of a YAML config file")
+	fs.String("addr", "", "address to listen on (IAM_ADDR)")
+	fs.String("log-level", "", "log level (LOG_LEVEL)")
+	fs.String("provider", "", "identity provider: appwrite, keycloak or local (IAM_PROVIDER)")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	cfg := Default()
+	if *file != "" {
+		if err := cfg.loadFile(*file); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.loadEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+
+	flags := map[string]*string{
+		"addr":      &cfg.Server.Addr,
+		"log-level": &cfg.Log.Level,
+		"provider":  &cfg.IAM.Provider,
+	}
+	fs.Visit(func(f *flag.Flag) {
+		if dst, ok := flags[f.Name]; ok {
+			*dst = f.Value.String()
+		}
+	})
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// loadEnv overrides every setting whose environment variable is set.
+func (c *Config) loadEnv(lookup func(string) (string, bool)) error {
+	var errs []error
+	walk(reflect.ValueOf(c).Elem(), "", "", func(_ string, key string, v reflect.Value) {
+		raw, ok := lookup(key)
+		if key == "" || !ok {
+			return
+		}
+		if err := setValue(v, raw); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	})
+	return errors.Join(errs...)
+}
+
+func setValue(v reflect.Value, raw string) error {
+	switch {
+	case v.Type() == reflect.TypeOf(time.Duration(0)):
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", raw)
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		v.SetInt(int64(n))
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		v.SetBool(b)
+	case v.Kind() == reflect.String:
+		v.SetString(raw)
+	case v.Type() == reflect.TypeOf([]string(nil)):
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported setting type %s", v.Type())
+	}
+	return nil
+}
+
+// Validate reports every invalid setting at once.
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.Server.Addr == "" {
+		fail("server.addr (IAM_ADDR) is required")
+	}
+	if c.Server.MetricsAddr == "" {
+		fail("server.metricsAddr (METRICS_ADDR) is required")
+	}
+	if c.Server.RequestTimeout <= 0 {
+		fail("server.requestTimeout (REQUEST_TIMEOUT) must be positive")
+	}
+	if c.Server.ReadTimeout <= 0 || c.Server.IdleTimeout <= 0 {
+		fail("server.readTimeout and server.idleTimeout must be positive")
+	}
+	if c.Server.WriteTimeout <= c.Server.RequestTimeout {
+		fail("server.writeTimeout (WRITE_TIMEOUT) must be longer than server.requestTimeout")
+	}
+	if c.Server.DrainDelay < 0 || c.Server.ShutdownTimeout <= 0 {
+		fail("server.drainDelay must not be negative and server.shutdownTimeout must be positive")
+	}
+	if c.Server.ReadyCacheTTL < 0 || c.Server.ReadyCheckTimeout <= 0 {
+		fail("server.readyCacheTtl must not be negative and server.readyCheckTimeout must be positive")
+	}
+	for _, proxy := range c.Server.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			fail("server.trustedProxies (TRUSTED_PROXIES): %q is not an IP or CIDR", proxy)
+		}
+	}
+	if _, err := log.ParseLevel(c.Log.Level); err != nil {
+		fail("log.level (LOG_LEVEL): %v", err)
+	}
+	if c.Log.LokiBatchSize <= 0 || c.Log.LokiBatchWait <= 0 {
+		fail("log.lokiBatchSize and log.lokiBatchWait must be positive")
+	}
+
+	if c.Upstream.Timeout <= 0 || c.Upstream.Timeout > c.Server.RequestTimeout {
+		fail("upstream.timeout (UPSTREAM_TIMEOUT) must be positive and at most server.requestTimeout")
+	}
+	if c.Upstream.RetryMax < 0 || c.Upstream.RetryBackoff < 0 || c.Upstream.RetryMaxBackoff < 0 {
+		fail("upstream retry settings must not be negative")
+	}
+	if c.Upstream.BreakerFailures < 0 {
+		fail("upstream.breakerFailures (IAM_BREAKER_FAILURES) must not be negative")
+	}
+	if c.Upstream.BreakerFailures > 0 && c.Upstream.BreakerCooldown <= 0 {
+		fail("upstream.breakerCooldown (IAM_BREAKER_COOLDOWN) must be positive")
+	}
+
+	checkURL := func(name string, raw string, required bool) {
+		if raw == "" {
+			if required {
+				fail("%s is required", name)
+			}
+			return
+		}
+		if u, err := url.Parse(raw); err != nil || u.Scheme == "" || u.Host == "" {
+			fail("%s must be an absolute URL, got %q", name, raw)
+		}
+	}
+	checkURL("iam.recoveryUrl (IAM_RECOVERY_URL)", c.IAM.RecoveryURL, false)
+	checkURL("iam.verificationUrl (IAM_VERIFICATION_URL)", c.IAM.VerificationURL, false)
+	checkURL("audit.webhookUrl (AUDIT_WEBHOOK_URL)", c.Audit.WebhookURL, false)
+	if c.Audit.WebhookTimeout <= 0 {
+		fail("audit.webhookTimeout (AUDIT_WEBHOOK_TIMEOUT) must be positive")
+	}
+	if c.Audit.Retention <= 0 {
+		fail("audit.retention (AUDIT_RETENTION) must be positive")
+	}
+	switch c.Audit.Store {
+	case constants.STORE_MEMORY:
+	case constants.STORE_REDIS:
+		if c.Redis.Addr == "" {
+			fail("redis.addr (REDIS_ADDR) is required by audit.store %q", c.Audit.Store)
+		}
+	default:
+		fail("audit.store (AUDIT_STORE): unknown store %q", c.Audit.Store)
+	}
+
+	if c.Lockout.Threshold < 0 || c.Lockout.IPThreshold < 0 {
+		fail("lockout thresholds must not be negative")
+	}
+	if c.Lockout.Window <= 0 || c.Lockout.Duration <= 0 {
+		fail("lockout.window and lockout.duration must be positive")
+	}
+	if c.Lockout.MaxDuration < c.Lockout.Duration {
+		fail("lockout.maxDuration (LOGIN_LOCKOUT_MAX_DURATION) must be at least lockout.duration")
+	}
+	switch c.Lockout.Store {
+	case constants.STORE_MEMORY:
+	case constants.STORE_REDIS:
+		if c.Redis.Addr == "" {
+			fail("redis.addr (REDIS_ADDR) is required by lockout.store %q", c.Lockout.Store)
+		}
+	default:
+		fail("lockout.store (LOGIN_LOCKOUT_STORE): unknown store %q", c.Lockout.Store)
+	}
+
+	checkLimit := func(name string, l Limit) {
+		if l.Requests < 0 {
+			fail("%s: requests must not be negative", name)
+		}
+		if l.Requests > 0 && (l.Period <= 0 || l.Burst <= 0) {
+			fail("%s: period and burst must be positive", name)
+		}
+	}
+	checkLimit("rateLimit.default (RATE_LIMIT_*)", c.RateLimit.Default)
+	checkLimit("rateLimit.signup (RATE_LIMIT_SIGNUP_*)", c.RateLimit.Signup)
+	checkLimit("rateLimit.password (RATE_LIMIT_PASSWORD_*)", c.RateLimit.Password)
+	checkLimit("rateLimit.login (RATE_LIMIT_LOGIN_*)", c.RateLimit.Login)
+
+	switch c.Webhooks.Store {
+	case "":
+	case constants.STORE_BOLT:
+		if c.Webhooks.DB == "" {
+			fail("webhooks.db (WEBHOOK_DB) is required by webhooks.store %q", c.Webhooks.Store)
+		}
+	case constants.STORE_REDIS:
+		if c.Redis.Addr == "" {
+			fail("redis.addr (REDIS_ADDR) is required by webhooks.store %q", c.Webhooks.Store)
+		}
+	default:
+		fail("webhooks.store (WEBHOOK_STORE): unknown store %q", c.Webhooks.Store)
+	}
+	if c.Webhooks.Timeout <= 0 || c.Webhooks.PollInterval <= 0 || c.Webhooks.History <= 0 {
+		fail("webhooks.timeout, webhooks.pollInterval and webhooks.history must be positive")
+	}
+	if c.Webhooks.MaxAttempts <= 0 || c.Webhooks.Backoff <= 0 {
+		fail("webhooks.maxAttempts and webhooks.backoff must be positive")
+	}
+	if c.Webhooks.MaxBackoff < c.Webhooks.Backoff {
+		fail("webhooks.maxBackoff (WEBHOOK_MAX_BACKOFF) must be at least webhooks.backoff")
+	}
+	for _, network := range c.Webhooks.AllowedNetworks {
+		if _, _, err := net.ParseCIDR(network); err != nil {
+			fail("webhooks.allowedNetworks (WEBHOOK_ALLOWED_NETWORKS): %q is not a CIDR", network)
+		}
+	}
+
+	switch c.IAM.Provider {
+	case constants.PROVIDER_APPWRITE:
+		checkURL("appwrite.host (IAM_HOST)", c.Appwrite.Host, true)
+		if c.Appwrite.Project == "" {
+			fail("appwrite.project (IAM_PROJECT) is required")
+		}
+		if c.Appwrite.Key == "" {
+			fail("appwrite.key (IAM_KEY) is required")
+		}
+	case constants.PROVIDER_KEYCLOAK:
+		checkURL("keycloak.adminBase (OAUTH_ADMIN_BASE)", c.Keycloak.AdminBase, true)
+		checkURL("keycloak.issuerBase (OAUTH_ISSUER_BASE)", c.Keycloak.IssuerBase, true)
+		if c.Keycloak.ClientID == "" {
+			fail("keycloak.clientId (OAUTH_CLIENT_ID) is required")
+		}
+		if c.Keycloak.ClientSecret == "" {
+			fail("keycloak.clientSecret (OAUTH_CLIENT_SECRET) is required")
+		}
+	case constants.PROVIDER_LOCAL:
+		if c.Local.DB == "" {
+			fail("local.db (IAM_LOCAL_DB) is required")
+		}
+	default:
+		fail("iam.provider (IAM_PROVIDER): unknown identity provider %q", c.IAM.Provider)
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return nil
+}
+
+// Fields is the effective configuration keyed by YAML path, e.g.
+// server.addr, for logging. Secrets are redacted.
+func (c *Config) Fields() log.Fields {
+	fields := log.Fields{}
+	walk(reflect.ValueOf(c).Elem(), "", "", func(path string, _ string, v reflect.Value) {
+		fields[path] = fmt.Sprint(v.Interface())
+	})
+	return fields
+}
+
+// walk calls fn for every setting in the struct v, with its YAML path and
+// environment variable. A struct field's env tag prefixes its settings'.
+func walk(
+	v reflect.Value,
+	prefix string,
+	envPrefix string,
+	fn func(path string, env string, v reflect.Value),
+) {
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		path := prefix + strings.Split(f.Tag.Get("yaml"), ",")[0]
+		env := f.Tag.Get("env")
+		if env != "" {
+			env = envPrefix + env
+		}
+		if f.Type.Kind() == reflect.Struct {
+			if env != "" {
+				env += "_"
+			}
+			walk(v.Field(i), path+".", env, fn)
+			continue
+		}
+		fn(path, env, v.Field(i))
+	}
+}
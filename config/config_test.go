@@ -0,0 +1,347 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+)
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "iam.yaml")
+	assert.Nil(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func setAppwriteEnv(t *testing.T) {
+	t.Setenv("IAM_PROVIDER", constants.PROVIDER_APPWRITE)
+	t.Setenv("IAM_HOST", "https://aw/v1")
+	t.Setenv("IAM_PROJECT", "p")
+	t.Setenv("IAM_KEY", "k")
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	setAppwriteEnv(t)
+	path := writeConfigFile(t, `
+server:
+  addr: ":9000"
+  requestTimeout: 20s
+  writeTimeout: 25s
+log:
+  level: debug
+upstream:
+  retryMax: 4
+appwrite:
+  project: from-file
+`)
+	t.Setenv("IAM_CONFIG", path)
+	t.Setenv("LOG_LEVEL", "warn")
+	t.Setenv("IAM_BREAKER_COOLDOWN", "1m")
+	t.Setenv("RATE_LIMIT_SIGNUP_BURST", "3")
+	t.Setenv("AUDIT_CHANGES", "false")
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 192.168.1.1")
+
+	cfg, err := Load([]string{"-log-level", "error"})
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	// File over defaults.
+	assert.Equal(t, ":9000", cfg.Server.Addr)
+	assert.Equal(t, 20*time.Second, cfg.Server.RequestTimeout)
+	assert.Equal(t, 4, cfg.Upstream.RetryMax)
+	// Env over file.
+	assert.Equal(t, "p", cfg.Appwrite.Project)
+	assert.Equal(t, time.Minute, cfg.Upstream.BreakerCooldown)
+	assert.Equal(t, 3, cfg.RateLimit.Signup.Burst)
+	assert.Equal(t, 100, cfg.RateLimit.Default.Burst)
+	assert.False(t, cfg.Audit.Changes)
+	assert.Equal(t, []string{"10.0.0.0/8", "192.168.1.1"}, cfg.Server.TrustedProxies)
+	// Flags over env.
+	assert.Equal(t, "error", cfg.Log.Level)
+	// Untouched defaults.
+	assert.Equal(t, 5*time.Second, cfg.Upstream.Timeout)
+	assert.Equal(t, "k", cfg.Appwrite.Key.Value())
+}
+
+func TestLoad_ConfigFlag(t *testing.T) {
+	setAppwriteEnv(t)
+	path := writeConfigFile(t, "server:\n  addr: \":9001\"\n")
+
+	cfg, err := Load([]string{"-config", path, "-addr", ":9002"})
+	assert.Nil(t, err)
+	assert.Equal(t, ":9002", cfg.Server.Addr)
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		env  map[string]string
+		file string
+		want string
+	}{
+		{
+			name: "Unknown Flag",
+			args: []string{"-nope"},
+			want: "flag provided but not defined",
+		},
+		{
+			name: "Missing File",
+			args: []string{"-config", filepath.Join(os.TempDir(), "missing-iam.yaml")},
+			want: "config file",
+		},
+		{
+			name: "Unknown Key",
+			file: "server:\n  port: 8080\n",
+			want: "field port not found",
+		},
+		{
+			name: "Bad Duration",
+			env:  map[string]string{"UPSTREAM_TIMEOUT": "5"},
+			want: `UPSTREAM_TIMEOUT: invalid duration "5"`,
+		},
+		{
+			name: "Bad Integer",
+			env:  map[string]string{"IAM_RETRY_MAX": "two"},
+			want: `IAM_RETRY_MAX: invalid integer "two"`,
+		},
+		{
+			name: "Bad Boolean",
+			env:  map[string]string{"AUDIT_CHANGES": "sometimes"},
+			want: `AUDIT_CHANGES: invalid boolean "sometimes"`,
+		},
+		{
+			name: "Invalid",
+			env:  map[string]string{"IAM_HOST": ""},
+			want: "appwrite.host (IAM_HOST) is required",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setAppwriteEnv(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			if tt.file != "" {
+				t.Setenv("IAM_CONFIG", writeConfigFile(t, tt.file))
+			}
+
+			cfg, err := Load(tt.args)
+			assert.Nil(t, cfg)
+			if assert.NotNil(t, err) {
+				assert.Contains(t, err.Error(), tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() *Config {
+		cfg := Default()
+		cfg.Appwrite = Appwrite{Host: "https://aw/v1", Project: "p", Key: "k"}
+		return cfg
+	}
+
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		want   []string
+	}{
+		{
+			name:   "Valid",
+			modify: func(c *Config) {},
+		},
+		{
+			name: "Server And Log",
+			modify: func(c *Config) {
+				c.Server.Addr = ""
+				c.Server.WriteTimeout = c.Server.RequestTimeout
+				c.Server.ShutdownTimeout = 0
+				c.Server.TrustedProxies = []string{"10.0.0.0/8", "ingress"}
+				c.Log.Level = "loud"
+				c.Log.LokiBatchSize = 0
+			},
+			want: []string{
+				"server.addr",
+				"server.writeTimeout",
+				"server.shutdownTimeout",
+				`server.trustedProxies (TRUSTED_PROXIES): "ingress" is not an IP or CIDR`,
+				"log.level",
+				"log.lokiBatchSize",
+			},
+		},
+		{
+			name: "Upstream",
+			modify: func(c *Config) {
+				c.Upstream.Timeout = time.Minute
+				c.Upstream.RetryMax = -1
+				c.Upstream.BreakerCooldown = 0
+			},
+			want: []string{"upstream.timeout", "retry", "upstream.breakerCooldown"},
+		},
+		{
+			name: "Breaker Disabled",
+			modify: func(c *Config) {
+				c.Upstream.BreakerFailures = 0
+				c.Upstream.BreakerCooldown = 0
+			},
+		},
+		{
+			name: "Audit",
+			modify: func(c *Config) {
+				c.Audit.WebhookURL = "hooks.example.com"
+				c.Audit.WebhookTimeout = 0
+				c.Audit.Retention = 0
+				c.Audit.Store = constants.STORE_BOLT
+			},
+			want: []string{
+				"audit.webhookUrl",
+				"audit.webhookTimeout",
+				"audit.retention",
+				`audit.store (AUDIT_STORE): unknown store "bolt"`,
+			},
+		},
+		{
+			name: "Lockout",
+			modify: func(c *Config) {
+				c.Lockout.IPThreshold = -1
+				c.Lockout.Window = 0
+				c.Lockout.MaxDuration = time.Second
+				c.Lockout.Store = constants.STORE_REDIS
+			},
+			want: []string{
+				"lockout thresholds",
+				"lockout.window",
+				"lockout.maxDuration",
+				"redis.addr",
+			},
+		},
+		{
+			name: "Lockout Store",
+			modify: func(c *Config) {
+				c.Lockout.Store = "memcached"
+			},
+			want: []string{`unknown store "memcached"`},
+		},
+		{
+			name: "Rate Limit",
+			modify: func(c *Config) {
+				c.RateLimit.Default.Requests = -1
+				c.RateLimit.Signup.Burst = 0
+				c.RateLimit.Login = Limit{}
+			},
+			want: []string{"rateLimit.default", "rateLimit.signup"},
+		},
+		{
+			name: "Webhooks",
+			modify: func(c *Config) {
+				c.Webhooks.Store = constants.STORE_REDIS
+				c.Webhooks.PollInterval = 0
+				c.Webhooks.MaxAttempts = 0
+				c.Webhooks.MaxBackoff = time.Second
+				c.Webhooks.AllowedNetworks = []string{"10.0.0.0/8", "10.0.0.1"}
+			},
+			want: []string{
+				"redis.addr",
+				"webhooks.pollInterval",
+				"webhooks.maxAttempts",
+				"webhooks.maxBackoff",
+				`webhooks.allowedNetworks (WEBHOOK_ALLOWED_NETWORKS): "10.0.0.1" is not a CIDR`,
+			},
+		},
+		{
+			name: "Webhooks Store",
+			modify: func(c *Config) {
+				c.Webhooks.Store = "sqlite"
+			},
+			want: []string{`unknown store "sqlite"`},
+		},
+		{
+			name: "Appwrite",
+			modify: func(c *Config) {
+				c.Appwrite = Appwrite{Host: "aw"}
+				c.IAM.RecoveryURL = "/recovery"
+			},
+			want: []string{
+				"appwrite.host",
+				"appwrite.project",
+				"appwrite.key",
+				"iam.recoveryUrl",
+			},
+		},
+		{
+			name: "Keycloak",
+			modify: func(c *Config) {
+				c.IAM.Provider = constants.PROVIDER_KEYCLOAK
+				c.Keycloak.AdminBase = "https://kc/admin/realms/blog"
+			},
+			want: []string{"keycloak.issuerBase", "keycloak.clientId", "keycloak.clientSecret"},
+		},
+		{
+			name: "Local",
+			modify: func(c *Config) {
+				c.IAM.Provider = constants.PROVIDER_LOCAL
+				c.Local.DB = ""
+			},
+			want: []string{"local.db"},
+		},
+		{
+			name: "Unknown Provider",
+			modify: func(c *Config) {
+				c.IAM.Provider = "okta"
+			},
+			want: []string{`unknown identity provider "okta"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid()
+			tt.modify(cfg)
+
+			err := cfg.Validate()
+			if len(tt.want) == 0 {
+				assert.Nil(t, err)
+				return
+			}
+			if assert.NotNil(t, err) {
+				assert.True(t, strings.HasPrefix(err.Error(), "invalid configuration:"))
+				for _, w := range tt.want {
+					assert.Contains(t, err.Error(), w)
+				}
+			}
+		})
+	}
+}
+
+func TestConfig_Redaction(t *testing.T) {
+	cfg := Default()
+	cfg.Appwrite.Key = "aw-secret"
+	cfg.Keycloak.ClientSecret = "kc-secret"
+	cfg.Audit.WebhookToken = "hook-secret"
+	cfg.Redis.Password = "redis-secret"
+
+	fields := cfg.Fields()
+	assert.Equal(t, "[REDACTED]", fields["appwrite.key"])
+	assert.Equal(t, "[REDACTED]", fields["keycloak.clientSecret"])
+	assert.Equal(t, "[REDACTED]", fields["audit.webhookToken"])
+	assert.Equal(t, "[REDACTED]", fields["redis.password"])
+	assert.Equal(t, ":8080", fields["server.addr"])
+	assert.Equal(t, "5s", fields["upstream.timeout"])
+	assert.Equal(t, "", fields["appwrite.host"])
+
+	for _, s := range []string{fmt.Sprintf("%+v", cfg), fmt.Sprint(fields)} {
+		assert.NotContains(t, s, "aw-secret")
+		assert.NotContains(t, s, "kc-secret")
+		assert.NotContains(t, s, "hook-secret")
+		assert.NotContains(t, s, "redis-secret")
+	}
+	assert.Equal(t, "aw-secret", cfg.Appwrite.Key.Value())
+}
@@ -1,7 +1,59 @@
 package constants
 
 var (
-	NoCustomersFound = "no customers exist"
-	NoCustomerFound  = "no customer exists with the given information"
-	NoUserFound      = "User with the requested ID could not be found."
+	NoCustomersFound   = "no customers exist"
+	NoCustomerFound    = "no customer exists with the given information"
+	NoUserFound        = "User with the requested ID could not be found."
+	NoSessionFound     = "Session with the requested ID could not be found."
+	MissingUserSession = "A user session JWT is required in the X-Appwrite-JWT header."
+	InvalidUserSession = "The user session is invalid or has expired."
+	Forbidden          = "You do not have permission to perform this action."
+	InvalidOldPassword = "The current password is incorrect."
+	MissingOldPassword = "oldPassword is required"
+	Unsupported        = "This operation is not supported by the configured identity provider."
+	UserAlreadyExists  = "A user with the same id or email already exists."
+	InvalidCredentials = "Invalid credentials. Please check the email and password."
+	UserBlocked        = "The current user has been blocked."
+	InvalidToken       = "Invalid token passed in the request."
+	RequestTimeout     = "The identity provider did not respond in time."
+	RequestCanceled    = "The request was canceled by the client."
+	ProviderDown       = "The identity provider is unavailable. Please try again later."
+	ProviderFailed     = "The identity provider could not complete the request."
+	RateLimited        = "Too many requests. Please try again later."
+	LoginLocked        = "Too many failed login attempts. Please try again later."
+	LockoutClearFailed = "The login lockout could not be cleared. Please try again."
+	WebhooksDisabled   = "Webhooks are not enabled on this service."
+	NoWebhookFound     = "Webhook subscription with the requested ID could not be found."
+	WebhookStoreFailed = "The webhook store could not complete the request."
+	WebhookURLBlocked  = "Webhook URLs must point at public addresses or allowed networks."
+	NotOwnAccount      = "Verification can only be sent when updating your own account."
+	InvalidUserID      = "userId must be up to 36 letters, digits, periods, hyphens or underscores."
 )
+
+const (
+	ERR_TYPE_FORBIDDEN            = "user_forbidden"
+	ERR_TYPE_INVALID_OLD_PASSWORD = "user_invalid_old_password"
+	ERR_TYPE_UNSUPPORTED          = "provider_unsupported"
+	ERR_TYPE_USER_ALREADY_EXISTS  = "user_already_exists"
+	ERR_TYPE_INVALID_CREDENTIALS  = "user_invalid_credentials"
+	ERR_TYPE_USER_BLOCKED         = "user_blocked"
+	ERR_TYPE_INVALID_TOKEN        = "user_invalid_token"
+	ERR_TYPE_TIMEOUT              = "general_timeout"
+	ERR_TYPE_CANCELED             = "general_canceled"
+	ERR_TYPE_PROVIDER_DOWN        = "provider_unavailable"
+	ERR_TYPE_PROVIDER_ERROR       = "provider_error"
+	ERR_TYPE_RATE_LIMITED         = "general_rate_limit_exceeded"
+	ERR_TYPE_ARGUMENT_INVALID     = "general_argument_invalid"
+	ERR_TYPE_NOT_FOUND            = "general_not_found"
+	ERR_TYPE_UNAUTHORIZED_SCOPE   = "general_unauthorized_scope"
+	ERR_TYPE_UNAUTHORIZED         = "user_unauthorized"
+	ERR_TYPE_PROJECT_NOT_FOUND    = "project_not_found"
+	ERR_TYPE_USER_NOT_FOUND       = "user_not_found"
+	ERR_TYPE_SESSION_NOT_FOUND    = "user_session_not_found"
+	ERR_TYPE_INVALID_SESSION      = "user_invalid_session"
+	ERR_TYPE_UNKNOWN              = "general_unknown"
+)
+
+// STATUS_CLIENT_CLOSED_REQUEST is the non-standard status, popularised by
+// nginx, for a request the client abandoned before it was answered.
+const STATUS_CLIENT_CLOSED_REQUEST = 499
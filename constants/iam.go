@@ -3,4 +3,163 @@ package constants
 const (
 	AW_HEADER_PROJECT_ID = "X-Appwrite-Project"
 	AW_HEADER_KEY        = "X-Appwrite-Key"
+	AW_HEADER_JWT        = "X-Appwrite-JWT"
+)
+
+// AW_HEALTH_PASS is the status of a healthy Appwrite health check.
+const AW_HEALTH_PASS = "pass"
+
+// HEADER_ERROR_CODE carries the stable type of a failed request's error, e.g.
+// user_already_exists.
+const HEADER_ERROR_CODE = "X-Error-Code"
+
+const HEADER_RETRY_AFTER = "Retry-After"
+
+// The RateLimit headers of draft-ietf-httpapi-ratelimit-headers, describing
+// the client's quota on the route it called.
+const (
+	HEADER_RATELIMIT_LIMIT     = "RateLimit-Limit"
+	HEADER_RATELIMIT_REMAINING = "RateLimit-Remaining"
+	HEADER_RATELIMIT_RESET     = "RateLimit-Reset"
+	HEADER_RATELIMIT_POLICY    = "RateLimit-Policy"
+)
+
+// Route groups that are rate limited separately.
+const (
+	RATE_LIMIT_GROUP_DEFAULT  = "default"
+	RATE_LIMIT_GROUP_SIGNUP   = "signup"
+	RATE_LIMIT_GROUP_PASSWORD = "password"
+	RATE_LIMIT_GROUP_LOGIN    = "login"
+)
+
+const (
+	DEFAULT_USER_LIST_LIMIT = 25
+	MAX_USER_LIST_LIMIT     = 100
+)
+
+const (
+	MAX_PREFS_BYTES    = 16 * 1024
+	MAX_PREF_KEY_CHARS = 64
+)
+
+const (
+	ROLE_ADMIN  = "admin"
+	ROLE_AUTHOR = "author"
+	ROLE_READER = "reader"
+)
+
+var ROLES = []string{ROLE_ADMIN, ROLE_AUTHOR, ROLE_READER}
+
+const CALLER_CONTEXT_KEY = "iamCaller"
+
+// Stores that state shared by requests, e.g. login lockouts, can be kept in.
+const (
+	STORE_MEMORY = "memory"
+	STORE_BOLT   = "bolt"
+	STORE_REDIS  = "redis"
+)
+
+const (
+	PROVIDER_APPWRITE = "appwrite"
+	PROVIDER_KEYCLOAK = "keycloak"
+	PROVIDER_LOCAL    = "local"
+)
+
+const (
+	KC_ATTR_PHONE          = "phoneNumber"
+	KC_ATTR_PHONE_VERIFIED = "phoneNumberVerified"
+	KC_ATTR_PREFS          = "prefs"
+)
+
+// HEADER_REQUEST_ID carries the request's ID, taken from the caller or
+// generated, and REQUEST_ID_CONTEXT_KEY holds it on the gin context.
+const (
+	HEADER_REQUEST_ID      = "X-Request-ID"
+	REQUEST_ID_CONTEXT_KEY = "iamRequestID"
+	MAX_REQUEST_ID_CHARS   = 128
+)
+
+// Audit event actions. Every mutating handler records one.
+const (
+	AUDIT_USER_CREATE                     = "user.create"
+	AUDIT_USER_UPDATE_PASSWORD            = "user.update_password"
+	AUDIT_USER_UPDATE_EMAIL               = "user.update_email"
+	AUDIT_USER_UPDATE_PHONE               = "user.update_phone"
+	AUDIT_USER_UPDATE_NAME                = "user.update_name"
+	AUDIT_USER_UPDATE_STATUS              = "user.update_status"
+	AUDIT_USER_UPDATE_VERIFICATION        = "user.update_verification"
+	AUDIT_USER_UPDATE_PREFS               = "user.update_prefs"
+	AUDIT_USER_UPDATE_ROLES               = "user.update_roles"
+	AUDIT_USER_DELETE                     = "user.delete"
+	AUDIT_USER_RECOVERY_CREATE            = "user.recovery_create"
+	AUDIT_USER_RECOVERY_CONFIRM           = "user.recovery_confirm"
+	AUDIT_USER_EMAIL_VERIFICATION_SEND    = "user.email_verification_send"
+	AUDIT_USER_EMAIL_VERIFICATION_CONFIRM = "user.email_verification_confirm"
+	AUDIT_USER_PHONE_VERIFICATION_SEND    = "user.phone_verification_send"
+	AUDIT_USER_PHONE_VERIFICATION_CONFIRM = "user.phone_verification_confirm"
+	AUDIT_SESSION_CREATE                  = "session.create"
+	AUDIT_SESSION_DELETE                  = "session.delete"
+	AUDIT_SESSION_DELETE_ALL              = "session.delete_all"
+	AUDIT_SESSION_LOCKOUT_CLEAR           = "session.lockout_clear"
+	AUDIT_WEBHOOK_CREATE                  = "webhook.create"
+	AUDIT_WEBHOOK_DELETE                  = "webhook.delete"
+)
+
+const (
+	AUDIT_OUTCOME_SUCCESS = "success"
+	AUDIT_OUTCOME_FAILURE = "failure"
+)
+
+const (
+	AUDIT_ACTOR_USER    = "user"
+	AUDIT_ACTOR_SERVICE = "service"
+)
+
+const (
+	DEFAULT_AUDIT_LIST_LIMIT = 50
+	MAX_AUDIT_LIST_LIMIT     = 500
+)
+
+// Webhook event types, sent to subscribers after the change was made.
+const (
+	WEBHOOK_EVENT_USER_CREATED       = "user.created"
+	WEBHOOK_EVENT_USER_DELETED       = "user.deleted"
+	WEBHOOK_EVENT_USER_EMAIL_CHANGED = "user.email_changed"
+	WEBHOOK_EVENT_USER_PHONE_CHANGED = "user.phone_changed"
+	WEBHOOK_EVENT_USER_NAME_CHANGED  = "user.name_changed"
+	WEBHOOK_EVENT_USER_BLOCKED       = "user.blocked"
+	WEBHOOK_EVENT_USER_UNBLOCKED     = "user.unblocked"
+)
+
+var WEBHOOK_EVENTS = []string{
+	WEBHOOK_EVENT_USER_CREATED,
+	WEBHOOK_EVENT_USER_DELETED,
+	WEBHOOK_EVENT_USER_EMAIL_CHANGED,
+	WEBHOOK_EVENT_USER_PHONE_CHANGED,
+	WEBHOOK_EVENT_USER_NAME_CHANGED,
+	WEBHOOK_EVENT_USER_BLOCKED,
+	WEBHOOK_EVENT_USER_UNBLOCKED,
+}
+
+// A webhook delivery is pending until it is delivered or has failed every
+// attempt.
+const (
+	WEBHOOK_STATUS_PENDING   = "pending"
+	WEBHOOK_STATUS_DELIVERED = "delivered"
+	WEBHOOK_STATUS_FAILED    = "failed"
+)
+
+// Headers of a webhook delivery. HEADER_WEBHOOK_SIGNATURE is "sha256=" and the
+// hex HMAC-SHA256, keyed with the subscription secret, of the timestamp
+// header, a dot and the body.
+const (
+	HEADER_WEBHOOK_ID        = "X-Webhook-Id"
+	HEADER_WEBHOOK_EVENT     = "X-Webhook-Event"
+	HEADER_WEBHOOK_TIMESTAMP = "X-Webhook-Timestamp"
+	HEADER_WEBHOOK_SIGNATURE = "X-Webhook-Signature"
+)
+
+const (
+	DEFAULT_WEBHOOK_DELIVERY_LIMIT = 50
+	MAX_WEBHOOK_DELIVERY_LIMIT     = 500
 )
@@ -0,0 +1,362 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"gitea.slauson.io/slausonio/go-types/siogeneric"
+	"gitea.slauson.io/slausonio/iam-ms/audit"
+	"gitea.slauson.io/slausonio/iam-ms/config"
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+	"gitea.slauson.io/slausonio/iam-ms/model"
+	"gitea.slauson.io/slausonio/iam-ms/utils"
+)
+
+var mAdmin = &model.Caller{ID: "admin1", Roles: []string{constants.ROLE_ADMIN}}
+
+func auditContext(caller *model.Caller, method string, content any) *gin.Context {
+	c := meContext(caller)
+	c.Set(constants.REQUEST_ID_CONTEXT_KEY, "req-1")
+	c.Params = gin.Params{gin.Param{Key: "id", Value: "a"}}
+	if content != nil {
+		MockJson(c, content, method)
+	}
+	return c
+}
+
+// lastEvent is the single event a recorded.
+func lastEvent(t *testing.T, a *audit.Auditor) model.AuditEvent {
+	t.Helper()
+	events, err := a.Query(context.Background(), &model.AuditQueryParams{})
+	if err != nil || !assert.Len(t, events, 1) {
+		t.FailNow()
+	}
+	return events[0]
+}
+
+func TestNewAuditController(t *testing.T) {
+	ac := NewAuditController(config.Default())
+	assert.NotNil(t, ac)
+}
+
+func TestListEvents(t *testing.T) {
+	a := audit.New(audit.NewMemoryStore(10))
+	ac := &AuditController{a: a}
+	for _, target := range []string{"a", "b"} {
+		a.Record(
+			audit.NewEvent(auditContext(mAdmin, "", nil), constants.AUDIT_USER_DELETE, target),
+			nil,
+		)
+	}
+
+	var (
+		w    = httptest.NewRecorder()
+		c, _ = gin.CreateTestContext(w)
+	)
+	c.Request = httptest.NewRequest("GET", "/api/iam/v1/audit?target=b&outcome=success", nil)
+	ac.ListEvents(c)
+
+	assert.Nil(t, c.Errors)
+	var result model.AuditEventList
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &result))
+	if assert.Len(t, result.Events, 1) {
+		assert.Equal(t, "b", result.Events[0].TargetID)
+		assert.Equal(t, "admin1", result.Events[0].Actor.ID)
+	}
+}
+
+func TestListEventsBadParams(t *testing.T) {
+	ac := &AuditController{a: audit.New(audit.NewMemoryStore(10))}
+
+	for _, query := range []string{"limit=abc", "limit=501", "since=yesterday"} {
+		var (
+			w    = httptest.NewRecorder()
+			c, _ = gin.CreateTestContext(w)
+		)
+		c.Request = httptest.NewRequest("GET", "/api/iam/v1/audit?"+query, nil)
+		ac.ListEvents(c)
+
+		assert.Truef(t, c.Errors != nil, "c.Errors shouldnt be nil for %s", query)
+	}
+}
+
+func TestUserController_AuditUpdate(t *testing.T) {
+	uc, ms, _ := initController(t)
+	uc.a = audit.New(audit.NewMemoryStore(10)).WithChanges()
+
+	before := &model.User{ID: "a", Name: "Matt", Email: "t@t.com"}
+	after := &model.User{ID: "a", Name: "Matt Slauson", Email: "t@t.com"}
+	ms.On("GetUserByID", mock.Anything, "a").Return(before, nil)
+	ms.On("UpdateName", mock.Anything, "a", mock.AnythingOfType("*model.UpdateNameRequest")).
+		Return(after, nil)
+
+	c := auditContext(mAdmin, "PUT", &model.UpdateNameRequest{Name: "Matt Slauson"})
+	uc.UpdateName(c)
+	assert.Nil(t, c.Errors)
+
+	e := lastEvent(t, uc.a)
+	assert.Equal(t, constants.AUDIT_USER_UPDATE_NAME, e.Action)
+	assert.Equal(t, "a", e.TargetID)
+	assert.Equal(t, model.AuditActor{
+		Type:  constants.AUDIT_ACTOR_USER,
+		ID:    "admin1",
+		Roles: []string{constants.ROLE_ADMIN},
+	}, e.Actor)
+	assert.Equal(t, "req-1", e.RequestID)
+	assert.Equal(t, constants.AUDIT_OUTCOME_SUCCESS, e.Outcome)
+	assert.Equal(t, map[string]model.AuditChange{
+		"name": {Before: "Matt", After: "Matt Slauson"},
+	}, e.Changes)
+}
+
+// Without changes an update costs no lookup of the user before it.
+func TestUserController_AuditUpdateNoChanges(t *testing.T) {
+	uc, ms, _ := initController(t)
+	uc.a = audit.New(audit.NewMemoryStore(10))
+
+	ms.On("UpdateName", mock.Anything, "a", mock.AnythingOfType("*model.UpdateNameRequest")).
+		Return(&model.User{ID: "a", Name: "Matt Slauson"}, nil)
+
+	c := auditContext(mAdmin, "PUT", &model.UpdateNameRequest{Name: "Matt Slauson"})
+	uc.UpdateName(c)
+	assert.Nil(t, c.Errors)
+
+	ms.AssertNotCalled(t, "GetUserByID", mock.Anything, mock.Anything)
+	e := lastEvent(t, uc.a)
+	assert.Equal(t, constants.AUDIT_USER_UPDATE_NAME, e.Action)
+	assert.Equal(t, constants.AUDIT_OUTCOME_SUCCESS, e.Outcome)
+	assert.Nil(t, e.Changes)
+}
+
+func TestUserController_AuditFailure(t *testing.T) {
+	uc, ms, _ := initController(t)
+	uc.a = audit.New(audit.NewMemoryStore(10))
+
+	ms.On("UpdateStatus", mock.Anything, "a", mock.AnythingOfType("*model.UpdateStatusRequest")).
+		Return(
+			nil,
+			utils.NewIamError(http.StatusNotFound, constants.ERR_TYPE_USER_NOT_FOUND, "nope"),
+		)
+
+	blocked := false
+	c := auditContext(mAdmin, "PUT", &model.UpdateStatusRequest{Status: &blocked})
+	uc.UpdateStatus(c)
+	assert.NotNil(t, c.Errors)
+
+	e := lastEvent(t, uc.a)
+	assert.Equal(t, constants.AUDIT_USER_UPDATE_STATUS, e.Action)
+	assert.Equal(t, constants.AUDIT_OUTCOME_FAILURE, e.Outcome)
+	assert.Equal(t, http.StatusNotFound, e.Status)
+	assert.Equal(t, constants.ERR_TYPE_USER_NOT_FOUND, e.Error)
+	assert.Nil(t, e.Changes)
+}
+
+func TestUserController_AuditCreate(t *testing.T) {
+	uc, ms, _ := initController(t)
+	uc.a = audit.New(audit.NewMemoryStore(10))
+
+	ms.On("CreateUser", mock.Anything, mock.AnythingOfType("*siogeneric.AwCreateUserRequest")).
+		Return(&model.User{ID: "new", Email: "t@t.com", Status: true}, nil)
+
+	c := auditContext(nil, "POST", &siogeneric.AwCreateUserRequest{
+		UserID:   "new",
+		Phone:    "2121212131",
+		Email:    "t@t.com",
+		Name:     "b",
+		Password: "MattTesting&*^1",
+	})
+	uc.CreateUser(c)
+	assert.Nil(t, c.Errors)
+
+	e := lastEvent(t, uc.a)
+	assert.Equal(t, constants.AUDIT_USER_CREATE, e.Action)
+	assert.Equal(t, "new", e.TargetID)
+	assert.Equal(t, model.AuditActor{Type: constants.AUDIT_ACTOR_SERVICE}, e.Actor)
+	assert.Equal(t, model.AuditChange{After: "t@t.com"}, e.Changes["email"])
+	assert.NotContains(t, e.Changes, "password")
+}
+
+func TestUserController_AuditDelete(t *testing.T) {
+	uc, ms, _ := initController(t)
+	uc.a = audit.New(audit.NewMemoryStore(10)).WithChanges()
+
+	ms.On("GetUserByID", mock.Anything, "a").Return(&model.User{ID: "a", Email: "t@t.com"}, nil)
+	ms.On("DeleteUser", mock.Anything, "a").Return(siogeneric.SuccessResponse{Success: true}, nil)
+
+	c := auditContext(mAdmin, "DELETE", nil)
+	uc.DeleteUser(c)
+	assert.Nil(t, c.Errors)
+
+	e := lastEvent(t, uc.a)
+	assert.Equal(t, constants.AUDIT_USER_DELETE, e.Action)
+	assert.Equal(t, model.AuditChange{Before: "t@t.com"}, e.Changes["email"])
+}
+
+func TestUserController_AuditPrefs(t *testing.T) {
+	uc, ms, _ := initController(t)
+	uc.a = audit.New(audit.NewMemoryStore(10)).WithChanges()
+
+	ms.On("GetPrefs", mock.Anything, "a").Return(model.Prefs{"theme": "dark"}, nil)
+	ms.On("UpdatePrefs", mock.Anything, "a", mock.AnythingOfType("*model.UpdatePrefsRequest")).
+		Return(model.Prefs{"theme": "light"}, nil)
+
+	c := auditContext(
+		mAdmin,
+		"PATCH",
+		&model.UpdatePrefsRequest{Prefs: model.Prefs{"theme": "light"}},
+	)
+	uc.UpdatePrefs(c)
+	assert.Nil(t, c.Errors)
+
+	e := lastEvent(t, uc.a)
+	assert.Equal(t, constants.AUDIT_USER_UPDATE_PREFS, e.Action)
+	assert.Equal(t, map[string]model.AuditChange{
+		"prefs.theme": {Before: "dark", After: "light"},
+	}, e.Changes)
+}
+
+func TestUserController_AuditRecovery(t *testing.T) {
+	uc, ms, _ := initController(t)
+	uc.a = audit.New(audit.NewMemoryStore(10))
+
+	ms.On(
+		"CreatePasswordRecovery",
+		mock.Anything,
+		mock.AnythingOfType("*model.PasswordRecoveryRequest"),
+	).
+		Return(siogeneric.SuccessResponse{Success: true}, nil)
+
+	c := auditContext(nil, "POST", &model.PasswordRecoveryRequest{Email: "t@t.com"})
+	uc.CreatePasswordRecovery(c)
+	assert.Nil(t, c.Errors)
+
+	e := lastEvent(t, uc.a)
+	assert.Equal(t, constants.AUDIT_USER_RECOVERY_CREATE, e.Action)
+	assert.Equal(t, map[string]string{"email": "t@t.com"}, e.Meta)
+}
+
+func TestSessionController_Audit(t *testing.T) {
+	sc, ss, _ := initControllerForSessionTests(t)
+	sc.a = audit.New(audit.NewMemoryStore(10))
+
+	ss.On(
+		"CreateEmailSession",
+		mock.Anything,
+		mock.AnythingOfType("*siogeneric.AwEmailSessionRequest"),
+		mock.AnythingOfType("string"),
+	).
+		Return(&model.Session{ID: "s1", UserID: "a"}, nil)
+	ss.On("DeleteSession", mock.Anything, "a", "s1").
+		Return(siogeneric.SuccessResponse{Success: true}, nil)
+	ss.On("DeleteSessions", mock.Anything, "a").
+		Return(siogeneric.SuccessResponse{Success: true}, nil)
+
+	c := auditContext(
+		nil,
+		"POST",
+		&siogeneric.AwEmailSessionRequest{Email: "t@t.com", Password: "pw"},
+	)
+	sc.CreateEmailSession(c)
+	c = auditContext(mAdmin, "DELETE", nil)
+	c.Params = append(c.Params, gin.Param{Key: "sessionId", Value: "s1"})
+	sc.DeleteSession(c)
+	sc.DeleteSessions(auditContext(mAdmin, "DELETE", nil))
+
+	events, _ := sc.a.Query(context.Background(), &model.AuditQueryParams{})
+	if !assert.Len(t, events, 3) {
+		return
+	}
+	all, one, login := events[0], events[1], events[2]
+
+	assert.Equal(t, constants.AUDIT_SESSION_CREATE, login.Action)
+	assert.Equal(t, "a", login.TargetID)
+	assert.Equal(t, map[string]string{"email": "t@t.com", "sessionId": "s1"}, login.Meta)
+
+	assert.Equal(t, constants.AUDIT_SESSION_DELETE, one.Action)
+	assert.Equal(t, map[string]string{"sessionId": "s1"}, one.Meta)
+
+	assert.Equal(t, constants.AUDIT_SESSION_DELETE_ALL, all.Action)
+	assert.Equal(t, "admin1", all.Actor.ID)
+}
+
+func TestSessionController_AuditLoginFailure(t *testing.T) {
+	sc, ss, _ := initControllerForSessionTests(t)
+	sc.a = audit.New(audit.NewMemoryStore(10))
+
+	ss.On(
+		"CreateEmailSession",
+		mock.Anything,
+		mock.AnythingOfType("*siogeneric.AwEmailSessionRequest"),
+		mock.AnythingOfType("string"),
+	).
+		Return(nil, utils.NewIamError(
+			http.StatusUnauthorized,
+			constants.ERR_TYPE_INVALID_CREDENTIALS,
+			"invalid credentials",
+		))
+
+	c := auditContext(
+		nil,
+		"POST",
+		&siogeneric.AwEmailSessionRequest{Email: "t@t.com", Password: "pw"},
+	)
+	sc.CreateEmailSession(c)
+
+	e := lastEvent(t, sc.a)
+	assert.Equal(t, constants.AUDIT_OUTCOME_FAILURE, e.Outcome)
+	assert.Equal(t, constants.ERR_TYPE_INVALID_CREDENTIALS, e.Error)
+	assert.Empty(t, e.TargetID)
+	assert.Equal(t, map[string]string{"email": "t@t.com"}, e.Meta)
+}
+
+func TestRoleController_Audit(t *testing.T) {
+	rc, rs := initRoleController(t)
+	rc.a = audit.New(audit.NewMemoryStore(10)).WithChanges()
+
+	rs.On("GetRoles", mock.Anything, "a").
+		Return(&model.RolesResponse{ID: "a", Roles: []string{constants.ROLE_READER}}, nil)
+	rs.On("UpdateRoles", mock.Anything, "a", mock.AnythingOfType("*model.UpdateRolesRequest")).
+		Return(&model.RolesResponse{ID: "a", Roles: []string{constants.ROLE_AUTHOR}}, nil)
+
+	c := auditContext(
+		mAdmin,
+		"PUT",
+		&model.UpdateRolesRequest{Roles: []string{constants.ROLE_AUTHOR}},
+	)
+	rc.UpdateRoles(c)
+	assert.Nil(t, c.Errors)
+
+	e := lastEvent(t, rc.a)
+	assert.Equal(t, constants.AUDIT_USER_UPDATE_ROLES, e.Action)
+	assert.Equal(t, map[string]model.AuditChange{
+		"roles": {
+			Before: []string{constants.ROLE_READER},
+			After:  []string{constants.ROLE_AUTHOR},
+		},
+	}, e.Changes)
+}
+
+// The self-service routes run the same handlers, so they are audited with the
+// caller as both actor and target.
+func TestMeController_Audit(t *testing.T) {
+	mc, us, _ := initMeController(t)
+	mc.uc.a = audit.New(audit.NewMemoryStore(10))
+
+	us.On("UpdateName", mock.Anything, "me", mock.AnythingOfType("*model.UpdateNameRequest")).
+		Return(&model.User{ID: "me", Name: "New"}, nil)
+
+	c := auditContext(&model.Caller{ID: "me"}, "PUT", &model.UpdateNameRequest{Name: "New"})
+	mc.UpdateMyName(c)
+	assert.Nil(t, c.Errors)
+
+	e := lastEvent(t, mc.uc.a)
+	assert.Equal(t, "me", e.TargetID)
+	assert.Equal(t, "me", e.Actor.ID)
+}
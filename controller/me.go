@@ -0,0 +1,201 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"gitea.slauson.io/slausonio/go-utils/sioerror"
+	"gitea.slauson.io/slausonio/iam-ms/config"
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+	"gitea.slauson.io/slausonio/iam-ms/model"
+)
+
+// MeController serves the self-service routes, which always act on the caller
+// resolved from their session rather than on a user ID taken from the path.
+type MeController struct {
+	uc *UserController
+	sc *SessionController
+}
+
+//go:generate mockery --name IamMeController
+type IamMeController interface {
+	GetMe(c *gin.Context)
+	UpdateMyPassword(c *gin.Context)
+	UpdateMyEmail(c *gin.Context)
+	UpdateMyPhone(c *gin.Context)
+	UpdateMyName(c *gin.Context)
+	ListMySessions(c *gin.Context)
+	DeleteMySessions(c *gin.Context)
+	DeleteMySession(c *gin.Context)
+}
+
+func NewMeController(cfg *config.Config) *MeController {
+	return &MeController{
+		uc: NewUserController(cfg),
+		sc: NewSessionController(cfg),
+	}
+}
+
+// @Summary Get Me
+// GET
+// @Description Get the caller's own profile
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Param X-Appwrite-JWT header string true "Caller session JWT"
+// @Success 200 {object} model.User
+// @Failure 401 {object} siogeneric.ErrorResponse
+// @Failure 404 {object} siogeneric.ErrorResponse
+// @Failure 500 {object} siogeneric.ErrorResponse
+// @Router /api/iam/v1/me [get]
+func (mc *MeController) GetMe(c *gin.Context) {
+	if id, ok := callerID(c); ok {
+		mc.uc.getUser(c, id)
+	}
+}
+
+// @Summary Update My Password
+// PUT
+// @Description Change the caller's password. The current password is required.
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Param updateRequest body model.UpdateOwnPasswordRequest true "Update Password Request"
+// @Param X-Appwrite-JWT header string true "Caller session JWT"
+// @Success 200 {object} model.User
+// @Failure 400 {object} siogeneric.ErrorResponse
+// @Failure 401 {object} siogeneric.ErrorResponse
+// @Failure 404 {object} siogeneric.ErrorResponse
+// @Failure 429 {object} siogeneric.ErrorResponse
+// @Failure 500 {object} siogeneric.ErrorResponse
+// @Router /api/iam/v1/me/password [put]
+func (mc *MeController) UpdateMyPassword(c *gin.Context) {
+	if id, ok := callerID(c); ok {
+		mc.uc.updateOwnPassword(c, id)
+	}
+}
+
+// @Summary Update My Email
+// PUT
+// @Description Change the caller's email. The current password is required.
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Param updateRequest body model.UpdateOwnEmailRequest true "Update Email Request"
+// @Param verify query bool false "Send a verification email to the new address"
+// @Param X-Appwrite-JWT header string true "Caller session JWT"
+// @Success 200 {object} model.User
+// @Failure 400 {object} siogeneric.ErrorResponse
+// @Failure 401 {object} siogeneric.ErrorResponse
+// @Failure 404 {object} siogeneric.ErrorResponse
+// @Failure 429 {object} siogeneric.ErrorResponse
+// @Failure 500 {object} siogeneric.ErrorResponse
+// @Router /api/iam/v1/me/email [put]
+func (mc *MeController) UpdateMyEmail(c *gin.Context) {
+	if id, ok := callerID(c); ok {
+		mc.uc.updateOwnEmail(c, id)
+	}
+}
+
+// @Summary Update My Phone
+// PUT
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Param updateRequest body siogeneric.UpdatePhoneRequest true "Update Phone Request"
+// @Param verify query bool false "Text a verification code to the new number"
+// @Param X-Appwrite-JWT header string true "Caller session JWT"
+// @Success 200 {object} model.User
+// @Failure 400 {object} siogeneric.ErrorResponse
+// @Failure 401 {object} siogeneric.ErrorResponse
+// @Failure 404 {object} siogeneric.ErrorResponse
+// @Failure 500 {object} siogeneric.ErrorResponse
+// @Router /api/iam/v1/me/phone [put]
+func (mc *MeController) UpdateMyPhone(c *gin.Context) {
+	if id, ok := callerID(c); ok {
+		mc.uc.updatePhone(c, id)
+	}
+}
+
+// @Summary Update My Name
+// PUT
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Param updateRequest body model.UpdateNameRequest true "Update Name Request"
+// @Param X-Appwrite-JWT header string true "Caller session JWT"
+// @Success 200 {object} model.User
+// @Failure 400 {object} siogeneric.ErrorResponse
+// @Failure 401 {object} siogeneric.ErrorResponse
+// @Failure 404 {object} siogeneric.ErrorResponse
+// @Failure 500 {object} siogeneric.ErrorResponse
+// @Router /api/iam/v1/me/name [put]
+func (mc *MeController) UpdateMyName(c *gin.Context) {
+	if id, ok := callerID(c); ok {
+		mc.uc.updateName(c, id)
+	}
+}
+
+// @Summary List My Sessions
+// GET
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Param X-Appwrite-JWT header string true "Caller session JWT"
+// @Success 200 {object} model.SessionListResponse
+// @Failure 401 {object} siogeneric.ErrorResponse
+// @Failure 404 {object} siogeneric.ErrorResponse
+// @Failure 500 {object} siogeneric.ErrorResponse
+// @Router /api/iam/v1/me/sessions [get]
+func (mc *MeController) ListMySessions(c *gin.Context) {
+	if id, ok := callerID(c); ok {
+		mc.sc.listSessions(c, id)
+	}
+}
+
+// @Summary Delete My Sessions
+// DELETE
+// @Description Sign the caller out of every session
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Param X-Appwrite-JWT header string true "Caller session JWT"
+// @Success 200 {object} siogeneric.SuccessResponse
+// @Failure 401 {object} siogeneric.ErrorResponse
+// @Failure 404 {object} siogeneric.ErrorResponse
+// @Failure 500 {object} siogeneric.ErrorResponse
+// @Router /api/iam/v1/me/sessions [delete]
+func (mc *MeController) DeleteMySessions(c *gin.Context) {
+	if id, ok := callerID(c); ok {
+		mc.sc.deleteSessions(c, id)
+	}
+}
+
+// @Summary Delete My Session
+// DELETE
+// @Tags me
+// @Accept  json
+// @Produce  json
+// @Param sessionId path string true "Session ID"
+// @Param X-Appwrite-JWT header string true "Caller session JWT"
+// @Success 200 {object} siogeneric.SuccessResponse
+// @Failure 401 {object} siogeneric.ErrorResponse
+// @Failure 404 {object} siogeneric.ErrorResponse
+// @Failure 500 {object} siogeneric.ErrorResponse
+// @Router /api/iam/v1/me/sessions/:sessionId [delete]
+func (mc *MeController) DeleteMySession(c *gin.Context) {
+	if id, ok := callerID(c); ok {
+		mc.sc.deleteSession(c, id, c.Param("sessionId"))
+	}
+}
+
+// callerID returns the ID of the caller resolved by the role middleware.
+func callerID(c *gin.Context) (string, bool) {
+	if v, ok := c.Get(constants.CALLER_CONTEXT_KEY); ok {
+		if caller, ok := v.(*model.Caller); ok && caller.ID != "" {
+			return caller.ID, true
+		}
+	}
+
+	_ = c.Error(sioerror.NewSioUnauthorizedError(constants.MissingUserSession))
+	return "", false
+}
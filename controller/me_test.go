@@ -0,0 +1,246 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"gitea.slauson.io/slausonio/go-types/siogeneric"
+	"gitea.slauson.io/slausonio/iam-ms/config"
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+	"gitea.slauson.io/slausonio/iam-ms/model"
+	"gitea.slauson.io/slausonio/iam-ms/service/mocks"
+)
+
+func initMeController(
+	t *testing.T,
+) (*MeController, *mocks.IamUserService, *mocks.IamSessionService) {
+	us := mocks.NewIamUserService(t)
+	ss := mocks.NewIamSessionService(t)
+	mc := &MeController{
+		uc: &UserController{s: us},
+		sc: &SessionController{s: ss},
+	}
+	return mc, us, ss
+}
+
+func meContext(caller *model.Caller) *gin.Context {
+	var (
+		w    = httptest.NewRecorder()
+		c, _ = gin.CreateTestContext(w)
+	)
+	c.Request = &http.Request{
+		Header: make(http.Header),
+	}
+	if caller != nil {
+		c.Set(constants.CALLER_CONTEXT_KEY, caller)
+	}
+	return c
+}
+
+func TestNewMeController(t *testing.T) {
+	mc := NewMeController(config.Default())
+	assert.NotNil(t, mc)
+}
+
+func TestGetMe(t *testing.T) {
+	mc, us, _ := initMeController(t)
+
+	c := meContext(&model.Caller{ID: "a"})
+	us.On("GetUserByID", mock.Anything, "a").Return(mUserPtr, nil)
+	mc.GetMe(c)
+
+	assert.Truef(t, c.Errors == nil, "c.Errors should be nil")
+}
+
+func TestGetMeNoCaller(t *testing.T) {
+	mc, _, _ := initMeController(t)
+
+	c := meContext(nil)
+	mc.GetMe(c)
+
+	assert.Truef(t, c.Errors != nil, "c.Errors shouldnt be nil")
+}
+
+func TestUpdateMyPassword(t *testing.T) {
+	mc, us, _ := initMeController(t)
+
+	c := meContext(&model.Caller{ID: "a"})
+	MockJson(
+		c,
+		&model.UpdateOwnPasswordRequest{OldPassword: "old", Password: "Password123!"},
+		"PUT",
+	)
+	us.On(
+		"UpdateOwnPassword",
+		mock.Anything,
+		"a",
+		mock.AnythingOfType("*model.UpdateOwnPasswordRequest"),
+		mock.AnythingOfType("string"),
+	).
+		Return(mUserPtr, nil)
+	mc.UpdateMyPassword(c)
+
+	assert.Truef(t, c.Errors == nil, "c.Errors should be nil")
+}
+
+func TestUpdateMyPasswordMissingOldPassword(t *testing.T) {
+	mc, _, _ := initMeController(t)
+
+	c := meContext(&model.Caller{ID: "a"})
+	MockJson(c, &model.UpdateOwnPasswordRequest{Password: "Password123!"}, "PUT")
+	mc.UpdateMyPassword(c)
+
+	assert.Truef(t, c.Errors != nil, "c.Errors shouldnt be nil")
+}
+
+func TestUpdateMyPasswordWrongOldPassword(t *testing.T) {
+	mc, us, _ := initMeController(t)
+
+	c := meContext(&model.Caller{ID: "a"})
+	MockJson(
+		c,
+		&model.UpdateOwnPasswordRequest{OldPassword: "wrong", Password: "Password123!"},
+		"PUT",
+	)
+	us.On(
+		"UpdateOwnPassword",
+		mock.Anything,
+		"a",
+		mock.AnythingOfType("*model.UpdateOwnPasswordRequest"),
+		mock.AnythingOfType("string"),
+	).
+		Return(nil, errors.New("asdf"))
+	mc.UpdateMyPassword(c)
+
+	assert.Truef(t, c.Errors != nil, "c.Errors shouldnt be nil")
+}
+
+func TestUpdateMyEmail(t *testing.T) {
+	mc, us, _ := initMeController(t)
+
+	c := meContext(&model.Caller{ID: "a"})
+	MockJson(c, &model.UpdateOwnEmailRequest{OldPassword: "old", Email: "t@t.com"}, "PUT")
+	us.On(
+		"UpdateOwnEmail",
+		mock.Anything,
+		"a",
+		mock.AnythingOfType("*model.UpdateOwnEmailRequest"),
+		mock.AnythingOfType("string"),
+	).
+		Return(mUserPtr, nil)
+	mc.UpdateMyEmail(c)
+
+	assert.Truef(t, c.Errors == nil, "c.Errors should be nil")
+}
+
+func TestUpdateMyEmailMissingOldPassword(t *testing.T) {
+	mc, _, _ := initMeController(t)
+
+	c := meContext(&model.Caller{ID: "a"})
+	MockJson(c, &model.UpdateOwnEmailRequest{Email: "t@t.com"}, "PUT")
+	mc.UpdateMyEmail(c)
+
+	assert.Truef(t, c.Errors != nil, "c.Errors shouldnt be nil")
+}
+
+func TestUpdateMyPhone(t *testing.T) {
+	mc, us, _ := initMeController(t)
+
+	c := meContext(&model.Caller{ID: "a"})
+	MockJson(c, &siogeneric.UpdatePhoneRequest{Number: "5555555555"}, "PUT")
+	us.On("UpdatePhone", mock.Anything, "a", mock.AnythingOfType("*siogeneric.UpdatePhoneRequest")).
+		Return(mUserPtr, nil)
+	mc.UpdateMyPhone(c)
+
+	assert.Truef(t, c.Errors == nil, "c.Errors should be nil")
+}
+
+func TestUpdateMyName(t *testing.T) {
+	tests := []struct {
+		name    string
+		request *model.UpdateNameRequest
+		called  bool
+		err     error
+	}{
+		{name: "happy", request: &model.UpdateNameRequest{Name: "Matt"}, called: true},
+		{name: "Missing Name", request: &model.UpdateNameRequest{}, err: errors.New("invalid")},
+		{
+			name:    "service failure",
+			request: &model.UpdateNameRequest{Name: "Matt"},
+			called:  true,
+			err:     errors.New("asdf"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mc, us, _ := initMeController(t)
+
+			c := meContext(&model.Caller{ID: "a"})
+			MockJson(c, tt.request, "PUT")
+			if tt.called {
+				us.On(
+					"UpdateName",
+					mock.Anything,
+					"a",
+					mock.AnythingOfType("*model.UpdateNameRequest"),
+				).
+					Return(mUserPtr, tt.err)
+			}
+			mc.UpdateMyName(c)
+			if tt.err == nil {
+				assert.Truef(t, c.Errors == nil, "c.Errors should be nil")
+			} else {
+				assert.Truef(t, c.Errors != nil, "c.Errors shouldnt be nil")
+			}
+		})
+	}
+}
+
+func TestListMySessions(t *testing.T) {
+	mc, _, ss := initMeController(t)
+
+	c := meContext(&model.Caller{ID: "a"})
+	ss.On("ListSessions", mock.Anything, "a").Return(&model.SessionListResponse{}, nil)
+	mc.ListMySessions(c)
+
+	assert.Truef(t, c.Errors == nil, "c.Errors should be nil")
+}
+
+func TestDeleteMySessions(t *testing.T) {
+	mc, _, ss := initMeController(t)
+
+	c := meContext(&model.Caller{ID: "a"})
+	ss.On("DeleteSessions", mock.Anything, "a").
+		Return(siogeneric.SuccessResponse{Success: true}, nil)
+	mc.DeleteMySessions(c)
+
+	assert.Truef(t, c.Errors == nil, "c.Errors should be nil")
+}
+
+func TestDeleteMySession(t *testing.T) {
+	mc, _, ss := initMeController(t)
+
+	c := meContext(&model.Caller{ID: "a"})
+	c.Params = gin.Params{gin.Param{Key: "sessionId", Value: "s"}}
+	ss.On("DeleteSession", mock.Anything, "a", "s").
+		Return(siogeneric.SuccessResponse{Success: true}, nil)
+	mc.DeleteMySession(c)
+
+	assert.Truef(t, c.Errors == nil, "c.Errors should be nil")
+}
+
+func TestDeleteMySessionNoCaller(t *testing.T) {
+	mc, _, _ := initMeController(t)
+
+	c := meContext(nil)
+	c.Params = gin.Params{gin.Param{Key: "sessionId", Value: "s"}}
+	mc.DeleteMySession(c)
+
+	assert.Truef(t, c.Errors != nil, "c.Errors shouldnt be nil")
+}
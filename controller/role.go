@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"gitea.slauson.io/slausonio/go-utils/sioUtils"
+	"gitea.slauson.io/slausonio/go-utils/sioerror"
+	"gitea.slauson.io/slausonio/iam-ms/audit"
+	"gitea.slauson.io/slausonio/iam-ms/config"
+	"gitea.slauson.io/slausonio/iam-ms/model"
+	"gitea.slauson.io/slausonio/iam-ms/service"
+	"gitea.slauson.io/slausonio/iam-ms/utils"
+)
+
+type RoleController struct {
+	s service.IamRoleService
+	a *audit.Auditor
+}
+
+//go:generate mockery --name IamRoleController
+type IamRoleController interface {
+	GetRoles(c *gin.Context)
+	UpdateRoles(c *gin.Context)
+}
+
+func NewRoleController(cfg *config.Config) *RoleController {
+	return &RoleController{
+		s: service.NewRoleService(cfg),
+		a: audit.Default(cfg),
+	}
+}
+
+// @Summary Get Roles
+// GET
+// @Description Get a user's roles
+// @Tags role
+// @Accept  json
+// @Produce  json
+// @Param id path string true "User ID"
+// @Param X-Appwrite-JWT header string true "Caller session JWT"
+// @Success 200 {object} model.RolesResponse
+// @Failure 400 {object} siogeneric.ErrorResponse
+// @Failure 401 {object} siogeneric.ErrorResponse
+// @Failure 403 {object} siogeneric.ErrorResponse
+// @Failure 404 {object} siogeneric.ErrorResponse
+// @Failure 500 {object} siogeneric.ErrorResponse
+// @Router /api/iam/v1/user/:id/roles [get]
+func (rc *RoleController) GetRoles(c *gin.Context) {
+	id := c.Param("id")
+	response, e := rc.s.GetRoles(c.Request.Context(), id)
+	if e != nil {
+		_ = c.Error(e)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// @Summary Update Roles
+// PUT
+// @Description Replace a user's roles (admin, author, reader)
+// @Tags role
+// @Accept  json
+// @Produce  json
+// @Param updateRequest body model.UpdateRolesRequest true "Update Roles Request"
+// @Param id path string true "User ID"
+// @Param X-Appwrite-JWT header string true "Caller session JWT"
+// @Success 200 {object} model.RolesResponse
+// @Failure 400 {object} siogeneric.ErrorResponse
+// @Failure 401 {object} siogeneric.ErrorResponse
+// @Failure 403 {object} siogeneric.ErrorResponse
+// @Failure 404 {object} siogeneric.ErrorResponse
+// @Failure 500 {object} siogeneric.ErrorResponse
+// @Router /api/iam/v1/user/:id/roles [put]
+func (rc *RoleController) UpdateRoles(c *gin.Context) {
+	validations := utils.NewIamValidations()
+	id := c.Param("id")
+	request := new(model.UpdateRolesRequest)
+	err := sioUtils.DecryptAndHandle(request, c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	err = validations.ValidateUpdateRolesRequest(request)
+	if err != nil {
+		_ = c.Error(sioerror.NewSioBadRequestError(err.Error()))
+		return
+	}
+
+	response, e := rc.auditRoles(c, id, func() (*model.RolesResponse, error) {
+		return rc.s.UpdateRoles(c.Request.Context(), id, request)
+	})
+	if e != nil {
+		_ = c.Error(e)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"gitea.slauson.io/slausonio/iam-ms/config"
+	"gitea.slauson.io/slausonio/iam-ms/model"
+	"gitea.slauson.io/slausonio/iam-ms/service/mocks"
+)
+
+func initRoleController(t *testing.T) (*RoleController, *mocks.IamRoleService) {
+	ms := mocks.NewIamRoleService(t)
+	rc := &RoleController{
+		s: ms,
+	}
+	return rc, ms
+}
+
+func TestNewRoleController(t *testing.T) {
+	rc := NewRoleController(config.Default())
+	assert.NotNil(t, rc)
+}
+
+func TestGetRoles(t *testing.T) {
+	rc, ms := initRoleController(t)
+
+	var (
+		w    = httptest.NewRecorder()
+		c, _ = gin.CreateTestContext(w)
+	)
+	c.Request = &http.Request{Header: make(http.Header)}
+	c.Params = gin.Params{gin.Param{Key: "id", Value: "a"}}
+	ms.On("GetRoles", mock.Anything, "a").
+		Return(&model.RolesResponse{ID: "a", Roles: []string{"reader"}}, nil)
+	rc.GetRoles(c)
+
+	assert.Truef(t, c.Errors == nil, "c.Errors should be nil")
+}
+
+func TestGetRolesError(t *testing.T) {
+	rc, ms := initRoleController(t)
+
+	var (
+		w    = httptest.NewRecorder()
+		c, _ = gin.CreateTestContext(w)
+	)
+	c.Request = &http.Request{Header: make(http.Header)}
+	c.Params = gin.Params{gin.Param{Key: "id", Value: "a"}}
+	ms.On("GetRoles", mock.Anything, "a").Return(nil, errors.New("asdf"))
+	rc.GetRoles(c)
+
+	assert.Truef(t, c.Errors != nil, "c.Errors shouldnt be nil")
+}
+
+func TestRoleController_UpdateRoles(t *testing.T) {
+	tests := []struct {
+		name    string
+		request *model.UpdateRolesRequest
+		called  bool
+		err     error
+	}{
+		{
+			name:    "happy",
+			request: &model.UpdateRolesRequest{Roles: []string{"admin", "author"}},
+			called:  true,
+		},
+		{
+			name:    "Unknown Role",
+			request: &model.UpdateRolesRequest{Roles: []string{"owner"}},
+			err:     errors.New("unknown"),
+		},
+		{
+			name:    "service failure",
+			request: &model.UpdateRolesRequest{Roles: []string{"reader"}},
+			called:  true,
+			err:     errors.New("asdf"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				w    = httptest.NewRecorder()
+				c, _ = gin.CreateTestContext(w)
+			)
+			c.Request = &http.Request{
+				Header: make(http.Header),
+			}
+			c.Params = gin.Params{gin.Param{Key: "id", Value: "a"}}
+
+			rc, ms := initRoleController(t)
+
+			MockJson(c, tt.request, "PUT")
+			if tt.called {
+				ms.On(
+					"UpdateRoles",
+					mock.Anything,
+					"a",
+					mock.AnythingOfType("*model.UpdateRolesRequest"),
+				).
+					Return(&model.RolesResponse{ID: "a", Roles: tt.request.Roles}, tt.err)
+			}
+			rc.UpdateRoles(c)
+			if tt.err == nil {
+				assert.Truef(t, c.Errors == nil, "c.Errors should be nil")
+			} else {
+				assert.Truef(t, c.Errors != nil, "c.Errors shouldnt be nil")
+			}
+		})
+	}
+}
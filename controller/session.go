@@ -7,22 +7,33 @@ import (
 
 	"gitea.slauson.io/slausonio/go-types/siogeneric"
 	"gitea.slauson.io/slausonio/go-utils/sioUtils"
+	"gitea.slauson.io/slausonio/go-utils/sioerror"
+	"gitea.slauson.io/slausonio/iam-ms/audit"
+	"gitea.slauson.io/slausonio/iam-ms/config"
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+	"gitea.slauson.io/slausonio/iam-ms/model"
 	"gitea.slauson.io/slausonio/iam-ms/service"
+	"gitea.slauson.io/slausonio/iam-ms/utils"
 )
 
 type SessionController struct {
 	s service.IamSessionService
+	a *audit.Auditor
 }
 
 //go:generate mockery --name IamSessionController
 type IamSessionController interface {
 	CreateEmailSession(c *gin.Context)
+	ListSessions(c *gin.Context)
 	DeleteSession(c *gin.Context)
+	DeleteSessions(c *gin.Context)
+	ClearLockout(c *gin.Context)
 }
 
-func NewSessionController() *SessionController {
+func NewSessionController(cfg *config.Config) *SessionController {
 	return &SessionController{
-		s: service.NewSessionService(),
+		s: service.NewSessionService(cfg),
+		a: audit.Default(cfg),
 	}
 }
 
@@ -32,10 +43,11 @@ func NewSessionController() *SessionController {
 // @Accept  json
 // @Produce  json
 // @Param sessionRequest body siogeneric.AwEmailSessionRequest true "Session Request"
-// @Success 200 {object} siogeneric.AwSession
+// @Success 200 {object} model.Session
 // @Failure 400 {object} siogeneric.ErrorResponse
 // @Failure 401 {object} siogeneric.ErrorResponse
 // @Failure 404 {object} siogeneric.ErrorResponse
+// @Failure 429 {object} siogeneric.ErrorResponse
 // @Failure 500 {object} siogeneric.ErrorResponse
 // @Router /api/iam/v1/session [post]
 func (sc *SessionController) CreateEmailSession(c *gin.Context) {
@@ -45,7 +57,14 @@ func (sc *SessionController) CreateEmailSession(c *gin.Context) {
 		_ = c.Error(err)
 		return
 	}
-	response, err := sc.s.CreateEmailSession(request)
+	response, err := sc.s.CreateEmailSession(c.Request.Context(), request, c.ClientIP())
+	meta := map[string]string{"email": request.Email}
+	target := ""
+	if err == nil {
+		target = response.UserID
+		meta["sessionId"] = response.ID
+	}
+	record(c, sc.a, constants.AUDIT_SESSION_CREATE, target, meta, err)
 	if err != nil {
 		_ = c.Error(err)
 		return
@@ -53,22 +72,126 @@ func (sc *SessionController) CreateEmailSession(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// @Summary List Sessions
+// GET
+// @Description List a user's active sessions with device, OS, IP and country
+// @Tags session
+// @Accept  json
+// @Produce  json
+// @Param id path string true "User ID"
+// @Param X-Appwrite-JWT header string true "Caller session JWT"
+// @Success 200 {object} model.SessionListResponse
+// @Failure 400 {object} siogeneric.ErrorResponse
+// @Failure 401 {object} siogeneric.ErrorResponse
+// @Failure 403 {object} siogeneric.ErrorResponse
+// @Failure 404 {object} siogeneric.ErrorResponse
+// @Failure 500 {object} siogeneric.ErrorResponse
+// @Router /api/iam/v1/session/:id [get]
+func (sc *SessionController) ListSessions(c *gin.Context) {
+	sc.listSessions(c, c.Param("id"))
+}
+
+func (sc *SessionController) listSessions(c *gin.Context, ID string) {
+	response, err := sc.s.ListSessions(c.Request.Context(), ID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
 // @Summary Delete Session
 // PUT
 // @Tags session
 // @Accept  json
 // @Produce  json
 // @Param id path string true "User ID"
+// @Param X-Appwrite-JWT header string true "Caller session JWT"
 // @Success 200 {object} siogeneric.SuccessResponse
 // @Failure 400 {object} siogeneric.ErrorResponse
 // @Failure 401 {object} siogeneric.ErrorResponse
+// @Failure 403 {object} siogeneric.ErrorResponse
 // @Failure 404 {object} siogeneric.ErrorResponse
 // @Failure 500 {object} siogeneric.ErrorResponse
 // @Router /api/iam/v1/session/:id/:sessionId [delete]
 func (sc *SessionController) DeleteSession(c *gin.Context) {
-	ID := c.Param("id")
-	sessionID := c.Param("sessionId")
-	response, err := sc.s.DeleteSession(ID, sessionID)
+	sc.deleteSession(c, c.Param("id"), c.Param("sessionId"))
+}
+
+func (sc *SessionController) deleteSession(c *gin.Context, ID, sessionID string) {
+	response, err := sc.s.DeleteSession(c.Request.Context(), ID, sessionID)
+	meta := map[string]string{"sessionId": sessionID}
+	record(c, sc.a, constants.AUDIT_SESSION_DELETE, ID, meta, err)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// @Summary Delete Sessions
+// DELETE
+// @Description Sign a user out of every session
+// @Tags session
+// @Accept  json
+// @Produce  json
+// @Param id path string true "User ID"
+// @Param X-Appwrite-JWT header string true "Caller session JWT"
+// @Success 200 {object} siogeneric.SuccessResponse
+// @Failure 400 {object} siogeneric.ErrorResponse
+// @Failure 401 {object} siogeneric.ErrorResponse
+// @Failure 403 {object} siogeneric.ErrorResponse
+// @Failure 404 {object} siogeneric.ErrorResponse
+// @Failure 500 {object} siogeneric.ErrorResponse
+// @Router /api/iam/v1/session/:id [delete]
+func (sc *SessionController) DeleteSessions(c *gin.Context) {
+	sc.deleteSessions(c, c.Param("id"))
+}
+
+func (sc *SessionController) deleteSessions(c *gin.Context, ID string) {
+	response, err := sc.s.DeleteSessions(c.Request.Context(), ID)
+	record(c, sc.a, constants.AUDIT_SESSION_DELETE_ALL, ID, nil, err)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// @Summary Clear Login Lockout
+// DELETE
+// @Description Lift the lockout of an email, a client IP or both after repeated failed logins, and forget their failures
+// @Tags session
+// @Accept  json
+// @Produce  json
+// @Param email query string false "Locked out email"
+// @Param ip query string false "Locked out client IP"
+// @Param X-Appwrite-JWT header string true "Caller session JWT"
+// @Success 200 {object} siogeneric.SuccessResponse
+// @Failure 400 {object} siogeneric.ErrorResponse
+// @Failure 401 {object} siogeneric.ErrorResponse
+// @Failure 403 {object} siogeneric.ErrorResponse
+// @Failure 500 {object} siogeneric.ErrorResponse
+// @Router /api/iam/v1/lockout [delete]
+func (sc *SessionController) ClearLockout(c *gin.Context) {
+	validations := utils.NewIamValidations()
+	params := new(model.ClearLockoutParams)
+	if err := c.ShouldBindQuery(params); err != nil {
+		_ = c.Error(sioerror.NewSioBadRequestError(err.Error()))
+		return
+	}
+
+	if err := validations.ValidateClearLockoutParams(params); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response, err := sc.s.ClearLockout(c.Request.Context(), params)
+	meta := map[string]string{"email": params.Email, "ip": params.IP}
+	record(c, sc.a, constants.AUDIT_SESSION_LOCKOUT_CLEAR, "", meta, err)
 	if err != nil {
 		_ = c.Error(err)
 		return
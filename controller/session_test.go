@@ -14,35 +14,30 @@ import (
 	"github.com/stretchr/testify/mock"
 
 	"gitea.slauson.io/slausonio/go-utils/sioUtils"
+	"gitea.slauson.io/slausonio/iam-ms/audit"
+	"gitea.slauson.io/slausonio/iam-ms/config"
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+	"gitea.slauson.io/slausonio/iam-ms/model"
 	"gitea.slauson.io/slausonio/iam-ms/service/mocks"
 )
 
-var mUserSession = &siogeneric.AwSession{
-	ID:                        "blah",
-	CreatedAt:                 "blah",
-	AwClientCode:              "blah",
-	AwClientEngine:            "blah",
-	AwClientEngineVersion:     "blah",
-	AwClientName:              "blah",
-	AwClientType:              "blah",
-	AwClientVersion:           "blah",
-	CountryCode:               "blah",
-	CountryName:               "blah",
-	Current:                   true,
-	DeviceBrand:               "blah",
-	DeviceModel:               "blah",
-	DeviceName:                "blah",
-	Expire:                    "blah",
-	Ip:                        "blah",
-	OsCode:                    "blah",
-	OsName:                    "blah",
-	OsVersion:                 "blah",
-	Provider:                  "blah",
-	ProviderAccessToken:       "blah",
-	ProviderAccessTokenExpiry: "blah",
-	ProviderRefreshToken:      "blah",
-	ProviderUid:               "blah",
-	UserId:                    "blah",
+var mUserSession = &model.Session{
+	ID:            "blah",
+	CreatedAt:     "blah",
+	UserID:        "blah",
+	Expire:        "blah",
+	Current:       true,
+	Provider:      "blah",
+	ClientName:    "blah",
+	ClientVersion: "blah",
+	DeviceName:    "blah",
+	DeviceBrand:   "blah",
+	DeviceModel:   "blah",
+	OsName:        "blah",
+	OsVersion:     "blah",
+	Ip:            "blah",
+	CountryCode:   "blah",
+	CountryName:   "blah",
 }
 
 func initControllerForSessionTests(
@@ -58,7 +53,7 @@ func initControllerForSessionTests(
 }
 
 func TestNewSessionController(t *testing.T) {
-	sc := NewSessionController()
+	sc := NewSessionController(config.Default())
 	assert.NotNil(t, sc)
 }
 
@@ -66,7 +61,7 @@ func TestSessionController_CreateEmailSession(t *testing.T) {
 	tests := []struct {
 		name    string
 		request *siogeneric.AwEmailSessionRequest
-		want    *siogeneric.AwSession
+		want    *model.Session
 		status  int
 	}{
 		{
@@ -108,7 +103,12 @@ func TestSessionController_CreateEmailSession(t *testing.T) {
 
 			MockJson(c, tt.request, "POST")
 			if tt.want != nil {
-				ss.On("CreateEmailSession", mock.AnythingOfType("*siogeneric.AwEmailSessionRequest")).
+				ss.On(
+					"CreateEmailSession",
+					mock.Anything,
+					mock.AnythingOfType("*siogeneric.AwEmailSessionRequest"),
+					mock.AnythingOfType("string"),
+				).
 					Return(tt.want, nil)
 			}
 			sc.CreateEmailSession(c)
@@ -140,7 +140,12 @@ func TestUserController_CreateEmailSessionServiceFailure(t *testing.T) {
 	}
 
 	MockJson(c, request, "POST")
-	ss.On("CreateEmailSession", mock.AnythingOfType("*siogeneric.AwEmailSessionRequest")).
+	ss.On(
+		"CreateEmailSession",
+		mock.Anything,
+		mock.AnythingOfType("*siogeneric.AwEmailSessionRequest"),
+		mock.AnythingOfType("string"),
+	).
 		Return(nil, errors.New("error"))
 	sc.CreateEmailSession(c)
 
@@ -159,7 +164,8 @@ func TestDeleteSession(t *testing.T) {
 	}
 
 	c.Params = gin.Params{gin.Param{Key: "id", Value: "a"}, gin.Param{Key: "sessionId", Value: "a"}}
-	ms.On("DeleteSession", "a", "a").Return(siogeneric.SuccessResponse{Success: true}, nil)
+	ms.On("DeleteSession", mock.Anything, "a", "a").
+		Return(siogeneric.SuccessResponse{Success: true}, nil)
 	uc.DeleteSession(c)
 
 	assert.Truef(t, c.Errors == nil, "c.Errors should be nil")
@@ -177,9 +183,136 @@ func TestDeleteSessionError(t *testing.T) {
 	}
 
 	c.Params = gin.Params{gin.Param{Key: "id", Value: "a"}, gin.Param{Key: "sessionId", Value: "a"}}
-	ms.On("DeleteSession", "a", "a").
+	ms.On("DeleteSession", mock.Anything, "a", "a").
 		Return(siogeneric.SuccessResponse{Success: false}, errors.New("asdf"))
 	uc.DeleteSession(c)
 
 	assert.Truef(t, c.Errors != nil, "c.Errors shouldnt be nil")
 }
+
+func TestListSessions(t *testing.T) {
+	sc, ms, _ := initControllerForSessionTests(t)
+
+	var (
+		w    = httptest.NewRecorder()
+		c, _ = gin.CreateTestContext(w)
+	)
+	c.Request = &http.Request{
+		Header: make(http.Header),
+	}
+
+	c.Params = gin.Params{gin.Param{Key: "id", Value: "a"}}
+	ms.On("ListSessions", mock.Anything, "a").Return(&model.SessionListResponse{
+		Total:    1,
+		Sessions: []model.SessionSummary{model.NewSessionSummary(mUserSession)},
+	}, nil)
+	sc.ListSessions(c)
+
+	assert.Truef(t, c.Errors == nil, "c.Errors should be nil")
+}
+
+func TestListSessionsError(t *testing.T) {
+	sc, ms, _ := initControllerForSessionTests(t)
+
+	var (
+		w    = httptest.NewRecorder()
+		c, _ = gin.CreateTestContext(w)
+	)
+	c.Request = &http.Request{
+		Header: make(http.Header),
+	}
+
+	c.Params = gin.Params{gin.Param{Key: "id", Value: "a"}}
+	ms.On("ListSessions", mock.Anything, "a").Return(nil, errors.New("asdf"))
+	sc.ListSessions(c)
+
+	assert.Truef(t, c.Errors != nil, "c.Errors shouldnt be nil")
+}
+
+func TestDeleteSessions(t *testing.T) {
+	sc, ms, _ := initControllerForSessionTests(t)
+
+	var (
+		w    = httptest.NewRecorder()
+		c, _ = gin.CreateTestContext(w)
+	)
+	c.Request = &http.Request{
+		Header: make(http.Header),
+	}
+
+	c.Params = gin.Params{gin.Param{Key: "id", Value: "a"}}
+	ms.On("DeleteSessions", mock.Anything, "a").
+		Return(siogeneric.SuccessResponse{Success: true}, nil)
+	sc.DeleteSessions(c)
+
+	assert.Truef(t, c.Errors == nil, "c.Errors should be nil")
+}
+
+func TestDeleteSessionsError(t *testing.T) {
+	sc, ms, _ := initControllerForSessionTests(t)
+
+	var (
+		w    = httptest.NewRecorder()
+		c, _ = gin.CreateTestContext(w)
+	)
+	c.Request = &http.Request{
+		Header: make(http.Header),
+	}
+
+	c.Params = gin.Params{gin.Param{Key: "id", Value: "a"}}
+	ms.On("DeleteSessions", mock.Anything, "a").
+		Return(siogeneric.SuccessResponse{Success: false}, errors.New("asdf"))
+	sc.DeleteSessions(c)
+
+	assert.Truef(t, c.Errors != nil, "c.Errors shouldnt be nil")
+}
+
+func TestClearLockout(t *testing.T) {
+	sc, ss, _ := initControllerForSessionTests(t)
+	sc.a = audit.New(audit.NewMemoryStore(10))
+
+	var (
+		w    = httptest.NewRecorder()
+		c, _ = gin.CreateTestContext(w)
+	)
+	c.Request = httptest.NewRequest("DELETE", "/api/iam/v1/lockout?email=t@t.com&ip=10.0.0.1", nil)
+	params := &model.ClearLockoutParams{Email: "t@t.com", IP: "10.0.0.1"}
+	ss.On("ClearLockout", mock.Anything, params).
+		Return(siogeneric.SuccessResponse{Success: true}, nil)
+	sc.ClearLockout(c)
+
+	assert.Truef(t, c.Errors == nil, "c.Errors should be nil")
+	e := lastEvent(t, sc.a)
+	assert.Equal(t, constants.AUDIT_SESSION_LOCKOUT_CLEAR, e.Action)
+	assert.Equal(t, map[string]string{"email": "t@t.com", "ip": "10.0.0.1"}, e.Meta)
+}
+
+func TestClearLockoutBadParams(t *testing.T) {
+	sc, _, _ := initControllerForSessionTests(t)
+
+	for _, query := range []string{"", "email=nope", "ip=nope"} {
+		var (
+			w    = httptest.NewRecorder()
+			c, _ = gin.CreateTestContext(w)
+		)
+		c.Request = httptest.NewRequest("DELETE", "/api/iam/v1/lockout?"+query, nil)
+		sc.ClearLockout(c)
+
+		assert.Truef(t, c.Errors != nil, "c.Errors shouldnt be nil for %q", query)
+	}
+}
+
+func TestClearLockoutError(t *testing.T) {
+	sc, ss, _ := initControllerForSessionTests(t)
+
+	var (
+		w    = httptest.NewRecorder()
+		c, _ = gin.CreateTestContext(w)
+	)
+	c.Request = httptest.NewRequest("DELETE", "/api/iam/v1/lockout?ip=10.0.0.1", nil)
+	ss.On("ClearLockout", mock.Anything, &model.ClearLockoutParams{IP: "10.0.0.1"}).
+		Return(siogeneric.SuccessResponse{Success: false}, errors.New("error"))
+	sc.ClearLockout(c)
+
+	assert.Truef(t, c.Errors != nil, "c.Errors shouldnt be nil")
+}
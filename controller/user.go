@@ -4,16 +4,22 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
 
 	"gitea.slauson.io/slausonio/go-types/siogeneric"
 	"gitea.slauson.io/slausonio/go-utils/sioUtils"
 	"gitea.slauson.io/slausonio/go-utils/sioerror"
+	"gitea.slauson.io/slausonio/iam-ms/audit"
+	"gitea.slauson.io/slausonio/iam-ms/config"
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+	"gitea.slauson.io/slausonio/iam-ms/model"
 	"gitea.slauson.io/slausonio/iam-ms/service"
 	"gitea.slauson.io/slausonio/iam-ms/utils"
 )
 
 type UserController struct {
 	s service.IamUserService
+	a *audit.Auditor
 }
 
 //go:generate mockery --name IamUserController
@@ -24,29 +30,64 @@ type IamUserController interface {
 	UpdatePassword(c *gin.Context)
 	UpdateEmail(c *gin.Context)
 	UpdatePhone(c *gin.Context)
+	UpdateName(c *gin.Context)
+	UpdateStatus(c *gin.Context)
+	GetPrefs(c *gin.Context)
+	UpdatePrefs(c *gin.Context)
 	DeleteUser(c *gin.Context)
+	CreatePasswordRecovery(c *gin.Context)
+	ConfirmPasswordRecovery(c *gin.Context)
+	SendEmailVerification(c *gin.Context)
+	ConfirmEmailVerification(c *gin.Context)
+	SendPhoneVerification(c *gin.Context)
+	ConfirmPhoneVerification(c *gin.Context)
+	UpdateVerification(c *gin.Context)
 }
 
-func NewUserController() *UserController {
+func NewUserController(cfg *config.Config) *UserController {
 	return &UserController{
-		s: service.NewUserService(),
+		s: service.NewUserService(cfg),
+		a: audit.Default(cfg),
 	}
 }
 
 // @Summary List Users
 // GET
-// @Description List Users
+// @Description List Users, paged with limit/offset or a cursor and optionally searched or filtered
 // @Tags user
 // @Accept  json
 // @Produce  json
-// @Success 200 {object} siogeneric.AwlistResponse
+// @Param limit query int false "Page size (max 100)"
+// @Param offset query int false "Number of users to skip"
+// @Param cursor query string false "Return users after this user ID"
+// @Param search query string false "Full text search term"
+// @Param email query string false "Filter by email"
+// @Param phone query string false "Filter by phone"
+// @Param name query string false "Filter by name"
+// @Param status query bool false "Filter by status"
+// @Param createdAfter query string false "Only users created after this RFC3339 timestamp"
+// @Param X-Appwrite-JWT header string true "Caller session JWT"
+// @Success 200 {object} model.UserListResponse
 // @Failure 400 {object} siogeneric.ErrorResponse
 // @Failure 401 {object} siogeneric.ErrorResponse
+// @Failure 403 {object} siogeneric.ErrorResponse
 // @Failure 404 {object} siogeneric.ErrorResponse
 // @Failure 500 {object} siogeneric.ErrorResponse
 // @Router /api/iam/v1/user [get]
 func (uc *UserController) ListUsers(c *gin.Context) {
-	result, e := uc.s.ListUsers()
+	validations := utils.NewIamValidations()
+	params := new(model.ListUsersParams)
+	if err := c.ShouldBindQuery(params); err != nil {
+		_ = c.Error(sioerror.NewSioBadRequestError(err.Error()))
+		return
+	}
+
+	if err := validations.ValidateListUsersParams(params); err != nil {
+		_ = c.Error(sioerror.NewSioBadRequestError(err.Error()))
+		return
+	}
+
+	result, e := uc.s.ListUsers(c.Request.Context(), params)
 
 	if e != nil {
 		_ = c.Error(e)
@@ -62,15 +103,20 @@ func (uc *UserController) ListUsers(c *gin.Context) {
 // @Accept  json
 // @Produce  json
 // @Param id path string true "User ID"
-// @Success 200 {object} siogeneric.AwUser
+// @Param X-Appwrite-JWT header string true "Caller session JWT"
+// @Success 200 {object} model.User
 // @Failure 400 {object} siogeneric.ErrorResponse
 // @Failure 401 {object} siogeneric.ErrorResponse
+// @Failure 403 {object} siogeneric.ErrorResponse
 // @Failure 404 {object} siogeneric.ErrorResponse
 // @Failure 500 {object} siogeneric.ErrorResponse
 // @Router /api/iam/v1/user/:id [get]
 func (uc *UserController) GetUserById(c *gin.Context) {
-	id := c.Param("id")
-	response, e := uc.s.GetUserByID(id)
+	uc.getUser(c, c.Param("id"))
+}
+
+func (uc *UserController) getUser(c *gin.Context, id string) {
+	response, e := uc.s.GetUserByID(c.Request.Context(), id)
 
 	if e != nil {
 		_ = c.Error(e)
@@ -86,10 +132,11 @@ func (uc *UserController) GetUserById(c *gin.Context) {
 // @Accept  json
 // @Produce  json
 // @Param createRequest body siogeneric.AwCreateUserRequest true "Create User Request"
-// @Success 200 {object} siogeneric.AwUser
+// @Success 200 {object} model.User
 // @Failure 400 {object} siogeneric.ErrorResponse
 // @Failure 401 {object} siogeneric.ErrorResponse
 // @Failure 404 {object} siogeneric.ErrorResponse
+// @Failure 429 {object} siogeneric.ErrorResponse
 // @Failure 500 {object} siogeneric.ErrorResponse
 // @Router /api/iam/v1/user [post]
 func (uc *UserController) CreateUser(c *gin.Context) {
@@ -107,7 +154,14 @@ func (uc *UserController) CreateUser(c *gin.Context) {
 		return
 	}
 
-	result, e := uc.s.CreateUser(request)
+	result, e := uc.auditUser(
+		c,
+		constants.AUDIT_USER_CREATE,
+		"",
+		func() (*model.User, error) {
+			return uc.s.CreateUser(c.Request.Context(), request)
+		},
+	)
 
 	if e != nil {
 		_ = c.Error(e)
@@ -118,20 +172,27 @@ func (uc *UserController) CreateUser(c *gin.Context) {
 
 // @Summary Update Password
 // PUT
+// @Description Set a user's password. Admin only; users change their own through /me/password, which requires the current one.
 // @Tags user
 // @Accept  json
 // @Produce  json
 // @Param updateRequest body siogeneric.UpdatePasswordRequest true "Update Password Request"
 // @Param id path string true "User ID"
-// @Success 200 {object} siogeneric.AwUser
+// @Param X-Appwrite-JWT header string true "Caller session JWT"
+// @Success 200 {object} model.User
 // @Failure 400 {object} siogeneric.ErrorResponse
 // @Failure 401 {object} siogeneric.ErrorResponse
+// @Failure 403 {object} siogeneric.ErrorResponse
 // @Failure 404 {object} siogeneric.ErrorResponse
+// @Failure 429 {object} siogeneric.ErrorResponse
 // @Failure 500 {object} siogeneric.ErrorResponse
 // @Router /api/iam/v1/user/:id/password [put]
 func (uc *UserController) UpdatePassword(c *gin.Context) {
+	uc.updatePassword(c, c.Param("id"))
+}
+
+func (uc *UserController) updatePassword(c *gin.Context, id string) {
 	validations := utils.NewIamValidations()
-	id := c.Param("id")
 	request := new(siogeneric.UpdatePasswordRequest)
 
 	err := sioUtils.DecryptAndHandle(request, c)
@@ -147,8 +208,46 @@ func (uc *UserController) UpdatePassword(c *gin.Context) {
 		return
 	}
 
-	result, e := uc.s.UpdatePassword(id, request)
+	result, e := uc.auditUser(
+		c,
+		constants.AUDIT_USER_UPDATE_PASSWORD,
+		id,
+		func() (*model.User, error) {
+			return uc.s.UpdatePassword(c.Request.Context(), id, request)
+		},
+	)
+
+	if e != nil {
+		_ = c.Error(e)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func (uc *UserController) updateOwnPassword(c *gin.Context, id string) {
+	validations := utils.NewIamValidations()
+	request := new(model.UpdateOwnPasswordRequest)
+
+	err := sioUtils.DecryptAndHandle(request, c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	err = validations.ValidateUpdateOwnPasswordRequest(request)
+	if err != nil {
+		_ = c.Error(sioerror.NewSioBadRequestError(err.Error()))
+		return
+	}
 
+	result, e := uc.auditUser(
+		c,
+		constants.AUDIT_USER_UPDATE_PASSWORD,
+		id,
+		func() (*model.User, error) {
+			return uc.s.UpdateOwnPassword(c.Request.Context(), id, request, c.ClientIP())
+		},
+	)
 	if e != nil {
 		_ = c.Error(e)
 		return
@@ -158,19 +257,26 @@ func (uc *UserController) UpdatePassword(c *gin.Context) {
 
 // @Summary Update Email
 // PUT
+// @Description Set a user's email. Admin only; users change their own through /me/email, which requires the current password.
 // @Tags user
 // @Accept  json
 // @Produce  json
 // @Param updateRequest body siogeneric.UpdateEmailRequest true "Update Email Request"
 // @Param id path string true "User ID"
-// @Success 200 {object} siogeneric.AwUser
+// @Param verify query bool false "Send a verification email to the new address; only when updating your own account"
+// @Param X-Appwrite-JWT header string true "Caller session JWT"
+// @Success 200 {object} model.User
 // @Failure 400 {object} siogeneric.ErrorResponse
 // @Failure 401 {object} siogeneric.ErrorResponse
+// @Failure 403 {object} siogeneric.ErrorResponse
 // @Failure 404 {object} siogeneric.ErrorResponse
 // @Router /api/iam/v1/user/:id/email [put]
 func (uc *UserController) UpdateEmail(c *gin.Context) {
+	uc.updateEmail(c, c.Param("id"))
+}
+
+func (uc *UserController) updateEmail(c *gin.Context, id string) {
 	validations := utils.NewIamValidations()
-	id := c.Param("id")
 	request := new(siogeneric.UpdateEmailRequest)
 	err := sioUtils.DecryptAndHandle(request, c)
 	if err != nil {
@@ -185,14 +291,78 @@ func (uc *UserController) UpdateEmail(c *gin.Context) {
 		return
 	}
 
-	result, e := uc.s.UpdateEmail(id, request)
+	uc.applyEmailUpdate(c, id, func() (*model.User, error) {
+		return uc.s.UpdateEmail(c.Request.Context(), id, request)
+	})
+}
+
+func (uc *UserController) updateOwnEmail(c *gin.Context, id string) {
+	validations := utils.NewIamValidations()
+	request := new(model.UpdateOwnEmailRequest)
+	err := sioUtils.DecryptAndHandle(request, c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	err = validations.ValidateUpdateOwnEmailRequest(request)
+	if err != nil {
+		_ = c.Error(sioerror.NewSioBadRequestError(err.Error()))
+		return
+	}
+
+	uc.applyEmailUpdate(c, id, func() (*model.User, error) {
+		return uc.s.UpdateOwnEmail(c.Request.Context(), id, request, c.ClientIP())
+	})
+}
+
+// applyEmailUpdate runs update and, when ?verify=true, sends a verification
+// email to the new address using the caller's session.
+func (uc *UserController) applyEmailUpdate(
+	c *gin.Context,
+	id string,
+	update func() (*model.User, error),
+) {
+	jwt, verify, ok := verificationSession(c, id)
+	if !ok {
+		return
+	}
+
+	result, e := uc.auditUser(c, constants.AUDIT_USER_UPDATE_EMAIL, id, update)
 	if e != nil {
 		_ = c.Error(e)
 		return
 	}
+
+	if verify {
+		if _, e := uc.s.SendEmailVerification(c.Request.Context(), jwt); e != nil {
+			log.Warnf("email updated but verification could not be sent for %s: %v", id, e)
+		}
+	}
 	c.JSON(http.StatusOK, result)
 }
 
+// verificationSession returns the caller's session when ?verify=true asks for
+// a verification to be sent with it. A session only verifies its own account,
+// so verify is refused unless the caller is the user id, e.g. for an admin.
+func verificationSession(c *gin.Context, id string) (string, bool, bool) {
+	if c.Query("verify") != "true" {
+		return "", false, true
+	}
+
+	jwt := c.GetHeader(constants.AW_HEADER_JWT)
+	if jwt == "" {
+		_ = c.Error(sioerror.NewSioUnauthorizedError(constants.MissingUserSession))
+		return "", false, false
+	}
+	v, _ := c.Get(constants.CALLER_CONTEXT_KEY)
+	if caller, _ := v.(*model.Caller); caller == nil || caller.ID != id {
+		_ = c.Error(sioerror.NewSioBadRequestError(constants.NotOwnAccount))
+		return "", false, false
+	}
+	return jwt, true, true
+}
+
 // @Summary Update Phone
 // PUT
 // @Tags user
@@ -200,15 +370,21 @@ func (uc *UserController) UpdateEmail(c *gin.Context) {
 // @Produce  json
 // @Param updateRequest body siogeneric.UpdatePhoneRequest true "Update Phone Request"
 // @Param id path string true "User ID"
-// @Success 200 {object} siogeneric.AwUser
+// @Param verify query bool false "Text a verification code to the new number; only when updating your own account"
+// @Param X-Appwrite-JWT header string true "Caller session JWT"
+// @Success 200 {object} model.User
 // @Failure 400 {object} siogeneric.ErrorResponse
 // @Failure 401 {object} siogeneric.ErrorResponse
+// @Failure 403 {object} siogeneric.ErrorResponse
 // @Failure 404 {object} siogeneric.ErrorResponse
 // @Failure 500 {object} siogeneric.ErrorResponse
 // @Router /api/iam/v1/user/:id/phone [put]
 func (uc *UserController) UpdatePhone(c *gin.Context) {
+	uc.updatePhone(c, c.Param("id"))
+}
+
+func (uc *UserController) updatePhone(c *gin.Context, id string) {
 	validations := utils.NewIamValidations()
-	id := c.Param("id")
 	request := new(siogeneric.UpdatePhoneRequest)
 	err := sioUtils.DecryptAndHandle(request, c)
 	if err != nil {
@@ -222,7 +398,190 @@ func (uc *UserController) UpdatePhone(c *gin.Context) {
 		return
 	}
 
-	result, e := uc.s.UpdatePhone(id, request)
+	jwt, verify, ok := verificationSession(c, id)
+	if !ok {
+		return
+	}
+
+	result, e := uc.auditUser(
+		c,
+		constants.AUDIT_USER_UPDATE_PHONE,
+		id,
+		func() (*model.User, error) {
+			return uc.s.UpdatePhone(c.Request.Context(), id, request)
+		},
+	)
+	if e != nil {
+		_ = c.Error(e)
+		return
+	}
+
+	if verify {
+		if _, e := uc.s.SendPhoneVerification(c.Request.Context(), jwt); e != nil {
+			log.Warnf("phone updated but verification could not be sent for %s: %v", id, e)
+		}
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// @Summary Update Name
+// PUT
+// @Description Update a user's display name
+// @Tags user
+// @Accept  json
+// @Produce  json
+// @Param updateRequest body model.UpdateNameRequest true "Update Name Request"
+// @Param id path string true "User ID"
+// @Param X-Appwrite-JWT header string true "Caller session JWT"
+// @Success 200 {object} model.User
+// @Failure 400 {object} siogeneric.ErrorResponse
+// @Failure 401 {object} siogeneric.ErrorResponse
+// @Failure 403 {object} siogeneric.ErrorResponse
+// @Failure 404 {object} siogeneric.ErrorResponse
+// @Failure 500 {object} siogeneric.ErrorResponse
+// @Router /api/iam/v1/user/:id/name [put]
+func (uc *UserController) UpdateName(c *gin.Context) {
+	uc.updateName(c, c.Param("id"))
+}
+
+func (uc *UserController) updateName(c *gin.Context, id string) {
+	validations := utils.NewIamValidations()
+	request := new(model.UpdateNameRequest)
+	err := sioUtils.DecryptAndHandle(request, c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	err = validations.ValidateUpdateNameRequest(request)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	result, e := uc.auditUser(
+		c,
+		constants.AUDIT_USER_UPDATE_NAME,
+		id,
+		func() (*model.User, error) {
+			return uc.s.UpdateName(c.Request.Context(), id, request)
+		},
+	)
+	if e != nil {
+		_ = c.Error(e)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// @Summary Update Status
+// PUT
+// @Description Enable or block a user. Blocking also revokes all of the user's sessions.
+// @Tags user
+// @Accept  json
+// @Produce  json
+// @Param updateRequest body model.UpdateStatusRequest true "Update Status Request"
+// @Param id path string true "User ID"
+// @Param X-Appwrite-JWT header string true "Caller session JWT"
+// @Success 200 {object} model.User
+// @Failure 400 {object} siogeneric.ErrorResponse
+// @Failure 401 {object} siogeneric.ErrorResponse
+// @Failure 403 {object} siogeneric.ErrorResponse
+// @Failure 404 {object} siogeneric.ErrorResponse
+// @Failure 500 {object} siogeneric.ErrorResponse
+// @Router /api/iam/v1/user/:id/status [put]
+func (uc *UserController) UpdateStatus(c *gin.Context) {
+	validations := utils.NewIamValidations()
+	id := c.Param("id")
+	request := new(model.UpdateStatusRequest)
+	err := sioUtils.DecryptAndHandle(request, c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	err = validations.ValidateUpdateStatusRequest(request)
+	if err != nil {
+		_ = c.Error(sioerror.NewSioBadRequestError(err.Error()))
+		return
+	}
+
+	result, e := uc.auditUser(
+		c,
+		constants.AUDIT_USER_UPDATE_STATUS,
+		id,
+		func() (*model.User, error) {
+			return uc.s.UpdateStatus(c.Request.Context(), id, request)
+		},
+	)
+	if e != nil {
+		_ = c.Error(e)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// @Summary Get Prefs
+// GET
+// @Description Get a user's preferences
+// @Tags user
+// @Accept  json
+// @Produce  json
+// @Param id path string true "User ID"
+// @Param X-Appwrite-JWT header string true "Caller session JWT"
+// @Success 200 {object} model.Prefs
+// @Failure 400 {object} siogeneric.ErrorResponse
+// @Failure 401 {object} siogeneric.ErrorResponse
+// @Failure 403 {object} siogeneric.ErrorResponse
+// @Failure 404 {object} siogeneric.ErrorResponse
+// @Failure 500 {object} siogeneric.ErrorResponse
+// @Router /api/iam/v1/user/:id/prefs [get]
+func (uc *UserController) GetPrefs(c *gin.Context) {
+	id := c.Param("id")
+	response, e := uc.s.GetPrefs(c.Request.Context(), id)
+
+	if e != nil {
+		_ = c.Error(e)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// @Summary Update Prefs
+// PATCH
+// @Description Merge keys into a user's preferences. Keys not provided are kept and a null value removes the key.
+// @Tags user
+// @Accept  json
+// @Produce  json
+// @Param updateRequest body model.UpdatePrefsRequest true "Update Prefs Request"
+// @Param id path string true "User ID"
+// @Param X-Appwrite-JWT header string true "Caller session JWT"
+// @Success 200 {object} model.Prefs
+// @Failure 400 {object} siogeneric.ErrorResponse
+// @Failure 401 {object} siogeneric.ErrorResponse
+// @Failure 403 {object} siogeneric.ErrorResponse
+// @Failure 404 {object} siogeneric.ErrorResponse
+// @Failure 500 {object} siogeneric.ErrorResponse
+// @Router /api/iam/v1/user/:id/prefs [patch]
+func (uc *UserController) UpdatePrefs(c *gin.Context) {
+	validations := utils.NewIamValidations()
+	id := c.Param("id")
+	request := new(model.UpdatePrefsRequest)
+	err := sioUtils.DecryptAndHandle(request, c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	err = validations.ValidateUpdatePrefsRequest(request)
+	if err != nil {
+		_ = c.Error(sioerror.NewSioBadRequestError(err.Error()))
+		return
+	}
+
+	result, e := uc.auditPrefs(c, id, func() (model.Prefs, error) {
+		return uc.s.UpdatePrefs(c.Request.Context(), id, request)
+	})
 	if e != nil {
 		_ = c.Error(e)
 		return
@@ -236,18 +595,280 @@ func (uc *UserController) UpdatePhone(c *gin.Context) {
 // @Accept  json
 // @Produce  json
 // @Param id path string true "User ID"
+// @Param X-Appwrite-JWT header string true "Caller session JWT"
 // @Success 200 {object} siogeneric.SuccessResponse
 // @Failure 400 {object} siogeneric.ErrorResponse
 // @Failure 401 {object} siogeneric.ErrorResponse
+// @Failure 403 {object} siogeneric.ErrorResponse
 // @Failure 404 {object} siogeneric.ErrorResponse
 // @Failure 500 {object} siogeneric.ErrorResponse
 // @Router /api/iam/v1/user/:id [delete]
 func (uc *UserController) DeleteUser(c *gin.Context) {
 	id := c.Param("id")
-	response, err := uc.s.DeleteUser(id)
+	var response siogeneric.SuccessResponse
+	_, err := uc.auditUser(c, constants.AUDIT_USER_DELETE, id, func() (*model.User, error) {
+		r, err := uc.s.DeleteUser(c.Request.Context(), id)
+		response = r
+		return nil, err
+	})
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// @Summary Create Password Recovery
+// POST
+// @Description Email the user a password recovery link. The response is the same whether or not an account has the email.
+// @Tags recovery
+// @Accept  json
+// @Produce  json
+// @Param recoveryRequest body model.PasswordRecoveryRequest true "Password Recovery Request"
+// @Success 200 {object} siogeneric.SuccessResponse
+// @Failure 400 {object} siogeneric.ErrorResponse
+// @Failure 401 {object} siogeneric.ErrorResponse
+// @Failure 429 {object} siogeneric.ErrorResponse
+// @Failure 500 {object} siogeneric.ErrorResponse
+// @Router /api/iam/v1/recovery [post]
+func (uc *UserController) CreatePasswordRecovery(c *gin.Context) {
+	validations := utils.NewIamValidations()
+	request := new(model.PasswordRecoveryRequest)
+	err := sioUtils.DecryptAndHandle(request, c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	err = validations.ValidatePasswordRecoveryRequest(request)
+	if err != nil {
+		_ = c.Error(sioerror.NewSioBadRequestError(err.Error()))
+		return
+	}
+
+	response, e := uc.s.CreatePasswordRecovery(c.Request.Context(), request)
+	meta := map[string]string{"email": request.Email}
+	record(c, uc.a, constants.AUDIT_USER_RECOVERY_CREATE, "", meta, e)
+	if e != nil {
+		_ = c.Error(e)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// @Summary Confirm Password Recovery
+// PUT
+// @Description Set a new password using the userId and secret from the recovery link
+// @Tags recovery
+// @Accept  json
+// @Produce  json
+// @Param confirmRequest body model.PasswordRecoveryConfirmRequest true "Password Recovery Confirm Request"
+// @Success 200 {object} siogeneric.SuccessResponse
+// @Failure 400 {object} siogeneric.ErrorResponse
+// @Failure 401 {object} siogeneric.ErrorResponse
+// @Failure 404 {object} siogeneric.ErrorResponse
+// @Failure 429 {object} siogeneric.ErrorResponse
+// @Failure 500 {object} siogeneric.ErrorResponse
+// @Router /api/iam/v1/recovery [put]
+func (uc *UserController) ConfirmPasswordRecovery(c *gin.Context) {
+	validations := utils.NewIamValidations()
+	request := new(model.PasswordRecoveryConfirmRequest)
+	err := sioUtils.DecryptAndHandle(request, c)
 	if err != nil {
 		_ = c.Error(err)
 		return
 	}
+
+	err = validations.ValidatePasswordRecoveryConfirmRequest(request)
+	if err != nil {
+		_ = c.Error(sioerror.NewSioBadRequestError(err.Error()))
+		return
+	}
+
+	response, e := uc.s.ConfirmPasswordRecovery(c.Request.Context(), request)
+	record(c, uc.a, constants.AUDIT_USER_RECOVERY_CONFIRM, request.UserID, nil, e)
+	if e != nil {
+		_ = c.Error(e)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// @Summary Send Email Verification
+// POST
+// @Description Email a verification link to the user owning the session
+// @Tags verification
+// @Accept  json
+// @Produce  json
+// @Param X-Appwrite-JWT header string true "User session JWT"
+// @Success 200 {object} siogeneric.SuccessResponse
+// @Failure 400 {object} siogeneric.ErrorResponse
+// @Failure 401 {object} siogeneric.ErrorResponse
+// @Failure 404 {object} siogeneric.ErrorResponse
+// @Failure 500 {object} siogeneric.ErrorResponse
+// @Router /api/iam/v1/verification/email [post]
+func (uc *UserController) SendEmailVerification(c *gin.Context) {
+	jwt := c.GetHeader(constants.AW_HEADER_JWT)
+	if jwt == "" {
+		_ = c.Error(sioerror.NewSioUnauthorizedError(constants.MissingUserSession))
+		return
+	}
+
+	response, e := uc.s.SendEmailVerification(c.Request.Context(), jwt)
+	record(c, uc.a, constants.AUDIT_USER_EMAIL_VERIFICATION_SEND, "", nil, e)
+	if e != nil {
+		_ = c.Error(e)
+		return
+	}
 	c.JSON(http.StatusOK, response)
 }
+
+// @Summary Confirm Email Verification
+// PUT
+// @Description Confirm an email address using the userId and secret from the verification link
+// @Tags verification
+// @Accept  json
+// @Produce  json
+// @Param confirmRequest body model.VerificationConfirmRequest true "Verification Confirm Request"
+// @Success 200 {object} siogeneric.SuccessResponse
+// @Failure 400 {object} siogeneric.ErrorResponse
+// @Failure 401 {object} siogeneric.ErrorResponse
+// @Failure 404 {object} siogeneric.ErrorResponse
+// @Failure 500 {object} siogeneric.ErrorResponse
+// @Router /api/iam/v1/verification/email [put]
+func (uc *UserController) ConfirmEmailVerification(c *gin.Context) {
+	validations := utils.NewIamValidations()
+	request := new(model.VerificationConfirmRequest)
+	err := sioUtils.DecryptAndHandle(request, c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	err = validations.ValidateVerificationConfirmRequest(request)
+	if err != nil {
+		_ = c.Error(sioerror.NewSioBadRequestError(err.Error()))
+		return
+	}
+
+	response, e := uc.s.ConfirmEmailVerification(c.Request.Context(), request)
+	record(c, uc.a, constants.AUDIT_USER_EMAIL_VERIFICATION_CONFIRM, request.UserID, nil, e)
+	if e != nil {
+		_ = c.Error(e)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// @Summary Send Phone Verification
+// POST
+// @Description Text a verification code to the user owning the session
+// @Tags verification
+// @Accept  json
+// @Produce  json
+// @Param X-Appwrite-JWT header string true "User session JWT"
+// @Success 200 {object} siogeneric.SuccessResponse
+// @Failure 400 {object} siogeneric.ErrorResponse
+// @Failure 401 {object} siogeneric.ErrorResponse
+// @Failure 404 {object} siogeneric.ErrorResponse
+// @Failure 500 {object} siogeneric.ErrorResponse
+// @Router /api/iam/v1/verification/phone [post]
+func (uc *UserController) SendPhoneVerification(c *gin.Context) {
+	jwt := c.GetHeader(constants.AW_HEADER_JWT)
+	if jwt == "" {
+		_ = c.Error(sioerror.NewSioUnauthorizedError(constants.MissingUserSession))
+		return
+	}
+
+	response, e := uc.s.SendPhoneVerification(c.Request.Context(), jwt)
+	record(c, uc.a, constants.AUDIT_USER_PHONE_VERIFICATION_SEND, "", nil, e)
+	if e != nil {
+		_ = c.Error(e)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// @Summary Confirm Phone Verification
+// PUT
+// @Description Confirm a phone number using the userId and the SMS code as the secret
+// @Tags verification
+// @Accept  json
+// @Produce  json
+// @Param confirmRequest body model.VerificationConfirmRequest true "Verification Confirm Request"
+// @Success 200 {object} siogeneric.SuccessResponse
+// @Failure 400 {object} siogeneric.ErrorResponse
+// @Failure 401 {object} siogeneric.ErrorResponse
+// @Failure 404 {object} siogeneric.ErrorResponse
+// @Failure 500 {object} siogeneric.ErrorResponse
+// @Router /api/iam/v1/verification/phone [put]
+func (uc *UserController) ConfirmPhoneVerification(c *gin.Context) {
+	validations := utils.NewIamValidations()
+	request := new(model.VerificationConfirmRequest)
+	err := sioUtils.DecryptAndHandle(request, c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	err = validations.ValidateVerificationConfirmRequest(request)
+	if err != nil {
+		_ = c.Error(sioerror.NewSioBadRequestError(err.Error()))
+		return
+	}
+
+	response, e := uc.s.ConfirmPhoneVerification(c.Request.Context(), request)
+	record(c, uc.a, constants.AUDIT_USER_PHONE_VERIFICATION_CONFIRM, request.UserID, nil, e)
+	if e != nil {
+		_ = c.Error(e)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// @Summary Update Verification
+// PUT
+// @Description Admin override to mark a user's email and/or phone as verified or unverified
+// @Tags user
+// @Accept  json
+// @Produce  json
+// @Param updateRequest body model.VerificationStatusRequest true "Verification Status Request"
+// @Param id path string true "User ID"
+// @Param X-Appwrite-JWT header string true "Caller session JWT"
+// @Success 200 {object} model.User
+// @Failure 400 {object} siogeneric.ErrorResponse
+// @Failure 401 {object} siogeneric.ErrorResponse
+// @Failure 403 {object} siogeneric.ErrorResponse
+// @Failure 404 {object} siogeneric.ErrorResponse
+// @Failure 500 {object} siogeneric.ErrorResponse
+// @Router /api/iam/v1/user/:id/verification [put]
+func (uc *UserController) UpdateVerification(c *gin.Context) {
+	validations := utils.NewIamValidations()
+	id := c.Param("id")
+	request := new(model.VerificationStatusRequest)
+	err := sioUtils.DecryptAndHandle(request, c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	err = validations.ValidateVerificationStatusRequest(request)
+	if err != nil {
+		_ = c.Error(sioerror.NewSioBadRequestError(err.Error()))
+		return
+	}
+
+	result, e := uc.auditUser(
+		c,
+		constants.AUDIT_USER_UPDATE_VERIFICATION,
+		id,
+		func() (*model.User, error) {
+			return uc.s.UpdateVerification(c.Request.Context(), id, request)
+		},
+	)
+	if e != nil {
+		_ = c.Error(e)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
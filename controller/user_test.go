@@ -7,6 +7,8 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
@@ -15,17 +17,21 @@ import (
 
 	"gitea.slauson.io/slausonio/go-types/siogeneric"
 	"gitea.slauson.io/slausonio/go-utils/sioUtils"
+	"gitea.slauson.io/slausonio/iam-ms/config"
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+	"gitea.slauson.io/slausonio/iam-ms/model"
 	"gitea.slauson.io/slausonio/iam-ms/service/mocks"
 )
 
 var (
-	mAwUser = siogeneric.AwUser{
+	mUser = model.User{
 		Email: "t@t.com",
 	}
-	mAwUserPtr = &mAwUser
-	mUserList  = &siogeneric.AwlistResponse{
+	mUserPtr     = &mUser
+	mUserListRes = &model.UserListResponse{
 		Total: 1,
-		Users: []siogeneric.AwUser{mAwUser},
+		Users: []model.User{mUser},
+		Limit: 25,
 	}
 )
 
@@ -43,6 +49,9 @@ func initController(
 
 func MockJson(c *gin.Context, content any, method string) {
 	c.Request.Method = method // or PUT
+	if c.Request.URL == nil {
+		c.Request.URL = &url.URL{}
+	}
 	c.Request.Header.Set("Content-Type", "application/json")
 
 	jsonBytes, err := json.Marshal(content)
@@ -57,7 +66,7 @@ func MockJson(c *gin.Context, content any, method string) {
 }
 
 func TestNewUserController(t *testing.T) {
-	uc := NewUserController()
+	uc := NewUserController(config.Default())
 	assert.NotNil(t, uc)
 }
 
@@ -68,12 +77,40 @@ func TestListUsers(t *testing.T) {
 		w    = httptest.NewRecorder()
 		c, _ = gin.CreateTestContext(w)
 	)
-	ms.On("ListUsers").Return(mUserList, nil)
+	c.Request = httptest.NewRequest("GET", "/api/iam/v1/user?limit=10&search=matt", nil)
+	ms.On("ListUsers", mock.Anything, mock.AnythingOfType("*model.ListUsersParams")).
+		Return(mUserListRes, nil)
 	uc.ListUsers(c)
 
 	assert.Truef(t, c.Errors == nil, "c.Errors should be nil")
 }
 
+func TestListUsersBadParams(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{name: "limit not a number", query: "limit=abc"},
+		{name: "limit too large", query: "limit=1000"},
+		{name: "negative offset", query: "offset=-1"},
+		{name: "bad createdAfter", query: "createdAfter=yesterday"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc, _, _ := initController(t)
+
+			var (
+				w    = httptest.NewRecorder()
+				c, _ = gin.CreateTestContext(w)
+			)
+			c.Request = httptest.NewRequest("GET", "/api/iam/v1/user?"+tt.query, nil)
+			uc.ListUsers(c)
+
+			assert.Truef(t, c.Errors != nil, "c.Errors shouldnt be nil")
+		})
+	}
+}
+
 func TestListUsersError(t *testing.T) {
 	uc, ms, _ := initController(t)
 
@@ -81,7 +118,9 @@ func TestListUsersError(t *testing.T) {
 		w    = httptest.NewRecorder()
 		c, _ = gin.CreateTestContext(w)
 	)
-	ms.On("ListUsers").Return(mUserList, errors.New("asdf"))
+	c.Request = httptest.NewRequest("GET", "/api/iam/v1/user", nil)
+	ms.On("ListUsers", mock.Anything, mock.AnythingOfType("*model.ListUsersParams")).
+		Return(mUserListRes, errors.New("asdf"))
 	uc.ListUsers(c)
 
 	assert.Truef(t, c.Errors != nil, "c.Errors shouldnt be nil")
@@ -91,7 +130,7 @@ func TestUserController_CreateUser(t *testing.T) {
 	tests := []struct {
 		name      string
 		request   *siogeneric.AwCreateUserRequest
-		result    *siogeneric.AwUser
+		result    *model.User
 		bindError error
 	}{
 		{
@@ -103,7 +142,7 @@ func TestUserController_CreateUser(t *testing.T) {
 				Name:     "b",
 				Password: "MattTesting&*^1",
 			},
-			result:    mAwUserPtr,
+			result:    mUserPtr,
 			bindError: nil,
 		},
 		{
@@ -287,7 +326,11 @@ func TestUserController_CreateUser(t *testing.T) {
 			MockJson(c, tt.request, "POST")
 
 			if tt.result != nil {
-				ms.On("CreateUser", mock.AnythingOfType("*siogeneric.AwCreateUserRequest")).
+				ms.On(
+					"CreateUser",
+					mock.Anything,
+					mock.AnythingOfType("*siogeneric.AwCreateUserRequest"),
+				).
 					Return(tt.result, nil)
 			}
 			uc.CreateUser(c)
@@ -326,8 +369,8 @@ func TestUserController_CreateUserServiceFailure(t *testing.T) {
 
 	MockJson(c, request, "POST")
 
-	ms.On("CreateUser", mock.AnythingOfType("*siogeneric.AwCreateUserRequest")).
-		Return(mAwUserPtr, errors.New("error"))
+	ms.On("CreateUser", mock.Anything, mock.AnythingOfType("*siogeneric.AwCreateUserRequest")).
+		Return(mUserPtr, errors.New("error"))
 	uc.CreateUser(c)
 
 	assert.Truef(t, c.Errors != nil, "c.Errors shouldn't be nil")
@@ -338,14 +381,14 @@ func TestUserController_UpdatePassword(t *testing.T) {
 		name      string
 		request   *siogeneric.UpdatePasswordRequest
 		status    int
-		result    *siogeneric.AwUser
+		result    *model.User
 		bindError error
 	}{
 		{
 			name:      "happy",
 			request:   &siogeneric.UpdatePasswordRequest{Password: "Mm112a23!"},
 			status:    http.StatusOK,
-			result:    mAwUserPtr,
+			result:    mUserPtr,
 			bindError: nil,
 		},
 		{
@@ -404,7 +447,12 @@ func TestUserController_UpdatePassword(t *testing.T) {
 			MockJson(c, tt.request, "PUT")
 
 			if tt.result != nil {
-				ms.On("UpdatePassword", "a", mock.AnythingOfType("*siogeneric.UpdatePasswordRequest")).
+				ms.On(
+					"UpdatePassword",
+					mock.Anything,
+					"a",
+					mock.AnythingOfType("*siogeneric.UpdatePasswordRequest"),
+				).
 					Return(tt.result, nil)
 			}
 			uc.UpdatePassword(c)
@@ -443,8 +491,13 @@ func TestUserController_UpdatePasswordServiceFailure(t *testing.T) {
 
 	MockJson(c, request, "PUT")
 
-	ms.On("UpdatePassword", "a", mock.AnythingOfType("*siogeneric.UpdatePasswordRequest")).
-		Return(mAwUserPtr, errors.New("error"))
+	ms.On(
+		"UpdatePassword",
+		mock.Anything,
+		"a",
+		mock.AnythingOfType("*siogeneric.UpdatePasswordRequest"),
+	).
+		Return(mUserPtr, errors.New("error"))
 	uc.UpdatePassword(c)
 
 	assert.Truef(t, c.Errors != nil, "c.Errors shouldn't be nil")
@@ -454,13 +507,13 @@ func TestUserController_UpdateEmail(t *testing.T) {
 	tests := []struct {
 		name      string
 		request   *siogeneric.UpdateEmailRequest
-		result    *siogeneric.AwUser
+		result    *model.User
 		bindError error
 	}{
 		{
 			name:    "happy",
 			request: &siogeneric.UpdateEmailRequest{Email: "fake@fake.com"},
-			result:  mAwUserPtr,
+			result:  mUserPtr,
 		},
 		{
 			name:      "No Email",
@@ -505,7 +558,12 @@ func TestUserController_UpdateEmail(t *testing.T) {
 			MockJson(c, tt.request, "PUT")
 
 			if tt.result != nil {
-				ms.On("UpdateEmail", "a", mock.AnythingOfType("*siogeneric.UpdateEmailRequest")).
+				ms.On(
+					"UpdateEmail",
+					mock.Anything,
+					"a",
+					mock.AnythingOfType("*siogeneric.UpdateEmailRequest"),
+				).
 					Return(tt.result, nil)
 			}
 			uc.UpdateEmail(c)
@@ -545,8 +603,8 @@ func TestUserController_UpdateEmailServiceFailure(t *testing.T) {
 
 	MockJson(c, request, "PUT")
 
-	ms.On("UpdateEmail", "a", mock.AnythingOfType("*siogeneric.UpdateEmailRequest")).
-		Return(mAwUserPtr, errors.New("error"))
+	ms.On("UpdateEmail", mock.Anything, "a", mock.AnythingOfType("*siogeneric.UpdateEmailRequest")).
+		Return(mUserPtr, errors.New("error"))
 	uc.UpdateEmail(c)
 
 	assert.Truef(t, c.Errors != nil, "c.Errors shouldn't be nil")
@@ -556,13 +614,13 @@ func TestUserController_UpdatePhone(t *testing.T) {
 	tests := []struct {
 		name    string
 		request *siogeneric.UpdatePhoneRequest
-		result  *siogeneric.AwUser
+		result  *model.User
 		bindErr error
 	}{
 		{
 			name:    "happy",
 			request: &siogeneric.UpdatePhoneRequest{Number: "1239323939"},
-			result:  mAwUserPtr,
+			result:  mUserPtr,
 		},
 		{
 			name:    "No Phone",
@@ -604,7 +662,12 @@ func TestUserController_UpdatePhone(t *testing.T) {
 
 			MockJson(c, tt.request, "PUT")
 			if tt.result != nil {
-				ms.On("UpdatePhone", "a", mock.AnythingOfType("*siogeneric.UpdatePhoneRequest")).
+				ms.On(
+					"UpdatePhone",
+					mock.Anything,
+					"a",
+					mock.AnythingOfType("*siogeneric.UpdatePhoneRequest"),
+				).
 					Return(tt.result, nil)
 			}
 
@@ -618,6 +681,71 @@ func TestUserController_UpdatePhone(t *testing.T) {
 	}
 }
 
+func TestUserController_UpdateName(t *testing.T) {
+	tests := []struct {
+		name    string
+		request *model.UpdateNameRequest
+		result  *model.User
+		err     error
+	}{
+		{
+			name:    "happy",
+			request: &model.UpdateNameRequest{Name: "Matt Slauson"},
+			result:  mUserPtr,
+		},
+		{
+			name:    "No Name",
+			request: &model.UpdateNameRequest{Name: ""},
+			result:  nil,
+		},
+		{
+			name:    "Name too long",
+			request: &model.UpdateNameRequest{Name: strings.Repeat("a", 129)},
+			result:  nil,
+		},
+		{
+			name:    "service failure",
+			request: &model.UpdateNameRequest{Name: "Matt Slauson"},
+			result:  nil,
+			err:     errors.New("asdf"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				w    = httptest.NewRecorder()
+				c, _ = gin.CreateTestContext(w)
+			)
+			c.Request = &http.Request{
+				Header: make(http.Header),
+			}
+
+			c.Params = gin.Params{gin.Param{Key: "id", Value: "a"}}
+
+			uc, ms, _ := initController(t)
+
+			MockJson(c, tt.request, "PUT")
+			if tt.result != nil || tt.err != nil {
+				ms.On(
+					"UpdateName",
+					mock.Anything,
+					"a",
+					mock.AnythingOfType("*model.UpdateNameRequest"),
+				).
+					Return(tt.result, tt.err)
+			}
+
+			uc.UpdateName(c)
+			if tt.result != nil {
+				assert.Truef(t, c.Errors == nil, "c.Errors should be nil")
+			} else {
+				assert.Truef(t, c.Errors != nil, "c.Errors shouldnt be nil")
+			}
+		})
+	}
+}
+
 func TestUserController_UpdatePhoneServiceFailure(t *testing.T) {
 	request := &siogeneric.UpdatePhoneRequest{Number: "3647586976"}
 
@@ -646,8 +774,8 @@ func TestUserController_UpdatePhoneServiceFailure(t *testing.T) {
 
 	MockJson(c, request, "PUT")
 
-	ms.On("UpdatePhone", "a", mock.AnythingOfType("*siogeneric.UpdatePhoneRequest")).
-		Return(mAwUserPtr, errors.New("error"))
+	ms.On("UpdatePhone", mock.Anything, "a", mock.AnythingOfType("*siogeneric.UpdatePhoneRequest")).
+		Return(mUserPtr, errors.New("error"))
 	uc.UpdatePhone(c)
 
 	assert.Truef(t, c.Errors != nil, "c.Errors shouldn't be nil")
@@ -665,7 +793,7 @@ func TestGetUserById(t *testing.T) {
 	}
 
 	c.Params = gin.Params{gin.Param{Key: "id", Value: "a"}}
-	ms.On("GetUserByID", "a").Return(mAwUserPtr, nil)
+	ms.On("GetUserByID", mock.Anything, "a").Return(mUserPtr, nil)
 	uc.GetUserById(c)
 
 	assert.Truef(t, c.Errors == nil, "c.Errors should be nil")
@@ -683,7 +811,7 @@ func TestGetUserByIdError(t *testing.T) {
 	}
 
 	c.Params = gin.Params{gin.Param{Key: "id", Value: "a"}}
-	ms.On("GetUserByID", "a").Return(nil, errors.New("asdf"))
+	ms.On("GetUserByID", mock.Anything, "a").Return(nil, errors.New("asdf"))
 	uc.GetUserById(c)
 
 	assert.Truef(t, c.Errors != nil, "c.Errors shouldnt be nil")
@@ -701,7 +829,7 @@ func TestDeleteUser(t *testing.T) {
 	}
 
 	c.Params = gin.Params{gin.Param{Key: "id", Value: "a"}}
-	ms.On("DeleteUser", "a").Return(siogeneric.SuccessResponse{Success: true}, nil)
+	ms.On("DeleteUser", mock.Anything, "a").Return(siogeneric.SuccessResponse{Success: true}, nil)
 	uc.DeleteUser(c)
 
 	assert.Truef(t, c.Errors == nil, "c.Errors should be nil")
@@ -719,8 +847,525 @@ func TestDeleteUserError(t *testing.T) {
 	}
 
 	c.Params = gin.Params{gin.Param{Key: "id", Value: "a"}}
-	ms.On("DeleteUser", "a").Return(siogeneric.SuccessResponse{Success: false}, errors.New("asdf"))
+	ms.On("DeleteUser", mock.Anything, "a").
+		Return(siogeneric.SuccessResponse{Success: false}, errors.New("asdf"))
 	uc.DeleteUser(c)
 
 	assert.Truef(t, c.Errors != nil, "c.Errors shouldnt be nil")
 }
+
+func TestUserController_CreatePasswordRecovery(t *testing.T) {
+	tests := []struct {
+		name    string
+		request *model.PasswordRecoveryRequest
+		result  *siogeneric.SuccessResponse
+		err     error
+	}{
+		{
+			name:    "happy",
+			request: &model.PasswordRecoveryRequest{Email: "t@t.com"},
+			result:  &siogeneric.SuccessResponse{Success: true},
+		},
+		{
+			name:    "service failure",
+			request: &model.PasswordRecoveryRequest{Email: "t@t.com"},
+			result:  &siogeneric.SuccessResponse{Success: false},
+			err:     errors.New("asdf"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				w    = httptest.NewRecorder()
+				c, _ = gin.CreateTestContext(w)
+			)
+			c.Request = &http.Request{
+				Header: make(http.Header),
+			}
+
+			uc, ms, eu := initController(t)
+
+			err := eu.EncryptInterface(tt.request)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			MockJson(c, tt.request, "POST")
+			ms.On(
+				"CreatePasswordRecovery",
+				mock.Anything,
+				mock.AnythingOfType("*model.PasswordRecoveryRequest"),
+			).
+				Return(*tt.result, tt.err)
+			uc.CreatePasswordRecovery(c)
+			if tt.err == nil {
+				assert.Truef(t, c.Errors == nil, "c.Errors should be nil")
+			} else {
+				assert.Truef(t, c.Errors != nil, "c.Errors shouldnt be nil")
+			}
+		})
+	}
+}
+
+func TestUserController_ConfirmPasswordRecovery(t *testing.T) {
+	tests := []struct {
+		name    string
+		request *model.PasswordRecoveryConfirmRequest
+		result  *siogeneric.SuccessResponse
+		err     error
+	}{
+		{
+			name: "happy",
+			request: &model.PasswordRecoveryConfirmRequest{
+				UserID:   "a",
+				Secret:   "b",
+				Password: "MattTesting&*^1",
+			},
+			result: &siogeneric.SuccessResponse{Success: true},
+		},
+		{
+			name: "Missing Secret",
+			request: &model.PasswordRecoveryConfirmRequest{
+				UserID:   "a",
+				Password: "MattTesting&*^1",
+			},
+			result: nil,
+		},
+		{
+			name: "service failure",
+			request: &model.PasswordRecoveryConfirmRequest{
+				UserID:   "a",
+				Secret:   "b",
+				Password: "MattTesting&*^1",
+			},
+			result: &siogeneric.SuccessResponse{Success: false},
+			err:    errors.New("asdf"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				w    = httptest.NewRecorder()
+				c, _ = gin.CreateTestContext(w)
+			)
+			c.Request = &http.Request{
+				Header: make(http.Header),
+			}
+
+			uc, ms, eu := initController(t)
+
+			err := eu.EncryptInterface(tt.request)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			MockJson(c, tt.request, "PUT")
+			if tt.result != nil {
+				ms.On(
+					"ConfirmPasswordRecovery",
+					mock.Anything,
+					mock.AnythingOfType("*model.PasswordRecoveryConfirmRequest"),
+				).
+					Return(*tt.result, tt.err)
+			}
+			uc.ConfirmPasswordRecovery(c)
+			if tt.result != nil && tt.err == nil {
+				assert.Truef(t, c.Errors == nil, "c.Errors should be nil")
+			} else {
+				assert.Truef(t, c.Errors != nil, "c.Errors shouldnt be nil")
+			}
+		})
+	}
+}
+
+func TestUserController_UpdateEmailVerify(t *testing.T) {
+	tests := []struct {
+		name   string
+		jwt    string
+		caller *model.Caller
+		result *model.User
+	}{
+		{name: "happy", jwt: "jwt", caller: &model.Caller{ID: "a"}, result: mUserPtr},
+		{name: "Missing JWT", jwt: "", caller: &model.Caller{ID: "a"}, result: nil},
+		{name: "Admin for another user", jwt: "jwt", caller: mAdmin, result: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				w    = httptest.NewRecorder()
+				c, _ = gin.CreateTestContext(w)
+			)
+			c.Request = httptest.NewRequest("PUT", "/api/iam/v1/user/a/email?verify=true", nil)
+			c.Request.Header.Set("X-Appwrite-JWT", tt.jwt)
+			c.Params = gin.Params{gin.Param{Key: "id", Value: "a"}}
+			c.Set(constants.CALLER_CONTEXT_KEY, tt.caller)
+
+			uc, ms, _ := initController(t)
+
+			request := &siogeneric.UpdateEmailRequest{Email: "t@t.com"}
+			MockJson(c, request, "PUT")
+			if tt.result != nil {
+				ms.On(
+					"UpdateEmail",
+					mock.Anything,
+					"a",
+					mock.AnythingOfType("*siogeneric.UpdateEmailRequest"),
+				).
+					Return(tt.result, nil)
+				ms.On("SendEmailVerification", mock.Anything, tt.jwt).
+					Return(siogeneric.SuccessResponse{Success: true}, nil)
+			}
+			uc.UpdateEmail(c)
+			if tt.result != nil {
+				assert.Truef(t, c.Errors == nil, "c.Errors should be nil")
+			} else {
+				assert.Truef(t, c.Errors != nil, "c.Errors shouldnt be nil")
+			}
+		})
+	}
+}
+
+func TestUserController_SendEmailVerification(t *testing.T) {
+	tests := []struct {
+		name string
+		jwt  string
+		err  error
+	}{
+		{name: "happy", jwt: "jwt"},
+		{name: "Missing JWT", jwt: "", err: errors.New("missing")},
+		{name: "service failure", jwt: "jwt", err: errors.New("asdf")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				w    = httptest.NewRecorder()
+				c, _ = gin.CreateTestContext(w)
+			)
+			c.Request = httptest.NewRequest("POST", "/api/iam/v1/verification/email", nil)
+			c.Request.Header.Set("X-Appwrite-JWT", tt.jwt)
+
+			uc, ms, _ := initController(t)
+			if tt.jwt != "" {
+				ms.On("SendEmailVerification", mock.Anything, tt.jwt).
+					Return(siogeneric.SuccessResponse{Success: tt.err == nil}, tt.err)
+			}
+			uc.SendEmailVerification(c)
+			if tt.err == nil {
+				assert.Truef(t, c.Errors == nil, "c.Errors should be nil")
+			} else {
+				assert.Truef(t, c.Errors != nil, "c.Errors shouldnt be nil")
+			}
+		})
+	}
+}
+
+func TestUserController_SendPhoneVerification(t *testing.T) {
+	var (
+		w    = httptest.NewRecorder()
+		c, _ = gin.CreateTestContext(w)
+	)
+	c.Request = httptest.NewRequest("POST", "/api/iam/v1/verification/phone", nil)
+	c.Request.Header.Set("X-Appwrite-JWT", "jwt")
+
+	uc, ms, _ := initController(t)
+	ms.On("SendPhoneVerification", mock.Anything, "jwt").
+		Return(siogeneric.SuccessResponse{Success: true}, nil)
+	uc.SendPhoneVerification(c)
+
+	assert.Truef(t, c.Errors == nil, "c.Errors should be nil")
+}
+
+func TestUserController_ConfirmEmailVerification(t *testing.T) {
+	tests := []struct {
+		name    string
+		request *model.VerificationConfirmRequest
+		err     error
+		called  bool
+	}{
+		{
+			name:    "happy",
+			request: &model.VerificationConfirmRequest{UserID: "a", Secret: "b"},
+			called:  true,
+		},
+		{
+			name:    "Missing Secret",
+			request: &model.VerificationConfirmRequest{UserID: "a"},
+			err:     errors.New("missing"),
+		},
+		{
+			name:    "service failure",
+			request: &model.VerificationConfirmRequest{UserID: "a", Secret: "b"},
+			err:     errors.New("asdf"),
+			called:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				w    = httptest.NewRecorder()
+				c, _ = gin.CreateTestContext(w)
+			)
+			c.Request = &http.Request{
+				Header: make(http.Header),
+			}
+
+			uc, ms, _ := initController(t)
+
+			MockJson(c, tt.request, "PUT")
+			if tt.called {
+				ms.On(
+					"ConfirmEmailVerification",
+					mock.Anything,
+					mock.AnythingOfType("*model.VerificationConfirmRequest"),
+				).
+					Return(siogeneric.SuccessResponse{Success: tt.err == nil}, tt.err)
+			}
+			uc.ConfirmEmailVerification(c)
+			if tt.err == nil {
+				assert.Truef(t, c.Errors == nil, "c.Errors should be nil")
+			} else {
+				assert.Truef(t, c.Errors != nil, "c.Errors shouldnt be nil")
+			}
+		})
+	}
+}
+
+func TestUserController_ConfirmPhoneVerification(t *testing.T) {
+	var (
+		w    = httptest.NewRecorder()
+		c, _ = gin.CreateTestContext(w)
+	)
+	c.Request = &http.Request{
+		Header: make(http.Header),
+	}
+
+	uc, ms, _ := initController(t)
+
+	MockJson(c, &model.VerificationConfirmRequest{UserID: "a", Secret: "123456"}, "PUT")
+	ms.On(
+		"ConfirmPhoneVerification",
+		mock.Anything,
+		mock.AnythingOfType("*model.VerificationConfirmRequest"),
+	).
+		Return(siogeneric.SuccessResponse{Success: true}, nil)
+	uc.ConfirmPhoneVerification(c)
+
+	assert.Truef(t, c.Errors == nil, "c.Errors should be nil")
+}
+
+func TestUserController_UpdateVerification(t *testing.T) {
+	verified := true
+	tests := []struct {
+		name    string
+		request *model.VerificationStatusRequest
+		result  *model.User
+		err     error
+	}{
+		{
+			name:    "happy",
+			request: &model.VerificationStatusRequest{Email: &verified},
+			result:  mUserPtr,
+		},
+		{
+			name:    "Nothing To Update",
+			request: &model.VerificationStatusRequest{},
+			err:     errors.New("missing"),
+		},
+		{
+			name:    "service failure",
+			request: &model.VerificationStatusRequest{Phone: &verified},
+			result:  nil,
+			err:     errors.New("asdf"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				w    = httptest.NewRecorder()
+				c, _ = gin.CreateTestContext(w)
+			)
+			c.Request = &http.Request{
+				Header: make(http.Header),
+			}
+			c.Params = gin.Params{gin.Param{Key: "id", Value: "a"}}
+
+			uc, ms, _ := initController(t)
+
+			MockJson(c, tt.request, "PUT")
+			if tt.request.Email != nil || tt.request.Phone != nil {
+				ms.On(
+					"UpdateVerification",
+					mock.Anything,
+					"a",
+					mock.AnythingOfType("*model.VerificationStatusRequest"),
+				).
+					Return(tt.result, tt.err)
+			}
+			uc.UpdateVerification(c)
+			if tt.err == nil {
+				assert.Truef(t, c.Errors == nil, "c.Errors should be nil")
+			} else {
+				assert.Truef(t, c.Errors != nil, "c.Errors shouldnt be nil")
+			}
+		})
+	}
+}
+
+func TestUserController_UpdateStatus(t *testing.T) {
+	blocked := false
+	tests := []struct {
+		name    string
+		request *model.UpdateStatusRequest
+		result  *model.User
+		err     error
+	}{
+		{
+			name:    "happy",
+			request: &model.UpdateStatusRequest{Status: &blocked},
+			result:  mUserPtr,
+		},
+		{
+			name:    "Missing Status",
+			request: &model.UpdateStatusRequest{},
+			err:     errors.New("missing"),
+		},
+		{
+			name:    "service failure",
+			request: &model.UpdateStatusRequest{Status: &blocked},
+			err:     errors.New("asdf"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				w    = httptest.NewRecorder()
+				c, _ = gin.CreateTestContext(w)
+			)
+			c.Request = &http.Request{
+				Header: make(http.Header),
+			}
+			c.Params = gin.Params{gin.Param{Key: "id", Value: "a"}}
+
+			uc, ms, _ := initController(t)
+
+			MockJson(c, tt.request, "PUT")
+			if tt.request.Status != nil {
+				ms.On(
+					"UpdateStatus",
+					mock.Anything,
+					"a",
+					mock.AnythingOfType("*model.UpdateStatusRequest"),
+				).
+					Return(tt.result, tt.err)
+			}
+			uc.UpdateStatus(c)
+			if tt.err == nil {
+				assert.Truef(t, c.Errors == nil, "c.Errors should be nil")
+			} else {
+				assert.Truef(t, c.Errors != nil, "c.Errors shouldnt be nil")
+			}
+		})
+	}
+}
+
+func TestGetPrefs(t *testing.T) {
+	uc, ms, _ := initController(t)
+
+	var (
+		w    = httptest.NewRecorder()
+		c, _ = gin.CreateTestContext(w)
+	)
+	c.Request = &http.Request{Header: make(http.Header)}
+	c.Params = gin.Params{gin.Param{Key: "id", Value: "a"}}
+	ms.On("GetPrefs", mock.Anything, "a").Return(model.Prefs{"theme": "dark"}, nil)
+	uc.GetPrefs(c)
+
+	assert.Truef(t, c.Errors == nil, "c.Errors should be nil")
+}
+
+func TestGetPrefsError(t *testing.T) {
+	uc, ms, _ := initController(t)
+
+	var (
+		w    = httptest.NewRecorder()
+		c, _ = gin.CreateTestContext(w)
+	)
+	c.Request = &http.Request{Header: make(http.Header)}
+	c.Params = gin.Params{gin.Param{Key: "id", Value: "a"}}
+	ms.On("GetPrefs", mock.Anything, "a").Return(nil, errors.New("asdf"))
+	uc.GetPrefs(c)
+
+	assert.Truef(t, c.Errors != nil, "c.Errors shouldnt be nil")
+}
+
+func TestUserController_UpdatePrefs(t *testing.T) {
+	tests := []struct {
+		name    string
+		request *model.UpdatePrefsRequest
+		called  bool
+		err     error
+	}{
+		{
+			name:    "happy",
+			request: &model.UpdatePrefsRequest{Prefs: model.Prefs{"theme": "dark"}},
+			called:  true,
+		},
+		{
+			name:    "Empty Prefs",
+			request: &model.UpdatePrefsRequest{Prefs: model.Prefs{}},
+			err:     errors.New("empty"),
+		},
+		{
+			name:    "Bad Key",
+			request: &model.UpdatePrefsRequest{Prefs: model.Prefs{"$theme": "dark"}},
+			err:     errors.New("bad key"),
+		},
+		{
+			name:    "service failure",
+			request: &model.UpdatePrefsRequest{Prefs: model.Prefs{"theme": "dark"}},
+			called:  true,
+			err:     errors.New("asdf"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				w    = httptest.NewRecorder()
+				c, _ = gin.CreateTestContext(w)
+			)
+			c.Request = &http.Request{
+				Header: make(http.Header),
+			}
+			c.Params = gin.Params{gin.Param{Key: "id", Value: "a"}}
+
+			uc, ms, _ := initController(t)
+
+			MockJson(c, tt.request, "PATCH")
+			if tt.called {
+				ms.On(
+					"UpdatePrefs",
+					mock.Anything,
+					"a",
+					mock.AnythingOfType("*model.UpdatePrefsRequest"),
+				).
+					Return(tt.request.Prefs, tt.err)
+			}
+			uc.UpdatePrefs(c)
+			if tt.err == nil {
+				assert.Truef(t, c.Errors == nil, "c.Errors should be nil")
+			} else {
+				assert.Truef(t, c.Errors != nil, "c.Errors shouldnt be nil")
+			}
+		})
+	}
+}
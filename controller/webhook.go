@@ -0,0 +1,208 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"gitea.slauson.io/slausonio/go-types/siogeneric"
+	"gitea.slauson.io/slausonio/go-utils/sioUtils"
+	"gitea.slauson.io/slausonio/go-utils/sioerror"
+	"gitea.slauson.io/slausonio/iam-ms/audit"
+	"gitea.slauson.io/slausonio/iam-ms/config"
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+	"gitea.slauson.io/slausonio/iam-ms/model"
+	"gitea.slauson.io/slausonio/iam-ms/utils"
+	"gitea.slauson.io/slausonio/iam-ms/webhook"
+)
+
+// WebhookController lets admins manage webhook subscriptions and inspect their
+// deliveries.
+type WebhookController struct {
+	w *webhook.Dispatcher
+	a *audit.Auditor
+}
+
+//go:generate mockery --name IamWebhookController
+type IamWebhookController interface {
+	CreateSubscription(c *gin.Context)
+	ListSubscriptions(c *gin.Context)
+	DeleteSubscription(c *gin.Context)
+	ListDeliveries(c *gin.Context)
+}
+
+func NewWebhookController(cfg *config.Config) *WebhookController {
+	return &WebhookController{
+		w: webhook.Default(cfg),
+		a: audit.Default(cfg),
+	}
+}
+
+// @Summary Create Webhook Subscription
+// POST
+// @Description Subscribe a URL to user events, or to every event when none are listed. The response carries the secret that signs each delivery's X-Webhook-Signature; it is not shown again.
+// @Tags webhook
+// @Accept  json
+// @Produce  json
+// @Param createRequest body model.CreateWebhookSubscriptionRequest true "Create Subscription Request"
+// @Param X-Appwrite-JWT header string true "Caller session JWT"
+// @Success 200 {object} model.WebhookSubscription
+// @Failure 400 {object} siogeneric.ErrorResponse
+// @Failure 401 {object} siogeneric.ErrorResponse
+// @Failure 403 {object} siogeneric.ErrorResponse
+// @Failure 500 {object} siogeneric.ErrorResponse
+// @Failure 501 {object} siogeneric.ErrorResponse
+// @Router /api/iam/v1/webhooks [post]
+func (wc *WebhookController) CreateSubscription(c *gin.Context) {
+	if !wc.enabled(c) {
+		return
+	}
+
+	validations := utils.NewIamValidations()
+	request := new(model.CreateWebhookSubscriptionRequest)
+	err := sioUtils.DecryptAndHandle(request, c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	if err := validations.ValidateCreateWebhookSubscriptionRequest(request); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response, err := wc.w.Subscribe(c.Request.Context(), request)
+	meta := map[string]string{"url": request.URL}
+	if err == nil {
+		meta["subscriptionId"] = response.ID
+	}
+	record(c, wc.a, constants.AUDIT_WEBHOOK_CREATE, "", meta, err)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// @Summary List Webhook Subscriptions
+// GET
+// @Description List every webhook subscription, oldest first, without secrets
+// @Tags webhook
+// @Accept  json
+// @Produce  json
+// @Param X-Appwrite-JWT header string true "Caller session JWT"
+// @Success 200 {object} model.WebhookSubscriptionList
+// @Failure 401 {object} siogeneric.ErrorResponse
+// @Failure 403 {object} siogeneric.ErrorResponse
+// @Failure 500 {object} siogeneric.ErrorResponse
+// @Failure 501 {object} siogeneric.ErrorResponse
+// @Router /api/iam/v1/webhooks [get]
+func (wc *WebhookController) ListSubscriptions(c *gin.Context) {
+	if !wc.enabled(c) {
+		return
+	}
+
+	response, err := wc.w.Subscriptions(c.Request.Context())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// @Summary Delete Webhook Subscription
+// DELETE
+// @Description Unsubscribe. Deliveries still pending for the subscription fail.
+// @Tags webhook
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Subscription ID"
+// @Param X-Appwrite-JWT header string true "Caller session JWT"
+// @Success 200 {object} siogeneric.SuccessResponse
+// @Failure 401 {object} siogeneric.ErrorResponse
+// @Failure 403 {object} siogeneric.ErrorResponse
+// @Failure 404 {object} siogeneric.ErrorResponse
+// @Failure 500 {object} siogeneric.ErrorResponse
+// @Failure 501 {object} siogeneric.ErrorResponse
+// @Router /api/iam/v1/webhooks/:id [delete]
+func (wc *WebhookController) DeleteSubscription(c *gin.Context) {
+	if !wc.enabled(c) {
+		return
+	}
+
+	id := c.Param("id")
+	err := wc.w.Unsubscribe(c.Request.Context(), id)
+	record(
+		c,
+		wc.a,
+		constants.AUDIT_WEBHOOK_DELETE,
+		"",
+		map[string]string{"subscriptionId": id},
+		err,
+	)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, siogeneric.SuccessResponse{Success: true})
+}
+
+// @Summary List Webhook Deliveries
+// GET
+// @Description List webhook deliveries, newest first, with their payloads and the outcome of their attempts so far. Finished deliveries are kept for the configured history.
+// @Tags webhook
+// @Accept  json
+// @Produce  json
+// @Param limit query int false "Page size (default 50, max 500)"
+// @Param subscription query string false "Filter by subscription ID"
+// @Param event query string false "Filter by event type, e.g. user.deleted"
+// @Param status query string false "Filter by status: pending, delivered or failed"
+// @Param X-Appwrite-JWT header string true "Caller session JWT"
+// @Success 200 {object} model.WebhookDeliveryList
+// @Failure 400 {object} siogeneric.ErrorResponse
+// @Failure 401 {object} siogeneric.ErrorResponse
+// @Failure 403 {object} siogeneric.ErrorResponse
+// @Failure 500 {object} siogeneric.ErrorResponse
+// @Failure 501 {object} siogeneric.ErrorResponse
+// @Router /api/iam/v1/webhooks/deliveries [get]
+func (wc *WebhookController) ListDeliveries(c *gin.Context) {
+	if !wc.enabled(c) {
+		return
+	}
+
+	validations := utils.NewIamValidations()
+	params := new(model.WebhookDeliveryParams)
+	if err := c.ShouldBindQuery(params); err != nil {
+		_ = c.Error(sioerror.NewSioBadRequestError(err.Error()))
+		return
+	}
+
+	if err := validations.ValidateWebhookDeliveryParams(params); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response, err := wc.w.Deliveries(c.Request.Context(), params)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// enabled fails the request with a 501 unless webhooks are configured.
+func (wc *WebhookController) enabled(c *gin.Context) bool {
+	if wc.w != nil {
+		return true
+	}
+	_ = c.Error(utils.NewIamError(
+		http.StatusNotImplemented,
+		constants.ERR_TYPE_UNSUPPORTED,
+		constants.WebhooksDisabled,
+	))
+	return false
+}
@@ -0,0 +1,210 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"gitea.slauson.io/slausonio/iam-ms/audit"
+	"gitea.slauson.io/slausonio/iam-ms/config"
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+	"gitea.slauson.io/slausonio/iam-ms/model"
+	"gitea.slauson.io/slausonio/iam-ms/utils"
+	"gitea.slauson.io/slausonio/iam-ms/webhook"
+)
+
+func initWebhookController(t *testing.T) *WebhookController {
+	store, err := webhook.NewBoltStore(filepath.Join(t.TempDir(), "webhooks.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := webhook.New(store, config.Default().Webhooks)
+	t.Cleanup(func() { _ = w.Close(context.Background()) })
+	return &WebhookController{w: w, a: audit.New(audit.NewMemoryStore(10))}
+}
+
+func webhookContext(
+	method string,
+	target string,
+	content any,
+) (*gin.Context, *httptest.ResponseRecorder) {
+	var (
+		w    = httptest.NewRecorder()
+		c, _ = gin.CreateTestContext(w)
+	)
+	c.Request = httptest.NewRequest(method, target, nil)
+	c.Set(constants.CALLER_CONTEXT_KEY, mAdmin)
+	if content != nil {
+		MockJson(c, content, method)
+	}
+	return c, w
+}
+
+func assertIamStatus(t *testing.T, c *gin.Context, status int) {
+	t.Helper()
+	var ie *utils.IamError
+	if assert.NotNil(t, c.Errors) && assert.True(t, errors.As(c.Errors.Last(), &ie)) {
+		assert.Equal(t, status, ie.Status)
+	}
+}
+
+func TestNewWebhookController(t *testing.T) {
+	wc := NewWebhookController(config.Default())
+	assert.NotNil(t, wc)
+	assert.Nil(t, wc.w)
+}
+
+func TestWebhookController_Disabled(t *testing.T) {
+	wc := &WebhookController{}
+
+	handlers := []gin.HandlerFunc{
+		wc.CreateSubscription,
+		wc.ListSubscriptions,
+		wc.DeleteSubscription,
+		wc.ListDeliveries,
+	}
+	for _, h := range handlers {
+		c, _ := webhookContext("GET", "/api/iam/v1/webhooks", nil)
+		h(c)
+		assertIamStatus(t, c, http.StatusNotImplemented)
+	}
+}
+
+func TestCreateSubscription(t *testing.T) {
+	wc := initWebhookController(t)
+
+	c, w := webhookContext("POST", "/api/iam/v1/webhooks", &model.CreateWebhookSubscriptionRequest{
+		URL:    "https://blog.example.com/hooks/iam",
+		Events: []string{constants.WEBHOOK_EVENT_USER_DELETED},
+	})
+	wc.CreateSubscription(c)
+
+	assert.Nil(t, c.Errors)
+	var result model.WebhookSubscription
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.NotEmpty(t, result.ID)
+	assert.NotEmpty(t, result.Secret)
+	assert.Equal(t, []string{constants.WEBHOOK_EVENT_USER_DELETED}, result.Events)
+
+	e := lastEvent(t, wc.a)
+	assert.Equal(t, constants.AUDIT_WEBHOOK_CREATE, e.Action)
+	assert.Equal(t, constants.AUDIT_OUTCOME_SUCCESS, e.Outcome)
+	assert.Equal(t, map[string]string{
+		"subscriptionId": result.ID,
+		"url":            "https://blog.example.com/hooks/iam",
+	}, e.Meta)
+}
+
+func TestCreateSubscriptionInvalid(t *testing.T) {
+	wc := initWebhookController(t)
+
+	requests := []*model.CreateWebhookSubscriptionRequest{
+		{URL: "blog.example.com/hooks"},
+		{URL: "ftp://blog.example.com/hooks"},
+		{URL: "https://blog.example.com", Events: []string{"user.renamed"}},
+	}
+	for _, r := range requests {
+		c, _ := webhookContext("POST", "/api/iam/v1/webhooks", r)
+		wc.CreateSubscription(c)
+		assert.Truef(t, c.Errors != nil, "c.Errors shouldnt be nil for %v", r)
+	}
+
+	list, _ := wc.w.Subscriptions(context.Background())
+	assert.Empty(t, list.Subscriptions)
+}
+
+func TestListSubscriptions(t *testing.T) {
+	wc := initWebhookController(t)
+	sub, _ := wc.w.Subscribe(context.Background(), &model.CreateWebhookSubscriptionRequest{
+		URL: "https://blog.example.com/hooks/iam",
+	})
+
+	c, w := webhookContext("GET", "/api/iam/v1/webhooks", nil)
+	wc.ListSubscriptions(c)
+
+	assert.Nil(t, c.Errors)
+	var result model.WebhookSubscriptionList
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &result))
+	if assert.Len(t, result.Subscriptions, 1) {
+		assert.Equal(t, sub.ID, result.Subscriptions[0].ID)
+		assert.Empty(t, result.Subscriptions[0].Secret)
+	}
+	assert.NotContains(t, w.Body.String(), sub.Secret)
+}
+
+func TestDeleteSubscription(t *testing.T) {
+	wc := initWebhookController(t)
+	sub, _ := wc.w.Subscribe(context.Background(), &model.CreateWebhookSubscriptionRequest{
+		URL: "https://blog.example.com/hooks/iam",
+	})
+
+	c, _ := webhookContext("DELETE", "/api/iam/v1/webhooks/"+sub.ID, nil)
+	c.Params = gin.Params{gin.Param{Key: "id", Value: sub.ID}}
+	wc.DeleteSubscription(c)
+
+	assert.Nil(t, c.Errors)
+	e := lastEvent(t, wc.a)
+	assert.Equal(t, constants.AUDIT_WEBHOOK_DELETE, e.Action)
+	assert.Equal(t, map[string]string{"subscriptionId": sub.ID}, e.Meta)
+
+	list, _ := wc.w.Subscriptions(context.Background())
+	assert.Empty(t, list.Subscriptions)
+}
+
+func TestDeleteSubscriptionNotFound(t *testing.T) {
+	wc := initWebhookController(t)
+
+	c, _ := webhookContext("DELETE", "/api/iam/v1/webhooks/nope", nil)
+	c.Params = gin.Params{gin.Param{Key: "id", Value: "nope"}}
+	wc.DeleteSubscription(c)
+
+	assertIamStatus(t, c, http.StatusNotFound)
+	assert.Equal(t, constants.AUDIT_OUTCOME_FAILURE, lastEvent(t, wc.a).Outcome)
+}
+
+func TestListDeliveries(t *testing.T) {
+	wc := initWebhookController(t)
+	sub, _ := wc.w.Subscribe(context.Background(), &model.CreateWebhookSubscriptionRequest{
+		URL:    "https://blog.example.com/hooks/iam",
+		Events: []string{constants.WEBHOOK_EVENT_USER_DELETED},
+	})
+	for _, eventType := range []string{
+		constants.WEBHOOK_EVENT_USER_CREATED,
+		constants.WEBHOOK_EVENT_USER_DELETED,
+	} {
+		_ = wc.w.Publish(context.Background(), model.WebhookEvent{
+			Type: eventType,
+			User: model.WebhookUser{ID: "a"},
+		})
+	}
+
+	c, w := webhookContext("GET", "/api/iam/v1/webhooks/deliveries?status=pending&limit=10", nil)
+	wc.ListDeliveries(c)
+
+	assert.Nil(t, c.Errors)
+	var result model.WebhookDeliveryList
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &result))
+	if assert.Len(t, result.Deliveries, 1) {
+		assert.Equal(t, sub.ID, result.Deliveries[0].SubscriptionID)
+		assert.Equal(t, constants.WEBHOOK_EVENT_USER_DELETED, result.Deliveries[0].EventType)
+		assert.Equal(t, constants.WEBHOOK_STATUS_PENDING, result.Deliveries[0].Status)
+	}
+}
+
+func TestListDeliveriesBadParams(t *testing.T) {
+	wc := initWebhookController(t)
+
+	for _, query := range []string{"limit=abc", "limit=501", "status=lost", "event=user.renamed"} {
+		c, _ := webhookContext("GET", "/api/iam/v1/webhooks/deliveries?"+query, nil)
+		wc.ListDeliveries(c)
+
+		assert.Truef(t, c.Errors != nil, "c.Errors shouldnt be nil for %s", query)
+	}
+}
@@ -19,8 +19,9 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/iam/v1/session": {
-            "post": {
+        "/api/iam/v1/audit": {
+            "get": {
+                "description": "Lists recent identity changes, newest first. Events are kept up to the configured retention, in Redis when audit.store is redis and otherwise by each instance for the events it recorded itself; the audit sinks hold the complete log.",
                 "consumes": [
                     "application/json"
                 ],
@@ -28,25 +29,59 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "session"
+                    "audit"
                 ],
-                "summary": "Create Email Session",
+                "summary": "List audit events",
                 "parameters": [
                     {
-                        "description": "Session Request",
-                        "name": "sessionRequest",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/siogeneric.AwEmailSessionRequest"
-                        }
+                        "type": "integer",
+                        "description": "Page size (default 50, max 500)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by the ID of the user who made the change",
+                        "name": "actor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by the ID of the user changed",
+                        "name": "target",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by action, e.g. user.update_email",
+                        "name": "action",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by outcome: success or failure",
+                        "name": "outcome",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only events at or after this RFC3339 timestamp",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Caller session JWT",
+                        "name": "X-Appwrite-JWT",
+                        "in": "header",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/siogeneric.AwSession"
+                            "$ref": "#/definitions/model.AuditEventList"
                         }
                     },
                     "400": {
@@ -61,8 +96,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/siogeneric.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/siogeneric.ErrorResponse"
                         }
@@ -76,8 +111,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/iam/v1/session/:id/:sessionId": {
+        "/api/iam/v1/lockout": {
             "delete": {
+                "description": "Lift the lockout of an email, a client IP or both after repeated failed logins, and forget their failures",
                 "consumes": [
                     "application/json"
                 ],
@@ -87,13 +123,25 @@ const docTemplate = `{
                 "tags": [
                     "session"
                 ],
-                "summary": "Delete Session",
+                "summary": "Clear Login Lockout",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "User ID",
-                        "name": "id",
-                        "in": "path",
+                        "description": "Locked out email",
+                        "name": "email",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Locked out client IP",
+                        "name": "ip",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Caller session JWT",
+                        "name": "X-Appwrite-JWT",
+                        "in": "header",
                         "required": true
                     }
                 ],
@@ -116,8 +164,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/siogeneric.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/siogeneric.ErrorResponse"
                         }
@@ -131,9 +179,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/iam/v1/user": {
+        "/api/iam/v1/me": {
             "get": {
-                "description": "List Users",
+                "description": "Get the caller's own profile",
                 "consumes": [
                     "application/json"
                 ],
@@ -141,20 +189,23 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "me"
+                ],
+                "summary": "Get Me",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Caller session JWT",
+                        "name": "X-Appwrite-JWT",
+                        "in": "header",
+                        "required": true
+                    }
                 ],
-                "summary": "List Users",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/siogeneric.AwlistResponse"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                            "$ref": "#/definitions/model.User"
                         }
                     },
                     "401": {
@@ -176,9 +227,11 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "post": {
-                "description": "Create User",
+            }
+        },
+        "/api/iam/v1/me/email": {
+            "put": {
+                "description": "Change the caller's email. The current password is required.",
                 "consumes": [
                     "application/json"
                 ],
@@ -186,25 +239,38 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "me"
                 ],
-                "summary": "Create User",
+                "summary": "Update My Email",
                 "parameters": [
                     {
-                        "description": "Create User Request",
-                        "name": "createRequest",
+                        "description": "Update Email Request",
+                        "name": "updateRequest",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/siogeneric.AwCreateUserRequest"
+                            "$ref": "#/definitions/model.UpdateOwnEmailRequest"
                         }
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Send a verification email to the new address",
+                        "name": "verify",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Caller session JWT",
+                        "name": "X-Appwrite-JWT",
+                        "in": "header",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/siogeneric.AwUser"
+                            "$ref": "#/definitions/model.User"
                         }
                     },
                     "400": {
@@ -225,6 +291,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/siogeneric.ErrorResponse"
                         }
                     },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -234,9 +306,8 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/iam/v1/user/:id": {
-            "get": {
-                "description": "Get user by ID",
+        "/api/iam/v1/me/name": {
+            "put": {
                 "consumes": [
                     "application/json"
                 ],
@@ -244,15 +315,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "me"
                 ],
-                "summary": "Get user by ID",
+                "summary": "Update My Name",
                 "parameters": [
+                    {
+                        "description": "Update Name Request",
+                        "name": "updateRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.UpdateNameRequest"
+                        }
+                    },
                     {
                         "type": "string",
-                        "description": "User ID",
-                        "name": "id",
-                        "in": "path",
+                        "description": "Caller session JWT",
+                        "name": "X-Appwrite-JWT",
+                        "in": "header",
                         "required": true
                     }
                 ],
@@ -260,7 +340,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/siogeneric.AwUser"
+                            "$ref": "#/definitions/model.User"
                         }
                     },
                     "400": {
@@ -288,8 +368,11 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/api/iam/v1/me/password": {
+            "put": {
+                "description": "Change the caller's password. The current password is required.",
                 "consumes": [
                     "application/json"
                 ],
@@ -297,15 +380,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "me"
                 ],
-                "summary": "Delete User",
+                "summary": "Update My Password",
                 "parameters": [
+                    {
+                        "description": "Update Password Request",
+                        "name": "updateRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.UpdateOwnPasswordRequest"
+                        }
+                    },
                     {
                         "type": "string",
-                        "description": "User ID",
-                        "name": "id",
-                        "in": "path",
+                        "description": "Caller session JWT",
+                        "name": "X-Appwrite-JWT",
+                        "in": "header",
                         "required": true
                     }
                 ],
@@ -313,7 +405,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/siogeneric.SuccessResponse"
+                            "$ref": "#/definitions/model.User"
                         }
                     },
                     "400": {
@@ -334,6 +426,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/siogeneric.ErrorResponse"
                         }
                     },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -343,7 +441,7 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/iam/v1/user/:id/email": {
+        "/api/iam/v1/me/phone": {
             "put": {
                 "consumes": [
                     "application/json"
@@ -352,24 +450,30 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "me"
                 ],
-                "summary": "Update Email",
+                "summary": "Update My Phone",
                 "parameters": [
                     {
-                        "description": "Update Email Request",
+                        "description": "Update Phone Request",
                         "name": "updateRequest",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/siogeneric.UpdateEmailRequest"
+                            "$ref": "#/definitions/siogeneric.UpdatePhoneRequest"
                         }
                     },
+                    {
+                        "type": "boolean",
+                        "description": "Text a verification code to the new number",
+                        "name": "verify",
+                        "in": "query"
+                    },
                     {
                         "type": "string",
-                        "description": "User ID",
-                        "name": "id",
-                        "in": "path",
+                        "description": "Caller session JWT",
+                        "name": "X-Appwrite-JWT",
+                        "in": "header",
                         "required": true
                     }
                 ],
@@ -377,7 +481,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/siogeneric.AwUser"
+                            "$ref": "#/definitions/model.User"
                         }
                     },
                     "400": {
@@ -397,12 +501,18 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/siogeneric.ErrorResponse"
                         }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
                     }
                 }
             }
         },
-        "/api/iam/v1/user/:id/password": {
-            "put": {
+        "/api/iam/v1/me/sessions": {
+            "get": {
                 "consumes": [
                     "application/json"
                 ],
@@ -410,24 +520,63 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "me"
                 ],
-                "summary": "Update Password",
+                "summary": "List My Sessions",
                 "parameters": [
                     {
-                        "description": "Update Password Request",
-                        "name": "updateRequest",
-                        "in": "body",
-                        "required": true,
+                        "type": "string",
+                        "description": "Caller session JWT",
+                        "name": "X-Appwrite-JWT",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/siogeneric.UpdatePasswordRequest"
+                            "$ref": "#/definitions/model.SessionListResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
                         }
                     },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Sign the caller out of every session",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "me"
+                ],
+                "summary": "Delete My Sessions",
+                "parameters": [
                     {
                         "type": "string",
-                        "description": "User ID",
-                        "name": "id",
-                        "in": "path",
+                        "description": "Caller session JWT",
+                        "name": "X-Appwrite-JWT",
+                        "in": "header",
                         "required": true
                     }
                 ],
@@ -435,14 +584,64 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/siogeneric.AwUser"
+                            "$ref": "#/definitions/siogeneric.SuccessResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/siogeneric.ErrorResponse"
                         }
+                    }
+                }
+            }
+        },
+        "/api/iam/v1/me/sessions/:sessionId": {
+            "delete": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "me"
+                ],
+                "summary": "Delete My Session",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "sessionId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Caller session JWT",
+                        "name": "X-Appwrite-JWT",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.SuccessResponse"
+                        }
                     },
                     "401": {
                         "description": "Unauthorized",
@@ -465,8 +664,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/iam/v1/user/:id/phone": {
+        "/api/iam/v1/recovery": {
             "put": {
+                "description": "Set a new password using the userId and secret from the recovery link",
                 "consumes": [
                     "application/json"
                 ],
@@ -474,32 +674,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "recovery"
                 ],
-                "summary": "Update Phone",
+                "summary": "Confirm Password Recovery",
                 "parameters": [
                     {
-                        "description": "Update Phone Request",
-                        "name": "updateRequest",
+                        "description": "Password Recovery Confirm Request",
+                        "name": "confirmRequest",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/siogeneric.UpdatePhoneRequest"
+                            "$ref": "#/definitions/model.PasswordRecoveryConfirmRequest"
                         }
-                    },
-                    {
-                        "type": "string",
-                        "description": "User ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/siogeneric.AwUser"
+                            "$ref": "#/definitions/siogeneric.SuccessResponse"
                         }
                     },
                     "400": {
@@ -520,6 +713,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/siogeneric.ErrorResponse"
                         }
                     },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -527,74 +726,2218 @@ const docTemplate = `{
                         }
                     }
                 }
+            },
+            "post": {
+                "description": "Email the user a password recovery link. The response is the same whether or not an account has the email.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "recovery"
+                ],
+                "summary": "Create Password Recovery",
+                "parameters": [
+                    {
+                        "description": "Password Recovery Request",
+                        "name": "recoveryRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.PasswordRecoveryRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/iam/v1/session": {
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "session"
+                ],
+                "summary": "Create Email Session",
+                "parameters": [
+                    {
+                        "description": "Session Request",
+                        "name": "sessionRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.AwEmailSessionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Session"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/iam/v1/session/:id": {
+            "get": {
+                "description": "List a user's active sessions with device, OS, IP and country",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "session"
+                ],
+                "summary": "List Sessions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Caller session JWT",
+                        "name": "X-Appwrite-JWT",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.SessionListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Sign a user out of every session",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "session"
+                ],
+                "summary": "Delete Sessions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Caller session JWT",
+                        "name": "X-Appwrite-JWT",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/iam/v1/session/:id/:sessionId": {
+            "delete": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "session"
+                ],
+                "summary": "Delete Session",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Caller session JWT",
+                        "name": "X-Appwrite-JWT",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/iam/v1/user": {
+            "get": {
+                "description": "List Users, paged with limit/offset or a cursor and optionally searched or filtered",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "List Users",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Page size (max 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of users to skip",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Return users after this user ID",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Full text search term",
+                        "name": "search",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by email",
+                        "name": "email",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by phone",
+                        "name": "phone",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by name",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Filter by status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only users created after this RFC3339 timestamp",
+                        "name": "createdAfter",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Caller session JWT",
+                        "name": "X-Appwrite-JWT",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.UserListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create User",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Create User",
+                "parameters": [
+                    {
+                        "description": "Create User Request",
+                        "name": "createRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.AwCreateUserRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/iam/v1/user/:id": {
+            "get": {
+                "description": "Get user by ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Get user by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Caller session JWT",
+                        "name": "X-Appwrite-JWT",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Delete User",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Caller session JWT",
+                        "name": "X-Appwrite-JWT",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/iam/v1/user/:id/email": {
+            "put": {
+                "description": "Set a user's email. Admin only; users change their own through /me/email, which requires the current password.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Update Email",
+                "parameters": [
+                    {
+                        "description": "Update Email Request",
+                        "name": "updateRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.UpdateEmailRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Send a verification email to the new address; only when updating your own account",
+                        "name": "verify",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Caller session JWT",
+                        "name": "X-Appwrite-JWT",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/iam/v1/user/:id/name": {
+            "put": {
+                "description": "Update a user's display name",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Update Name",
+                "parameters": [
+                    {
+                        "description": "Update Name Request",
+                        "name": "updateRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.UpdateNameRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Caller session JWT",
+                        "name": "X-Appwrite-JWT",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/iam/v1/user/:id/password": {
+            "put": {
+                "description": "Set a user's password. Admin only; users change their own through /me/password, which requires the current one.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Update Password",
+                "parameters": [
+                    {
+                        "description": "Update Password Request",
+                        "name": "updateRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.UpdatePasswordRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Caller session JWT",
+                        "name": "X-Appwrite-JWT",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/iam/v1/user/:id/phone": {
+            "put": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Update Phone",
+                "parameters": [
+                    {
+                        "description": "Update Phone Request",
+                        "name": "updateRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.UpdatePhoneRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Text a verification code to the new number; only when updating your own account",
+                        "name": "verify",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Caller session JWT",
+                        "name": "X-Appwrite-JWT",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/iam/v1/user/:id/prefs": {
+            "get": {
+                "description": "Get a user's preferences",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Get Prefs",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Caller session JWT",
+                        "name": "X-Appwrite-JWT",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Prefs"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Merge keys into a user's preferences. Keys not provided are kept and a null value removes the key.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Update Prefs",
+                "parameters": [
+                    {
+                        "description": "Update Prefs Request",
+                        "name": "updateRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.UpdatePrefsRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Caller session JWT",
+                        "name": "X-Appwrite-JWT",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Prefs"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/iam/v1/user/:id/roles": {
+            "get": {
+                "description": "Get a user's roles",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "role"
+                ],
+                "summary": "Get Roles",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Caller session JWT",
+                        "name": "X-Appwrite-JWT",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.RolesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Replace a user's roles (admin, author, reader)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "role"
+                ],
+                "summary": "Update Roles",
+                "parameters": [
+                    {
+                        "description": "Update Roles Request",
+                        "name": "updateRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.UpdateRolesRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Caller session JWT",
+                        "name": "X-Appwrite-JWT",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.RolesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/iam/v1/user/:id/status": {
+            "put": {
+                "description": "Enable or block a user. Blocking also revokes all of the user's sessions.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Update Status",
+                "parameters": [
+                    {
+                        "description": "Update Status Request",
+                        "name": "updateRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.UpdateStatusRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Caller session JWT",
+                        "name": "X-Appwrite-JWT",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/iam/v1/user/:id/verification": {
+            "put": {
+                "description": "Admin override to mark a user's email and/or phone as verified or unverified",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Update Verification",
+                "parameters": [
+                    {
+                        "description": "Verification Status Request",
+                        "name": "updateRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.VerificationStatusRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Caller session JWT",
+                        "name": "X-Appwrite-JWT",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/iam/v1/verification/email": {
+            "put": {
+                "description": "Confirm an email address using the userId and secret from the verification link",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "verification"
+                ],
+                "summary": "Confirm Email Verification",
+                "parameters": [
+                    {
+                        "description": "Verification Confirm Request",
+                        "name": "confirmRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.VerificationConfirmRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Email a verification link to the user owning the session",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "verification"
+                ],
+                "summary": "Send Email Verification",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User session JWT",
+                        "name": "X-Appwrite-JWT",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/iam/v1/verification/phone": {
+            "put": {
+                "description": "Confirm a phone number using the userId and the SMS code as the secret",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "verification"
+                ],
+                "summary": "Confirm Phone Verification",
+                "parameters": [
+                    {
+                        "description": "Verification Confirm Request",
+                        "name": "confirmRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.VerificationConfirmRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Text a verification code to the user owning the session",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "verification"
+                ],
+                "summary": "Send Phone Verification",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User session JWT",
+                        "name": "X-Appwrite-JWT",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/iam/v1/webhooks": {
+            "get": {
+                "description": "List every webhook subscription, oldest first, without secrets",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhook"
+                ],
+                "summary": "List Webhook Subscriptions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Caller session JWT",
+                        "name": "X-Appwrite-JWT",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.WebhookSubscriptionList"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "Not Implemented",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Subscribe a URL to user events, or to every event when none are listed. The response carries the secret that signs each delivery's X-Webhook-Signature; it is not shown again.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhook"
+                ],
+                "summary": "Create Webhook Subscription",
+                "parameters": [
+                    {
+                        "description": "Create Subscription Request",
+                        "name": "createRequest",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.CreateWebhookSubscriptionRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Caller session JWT",
+                        "name": "X-Appwrite-JWT",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.WebhookSubscription"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "Not Implemented",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/iam/v1/webhooks/:id": {
+            "delete": {
+                "description": "Unsubscribe. Deliveries still pending for the subscription fail.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhook"
+                ],
+                "summary": "Delete Webhook Subscription",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Caller session JWT",
+                        "name": "X-Appwrite-JWT",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.SuccessResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "Not Implemented",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/iam/v1/webhooks/deliveries": {
+            "get": {
+                "description": "List webhook deliveries, newest first, with their payloads and the outcome of their attempts so far. Finished deliveries are kept for the configured history.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhook"
+                ],
+                "summary": "List Webhook Deliveries",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 50, max 500)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by subscription ID",
+                        "name": "subscription",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by event type, e.g. user.deleted",
+                        "name": "event",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by status: pending, delivered or failed",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Caller session JWT",
+                        "name": "X-Appwrite-JWT",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.WebhookDeliveryList"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "Not Implemented",
+                        "schema": {
+                            "$ref": "#/definitions/siogeneric.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/healthz": {
+            "get": {
+                "description": "Reports that the process is up and serving. It does not check dependencies, so an outage upstream does not get the instance restarted.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Liveness",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Reports whether the instance should receive traffic, with the status of each dependency. Fails with 503 when a dependency is down or once shutdown began.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Readiness",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    }
+                }
             }
         }
     },
     "definitions": {
-        "siogeneric.AwCreateUserRequest": {
+        "health.CheckResult": {
+            "type": "object",
+            "properties": {
+                "status": {
+                    "type": "string",
+                    "example": "ok"
+                }
+            }
+        },
+        "health.Report": {
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/health.CheckResult"
+                    }
+                },
+                "status": {
+                    "type": "string",
+                    "example": "ok"
+                }
+            }
+        },
+        "model.AuditActor": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
+                },
+                "roles": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "type": {
+                    "type": "string",
+                    "example": "user"
+                }
+            }
+        },
+        "model.AuditChange": {
+            "type": "object",
+            "properties": {
+                "after": {},
+                "before": {}
+            }
+        },
+        "model.AuditEvent": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string",
+                    "example": "user.update_email"
+                },
+                "actor": {
+                    "$ref": "#/definitions/model.AuditActor"
+                },
+                "changes": {
+                    "description": "Changes are the user's fields that changed, keyed by JSON name. Prefs\nare keyed prefs.\u003ckey\u003e.",
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/model.AuditChange"
+                    }
+                },
+                "clientIp": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "meta": {
+                    "description": "Meta holds action specific details, e.g. the session ID of a logout.",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "outcome": {
+                    "type": "string",
+                    "example": "success"
+                },
+                "requestId": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 200
+                },
+                "targetId": {
+                    "description": "TargetID is the user the change was made to, when known.",
+                    "type": "string"
+                },
+                "time": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.AuditEventList": {
+            "type": "object",
+            "properties": {
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.AuditEvent"
+                    }
+                }
+            }
+        },
+        "model.CreateWebhookSubscriptionRequest": {
+            "type": "object",
+            "required": [
+                "url"
+            ],
+            "properties": {
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.PasswordRecoveryConfirmRequest": {
             "type": "object",
             "required": [
-                "email",
-                "name",
                 "password",
-                "phone",
+                "secret",
                 "userId"
             ],
+            "properties": {
+                "password": {
+                    "type": "string"
+                },
+                "secret": {
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.PasswordRecoveryRequest": {
+            "type": "object",
+            "required": [
+                "email"
+            ],
             "properties": {
                 "email": {
                     "type": "string"
+                }
+            }
+        },
+        "model.Prefs": {
+            "type": "object",
+            "additionalProperties": {}
+        },
+        "model.RolesResponse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "string"
                 },
-                "name": {
+                "roles": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "model.Session": {
+            "type": "object",
+            "properties": {
+                "$createdAt": {
+                    "type": "string"
+                },
+                "$id": {
+                    "type": "string"
+                },
+                "clientName": {
+                    "type": "string"
+                },
+                "clientVersion": {
+                    "type": "string"
+                },
+                "countryCode": {
                     "type": "string"
                 },
-                "password": {
+                "countryName": {
                     "type": "string"
                 },
-                "phone": {
+                "current": {
+                    "type": "boolean"
+                },
+                "deviceBrand": {
                     "type": "string"
                 },
-                "userId": {
+                "deviceModel": {
                     "type": "string"
-                }
-            }
-        },
-        "siogeneric.AwEmailSessionRequest": {
-            "type": "object",
-            "required": [
-                "email",
-                "password"
-            ],
-            "properties": {
-                "email": {
+                },
+                "deviceName": {
                     "type": "string"
                 },
-                "password": {
+                "expire": {
                     "type": "string"
-                }
-            }
-        },
-        "siogeneric.AwSession": {
-            "type": "object",
-            "properties": {
-                "$createdAt": {
+                },
+                "ip": {
                     "type": "string"
                 },
-                "$id": {
+                "osName": {
                     "type": "string"
                 },
-                "clientCode": {
+                "osVersion": {
                     "type": "string"
                 },
-                "clientEngine": {
+                "provider": {
                     "type": "string"
                 },
-                "clientEngineVersion": {
+                "secret": {
+                    "description": "Secret is the session token, when the provider hands one out.",
                     "type": "string"
                 },
-                "clientName": {
+                "userId": {
                     "type": "string"
+                }
+            }
+        },
+        "model.SessionListResponse": {
+            "type": "object",
+            "properties": {
+                "sessions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.SessionSummary"
+                    }
                 },
-                "clientType": {
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.SessionSummary": {
+            "type": "object",
+            "properties": {
+                "clientName": {
                     "type": "string"
                 },
                 "clientVersion": {
@@ -606,6 +2949,9 @@ const docTemplate = `{
                 "countryName": {
                     "type": "string"
                 },
+                "createdAt": {
+                    "type": "string"
+                },
                 "current": {
                     "type": "boolean"
                 },
@@ -621,10 +2967,10 @@ const docTemplate = `{
                 "expire": {
                     "type": "string"
                 },
-                "ip": {
+                "id": {
                     "type": "string"
                 },
-                "osCode": {
+                "ip": {
                     "type": "string"
                 },
                 "osName": {
@@ -635,25 +2981,87 @@ const docTemplate = `{
                 },
                 "provider": {
                     "type": "string"
-                },
-                "providerAccessToken": {
+                }
+            }
+        },
+        "model.UpdateNameRequest": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "name": {
                     "type": "string"
-                },
-                "providerAccessTokenExpiry": {
+                }
+            }
+        },
+        "model.UpdateOwnEmailRequest": {
+            "type": "object",
+            "required": [
+                "email",
+                "oldPassword"
+            ],
+            "properties": {
+                "email": {
                     "type": "string"
                 },
-                "providerRefreshToken": {
+                "oldPassword": {
                     "type": "string"
-                },
-                "providerUid": {
+                }
+            }
+        },
+        "model.UpdateOwnPasswordRequest": {
+            "type": "object",
+            "required": [
+                "oldPassword",
+                "password"
+            ],
+            "properties": {
+                "oldPassword": {
                     "type": "string"
                 },
-                "userId": {
+                "password": {
                     "type": "string"
                 }
             }
         },
-        "siogeneric.AwUser": {
+        "model.UpdatePrefsRequest": {
+            "type": "object",
+            "required": [
+                "prefs"
+            ],
+            "properties": {
+                "prefs": {
+                    "$ref": "#/definitions/model.Prefs"
+                }
+            }
+        },
+        "model.UpdateRolesRequest": {
+            "type": "object",
+            "required": [
+                "roles"
+            ],
+            "properties": {
+                "roles": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "model.UpdateStatusRequest": {
+            "type": "object",
+            "required": [
+                "status"
+            ],
+            "properties": {
+                "status": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "model.User": {
             "type": "object",
             "properties": {
                 "$createdAt": {
@@ -671,18 +3079,9 @@ const docTemplate = `{
                 "emailVerification": {
                     "type": "boolean"
                 },
-                "hash": {
-                    "type": "string"
-                },
-                "hashOptions": {
-                    "$ref": "#/definitions/siogeneric.HashOptions"
-                },
                 "name": {
                     "type": "string"
                 },
-                "password": {
-                    "type": "string"
-                },
                 "passwordUpdate": {
                     "type": "string"
                 },
@@ -693,8 +3092,7 @@ const docTemplate = `{
                     "type": "boolean"
                 },
                 "prefs": {
-                    "type": "object",
-                    "additionalProperties": {}
+                    "$ref": "#/definitions/model.Prefs"
                 },
                 "registration": {
                     "type": "string"
@@ -704,45 +3102,199 @@ const docTemplate = `{
                 }
             }
         },
-        "siogeneric.AwlistResponse": {
+        "model.UserListResponse": {
             "type": "object",
             "properties": {
+                "limit": {
+                    "type": "integer"
+                },
+                "nextCursor": {
+                    "type": "string"
+                },
+                "offset": {
+                    "type": "integer"
+                },
                 "total": {
                     "type": "integer"
                 },
                 "users": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/siogeneric.AwUser"
+                        "$ref": "#/definitions/model.User"
                     }
                 }
             }
         },
-        "siogeneric.ErrorResponse": {
+        "model.VerificationConfirmRequest": {
             "type": "object",
+            "required": [
+                "secret",
+                "userId"
+            ],
             "properties": {
-                "error": {
+                "secret": {
                     "type": "string"
                 },
-                "method": {
+                "userId": {
                     "type": "string"
+                }
+            }
+        },
+        "model.VerificationStatusRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "boolean"
                 },
-                "path": {
-                    "type": "string"
+                "phone": {
+                    "type": "boolean"
                 }
             }
         },
-        "siogeneric.HashOptions": {
+        "model.WebhookDelivery": {
             "type": "object",
             "properties": {
-                "memoryCost": {
+                "attempts": {
                     "type": "integer"
                 },
-                "threads": {
-                    "type": "integer"
+                "createdAt": {
+                    "type": "string"
+                },
+                "eventId": {
+                    "type": "string"
+                },
+                "eventType": {
+                    "type": "string"
                 },
-                "timeCost": {
+                "id": {
+                    "type": "string"
+                },
+                "lastError": {
+                    "type": "string"
+                },
+                "lastStatus": {
+                    "description": "LastStatus is the HTTP status the last attempt got, or 0 when it got\nnone; LastError says why it failed.",
                     "type": "integer"
+                },
+                "nextAttempt": {
+                    "description": "NextAttempt is when a pending delivery is next tried.",
+                    "type": "string"
+                },
+                "payload": {
+                    "type": "object"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "subscriptionId": {
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.WebhookDeliveryList": {
+            "type": "object",
+            "properties": {
+                "deliveries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.WebhookDelivery"
+                    }
+                }
+            }
+        },
+        "model.WebhookSubscription": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "secret": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.WebhookSubscriptionList": {
+            "type": "object",
+            "properties": {
+                "subscriptions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.WebhookSubscription"
+                    }
+                }
+            }
+        },
+        "siogeneric.AwCreateUserRequest": {
+            "type": "object",
+            "required": [
+                "email",
+                "name",
+                "password",
+                "phone",
+                "userId"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                },
+                "phone": {
+                    "type": "string"
+                },
+                "userId": {
+                    "type": "string"
+                }
+            }
+        },
+        "siogeneric.AwEmailSessionRequest": {
+            "type": "object",
+            "required": [
+                "email",
+                "password"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "siogeneric.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "method": {
+                    "type": "string"
+                },
+                "path": {
+                    "type": "string"
                 }
             }
         },
@@ -0,0 +1,167 @@
+// Package health reports whether this instance is alive and whether it should
+// receive traffic.
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	StatusOK          = "ok"
+	StatusDown        = "down"
+	StatusUnavailable = "unavailable"
+	StatusDraining    = "draining"
+)
+
+// Dependency is something the instance cannot serve requests without.
+type Dependency struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// Report is the readiness response.
+type Report struct {
+	Status string                 `json:"status" example:"ok"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// CheckResult is the outcome of a dependency's most recent check. The probe is
+// unauthenticated, so why a check failed is logged rather than returned.
+type CheckResult struct {
+	Status    string    `json:"status" example:"ok"`
+	CheckedAt time.Time `json:"-"`
+}
+
+// Readiness is the instance's readiness. It is ready while every dependency's
+// check passes, and stops being ready for good once draining begins on
+// shutdown.
+//
+// Check results are cached for ttl, so frequent probes from several sources do
+// not turn into a stream of calls to the dependencies.
+type Readiness struct {
+	draining atomic.Bool
+	ttl      time.Duration
+	timeout  time.Duration
+	deps     []*dependencyState
+	now      func() time.Time
+}
+
+type dependencyState struct {
+	Dependency
+	mu   sync.Mutex
+	last CheckResult
+}
+
+// NewReadiness checks deps, each bounded by timeout, at most once per ttl.
+func NewReadiness(ttl time.Duration, timeout time.Duration, deps ...Dependency) *Readiness {
+	r := &Readiness{
+		ttl:     ttl,
+		timeout: timeout,
+		now:     time.Now,
+	}
+	for _, d := range deps {
+		r.deps = append(r.deps, &dependencyState{Dependency: d})
+	}
+	return r
+}
+
+// StartDraining makes the readiness check fail from now on.
+func (r *Readiness) StartDraining() {
+	r.draining.Store(true)
+}
+
+func (r *Readiness) Draining() bool {
+	return r.draining.Load()
+}
+
+// Check reports the status of every dependency, checking those whose cached
+// result has expired concurrently.
+func (r *Readiness) Check() Report {
+	if r.Draining() {
+		return Report{Status: StatusDraining}
+	}
+
+	results := make([]CheckResult, len(r.deps))
+	var wg sync.WaitGroup
+	for i, d := range r.deps {
+		wg.Add(1)
+		go func(i int, d *dependencyState) {
+			defer wg.Done()
+			results[i] = r.check(d)
+		}(i, d)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: map[string]CheckResult{}}
+	for i, d := range r.deps {
+		report.Checks[d.Name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusUnavailable
+		}
+	}
+	return report
+}
+
+// check returns d's cached result or checks it again. Concurrent callers wait
+// for a single check rather than each starting one.
+func (r *Readiness) check(d *dependencyState) CheckResult {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.last.CheckedAt.IsZero() && r.now().Sub(d.last.CheckedAt) < r.ttl {
+		return d.last
+	}
+
+	// The result is shared, so it must not depend on any one probe's request.
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	start := r.now()
+	err := d.Check(ctx)
+	d.last = CheckResult{Status: StatusOK, CheckedAt: start}
+	if err != nil {
+		d.last.Status = StatusDown
+		log.Warnf(
+			"readiness check %s failed after %s: %v",
+			d.Name,
+			r.now().Sub(start),
+			err,
+		)
+	}
+	return d.last
+}
+
+// @Summary Readiness
+// GET
+// @Description Reports whether the instance should receive traffic, with the status of each dependency. Fails with 503 when a dependency is down or once shutdown began.
+// @Tags health
+// @Produce  json
+// @Success 200 {object} health.Report
+// @Failure 503 {object} health.Report
+// @Router /readyz [get]
+func (r *Readiness) Ready(c *gin.Context) {
+	report := r.Check()
+	if report.Status != StatusOK {
+		c.JSON(http.StatusServiceUnavailable, report)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// @Summary Liveness
+// GET
+// @Description Reports that the process is up and serving. It does not check dependencies, so an outage upstream does not get the instance restarted.
+// @Tags health
+// @Produce  json
+// @Success 200 {object} health.Report
+// @Router /healthz [get]
+func Live(c *gin.Context) {
+	c.JSON(http.StatusOK, Report{Status: StatusOK})
+}
@@ -0,0 +1,154 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeDependency fails while err is set and counts its checks.
+type fakeDependency struct {
+	mu     sync.Mutex
+	err    error
+	checks atomic.Int32
+}
+
+func (f *fakeDependency) check(ctx context.Context) error {
+	f.checks.Add(1)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.err
+}
+
+func (f *fakeDependency) fail(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.err = err
+}
+
+func initReadinessForTests(ttl time.Duration) (*Readiness, *fakeDependency, *time.Time) {
+	dep := &fakeDependency{}
+	r := NewReadiness(ttl, time.Second, Dependency{Name: "appwrite", Check: dep.check})
+	clock := time.Date(2023, 11, 14, 22, 13, 20, 0, time.UTC)
+	r.now = func() time.Time { return clock }
+	return r, dep, &clock
+}
+
+func serveReady(r *Readiness) (int, Report) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	r.Ready(c)
+
+	var report Report
+	_ = json.Unmarshal(w.Body.Bytes(), &report)
+	return w.Code, report
+}
+
+func TestReadiness_Ready(t *testing.T) {
+	r, dep, _ := initReadinessForTests(0)
+
+	code, report := serveReady(r)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, StatusOK, report.Status)
+	assert.Equal(t, StatusOK, report.Checks["appwrite"].Status)
+
+	dep.fail(fmt.Errorf("connection refused"))
+	code, report = serveReady(r)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, StatusUnavailable, report.Status)
+	assert.Equal(t, StatusDown, report.Checks["appwrite"].Status)
+}
+
+func TestReadiness_ErrorsLogged(t *testing.T) {
+	hook := test.NewGlobal()
+	t.Cleanup(hook.Reset)
+	r, dep, _ := initReadinessForTests(0)
+
+	dep.fail(fmt.Errorf("dial tcp appwrite.internal:443: connection refused"))
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	r.Ready(c)
+
+	assert.JSONEq(
+		t,
+		`{"status":"unavailable","checks":{"appwrite":{"status":"down"}}}`,
+		w.Body.String(),
+	)
+	if assert.NotNil(t, hook.LastEntry()) {
+		assert.Contains(t, hook.LastEntry().Message, "appwrite.internal:443")
+	}
+}
+
+func TestReadiness_Draining(t *testing.T) {
+	r, dep, _ := initReadinessForTests(0)
+
+	r.StartDraining()
+	assert.True(t, r.Draining())
+
+	code, report := serveReady(r)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, StatusDraining, report.Status)
+	assert.Equal(t, int32(0), dep.checks.Load(), "dependencies are not checked while draining")
+}
+
+func TestReadiness_Cache(t *testing.T) {
+	r, dep, clock := initReadinessForTests(5 * time.Second)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.Check()
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), dep.checks.Load())
+
+	// A failure shows up once the cached success expires.
+	dep.fail(fmt.Errorf("invalid key"))
+	*clock = clock.Add(4 * time.Second)
+	assert.Equal(t, StatusOK, r.Check().Status)
+
+	*clock = clock.Add(time.Second)
+	assert.Equal(t, StatusUnavailable, r.Check().Status)
+	assert.Equal(t, int32(2), dep.checks.Load())
+}
+
+func TestReadiness_CheckTimeout(t *testing.T) {
+	r := NewReadiness(0, 10*time.Millisecond, Dependency{
+		Name: "appwrite",
+		Check: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	})
+
+	hook := test.NewGlobal()
+	t.Cleanup(hook.Reset)
+
+	result := r.Check().Checks["appwrite"]
+	assert.Equal(t, StatusDown, result.Status)
+	if assert.NotNil(t, hook.LastEntry()) {
+		assert.Contains(t, hook.LastEntry().Message, context.DeadlineExceeded.Error())
+	}
+}
+
+func TestLive(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	Live(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, w.Body.String())
+}
@@ -1,15 +1,18 @@
 package main
 
 import (
+	"encoding/json"
 	"net/http"
 	"testing"
 
 	"gitea.slauson.io/slausonio/go-testing/siotest"
 	"github.com/stretchr/testify/assert"
+
+	"gitea.slauson.io/slausonio/iam-ms/health"
 )
 
 func TestHealthCheckRoot(t *testing.T) {
-	ts, _ := siotest.RunTestServer(t, CreateRouter())
+	ts, _ := siotest.RunTestServer(t, testRouter(t))
 	defer ts.Close()
 
 	req, err := http.NewRequest("GET", ts.URL+"/", nil)
@@ -34,7 +37,7 @@ func TestHealthCheckRoot(t *testing.T) {
 }
 
 func TestHealthCheckContextPath(t *testing.T) {
-	ts, _ := siotest.RunTestServer(t, CreateRouter())
+	ts, _ := siotest.RunTestServer(t, testRouter(t))
 	defer ts.Close()
 
 	req, err := http.NewRequest("GET", ts.URL+"/api/iam", nil)
@@ -57,3 +60,43 @@ func TestHealthCheckContextPath(t *testing.T) {
 		resp.StatusCode,
 	)
 }
+
+func TestHealthz(t *testing.T) {
+	ts, _ := siotest.RunTestServer(t, testRouter(t))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestReadyz(t *testing.T) {
+	cfg := testConfig(t)
+	ready := newReadiness(cfg)
+	ts, _ := siotest.RunTestServer(t, CreateRouter(cfg, ready))
+	defer ts.Close()
+
+	get := func() (int, health.Report) {
+		resp, err := http.Get(ts.URL + "/readyz")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		var report health.Report
+		assert.Nil(t, json.NewDecoder(resp.Body).Decode(&report))
+		return resp.StatusCode, report
+	}
+
+	code, report := get()
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, health.StatusOK, report.Checks[cfg.IAM.Provider].Status)
+
+	ready.StartDraining()
+	code, report = get()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, health.StatusDraining, report.Status)
+}
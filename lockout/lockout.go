@@ -0,0 +1,217 @@
+// Package lockout locks out logins for emails and client IPs that keep
+// failing to log in, for longer each time it happens again.
+package lockout
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"gitea.slauson.io/slausonio/iam-ms/config"
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+	"gitea.slauson.io/slausonio/iam-ms/utils"
+)
+
+// Policy is when a key gets locked out and for how long.
+type Policy struct {
+	// Threshold failures within Window lock the key out. 0 disables the
+	// policy.
+	Threshold int
+	Window    time.Duration
+	// Duration is the first lockout's length. Each lockout that follows
+	// within Window of the previous one ending doubles it, up to MaxDuration.
+	Duration    time.Duration
+	MaxDuration time.Duration
+}
+
+// State is a key's failures and lockouts.
+type State struct {
+	Failures    int       `json:"failures"`
+	WindowStart time.Time `json:"windowStart"`
+	Lockouts    int       `json:"lockouts"`
+	LockedUntil time.Time `json:"lockedUntil"`
+}
+
+// fail records a failure at now, locking the key out when it reaches the
+// threshold. It returns the new state and how long to keep it.
+func (p Policy) fail(s State, now time.Time) (State, time.Duration) {
+	if s.Failures == 0 || now.Sub(s.WindowStart) >= p.Window {
+		s.Failures = 0
+		s.WindowStart = now
+	}
+	s.Failures++
+
+	if s.Failures >= p.Threshold {
+		s.Lockouts++
+		s.LockedUntil = now.Add(p.lockoutFor(s.Lockouts))
+		s.Failures = 0
+	}
+
+	// The state outlives the lockout by Window, so a key failing again soon
+	// after it ends is locked out for longer.
+	until := now
+	if s.LockedUntil.After(until) {
+		until = s.LockedUntil
+	}
+	return s, until.Add(p.Window).Sub(now)
+}
+
+// lockoutFor is the length of the nth lockout in a row.
+func (p Policy) lockoutFor(n int) time.Duration {
+	d := p.Duration
+	for i := 1; i < n && d < p.MaxDuration; i++ {
+		d *= 2
+	}
+	if d > p.MaxDuration {
+		return p.MaxDuration
+	}
+	return d
+}
+
+// Guard tracks failed logins per email and per client IP.
+type Guard struct {
+	store Store
+	email Policy
+	ip    Policy
+	now   func() time.Time
+}
+
+var (
+	defaultOnce  sync.Once
+	defaultGuard *Guard
+)
+
+func New(store Store, email Policy, ip Policy) *Guard {
+	return &Guard{
+		store: store,
+		email: email,
+		ip:    ip,
+		now:   time.Now,
+	}
+}
+
+// FromConfig builds a Guard keeping its counts in the configured store.
+func FromConfig(cfg *config.Config) *Guard {
+	var store Store = NewMemoryStore()
+	if cfg.Lockout.Store == constants.STORE_REDIS {
+		store = NewRedisStore(cfg)
+	}
+
+	policy := func(threshold int) Policy {
+		return Policy{
+			Threshold:   threshold,
+			Window:      cfg.Lockout.Window,
+			Duration:    cfg.Lockout.Duration,
+			MaxDuration: cfg.Lockout.MaxDuration,
+		}
+	}
+	return New(store, policy(cfg.Lockout.Threshold), policy(cfg.Lockout.IPThreshold))
+}
+
+// Default is the Guard shared by the session service and its admin routes.
+func Default(cfg *config.Config) *Guard {
+	defaultOnce.Do(func() {
+		defaultGuard = FromConfig(cfg)
+	})
+	return defaultGuard
+}
+
+func emailKey(email string) string {
+	return "email:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+// Check returns a 429 telling the client how long to wait while email or ip
+// is locked out, and nil otherwise. It fails open: when the store cannot be
+// reached, logins go ahead unthrottled.
+func (g *Guard) Check(ctx context.Context, email string, ip string) error {
+	now := g.now()
+	var wait time.Duration
+	for _, k := range g.keys(email, ip) {
+		s, err := g.store.Get(ctx, k.key)
+		if err != nil {
+			log.Errorf("login lockout of %s not checked: %v", k.key, err)
+			continue
+		}
+		if left := s.LockedUntil.Sub(now); left > wait {
+			wait = left
+		}
+	}
+
+	if wait <= 0 {
+		return nil
+	}
+	return &utils.IamError{
+		Status:     http.StatusTooManyRequests,
+		Type:       constants.ERR_TYPE_RATE_LIMITED,
+		Message:    constants.LoginLocked,
+		RetryAfter: wait,
+	}
+}
+
+// Fail records a failed login for email and ip.
+func (g *Guard) Fail(ctx context.Context, email string, ip string) {
+	now := g.now()
+	for _, k := range g.keys(email, ip) {
+		policy := k.policy
+		s, err := g.store.Update(ctx, k.key, func(s State) (State, time.Duration) {
+			return policy.fail(s, now)
+		})
+		if err != nil {
+			log.Errorf("failed login for %s not recorded: %v", k.key, err)
+			continue
+		}
+		if s.LockedUntil.After(now) {
+			log.Warnf("login locked out for %s until %s", k.key, s.LockedUntil.Format(time.RFC3339))
+		}
+	}
+}
+
+// Succeed forgets email's failures. The IP's are kept, so logging in to one
+// account does not reset an IP that is guessing at others.
+func (g *Guard) Succeed(ctx context.Context, email string) {
+	if g.email.Threshold == 0 {
+		return
+	}
+	if err := g.store.Delete(ctx, emailKey(email)); err != nil {
+		log.Errorf("failed logins for %s not reset: %v", emailKey(email), err)
+	}
+}
+
+// Clear lifts the lockouts of email and ip, either of which may be empty, and
+// forgets their failures.
+func (g *Guard) Clear(ctx context.Context, email string, ip string) error {
+	if email != "" {
+		if err := g.store.Delete(ctx, emailKey(email)); err != nil {
+			return err
+		}
+	}
+	if ip != "" {
+		return g.store.Delete(ctx, ipKey(ip))
+	}
+	return nil
+}
+
+type policyKey struct {
+	key    string
+	policy Policy
+}
+
+// keys are the keys for email and ip whose policies are enabled.
+func (g *Guard) keys(email string, ip string) []policyKey {
+	var keys []policyKey
+	if g.email.Threshold > 0 && email != "" {
+		keys = append(keys, policyKey{emailKey(email), g.email})
+	}
+	if g.ip.Threshold > 0 && ip != "" {
+		keys = append(keys, policyKey{ipKey(ip), g.ip})
+	}
+	return keys
+}
@@ -0,0 +1,199 @@
+package lockout
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"gitea.slauson.io/slausonio/iam-ms/config"
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+	"gitea.slauson.io/slausonio/iam-ms/utils"
+)
+
+var (
+	tStart  = time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	tPolicy = Policy{
+		Threshold:   3,
+		Window:      15 * time.Minute,
+		Duration:    time.Minute,
+		MaxDuration: 5 * time.Minute,
+	}
+)
+
+// testGuard is a Guard over a memory store whose clock is *now.
+func testGuard(email Policy, ip Policy) (*Guard, *time.Time) {
+	now := tStart
+	clock := func() time.Time { return now }
+	store := NewMemoryStore()
+	store.now = clock
+	g := New(store, email, ip)
+	g.now = clock
+	return g, &now
+}
+
+func assertLocked(t *testing.T, err error, wait time.Duration) {
+	t.Helper()
+	var ie *utils.IamError
+	if assert.ErrorAs(t, err, &ie) {
+		assert.Equal(t, http.StatusTooManyRequests, ie.Status)
+		assert.Equal(t, constants.ERR_TYPE_RATE_LIMITED, ie.Type)
+		assert.Equal(t, wait, ie.RetryAfter)
+	}
+}
+
+func TestPolicy_LockoutFor(t *testing.T) {
+	for n, want := range map[int]time.Duration{
+		1: time.Minute,
+		2: 2 * time.Minute,
+		3: 4 * time.Minute,
+		4: 5 * time.Minute,
+		9: 5 * time.Minute,
+	} {
+		assert.Equalf(t, want, tPolicy.lockoutFor(n), "lockout %d", n)
+	}
+}
+
+func TestPolicy_Fail(t *testing.T) {
+	s, ttl := tPolicy.fail(State{}, tStart)
+	assert.Equal(t, State{Failures: 1, WindowStart: tStart}, s)
+	assert.Equal(t, tPolicy.Window, ttl)
+
+	s, _ = tPolicy.fail(s, tStart.Add(time.Minute))
+	s, ttl = tPolicy.fail(s, tStart.Add(2*time.Minute))
+	assert.Equal(t, 0, s.Failures)
+	assert.Equal(t, 1, s.Lockouts)
+	assert.Equal(t, tStart.Add(3*time.Minute), s.LockedUntil)
+	assert.Equal(t, time.Minute+tPolicy.Window, ttl)
+
+	// Failures spread wider than the window never add up.
+	s = State{}
+	for i := 0; i < 5; i++ {
+		s, _ = tPolicy.fail(s, tStart.Add(time.Duration(i)*tPolicy.Window))
+	}
+	assert.Equal(t, 1, s.Failures)
+	assert.Zero(t, s.Lockouts)
+}
+
+func TestGuard_Escalates(t *testing.T) {
+	g, now := testGuard(tPolicy, Policy{})
+	ctx := context.Background()
+
+	for _, wait := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute} {
+		for i := 0; i < tPolicy.Threshold; i++ {
+			assert.Nil(t, g.Check(ctx, "T@t.com", "10.0.0.1"))
+			g.Fail(ctx, "t@t.com ", "10.0.0.1")
+		}
+		assertLocked(t, g.Check(ctx, "t@t.com", "10.0.0.2"), wait)
+
+		*now = now.Add(wait - time.Second)
+		assertLocked(t, g.Check(ctx, "t@t.com", ""), time.Second)
+		*now = now.Add(time.Second)
+	}
+	assert.Nil(t, g.Check(ctx, "t@t.com", "10.0.0.1"))
+
+	// Staying away for a window after the lockout ends starts over.
+	*now = now.Add(tPolicy.Window)
+	for i := 0; i < tPolicy.Threshold; i++ {
+		g.Fail(ctx, "t@t.com", "10.0.0.1")
+	}
+	assertLocked(t, g.Check(ctx, "t@t.com", ""), time.Minute)
+}
+
+func TestGuard_IP(t *testing.T) {
+	ipPolicy := tPolicy
+	ipPolicy.Threshold = 2
+	g, _ := testGuard(tPolicy, ipPolicy)
+	ctx := context.Background()
+
+	g.Fail(ctx, "a@t.com", "10.0.0.1")
+	g.Fail(ctx, "b@t.com", "10.0.0.1")
+
+	assertLocked(t, g.Check(ctx, "c@t.com", "10.0.0.1"), time.Minute)
+	assert.Nil(t, g.Check(ctx, "c@t.com", "10.0.0.2"))
+
+	// Logging in to one account does not forgive the IP.
+	g.Succeed(ctx, "a@t.com")
+	assertLocked(t, g.Check(ctx, "c@t.com", "10.0.0.1"), time.Minute)
+}
+
+func TestGuard_SucceedAndClear(t *testing.T) {
+	g, _ := testGuard(tPolicy, tPolicy)
+	ctx := context.Background()
+
+	g.Fail(ctx, "t@t.com", "10.0.0.1")
+	g.Fail(ctx, "t@t.com", "10.0.0.1")
+	g.Succeed(ctx, "t@t.com")
+	g.Fail(ctx, "t@t.com", "10.0.0.2")
+	assert.Nil(t, g.Check(ctx, "t@t.com", ""))
+
+	for i := 0; i < tPolicy.Threshold; i++ {
+		g.Fail(ctx, "t@t.com", "10.0.0.1")
+	}
+	assertLocked(t, g.Check(ctx, "t@t.com", ""), time.Minute)
+	assertLocked(t, g.Check(ctx, "", "10.0.0.1"), time.Minute)
+
+	assert.Nil(t, g.Clear(ctx, "T@T.com", ""))
+	assert.Nil(t, g.Check(ctx, "t@t.com", ""))
+	assertLocked(t, g.Check(ctx, "", "10.0.0.1"), time.Minute)
+
+	assert.Nil(t, g.Clear(ctx, "", "10.0.0.1"))
+	assert.Nil(t, g.Check(ctx, "t@t.com", "10.0.0.1"))
+}
+
+func TestGuard_Disabled(t *testing.T) {
+	g, _ := testGuard(Policy{}, Policy{})
+	ctx := context.Background()
+
+	for i := 0; i < 10; i++ {
+		g.Fail(ctx, "t@t.com", "10.0.0.1")
+	}
+	assert.Nil(t, g.Check(ctx, "t@t.com", "10.0.0.1"))
+}
+
+// brokenStore fails every call.
+type brokenStore struct{}
+
+var errBroken = errors.New("store down")
+
+func (brokenStore) Get(context.Context, string) (State, error) {
+	return State{}, errBroken
+}
+
+func (brokenStore) Update(
+	context.Context,
+	string,
+	func(State) (State, time.Duration),
+) (State, error) {
+	return State{}, errBroken
+}
+
+func (brokenStore) Delete(context.Context, string) error {
+	return errBroken
+}
+
+func TestGuard_FailsOpen(t *testing.T) {
+	g := New(brokenStore{}, tPolicy, tPolicy)
+	ctx := context.Background()
+
+	for i := 0; i < tPolicy.Threshold; i++ {
+		g.Fail(ctx, "t@t.com", "10.0.0.1")
+	}
+	assert.Nil(t, g.Check(ctx, "t@t.com", "10.0.0.1"))
+	assert.ErrorIs(t, g.Clear(ctx, "t@t.com", ""), errBroken)
+}
+
+func TestFromConfig(t *testing.T) {
+	cfg := config.Default()
+	g := FromConfig(cfg)
+	assert.IsType(t, &MemoryStore{}, g.store)
+	assert.Equal(t, cfg.Lockout.Threshold, g.email.Threshold)
+	assert.Equal(t, cfg.Lockout.IPThreshold, g.ip.Threshold)
+
+	cfg.Lockout.Store = constants.STORE_REDIS
+	cfg.Redis.Addr = "localhost:6379"
+	assert.IsType(t, &RedisStore{}, FromConfig(cfg).store)
+}
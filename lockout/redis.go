@@ -0,0 +1,98 @@
+package lockout
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"gitea.slauson.io/slausonio/iam-ms/config"
+)
+
+const (
+	redisKeyPrefix = "iam:lockout:"
+	// redisMaxAttempts bounds retrying an update that lost a race with
+	// another replica's.
+	redisMaxAttempts = 5
+)
+
+// RedisStore keeps states in Redis, shared by every replica. Each is a JSON
+// value that expires with its state.
+type RedisStore struct {
+	client *redis.Client
+}
+
+func NewRedisStore(cfg *config.Config) *RedisStore {
+	return NewRedisStoreFor(redis.NewClient(&redis.Options{
+		Addr:     cfg.Redis.Addr,
+		Password: cfg.Redis.Password.Value(),
+		DB:       cfg.Redis.DB,
+	}))
+}
+
+func NewRedisStoreFor(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func (s *RedisStore) Get(ctx context.Context, key string) (State, error) {
+	return s.get(ctx, s.client, redisKeyPrefix+key)
+}
+
+// Update runs fn in a transaction watching key, trying again when another
+// replica changed key in the meantime.
+func (s *RedisStore) Update(
+	ctx context.Context,
+	key string,
+	fn func(s State) (State, time.Duration),
+) (State, error) {
+	key = redisKeyPrefix + key
+
+	var result State
+	update := func(tx *redis.Tx) error {
+		current, err := s.get(ctx, tx, key)
+		if err != nil {
+			return err
+		}
+
+		next, ttl := fn(current)
+		value, err := json.Marshal(next)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(p redis.Pipeliner) error {
+			p.Set(ctx, key, value, ttl)
+			return nil
+		})
+		result = next
+		return err
+	}
+
+	for i := 0; i < redisMaxAttempts; i++ {
+		err := s.client.Watch(ctx, update, key)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return result, err
+		}
+	}
+	return State{}, fmt.Errorf("%s changed by others %d times in a row", key, redisMaxAttempts)
+}
+
+func (s *RedisStore) Delete(ctx context.Context, key string) error {
+	return s.client.Del(ctx, redisKeyPrefix+key).Err()
+}
+
+func (s *RedisStore) get(ctx context.Context, c redis.Cmdable, key string) (State, error) {
+	var state State
+	value, err := c.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+	err = json.Unmarshal(value, &state)
+	return state, err
+}
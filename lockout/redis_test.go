@@ -0,0 +1,94 @@
+package lockout
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func initRedisStoreTest(t *testing.T) (*RedisStore, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	return NewRedisStoreFor(redis.NewClient(&redis.Options{Addr: mr.Addr()})), mr
+}
+
+func TestRedisStore(t *testing.T) {
+	s, mr := initRedisStoreTest(t)
+	ctx := context.Background()
+
+	state, err := s.Get(ctx, "k")
+	assert.Nil(t, err)
+	assert.Zero(t, state)
+
+	locked := tStart.Add(time.Minute)
+	state, err = s.Update(ctx, "k", func(s State) (State, time.Duration) {
+		s.Lockouts++
+		s.LockedUntil = locked
+		return s, time.Minute
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, state.Lockouts)
+
+	state, err = s.Get(ctx, "k")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, state.Lockouts)
+	assert.True(t, locked.Equal(state.LockedUntil))
+	assert.Equal(t, time.Minute, mr.TTL(redisKeyPrefix+"k"))
+
+	mr.FastForward(time.Minute)
+	state, _ = s.Get(ctx, "k")
+	assert.Zero(t, state)
+
+	_, _ = s.Update(ctx, "k", func(s State) (State, time.Duration) {
+		return State{Failures: 1}, time.Minute
+	})
+	assert.Nil(t, s.Delete(ctx, "k"))
+	assert.False(t, mr.Exists(redisKeyPrefix+"k"))
+}
+
+func TestRedisStore_Concurrent(t *testing.T) {
+	s, _ := initRedisStoreTest(t)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				// Lost races past redisMaxAttempts are errors, not lost updates.
+				for {
+					_, err := s.Update(ctx, "k", func(s State) (State, time.Duration) {
+						s.Failures++
+						return s, time.Minute
+					})
+					if err == nil {
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	state, err := s.Get(ctx, "k")
+	assert.Nil(t, err)
+	assert.Equal(t, 20, state.Failures)
+}
+
+func TestRedisStore_Down(t *testing.T) {
+	s, mr := initRedisStoreTest(t)
+	mr.Close()
+	ctx := context.Background()
+
+	_, err := s.Get(ctx, "k")
+	assert.NotNil(t, err)
+	_, err = s.Update(ctx, "k", func(s State) (State, time.Duration) {
+		return s, time.Minute
+	})
+	assert.NotNil(t, err)
+}
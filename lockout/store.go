@@ -0,0 +1,95 @@
+package lockout
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the memory store drops expired states.
+const sweepInterval = time.Minute
+
+// Store keeps each key's State until it expires.
+type Store interface {
+	// Get returns key's state, which is zero when there is none.
+	Get(ctx context.Context, key string) (State, error)
+	// Update replaces key's state with what fn makes of it, atomically, and
+	// keeps the result for the duration fn returns.
+	Update(
+		ctx context.Context,
+		key string,
+		fn func(s State) (State, time.Duration),
+	) (State, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// MemoryStore keeps states in this process, so each replica counts the
+// failures it sees on its own.
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]memoryEntry
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type memoryEntry struct {
+	state   State
+	expires time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries: map[string]memoryEntry{},
+		now:     time.Now,
+	}
+}
+
+func (s *MemoryStore) Get(_ context.Context, key string) (State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.get(key), nil
+}
+
+func (s *MemoryStore) Update(
+	_ context.Context,
+	key string,
+	fn func(s State) (State, time.Duration),
+) (State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep()
+	state, ttl := fn(s.get(key))
+	s.entries[key] = memoryEntry{state: state, expires: s.now().Add(ttl)}
+	return state, nil
+}
+
+func (s *MemoryStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+func (s *MemoryStore) get(key string) State {
+	e, ok := s.entries[key]
+	if !ok || !s.now().Before(e.expires) {
+		return State{}
+	}
+	return e.state
+}
+
+// sweep drops expired states now and then, so guessing at many emails does
+// not grow the store without bound.
+func (s *MemoryStore) sweep() {
+	now := s.now()
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for k, e := range s.entries {
+		if !now.Before(e.expires) {
+			delete(s.entries, k)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package lockout
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStore(t *testing.T) {
+	now := tStart
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	state, err := s.Get(ctx, "k")
+	assert.Nil(t, err)
+	assert.Zero(t, state)
+
+	state, err = s.Update(ctx, "k", func(s State) (State, time.Duration) {
+		s.Failures++
+		return s, time.Minute
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, state.Failures)
+
+	state, _ = s.Get(ctx, "k")
+	assert.Equal(t, 1, state.Failures)
+
+	now = now.Add(time.Minute)
+	state, _ = s.Get(ctx, "k")
+	assert.Zero(t, state)
+
+	_, _ = s.Update(ctx, "k", func(s State) (State, time.Duration) {
+		return State{Failures: 2}, time.Minute
+	})
+	assert.Nil(t, s.Delete(ctx, "k"))
+	state, _ = s.Get(ctx, "k")
+	assert.Zero(t, state)
+}
+
+func TestMemoryStore_Sweep(t *testing.T) {
+	now := tStart
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for _, k := range []string{"a", "b"} {
+		_, _ = s.Update(ctx, k, func(s State) (State, time.Duration) {
+			return s, time.Second
+		})
+	}
+	assert.Len(t, s.entries, 2)
+
+	now = now.Add(sweepInterval)
+	_, _ = s.Update(ctx, "c", func(s State) (State, time.Duration) {
+		return s, time.Hour
+	})
+	assert.Len(t, s.entries, 1)
+	assert.Contains(t, s.entries, "c")
+}
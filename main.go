@@ -1,28 +1,37 @@
 package main
 
 import (
-	"net/http"
+	"context"
+	"errors"
+	"flag"
 	"os"
+	"os/signal"
+	"syscall"
 
 	log "github.com/sirupsen/logrus"
 
-	"gitea.slauson.io/slausonio/go-prom/sioprom"
 	_ "gitea.slauson.io/slausonio/go-types/siogeneric"
+	"gitea.slauson.io/slausonio/iam-ms/config"
 	_ "gitea.slauson.io/slausonio/iam-ms/docs"
 	"gitea.slauson.io/slausonio/sio-loki/hooks"
 )
 
 func init() {
-	lh := hooks.NewLokiHook(
-		10,
-		5,
-		map[string]string{"app": "iam-ms", "environment": os.Getenv("ENV")},
-	)
 	log.SetFormatter(&log.JSONFormatter{})
 
 	log.SetOutput(os.Stdout)
+}
+
+// initLogging applies the configured level and ships logs to Loki.
+func initLogging(cfg *config.Config) {
+	lh := hooks.NewLokiHook(
+		cfg.Log.LokiBatchSize,
+		cfg.Log.LokiBatchWait,
+		map[string]string{"app": "iam-ms", "environment": cfg.Env},
+	)
 
-	log.SetLevel(log.InfoLevel)
+	level, _ := log.ParseLevel(cfg.Log.Level)
+	log.SetLevel(level)
 
 	log.AddHook(lh)
 }
@@ -34,9 +43,34 @@ func init() {
 // @contact.name Matthew Slauson
 // @contact.email matthew@slauson.io
 func main() {
-	go func() { sioprom.InitPrometheus() }()
-	r := CreateRouter()
-	err := http.ListenAndServe(":8080", r)
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		log.Fatalf("error: %v", err)
+	}
+	initLogging(cfg)
+	log.WithFields(cfg.Fields()).Info("configuration loaded")
+
+	ready := newReadiness(cfg)
+	api, err := listen(cfg, "api", cfg.Server.Addr, CreateRouter(cfg, ready))
+	if err != nil {
+		log.Fatalf("error: %v", err)
+	}
+	metrics, err := listen(cfg, "metrics", cfg.Server.MetricsAddr, metricsHandler())
+	if err != nil {
+		log.Fatalf("error: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	err = serve(ctx, cfg, ready, func(ctx context.Context) {
+		closeWebhooks(ctx, cfg)
+		closeAudit(ctx, cfg)
+	}, api, metrics)
+	flushLogs(cfg)
 	if err != nil {
 		log.Fatalf("error: %v", err)
 	}
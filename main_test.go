@@ -4,9 +4,30 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"gitea.slauson.io/slausonio/iam-ms/config"
 )
 
-func TestMain(t *testing.T) {
+// testConfig loads the configuration the way main does, from the environment
+// TestMain prepared.
+func testConfig(t *testing.T) *config.Config {
+	t.Helper()
+	cfg, err := config.Load(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func testRouter(t *testing.T) *gin.Engine {
+	cfg := testConfig(t)
+	return CreateRouter(cfg, newReadiness(cfg))
+}
+
+func TestCreateRouter(t *testing.T) {
 	// Create a new request to the server
 	req, err := http.NewRequest("GET", "/", nil)
 	if err != nil {
@@ -17,7 +38,7 @@ func TestMain(t *testing.T) {
 	rr := httptest.NewRecorder()
 
 	// Call the CreateRouter function to create the server router
-	router := CreateRouter()
+	router := testRouter(t)
 
 	// Serve the request using the router
 	router.ServeHTTP(rr, req)
@@ -27,3 +48,23 @@ func TestMain(t *testing.T) {
 		t.Errorf("handler returned wrong status code: got %v, want %v", status, http.StatusOK)
 	}
 }
+
+// Without trusted proxies a forwarded client IP is not believed.
+func TestCreateRouter_TrustedProxies(t *testing.T) {
+	cfg := testConfig(t)
+	var clientIP string
+	r := CreateRouter(cfg, newReadiness(cfg))
+	r.GET("/ip", func(c *gin.Context) { clientIP = c.ClientIP() })
+
+	req := httptest.NewRequest("GET", "/ip", nil)
+	req.RemoteAddr = "10.1.2.3:4567"
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, "10.1.2.3", clientIP)
+
+	cfg.Server.TrustedProxies = []string{"10.0.0.0/8"}
+	r = CreateRouter(cfg, newReadiness(cfg))
+	r.GET("/ip", func(c *gin.Context) { clientIP = c.ClientIP() })
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, "203.0.113.7", clientIP)
+}
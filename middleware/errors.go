@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+	"gitea.slauson.io/slausonio/iam-ms/utils"
+)
+
+// ErrorCodes runs just inside siomw.ErrorHandler. For an utils.IamError it
+// sends the error type in the X-Error-Code header and any wait in whole
+// seconds in the Retry-After header, then swaps in the equivalent sioerror,
+// which is what siomw.ErrorHandler renders.
+func ErrorCodes(c *gin.Context) {
+	c.Next()
+
+	for _, e := range c.Errors {
+		var ie *utils.IamError
+		if !errors.As(e.Err, &ie) {
+			continue
+		}
+		if ie.Type != "" {
+			c.Header(constants.HEADER_ERROR_CODE, ie.Type)
+		}
+		if ie.RetryAfter > 0 {
+			c.Header(constants.HEADER_RETRY_AFTER, strconv.Itoa(seconds(ie.RetryAfter)))
+		}
+		e.Err = ie.SioError()
+	}
+}
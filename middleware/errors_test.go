@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"gitea.slauson.io/slausonio/go-utils/sioerror"
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+	"gitea.slauson.io/slausonio/iam-ms/utils"
+)
+
+func TestErrorCodes(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		code       string
+		retryAfter string
+	}{
+		{
+			name: "iam error",
+			err: utils.NewIamError(
+				http.StatusConflict,
+				constants.ERR_TYPE_USER_ALREADY_EXISTS,
+				constants.UserAlreadyExists,
+			),
+			code: constants.ERR_TYPE_USER_ALREADY_EXISTS,
+		},
+		{
+			name: "retry after",
+			err: &utils.IamError{
+				Status:     http.StatusTooManyRequests,
+				Type:       constants.ERR_TYPE_RATE_LIMITED,
+				Message:    constants.LoginLocked,
+				RetryAfter: 1500 * time.Millisecond,
+			},
+			code:       constants.ERR_TYPE_RATE_LIMITED,
+			retryAfter: "2",
+		},
+		{
+			name: "sioerror",
+			err:  sioerror.NewSioBadRequestError("bad"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				w    = httptest.NewRecorder()
+				_, r = gin.CreateTestContext(w)
+				seen error
+			)
+			r.Use(func(c *gin.Context) {
+				c.Next()
+				seen = c.Errors.Last().Err
+			})
+			r.Use(ErrorCodes)
+			r.GET("/", func(c *gin.Context) { _ = c.Error(tt.err) })
+			r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+			assert.Equal(t, tt.code, w.Header().Get(constants.HEADER_ERROR_CODE))
+			assert.Equal(t, tt.retryAfter, w.Header().Get(constants.HEADER_RETRY_AFTER))
+			var ie *utils.IamError
+			assert.False(t, errors.As(seen, &ie), "siomw.ErrorHandler gets an sioerror")
+			assert.Equal(t, tt.err.Error(), seen.Error())
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"gitea.slauson.io/slausonio/iam-ms/config"
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+	"gitea.slauson.io/slausonio/iam-ms/model"
+	"gitea.slauson.io/slausonio/iam-ms/utils"
+)
+
+// rateLimitSweepInterval is how often a limiter drops the buckets of clients
+// that have gone quiet.
+const rateLimitSweepInterval = time.Minute
+
+// rateLimitRejections is registered with the default Prometheus registry,
+// which is what sioprom serves on /metrics.
+var rateLimitRejections = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "iam_rate_limit_rejections_total",
+	Help: "Requests rejected with a 429 by the rate limiter, by route group.",
+}, []string{"group"})
+
+// RateKey names the client a request counts against.
+type RateKey func(c *gin.Context) string
+
+// ByIP counts requests against the client IP.
+func ByIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// BySubject counts requests against the caller resolved by RoleMiddleware,
+// so it must run after it. Anonymous requests count against the client IP.
+func BySubject(c *gin.Context) string {
+	if v, ok := c.Get(constants.CALLER_CONTEXT_KEY); ok {
+		return "sub:" + v.(*model.Caller).ID
+	}
+	return ByIP(c)
+}
+
+// RateLimit admits each client's requests to group through a token bucket of
+// limit, sending the RateLimit headers with every response and rejecting the
+// request with a 429 once the bucket is empty. Where several limits apply to
+// a request, the headers describe whichever has the least remaining.
+func RateLimit(group string, limit config.Limit, key RateKey) gin.HandlerFunc {
+	if limit.Requests <= 0 {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+	return newRateLimiter(group, limit, key).handle
+}
+
+type rateLimiter struct {
+	group string
+	limit config.Limit
+	key   RateKey
+	// interval is how long the bucket takes to regain one request.
+	interval  time.Duration
+	rejected  prometheus.Counter
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// quota is a client's standing after taking a request from its bucket.
+type quota struct {
+	allowed   bool
+	remaining int
+	// reset is how long the bucket takes to fill up again and retry how long
+	// until it holds a request.
+	reset time.Duration
+	retry time.Duration
+}
+
+func newRateLimiter(group string, limit config.Limit, key RateKey) *rateLimiter {
+	return &rateLimiter{
+		group:    group,
+		limit:    limit,
+		key:      key,
+		interval: limit.Period / time.Duration(limit.Requests),
+		rejected: rateLimitRejections.WithLabelValues(group),
+		buckets:  map[string]*bucket{},
+		now:      time.Now,
+	}
+}
+
+func (l *rateLimiter) handle(c *gin.Context) {
+	q := l.take(l.key(c))
+	l.setHeaders(c, q)
+
+	if !q.allowed {
+		l.rejected.Inc()
+		_ = c.Error(&utils.IamError{
+			Status:     http.StatusTooManyRequests,
+			Type:       constants.ERR_TYPE_RATE_LIMITED,
+			Message:    constants.RateLimited,
+			RetryAfter: q.retry,
+		})
+		c.Abort()
+		return
+	}
+	c.Next()
+}
+
+func (l *rateLimiter) take(key string) quota {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.limit.Burst), last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = l.refill(b, now)
+	b.last = now
+
+	q := quota{allowed: b.tokens >= 1}
+	if q.allowed {
+		b.tokens--
+	} else {
+		q.retry = l.wait(1 - b.tokens)
+	}
+	q.remaining = int(b.tokens)
+	q.reset = l.wait(float64(l.limit.Burst) - b.tokens)
+	return q
+}
+
+// refill is what b holds at now.
+func (l *rateLimiter) refill(b *bucket, now time.Time) float64 {
+	tokens := b.tokens + float64(now.Sub(b.last))/float64(l.interval)
+	return math.Min(tokens, float64(l.limit.Burst))
+}
+
+// wait is how long the bucket takes to regain tokens.
+func (l *rateLimiter) wait(tokens float64) time.Duration {
+	return time.Duration(tokens * float64(l.interval))
+}
+
+// sweep drops full buckets now and then, since a client without one is
+// treated the same, so the map does not grow with every client ever seen.
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < rateLimitSweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for k, b := range l.buckets {
+		if l.refill(b, now) >= float64(l.limit.Burst) {
+			delete(l.buckets, k)
+		}
+	}
+}
+
+func (l *rateLimiter) setHeaders(c *gin.Context, q quota) {
+	h := c.Writer.Header()
+	if prev, err := strconv.Atoi(h.Get(constants.HEADER_RATELIMIT_REMAINING)); err == nil &&
+		prev <= q.remaining {
+		return
+	}
+
+	h.Set(constants.HEADER_RATELIMIT_LIMIT, strconv.Itoa(l.limit.Burst))
+	h.Set(constants.HEADER_RATELIMIT_REMAINING, strconv.Itoa(q.remaining))
+	h.Set(constants.HEADER_RATELIMIT_RESET, strconv.Itoa(seconds(q.reset)))
+	h.Set(constants.HEADER_RATELIMIT_POLICY, fmt.Sprintf(
+		"%d;w=%d;burst=%d",
+		l.limit.Requests,
+		seconds(l.limit.Period),
+		l.limit.Burst,
+	))
+}
+
+// seconds rounds d up to whole seconds, as the headers carry them.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"gitea.slauson.io/slausonio/iam-ms/config"
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+	"gitea.slauson.io/slausonio/iam-ms/model"
+	"gitea.slauson.io/slausonio/iam-ms/utils"
+)
+
+var tLimit = config.Limit{Requests: 1, Period: time.Minute, Burst: 2}
+
+// testRateLimiter is a limiter of tLimit whose clock is *now.
+func testRateLimiter(group string, key RateKey) (*rateLimiter, *time.Time) {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	l := newRateLimiter(group, tLimit, key)
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+func rateLimitContext(ip string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/api/iam/v1/user", nil)
+	c.Request.RemoteAddr = ip + ":1234"
+	return c
+}
+
+func assertQuota(t *testing.T, c *gin.Context, remaining string, reset string) {
+	t.Helper()
+	h := c.Writer.Header()
+	assert.Equal(t, "2", h.Get(constants.HEADER_RATELIMIT_LIMIT))
+	assert.Equal(t, remaining, h.Get(constants.HEADER_RATELIMIT_REMAINING))
+	assert.Equal(t, reset, h.Get(constants.HEADER_RATELIMIT_RESET))
+	assert.Equal(t, "1;w=60;burst=2", h.Get(constants.HEADER_RATELIMIT_POLICY))
+}
+
+func TestRateLimit(t *testing.T) {
+	l, now := testRateLimiter("test_bucket", ByIP)
+	rejected := testutil.ToFloat64(l.rejected)
+
+	c := rateLimitContext("10.0.0.1")
+	l.handle(c)
+	assert.False(t, c.IsAborted())
+	assertQuota(t, c, "1", "60")
+
+	c = rateLimitContext("10.0.0.1")
+	l.handle(c)
+	assert.False(t, c.IsAborted())
+	assertQuota(t, c, "0", "120")
+
+	c = rateLimitContext("10.0.0.1")
+	l.handle(c)
+	assert.True(t, c.IsAborted())
+	assertQuota(t, c, "0", "120")
+	var ie *utils.IamError
+	if assert.Len(t, c.Errors, 1) && assert.ErrorAs(t, c.Errors[0], &ie) {
+		assert.Equal(t, http.StatusTooManyRequests, ie.Status)
+		assert.Equal(t, constants.ERR_TYPE_RATE_LIMITED, ie.Type)
+		assert.Equal(t, time.Minute, ie.RetryAfter)
+	}
+	assert.Equal(t, rejected+1, testutil.ToFloat64(l.rejected))
+
+	// Other clients have buckets of their own.
+	c = rateLimitContext("10.0.0.2")
+	l.handle(c)
+	assert.False(t, c.IsAborted())
+
+	*now = now.Add(30 * time.Second)
+	c = rateLimitContext("10.0.0.1")
+	l.handle(c)
+	assert.True(t, c.IsAborted())
+	if assert.ErrorAs(t, c.Errors[0], &ie) {
+		assert.Equal(t, 30*time.Second, ie.RetryAfter)
+	}
+
+	*now = now.Add(30 * time.Second)
+	c = rateLimitContext("10.0.0.1")
+	l.handle(c)
+	assert.False(t, c.IsAborted())
+	assertQuota(t, c, "0", "120")
+	assert.Equal(t, rejected+2, testutil.ToFloat64(l.rejected))
+}
+
+func TestRateLimit_Disabled(t *testing.T) {
+	h := RateLimit("test_disabled", config.Limit{}, ByIP)
+	for i := 0; i < 10; i++ {
+		c := rateLimitContext("10.0.0.1")
+		h(c)
+		assert.False(t, c.IsAborted())
+		assert.Empty(t, c.Writer.Header().Get(constants.HEADER_RATELIMIT_LIMIT))
+	}
+}
+
+func TestRateLimit_LeastRemainingWins(t *testing.T) {
+	loose := RateLimit(
+		"test_loose",
+		config.Limit{Requests: 100, Period: time.Minute, Burst: 100},
+		ByIP,
+	)
+	strict, _ := testRateLimiter("test_strict", ByIP)
+
+	c := rateLimitContext("10.0.0.1")
+	loose(c)
+	strict.handle(c)
+	assertQuota(t, c, "1", "60")
+
+	c = rateLimitContext("10.0.0.1")
+	strict.handle(c)
+	loose(c)
+	assertQuota(t, c, "0", "120")
+}
+
+func TestRateLimit_Sweep(t *testing.T) {
+	l, now := testRateLimiter("test_sweep", ByIP)
+
+	l.handle(rateLimitContext("10.0.0.1"))
+	l.handle(rateLimitContext("10.0.0.2"))
+	l.handle(rateLimitContext("10.0.0.2"))
+	assert.Len(t, l.buckets, 2)
+
+	// 10.0.0.1 has filled up again by now, 10.0.0.2 has not.
+	*now = now.Add(time.Minute)
+	l.handle(rateLimitContext("10.0.0.3"))
+	assert.Len(t, l.buckets, 2)
+	assert.NotContains(t, l.buckets, "ip:10.0.0.1")
+}
+
+func TestBySubject(t *testing.T) {
+	c := rateLimitContext("10.0.0.1")
+	assert.Equal(t, "ip:10.0.0.1", BySubject(c))
+
+	c.Set(constants.CALLER_CONTEXT_KEY, &model.Caller{ID: "a"})
+	assert.Equal(t, "sub:a", BySubject(c))
+	assert.Equal(t, "ip:10.0.0.1", ByIP(c))
+}
@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"gitea.slauson.io/slausonio/iam-ms/config"
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+	"gitea.slauson.io/slausonio/iam-ms/model"
+	"gitea.slauson.io/slausonio/iam-ms/service/mocks"
+)
+
+func initRoleMiddlewareTest(t *testing.T) (*RoleMiddleware, *mocks.IamRoleService) {
+	rs := mocks.NewIamRoleService(t)
+	return &RoleMiddleware{s: rs}, rs
+}
+
+func runMiddleware(h gin.HandlerFunc, jwt string, id string) (*gin.Context, bool) {
+	var (
+		w    = httptest.NewRecorder()
+		c, _ = gin.CreateTestContext(w)
+	)
+	c.Request = httptest.NewRequest("GET", "/api/iam/v1/user/"+id, nil)
+	if jwt != "" {
+		c.Request.Header.Set(constants.AW_HEADER_JWT, jwt)
+	}
+	c.Params = gin.Params{gin.Param{Key: "id", Value: id}}
+
+	h(c)
+	return c, !c.IsAborted()
+}
+
+func TestNewRoleMiddleware(t *testing.T) {
+	assert.NotNil(t, NewRoleMiddleware(config.Default()))
+}
+
+func TestRequireRole(t *testing.T) {
+	tests := []struct {
+		name      string
+		jwt       string
+		caller    *model.Caller
+		callerErr error
+		allowed   bool
+	}{
+		{
+			name:    "admin allowed",
+			jwt:     "jwt",
+			caller:  &model.Caller{ID: "a", Roles: []string{constants.ROLE_ADMIN}},
+			allowed: true,
+		},
+		{
+			name:    "reader forbidden",
+			jwt:     "jwt",
+			caller:  &model.Caller{ID: "a", Roles: []string{constants.ROLE_READER}},
+			allowed: false,
+		},
+		{name: "missing jwt", jwt: "", allowed: false},
+		{name: "invalid jwt", jwt: "jwt", callerErr: errors.New("asdf"), allowed: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, rs := initRoleMiddlewareTest(t)
+			if tt.jwt != "" {
+				rs.On("GetCaller", mock.Anything, tt.jwt).Return(tt.caller, tt.callerErr)
+			}
+
+			c, allowed := runMiddleware(m.RequireRole(constants.ROLE_ADMIN), tt.jwt, "b")
+			assert.Equal(t, tt.allowed, allowed)
+			if tt.allowed {
+				assert.Truef(t, c.Errors == nil, "c.Errors should be nil")
+				caller, _ := c.Get(constants.CALLER_CONTEXT_KEY)
+				assert.Equal(t, tt.caller, caller)
+			} else {
+				assert.Truef(t, c.Errors != nil, "c.Errors shouldnt be nil")
+			}
+		})
+	}
+}
+
+func TestRequireCaller(t *testing.T) {
+	m, rs := initRoleMiddlewareTest(t)
+	rs.On("GetCaller", mock.Anything, "jwt").Return(&model.Caller{ID: "a"}, nil)
+
+	c, allowed := runMiddleware(m.RequireCaller(), "jwt", "")
+	assert.True(t, allowed)
+	caller, _ := c.Get(constants.CALLER_CONTEXT_KEY)
+	assert.Equal(t, &model.Caller{ID: "a"}, caller)
+
+	_, allowed = runMiddleware(m.RequireCaller(), "", "")
+	assert.False(t, allowed)
+}
+
+func TestRequireSelfOrRole(t *testing.T) {
+	tests := []struct {
+		name    string
+		caller  *model.Caller
+		id      string
+		allowed bool
+	}{
+		{name: "self allowed", caller: &model.Caller{ID: "a"}, id: "a", allowed: true},
+		{
+			name:    "admin allowed",
+			caller:  &model.Caller{ID: "a", Roles: []string{constants.ROLE_ADMIN}},
+			id:      "b",
+			allowed: true,
+		},
+		{
+			name:    "other user forbidden",
+			caller:  &model.Caller{ID: "a", Roles: []string{constants.ROLE_AUTHOR}},
+			id:      "b",
+			allowed: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, rs := initRoleMiddlewareTest(t)
+			rs.On("GetCaller", mock.Anything, "jwt").Return(tt.caller, nil)
+
+			c, allowed := runMiddleware(m.RequireSelfOrRole(constants.ROLE_ADMIN), "jwt", tt.id)
+			assert.Equal(t, tt.allowed, allowed)
+			if !tt.allowed {
+				assert.Truef(t, c.Errors != nil, "c.Errors shouldnt be nil")
+			}
+		})
+	}
+}
+
+func TestResolveCallerCached(t *testing.T) {
+	m, _ := initRoleMiddlewareTest(t)
+
+	var (
+		w    = httptest.NewRecorder()
+		c, _ = gin.CreateTestContext(w)
+	)
+	c.Request = &http.Request{Header: make(http.Header)}
+	cached := &model.Caller{ID: "a"}
+	c.Set(constants.CALLER_CONTEXT_KEY, cached)
+
+	caller, ok := m.resolveCaller(c)
+	assert.True(t, ok)
+	assert.Equal(t, cached, caller)
+}
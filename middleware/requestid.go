@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+	"gitea.slauson.io/slausonio/iam-ms/utils"
+)
+
+// RequestID gives every request an ID, keeping the X-Request-ID the caller
+// sent unless it is overlong, so one request can be followed across services.
+// The ID is echoed in the response and stored on the context under
+// constants.REQUEST_ID_CONTEXT_KEY.
+func RequestID(c *gin.Context) {
+	id := c.GetHeader(constants.HEADER_REQUEST_ID)
+	if id == "" || len(id) > constants.MAX_REQUEST_ID_CHARS {
+		var err error
+		if id, err = utils.NewID(); err != nil {
+			_ = c.Error(err)
+			c.Abort()
+			return
+		}
+	}
+
+	c.Set(constants.REQUEST_ID_CONTEXT_KEY, id)
+	c.Header(constants.HEADER_REQUEST_ID, id)
+	c.Next()
+}
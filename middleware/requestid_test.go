@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		generate bool
+	}{
+		{name: "From Caller", header: "req-1"},
+		{name: "Missing", generate: true},
+		{name: "Overlong", header: strings.Repeat("a", 129), generate: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				w       = httptest.NewRecorder()
+				_, r    = gin.CreateTestContext(w)
+				seen    string
+				request = httptest.NewRequest("GET", "/api/iam/v1/user", nil)
+			)
+			if tt.header != "" {
+				request.Header.Set(constants.HEADER_REQUEST_ID, tt.header)
+			}
+			r.Use(RequestID)
+			r.GET("/api/iam/v1/user", func(c *gin.Context) {
+				seen = c.GetString(constants.REQUEST_ID_CONTEXT_KEY)
+				c.Status(http.StatusOK)
+			})
+			r.ServeHTTP(w, request)
+
+			assert.Equal(t, seen, w.Header().Get(constants.HEADER_REQUEST_ID))
+			if tt.generate {
+				assert.Len(t, seen, 32)
+			} else {
+				assert.Equal(t, tt.header, seen)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeout(t *testing.T) {
+	var (
+		w       = httptest.NewRecorder()
+		_, r    = gin.CreateTestContext(w)
+		hasDl   bool
+		dl      time.Time
+		request = httptest.NewRequest("GET", "/api/iam/v1/user", nil)
+	)
+	r.Use(Timeout(time.Minute))
+	r.GET("/api/iam/v1/user", func(c *gin.Context) {
+		dl, hasDl = c.Request.Context().Deadline()
+		c.Status(http.StatusOK)
+	})
+	r.ServeHTTP(w, request)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, hasDl)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), dl, time.Second)
+}
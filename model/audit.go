@@ -0,0 +1,52 @@
+package model
+
+import "time"
+
+// AuditEvent records an attempt to change an identity, successful or not.
+type AuditEvent struct {
+	ID     string     `json:"id"`
+	Time   time.Time  `json:"time"`
+	Action string     `json:"action" example:"user.update_email"`
+	Actor  AuditActor `json:"actor"`
+	// TargetID is the user the change was made to, when known.
+	TargetID string `json:"targetId,omitempty"`
+	// Changes are the user's fields that changed, keyed by JSON name. Prefs
+	// are keyed prefs.<key>.
+	Changes map[string]AuditChange `json:"changes,omitempty"`
+	// Meta holds action specific details, e.g. the session ID of a logout.
+	Meta      map[string]string `json:"meta,omitempty"`
+	ClientIP  string            `json:"clientIp"`
+	RequestID string            `json:"requestId"`
+	Outcome   string            `json:"outcome" example:"success"`
+	Status    int               `json:"status" example:"200"`
+	Error     string            `json:"error,omitempty"`
+}
+
+// AuditActor is who made a change: an end user resolved from their session,
+// or the calling service on routes that do not resolve one.
+type AuditActor struct {
+	Type  string   `json:"type" example:"user"`
+	ID    string   `json:"id,omitempty"`
+	Roles []string `json:"roles,omitempty"`
+}
+
+type AuditChange struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// AuditQueryParams filters the audit log. Zero values mean the filter was not
+// supplied.
+type AuditQueryParams struct {
+	Actor   string `form:"actor"   json:"actor,omitempty"`
+	Target  string `form:"target"  json:"target,omitempty"`
+	Action  string `form:"action"  json:"action,omitempty"`
+	Outcome string `form:"outcome" json:"outcome,omitempty"`
+	Since   string `form:"since"   json:"since,omitempty"`
+	Limit   int    `form:"limit"   json:"limit,omitempty"`
+}
+
+// AuditEventList is the matching events, newest first.
+type AuditEventList struct {
+	Events []AuditEvent `json:"events"`
+}
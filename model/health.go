@@ -0,0 +1,8 @@
+package model
+
+// AwHealthStatus is Appwrite's response for GET /health.
+type AwHealthStatus struct {
+	Name   string `json:"name"`
+	Ping   int    `json:"ping"`
+	Status string `json:"status"`
+}
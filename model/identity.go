@@ -0,0 +1,62 @@
+package model
+
+// User is the provider-neutral view of an account. Its JSON keeps the
+// siogeneric.AwUser contract existing consumers decode, without password
+// hashes.
+type User struct {
+	ID                string `json:"$id"`
+	CreatedAt         string `json:"$createdAt"`
+	UpdatedAt         string `json:"$updatedAt"`
+	Name              string `json:"name"`
+	Email             string `json:"email"`
+	Phone             string `json:"phone"`
+	Status            bool   `json:"status"`
+	Registration      string `json:"registration"`
+	PasswordUpdate    string `json:"passwordUpdate"`
+	EmailVerification bool   `json:"emailVerification"`
+	PhoneVerification bool   `json:"phoneVerification"`
+	Prefs             Prefs  `json:"prefs"`
+}
+
+type UserList struct {
+	Total int    `json:"total"`
+	Users []User `json:"users"`
+}
+
+// NewUser holds what an identity provider needs to create an account.
+type NewUser struct {
+	ID       string
+	Email    string
+	Phone    string
+	Password string
+	Name     string
+}
+
+// UserLabels is a user's ID along with their labels, which carry roles.
+type UserLabels struct {
+	ID     string
+	Labels []string
+}
+
+// Session is the provider-neutral view of a session. Its JSON keeps the
+// siogeneric.AwSession contract, without provider tokens.
+type Session struct {
+	ID            string `json:"$id"`
+	CreatedAt     string `json:"$createdAt"`
+	UserID        string `json:"userId"`
+	Expire        string `json:"expire"`
+	Current       bool   `json:"current"`
+	Provider      string `json:"provider"`
+	ClientName    string `json:"clientName"`
+	ClientVersion string `json:"clientVersion"`
+	DeviceName    string `json:"deviceName"`
+	DeviceBrand   string `json:"deviceBrand"`
+	DeviceModel   string `json:"deviceModel"`
+	OsName        string `json:"osName"`
+	OsVersion     string `json:"osVersion"`
+	Ip            string `json:"ip"`
+	CountryCode   string `json:"countryCode"`
+	CountryName   string `json:"countryName"`
+	// Secret is the session token, when the provider hands one out.
+	Secret string `json:"secret,omitempty"`
+}
@@ -0,0 +1,55 @@
+package model
+
+// KcUser is Keycloak's UserRepresentation.
+type KcUser struct {
+	ID               string              `json:"id,omitempty"`
+	CreatedTimestamp int64               `json:"createdTimestamp,omitempty"`
+	Username         string              `json:"username,omitempty"`
+	Enabled          bool                `json:"enabled"`
+	EmailVerified    bool                `json:"emailVerified"`
+	FirstName        string              `json:"firstName"`
+	LastName         string              `json:"lastName"`
+	Email            string              `json:"email,omitempty"`
+	Attributes       map[string][]string `json:"attributes,omitempty"`
+	Credentials      []KcCredential      `json:"credentials,omitempty"`
+}
+
+type KcCredential struct {
+	Type      string `json:"type"`
+	Value     string `json:"value"`
+	Temporary bool   `json:"temporary"`
+}
+
+// KcRole is Keycloak's RoleRepresentation. Realm roles stand in for labels.
+type KcRole struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// KcSession is Keycloak's UserSessionRepresentation. Times are epoch millis.
+type KcSession struct {
+	ID         string            `json:"id"`
+	UserID     string            `json:"userId"`
+	IPAddress  string            `json:"ipAddress"`
+	Start      int64             `json:"start"`
+	LastAccess int64             `json:"lastAccess"`
+	Clients    map[string]string `json:"clients"`
+}
+
+// KcToken is the OpenID Connect token endpoint response.
+type KcToken struct {
+	AccessToken  string `json:"access_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	SessionState string `json:"session_state"`
+}
+
+type KcUserInfo struct {
+	Sub string `json:"sub"`
+}
+
+// KcError covers both the admin API and the OpenID Connect error shapes.
+type KcError struct {
+	Error            string `json:"error"`
+	ErrorMessage     string `json:"errorMessage"`
+	ErrorDescription string `json:"error_description"`
+}
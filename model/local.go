@@ -0,0 +1,25 @@
+package model
+
+// LocalUser is how the embedded local store persists a user.
+type LocalUser struct {
+	User
+	// Seq orders users by creation for listing and cursors.
+	Seq          uint64   `json:"seq"`
+	PasswordHash string   `json:"passwordHash"`
+	Labels       []string `json:"labels"`
+}
+
+// LocalSession is how the embedded local store persists a session. Only a
+// hash of the session token is kept.
+type LocalSession struct {
+	Session
+	TokenHash string `json:"tokenHash"`
+}
+
+// LocalSecret is a one time recovery or verification secret issued by the
+// embedded local store.
+type LocalSecret struct {
+	UserID string `json:"userId"`
+	Kind   string `json:"kind"`
+	Expire string `json:"expire"`
+}
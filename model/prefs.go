@@ -0,0 +1,15 @@
+package model
+
+// Prefs are the free form per-user settings stored with the user in the
+// identity provider, e.g. theme or newsletter opt-in.
+type Prefs map[string]any
+
+// UpdatePrefsRequest is merged into the user's existing prefs. Only the keys
+// provided are written and a null value removes the key.
+type UpdatePrefsRequest struct {
+	Prefs Prefs `json:"prefs" binding:"required"`
+}
+
+type AwUpdatePrefsRequest struct {
+	Prefs Prefs `json:"prefs"`
+}
@@ -0,0 +1,36 @@
+package model
+
+// PasswordRecoveryRequest starts the forgot password flow for an email.
+type PasswordRecoveryRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+// PasswordRecoveryConfirmRequest completes the forgot password flow using the
+// userId and secret from the recovery link.
+type PasswordRecoveryConfirmRequest struct {
+	UserID   string `json:"userId"   binding:"required"`
+	Secret   string `json:"secret"   binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type AwRecoveryRequest struct {
+	Email string `json:"email"`
+	URL   string `json:"url"`
+}
+
+type AwRecoveryConfirmRequest struct {
+	UserID        string `json:"userId"`
+	Secret        string `json:"secret"`
+	Password      string `json:"password"`
+	PasswordAgain string `json:"passwordAgain"`
+}
+
+// AwToken is Appwrite's token object returned by the recovery and
+// verification endpoints.
+type AwToken struct {
+	ID        string `json:"$id"`
+	CreatedAt string `json:"$createdAt"`
+	UserID    string `json:"userId"`
+	Secret    string `json:"secret"`
+	Expire    string `json:"expire"`
+}
@@ -0,0 +1,38 @@
+package model
+
+// AwUserLabels is the subset of an Appwrite user or account carrying labels,
+// which siogeneric.AwUser does not expose.
+type AwUserLabels struct {
+	ID     string   `json:"$id"`
+	Labels []string `json:"labels"`
+}
+
+type AwUpdateLabelsRequest struct {
+	Labels []string `json:"labels"`
+}
+
+// Caller is the end user making a request, resolved from their session.
+type Caller struct {
+	ID    string   `json:"id"`
+	Roles []string `json:"roles"`
+}
+
+func (c *Caller) HasRole(roles ...string) bool {
+	for _, want := range roles {
+		for _, have := range c.Roles {
+			if want == have {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type UpdateRolesRequest struct {
+	Roles []string `json:"roles" binding:"required"`
+}
+
+type RolesResponse struct {
+	ID    string   `json:"id"`
+	Roles []string `json:"roles"`
+}
@@ -0,0 +1,61 @@
+package model
+
+import "gitea.slauson.io/slausonio/go-types/siogeneric"
+
+// AwSessionList is Appwrite's response for a user's sessions.
+type AwSessionList struct {
+	Total    int                    `json:"total"`
+	Sessions []siogeneric.AwSession `json:"sessions"`
+}
+
+// SessionSummary is the support facing view of a session. Provider tokens are
+// deliberately left out.
+type SessionSummary struct {
+	ID            string `json:"id"`
+	CreatedAt     string `json:"createdAt"`
+	Expire        string `json:"expire"`
+	Current       bool   `json:"current"`
+	Provider      string `json:"provider"`
+	ClientName    string `json:"clientName"`
+	ClientVersion string `json:"clientVersion"`
+	DeviceName    string `json:"deviceName"`
+	DeviceBrand   string `json:"deviceBrand"`
+	DeviceModel   string `json:"deviceModel"`
+	OsName        string `json:"osName"`
+	OsVersion     string `json:"osVersion"`
+	Ip            string `json:"ip"`
+	CountryCode   string `json:"countryCode"`
+	CountryName   string `json:"countryName"`
+}
+
+type SessionListResponse struct {
+	Total    int              `json:"total"`
+	Sessions []SessionSummary `json:"sessions"`
+}
+
+func NewSessionSummary(s *Session) SessionSummary {
+	return SessionSummary{
+		ID:            s.ID,
+		CreatedAt:     s.CreatedAt,
+		Expire:        s.Expire,
+		Current:       s.Current,
+		Provider:      s.Provider,
+		ClientName:    s.ClientName,
+		ClientVersion: s.ClientVersion,
+		DeviceName:    s.DeviceName,
+		DeviceBrand:   s.DeviceBrand,
+		DeviceModel:   s.DeviceModel,
+		OsName:        s.OsName,
+		OsVersion:     s.OsVersion,
+		Ip:            s.Ip,
+		CountryCode:   s.CountryCode,
+		CountryName:   s.CountryName,
+	}
+}
+
+// ClearLockoutParams names the email, client IP or both whose login lockout
+// an admin lifts.
+type ClearLockoutParams struct {
+	Email string `form:"email" json:"email,omitempty"`
+	IP    string `form:"ip"    json:"ip,omitempty"`
+}
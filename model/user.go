@@ -0,0 +1,52 @@
+package model
+
+// ListUsersParams holds the paging, search and filter options accepted by the
+// list users endpoint. Zero values mean the option was not supplied.
+type ListUsersParams struct {
+	Limit        int    `form:"limit"        json:"limit,omitempty"`
+	Offset       int    `form:"offset"       json:"offset,omitempty"`
+	Cursor       string `form:"cursor"       json:"cursor,omitempty"`
+	Search       string `form:"search"       json:"search,omitempty"`
+	Email        string `form:"email"        json:"email,omitempty"`
+	Phone        string `form:"phone"        json:"phone,omitempty"`
+	Name         string `form:"name"         json:"name,omitempty"`
+	Status       *bool  `form:"status"       json:"status,omitempty"`
+	CreatedAfter string `form:"createdAfter" json:"createdAfter,omitempty"`
+}
+
+// UserListResponse is a page of users along with the information needed to
+// request the next page.
+type UserListResponse struct {
+	Total      int    `json:"total"`
+	Users      []User `json:"users"`
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// UpdateOwnPasswordRequest is a self-service password change, which must
+// prove knowledge of the current password.
+type UpdateOwnPasswordRequest struct {
+	OldPassword string `json:"oldPassword" binding:"required"`
+	Password    string `json:"password"    binding:"required"`
+}
+
+// UpdateOwnEmailRequest is a self-service email change, which must prove
+// knowledge of the current password.
+type UpdateOwnEmailRequest struct {
+	OldPassword string `json:"oldPassword" binding:"required"`
+	Email       string `json:"email"       binding:"required"`
+}
+
+type UpdateNameRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// UpdateStatusRequest enables (true) or blocks (false) a user.
+type UpdateStatusRequest struct {
+	Status *bool `json:"status" binding:"required"`
+}
+
+type AwUpdateStatusRequest struct {
+	Status bool `json:"status"`
+}
@@ -0,0 +1,27 @@
+package model
+
+// VerificationConfirmRequest completes an email or phone verification using
+// the userId and secret from the link or SMS code.
+type VerificationConfirmRequest struct {
+	UserID string `json:"userId" binding:"required"`
+	Secret string `json:"secret" binding:"required"`
+}
+
+// VerificationStatusRequest lets an admin mark a user's email and/or phone as
+// verified or unverified. Nil fields are left unchanged.
+type VerificationStatusRequest struct {
+	Email *bool `json:"email"`
+	Phone *bool `json:"phone"`
+}
+
+type AwVerificationRequest struct {
+	URL string `json:"url"`
+}
+
+type AwEmailVerificationStatusRequest struct {
+	EmailVerification bool `json:"emailVerification"`
+}
+
+type AwPhoneVerificationStatusRequest struct {
+	PhoneVerification bool `json:"phoneVerification"`
+}
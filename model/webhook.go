@@ -0,0 +1,110 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// WebhookEvent is the body POSTed to subscribers of its type.
+type WebhookEvent struct {
+	ID   string      `json:"id"`
+	Type string      `json:"type"`
+	Time time.Time   `json:"time"`
+	User WebhookUser `json:"user"`
+}
+
+// WebhookUser is the user an event is about as it was after the change. Only
+// the ID is set for a deleted user.
+type WebhookUser struct {
+	ID     string `json:"id"`
+	Name   string `json:"name,omitempty"`
+	Email  string `json:"email,omitempty"`
+	Phone  string `json:"phone,omitempty"`
+	Status *bool  `json:"status,omitempty"`
+}
+
+func NewWebhookUser(u *User) WebhookUser {
+	status := u.Status
+	return WebhookUser{
+		ID:     u.ID,
+		Name:   u.Name,
+		Email:  u.Email,
+		Phone:  u.Phone,
+		Status: &status,
+	}
+}
+
+// WebhookSubscription is a URL that is sent events of Events, or of every type
+// when Events is empty. Secret keys the payload signatures; it is only
+// returned when the subscription is created.
+type WebhookSubscription struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Matches reports whether events of eventType go to s.
+func (s *WebhookSubscription) Matches(eventType string) bool {
+	if len(s.Events) == 0 {
+		return true
+	}
+	for _, e := range s.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+type CreateWebhookSubscriptionRequest struct {
+	URL    string   `json:"url" binding:"required"`
+	Events []string `json:"events"`
+}
+
+type WebhookSubscriptionList struct {
+	Subscriptions []WebhookSubscription `json:"subscriptions"`
+}
+
+// WebhookDelivery is one event on its way to one subscription, and what
+// became of the attempts to send it so far.
+type WebhookDelivery struct {
+	ID             string          `json:"id"`
+	SubscriptionID string          `json:"subscriptionId"`
+	URL            string          `json:"url"`
+	EventID        string          `json:"eventId"`
+	EventType      string          `json:"eventType"`
+	Payload        json.RawMessage `json:"payload" swaggertype:"object"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	// NextAttempt is when a pending delivery is next tried.
+	NextAttempt time.Time `json:"nextAttempt"`
+	// LastStatus is the HTTP status the last attempt got, or 0 when it got
+	// none; LastError says why it failed.
+	LastStatus int       `json:"lastStatus,omitempty"`
+	LastError  string    `json:"lastError,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// WebhookDeliveryParams filters the delivery history. Zero values mean the
+// filter was not supplied.
+type WebhookDeliveryParams struct {
+	Subscription string `form:"subscription" json:"subscription,omitempty"`
+	Event        string `form:"event"        json:"event,omitempty"`
+	Status       string `form:"status"       json:"status,omitempty"`
+	Limit        int    `form:"limit"        json:"limit,omitempty"`
+}
+
+// Matches reports whether d passes the filters.
+func (p *WebhookDeliveryParams) Matches(d *WebhookDelivery) bool {
+	return (p.Subscription == "" || p.Subscription == d.SubscriptionID) &&
+		(p.Event == "" || p.Event == d.EventType) &&
+		(p.Status == "" || p.Status == d.Status)
+}
+
+// WebhookDeliveryList is the matching deliveries, newest first.
+type WebhookDeliveryList struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+}
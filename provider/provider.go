@@ -0,0 +1,88 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"gitea.slauson.io/slausonio/iam-ms/client"
+	"gitea.slauson.io/slausonio/iam-ms/config"
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+	"gitea.slauson.io/slausonio/iam-ms/model"
+)
+
+// IdentityProvider is the backend that owns users and sessions. Services only
+// depend on this interface, so backends can be swapped through iam.provider
+// without touching the services or controllers.
+//
+//go:generate mockery --name IdentityProvider
+type IdentityProvider interface {
+	ListUsers(ctx context.Context, p *model.ListUsersParams) (*model.UserList, error)
+	GetUserByID(ctx context.Context, id string) (*model.User, error)
+	CreateUser(ctx context.Context, u *model.NewUser) (*model.User, error)
+	UpdateEmail(ctx context.Context, id string, email string) (*model.User, error)
+	UpdatePhone(ctx context.Context, id string, number string) (*model.User, error)
+	UpdatePassword(ctx context.Context, id string, password string) (*model.User, error)
+	UpdateName(ctx context.Context, id string, name string) (*model.User, error)
+	UpdateStatus(ctx context.Context, id string, status bool) (*model.User, error)
+	UpdateEmailVerification(ctx context.Context, id string, verified bool) (*model.User, error)
+	UpdatePhoneVerification(ctx context.Context, id string, verified bool) (*model.User, error)
+	GetPrefs(ctx context.Context, id string) (model.Prefs, error)
+	UpdatePrefs(ctx context.Context, id string, prefs model.Prefs) (model.Prefs, error)
+	GetLabels(ctx context.Context, id string) (*model.UserLabels, error)
+	UpdateLabels(ctx context.Context, id string, labels []string) (*model.UserLabels, error)
+	GetSessionUser(ctx context.Context, token string) (*model.UserLabels, error)
+	DeleteUser(ctx context.Context, id string) error
+	CreateRecovery(ctx context.Context, email string) error
+	ConfirmRecovery(ctx context.Context, userID string, secret string, password string) error
+	SendEmailVerification(ctx context.Context, token string) error
+	ConfirmEmailVerification(ctx context.Context, userID string, secret string) error
+	SendPhoneVerification(ctx context.Context, token string) error
+	ConfirmPhoneVerification(ctx context.Context, userID string, secret string) error
+	CreateEmailSession(ctx context.Context, email string, password string) (*model.Session, error)
+	ListSessions(ctx context.Context, id string) ([]model.Session, error)
+	DeleteSession(ctx context.Context, id string, sessionID string) error
+	DeleteSessions(ctx context.Context, id string) error
+	// Ping checks the provider is reachable and accepts the service's
+	// credentials.
+	Ping(ctx context.Context) error
+}
+
+var (
+	defaultOnce     sync.Once
+	defaultProvider IdentityProvider
+)
+
+// New builds the identity provider selected by cfg.IAM.Provider. An empty
+// name selects Appwrite.
+func New(cfg *config.Config) (IdentityProvider, error) {
+	switch cfg.IAM.Provider {
+	case "", constants.PROVIDER_APPWRITE:
+		return client.NewAwProvider(cfg), nil
+	case constants.PROVIDER_KEYCLOAK:
+		return client.NewKcClient(cfg), nil
+	case constants.PROVIDER_LOCAL:
+		s, err := client.NewLocalStore(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	default:
+		return nil, fmt.Errorf("unknown identity provider %q", cfg.IAM.Provider)
+	}
+}
+
+// Default returns the process wide identity provider. It is built once, from
+// the first cfg it is given, so every service shares it.
+func Default(cfg *config.Config) IdentityProvider {
+	defaultOnce.Do(func() {
+		p, err := New(cfg)
+		if err != nil {
+			log.Fatalf("error: %v", err)
+		}
+		defaultProvider = p
+	})
+	return defaultProvider
+}
@@ -0,0 +1,51 @@
+package provider
+
+import (
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"gitea.slauson.io/slausonio/iam-ms/client"
+	"gitea.slauson.io/slausonio/iam-ms/config"
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    IdentityProvider
+		wantErr bool
+	}{
+		{name: "", want: &client.AwProvider{}},
+		{name: constants.PROVIDER_APPWRITE, want: &client.AwProvider{}},
+		{name: constants.PROVIDER_KEYCLOAK, want: &client.KcClient{}},
+		{name: constants.PROVIDER_LOCAL, want: &client.LocalStore{}},
+		{name: "okta", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.IAM.Provider = tt.name
+			cfg.Local.DB = filepath.Join(t.TempDir(), "iam.db")
+
+			p, err := New(cfg)
+			if tt.wantErr {
+				assert.Nil(t, p)
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.IsType(t, tt.want, p)
+			if c, ok := p.(io.Closer); ok {
+				assert.Nil(t, c.Close())
+			}
+		})
+	}
+}
+
+func TestDefault(t *testing.T) {
+	cfg := config.Default()
+	assert.Same(t, Default(cfg), Default(cfg))
+}
@@ -4,20 +4,55 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 
 	"gitea.slauson.io/slausonio/go-utils/siomw"
+	"gitea.slauson.io/slausonio/iam-ms/config"
+	"gitea.slauson.io/slausonio/iam-ms/constants"
 	"gitea.slauson.io/slausonio/iam-ms/controller"
+	"gitea.slauson.io/slausonio/iam-ms/health"
+	"gitea.slauson.io/slausonio/iam-ms/middleware"
 )
 
-func CreateRouter() *gin.Engine {
+func CreateRouter(cfg *config.Config, ready *health.Readiness) *gin.Engine {
 	r := gin.Default()
+	// Only the configured proxies may set the client IP that rate limits and
+	// lockouts count by; with none it is the remote address.
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Fatalf("error: %v", err)
+	}
+	r.Use(middleware.RequestID)
 	r.Use(siomw.PrometheusMiddleware())
 	r.Use(siomw.ErrorHandler)
+	r.Use(middleware.ErrorCodes)
+	r.Use(middleware.Timeout(cfg.Server.RequestTimeout))
+
+	uc := controller.NewUserController(cfg)
+	sc := controller.NewSessionController(cfg)
+	rc := controller.NewRoleController(cfg)
+	mc := controller.NewMeController(cfg)
+	ac := controller.NewAuditController(cfg)
+	wc := controller.NewWebhookController(cfg)
+	rm := middleware.NewRoleMiddleware(cfg)
+
+	admin := rm.RequireRole(constants.ROLE_ADMIN)
+	selfOrAdmin := rm.RequireSelfOrRole(constants.ROLE_ADMIN)
 
-	uc := controller.NewUserController()
-	sc := controller.NewSessionController()
+	limits := cfg.RateLimit
+	signup := middleware.RateLimit(
+		constants.RATE_LIMIT_GROUP_SIGNUP,
+		limits.Signup,
+		middleware.ByIP,
+	)
+	login := middleware.RateLimit(constants.RATE_LIMIT_GROUP_LOGIN, limits.Login, middleware.ByIP)
+	// Password routes run it after the role middleware has resolved the caller.
+	password := middleware.RateLimit(
+		constants.RATE_LIMIT_GROUP_PASSWORD,
+		limits.Password,
+		middleware.BySubject,
+	)
 
 	r.GET("/", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "ok"})
@@ -27,23 +62,77 @@ func CreateRouter() *gin.Engine {
 		c.JSON(http.StatusOK, gin.H{"status": "ok"})
 	})
 
-	v1 := r.Group("/api/iam/v1", siomw.AuthMiddleware)
+	r.GET("/healthz", health.Live)
+	r.GET("/readyz", ready.Ready)
+
+	v1 := r.Group(
+		"/api/iam/v1",
+		siomw.AuthMiddleware,
+		middleware.RateLimit(constants.RATE_LIMIT_GROUP_DEFAULT, limits.Default, middleware.ByIP),
+	)
 	{
+		me := v1.Group("/me", rm.RequireCaller())
+		{
+			me.GET("", mc.GetMe)
+			me.PUT("/password", password, mc.UpdateMyPassword)
+			me.PUT("/email", password, mc.UpdateMyEmail)
+			me.PUT("/phone", mc.UpdateMyPhone)
+			me.PUT("/name", mc.UpdateMyName)
+			me.GET("/sessions", mc.ListMySessions)
+			me.DELETE("/sessions", mc.DeleteMySessions)
+			me.DELETE("/sessions/:sessionId", mc.DeleteMySession)
+		}
+
 		user := v1.Group("/user")
 		{
-			user.GET("", uc.ListUsers)
-			user.POST("", uc.CreateUser)
-			user.GET("/:id", uc.GetUserById)
-			user.PUT("/:id/password", uc.UpdatePassword)
-			user.PUT("/:id/email", uc.UpdateEmail)
-			user.PUT("/:id/phone", uc.UpdatePhone)
-			user.DELETE("/:id", uc.DeleteUser)
+			user.GET("", admin, uc.ListUsers)
+			user.POST("", signup, uc.CreateUser)
+			user.GET("/:id", selfOrAdmin, uc.GetUserById)
+			// Without the old password these are for admins; users go through /me.
+			user.PUT("/:id/password", admin, password, uc.UpdatePassword)
+			user.PUT("/:id/email", admin, uc.UpdateEmail)
+			user.PUT("/:id/phone", selfOrAdmin, uc.UpdatePhone)
+			user.PUT("/:id/name", selfOrAdmin, uc.UpdateName)
+			user.PUT("/:id/verification", admin, uc.UpdateVerification)
+			user.PUT("/:id/status", admin, uc.UpdateStatus)
+			user.GET("/:id/prefs", selfOrAdmin, uc.GetPrefs)
+			user.PATCH("/:id/prefs", selfOrAdmin, uc.UpdatePrefs)
+			user.GET("/:id/roles", selfOrAdmin, rc.GetRoles)
+			user.PUT("/:id/roles", admin, rc.UpdateRoles)
+			user.DELETE("/:id", admin, uc.DeleteUser)
+		}
+
+		recovery := v1.Group("/recovery", password)
+		{
+			recovery.POST("", uc.CreatePasswordRecovery)
+			recovery.PUT("", uc.ConfirmPasswordRecovery)
+		}
+
+		verification := v1.Group("/verification")
+		{
+			verification.POST("/email", uc.SendEmailVerification)
+			verification.PUT("/email", uc.ConfirmEmailVerification)
+			verification.POST("/phone", uc.SendPhoneVerification)
+			verification.PUT("/phone", uc.ConfirmPhoneVerification)
+		}
+
+		v1.GET("/audit", admin, ac.ListEvents)
+		v1.DELETE("/lockout", admin, sc.ClearLockout)
+
+		webhooks := v1.Group("/webhooks", admin)
+		{
+			webhooks.GET("", wc.ListSubscriptions)
+			webhooks.POST("", wc.CreateSubscription)
+			webhooks.GET("/deliveries", wc.ListDeliveries)
+			webhooks.DELETE("/:id", wc.DeleteSubscription)
 		}
 
 		session := v1.Group("/session")
 		{
-			session.POST("/email", sc.CreateEmailSession)
-			session.DELETE("/:id/:sessionId", sc.DeleteSession)
+			session.POST("/email", login, sc.CreateEmailSession)
+			session.GET("/:id", selfOrAdmin, sc.ListSessions)
+			session.DELETE("/:id", selfOrAdmin, sc.DeleteSessions)
+			session.DELETE("/:id/:sessionId", selfOrAdmin, sc.DeleteSession)
 		}
 	}
 
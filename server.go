@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
+
+	"gitea.slauson.io/slausonio/iam-ms/audit"
+	"gitea.slauson.io/slausonio/iam-ms/config"
+	"gitea.slauson.io/slausonio/iam-ms/health"
+	"gitea.slauson.io/slausonio/iam-ms/provider"
+	"gitea.slauson.io/slausonio/iam-ms/webhook"
+)
+
+// endpoint is a server and the listener it serves on.
+type endpoint struct {
+	name string
+	srv  *http.Server
+	ln   net.Listener
+}
+
+// listen binds addr for h with the configured server timeouts, so a taken port
+// fails startup before anything is served.
+func listen(cfg *config.Config, name string, addr string, h http.Handler) (*endpoint, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &endpoint{
+		name: name,
+		srv: &http.Server{
+			Handler:      h,
+			ReadTimeout:  cfg.Server.ReadTimeout,
+			WriteTimeout: cfg.Server.WriteTimeout,
+			IdleTimeout:  cfg.Server.IdleTimeout,
+		},
+		ln: ln,
+	}, nil
+}
+
+// newReadiness makes readiness depend on the configured identity provider.
+func newReadiness(cfg *config.Config) *health.Readiness {
+	return health.NewReadiness(
+		cfg.Server.ReadyCacheTTL,
+		cfg.Server.ReadyCheckTimeout,
+		health.Dependency{Name: cfg.IAM.Provider, Check: provider.Default(cfg).Ping},
+	)
+}
+
+func metricsHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	return mux
+}
+
+// serve runs the endpoints until ctx is done or one of them fails, then shuts
+// them all down. On ctx the readiness check fails at once but requests are
+// still accepted for DrainDelay, giving load balancers time to stop routing
+// here. In-flight requests and then flush, which may be nil, share
+// ShutdownTimeout to finish, so shutdown takes no longer than the two.
+// Endpoints are shut down in order, so list the metrics endpoint last to keep
+// it scrapeable while the API drains.
+func serve(
+	ctx context.Context,
+	cfg *config.Config,
+	ready *health.Readiness,
+	flush func(ctx context.Context),
+	endpoints ...*endpoint,
+) error {
+	failed := make(chan error, len(endpoints))
+	for _, e := range endpoints {
+		e := e
+		go func() {
+			log.Infof("%s listening on %s", e.name, e.ln.Addr())
+			if err := e.srv.Serve(e.ln); !errors.Is(err, http.ErrServerClosed) {
+				failed <- err
+			}
+		}()
+	}
+
+	var err error
+	select {
+	case err = <-failed:
+		log.Errorf("server failed, shutting down: %v", err)
+	case <-ctx.Done():
+		log.Infof("shutdown requested, draining for %s", cfg.Server.DrainDelay)
+		ready.StartDraining()
+		time.Sleep(cfg.Server.DrainDelay)
+	}
+
+	sctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	errs := []error{err}
+	for _, e := range endpoints {
+		if serr := e.srv.Shutdown(sctx); serr != nil {
+			log.Errorf("%s did not shut down cleanly: %v", e.name, serr)
+			errs = append(errs, serr)
+		}
+	}
+	if flush != nil {
+		flush(sctx)
+	}
+	log.Info("shutdown complete")
+	return errors.Join(errs...)
+}
+
+// closeAudit delivers the audit events the sinks still hold, until ctx is
+// done.
+func closeAudit(ctx context.Context, cfg *config.Config) {
+	if err := audit.Default(cfg).Close(ctx); err != nil {
+		log.Errorf("audit sinks did not close cleanly: %v", err)
+	}
+}
+
+// closeWebhooks lets the webhook deliveries under way finish, until ctx is
+// done. Those still pending are sent after the next start.
+func closeWebhooks(ctx context.Context, cfg *config.Config) {
+	if err := webhook.Default(cfg).Close(ctx); err != nil {
+		log.Errorf("webhook dispatcher did not close cleanly: %v", err)
+	}
+}
+
+// flushLogs gives the Loki hook, which ships entries in the background, one
+// batch interval to send what it still holds.
+func flushLogs(cfg *config.Config) {
+	time.Sleep(time.Duration(cfg.Log.LokiBatchWait) * time.Second)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"gitea.slauson.io/slausonio/iam-ms/config"
+	"gitea.slauson.io/slausonio/iam-ms/health"
+)
+
+func serverTestConfig() *config.Config {
+	cfg := config.Default()
+	cfg.Server.DrainDelay = 50 * time.Millisecond
+	cfg.Server.ShutdownTimeout = 2 * time.Second
+	return cfg
+}
+
+func TestServe_Drain(t *testing.T) {
+	cfg := serverTestConfig()
+	ready := health.NewReadiness(0, time.Second)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	api, err := listen(cfg, "api", "127.0.0.1:0", http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-release
+			_, _ = io.WriteString(w, "done")
+		},
+	))
+	assert.Nil(t, err)
+	metrics, err := listen(cfg, "metrics", "127.0.0.1:0", metricsHandler())
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- serve(ctx, cfg, ready, nil, api, metrics) }()
+
+	inFlight := make(chan string, 1)
+	go func() {
+		res, err := http.Get(fmt.Sprintf("http://%s/", api.ln.Addr()))
+		if err != nil {
+			inFlight <- err.Error()
+			return
+		}
+		defer res.Body.Close()
+		b, _ := io.ReadAll(res.Body)
+		inFlight <- string(b)
+	}()
+	<-started
+
+	cancel()
+	assert.Eventually(t, ready.Draining, time.Second, 5*time.Millisecond)
+
+	// Metrics stay up while the API drains.
+	res, err := http.Get(fmt.Sprintf("http://%s/metrics", metrics.ln.Addr()))
+	if assert.Nil(t, err) {
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		_ = res.Body.Close()
+	}
+
+	close(release)
+	assert.Equal(t, "done", <-inFlight, "the in-flight request completes")
+	assert.Nil(t, <-served)
+
+	_, err = http.Get(fmt.Sprintf("http://%s/", api.ln.Addr()))
+	assert.NotNil(t, err)
+}
+
+func TestServe_ServerFails(t *testing.T) {
+	cfg := serverTestConfig()
+	ready := health.NewReadiness(0, time.Second)
+
+	api, err := listen(cfg, "api", "127.0.0.1:0", http.NotFoundHandler())
+	assert.Nil(t, err)
+	_ = api.ln.Close()
+
+	err = serve(context.Background(), cfg, ready, nil, api)
+	assert.NotNil(t, err)
+	assert.False(t, ready.Draining())
+}
+
+func TestServe_FlushSharesDeadline(t *testing.T) {
+	cfg := serverTestConfig()
+	ready := health.NewReadiness(0, time.Second)
+
+	api, err := listen(cfg, "api", "127.0.0.1:0", http.NotFoundHandler())
+	assert.Nil(t, err)
+	_ = api.ln.Close()
+
+	var deadline time.Time
+	start := time.Now()
+	_ = serve(context.Background(), cfg, ready, func(ctx context.Context) {
+		deadline, _ = ctx.Deadline()
+	}, api)
+	assert.WithinDuration(t, start.Add(cfg.Server.ShutdownTimeout), deadline, time.Second)
+}
+
+func TestListen_AddressInUse(t *testing.T) {
+	cfg := serverTestConfig()
+
+	first, err := listen(cfg, "api", "127.0.0.1:0", http.NotFoundHandler())
+	assert.Nil(t, err)
+	defer first.ln.Close()
+
+	_, err = listen(cfg, "api", first.ln.Addr().String(), http.NotFoundHandler())
+	assert.NotNil(t, err)
+}
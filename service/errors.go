@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+	"gitea.slauson.io/slausonio/iam-ms/utils"
+)
+
+// defaultErrTypes types provider errors that arrive without one.
+var defaultErrTypes = map[int]string{
+	http.StatusBadRequest:   constants.ERR_TYPE_ARGUMENT_INVALID,
+	http.StatusUnauthorized: constants.ERR_TYPE_UNAUTHORIZED,
+	http.StatusForbidden:    constants.ERR_TYPE_FORBIDDEN,
+	http.StatusNotFound:     constants.ERR_TYPE_NOT_FOUND,
+	http.StatusConflict:     constants.ERR_TYPE_USER_ALREADY_EXISTS,
+}
+
+// providerError maps a failed identity provider call to the error the API
+// returns. Client errors keep the provider's status, type and message, so
+// e.g. user_already_exists stays a 409. Upstream failures are reported as
+// provider_unavailable or provider_error rather than blamed on the request,
+// and a deadline or cancellation of ctx wins over whatever the call returned.
+func providerError(ctx context.Context, err error) error {
+	if cerr := utils.ContextError(ctx); cerr != nil {
+		return cerr
+	}
+
+	var ie *utils.IamError
+	if !errors.As(err, &ie) {
+		log.Errorf("identity provider call failed: %v", err)
+		return utils.NewIamError(
+			http.StatusBadGateway,
+			constants.ERR_TYPE_PROVIDER_ERROR,
+			constants.ProviderFailed,
+		)
+	}
+
+	switch {
+	case ie.Status == http.StatusNotImplemented, ie.Type == constants.ERR_TYPE_TIMEOUT:
+		return ie
+	case ie.Status == http.StatusTooManyRequests:
+		return utils.NewIamError(
+			http.StatusTooManyRequests,
+			constants.ERR_TYPE_RATE_LIMITED,
+			constants.RateLimited,
+		)
+	case ie.Status == http.StatusBadGateway,
+		ie.Status == http.StatusServiceUnavailable,
+		ie.Status == http.StatusGatewayTimeout:
+		return utils.NewIamError(
+			http.StatusServiceUnavailable,
+			constants.ERR_TYPE_PROVIDER_DOWN,
+			constants.ProviderDown,
+		)
+	case ie.Status >= http.StatusInternalServerError,
+		ie.Status < http.StatusBadRequest,
+		// The service's own project or API key was rejected, which is not
+		// something the API client can fix.
+		ie.Type == constants.ERR_TYPE_PROJECT_NOT_FOUND,
+		ie.Type == constants.ERR_TYPE_UNAUTHORIZED_SCOPE:
+		log.Errorf("identity provider call failed: %d %s: %s", ie.Status, ie.Type, ie.Message)
+		return utils.NewIamError(
+			http.StatusBadGateway,
+			constants.ERR_TYPE_PROVIDER_ERROR,
+			constants.ProviderFailed,
+		)
+	}
+
+	if ie.Type == "" {
+		return utils.NewIamError(ie.Status, defaultErrTypes[ie.Status], ie.Message)
+	}
+	return ie
+}
+
+// providerStatus is the status a failed identity provider call reported, or 0
+// when it never got an answer.
+func providerStatus(err error) int {
+	var ie *utils.IamError
+	if errors.As(err, &ie) {
+		return ie.Status
+	}
+	return 0
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+	"gitea.slauson.io/slausonio/iam-ms/utils"
+)
+
+var (
+	tUserNotFound = utils.NewIamError(
+		http.StatusNotFound,
+		constants.ERR_TYPE_USER_NOT_FOUND,
+		constants.NoUserFound,
+	)
+	tUserExists = utils.NewIamError(
+		http.StatusConflict,
+		constants.ERR_TYPE_USER_ALREADY_EXISTS,
+		"A user with the same email already exists in your project.",
+	)
+	tInvalidCredentials = utils.NewIamError(
+		http.StatusUnauthorized,
+		constants.ERR_TYPE_INVALID_CREDENTIALS,
+		constants.InvalidCredentials,
+	)
+	tInvalidToken = utils.NewIamError(
+		http.StatusUnauthorized,
+		constants.ERR_TYPE_INVALID_TOKEN,
+		constants.InvalidToken,
+	)
+)
+
+func assertIamError(t *testing.T, err error, status int, errType string) {
+	t.Helper()
+	var ie *utils.IamError
+	if assert.ErrorAs(t, err, &ie) {
+		assert.Equal(t, status, ie.Status)
+		assert.Equal(t, errType, ie.Type)
+	}
+}
+
+func TestProviderError(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		status  int
+		errType string
+		message string
+	}{
+		{
+			name:    "transport failure",
+			err:     tError,
+			status:  http.StatusBadGateway,
+			errType: constants.ERR_TYPE_PROVIDER_ERROR,
+			message: constants.ProviderFailed,
+		},
+		{
+			name:    "not found",
+			err:     tUserNotFound,
+			status:  http.StatusNotFound,
+			errType: constants.ERR_TYPE_USER_NOT_FOUND,
+			message: constants.NoUserFound,
+		},
+		{
+			name:    "conflict",
+			err:     tUserExists,
+			status:  http.StatusConflict,
+			errType: constants.ERR_TYPE_USER_ALREADY_EXISTS,
+			message: "A user with the same email already exists in your project.",
+		},
+		{
+			name:    "invalid credentials",
+			err:     tInvalidCredentials,
+			status:  http.StatusUnauthorized,
+			errType: constants.ERR_TYPE_INVALID_CREDENTIALS,
+			message: constants.InvalidCredentials,
+		},
+		{
+			name:    "untyped",
+			err:     utils.NewIamError(http.StatusBadRequest, "", "Invalid `email` param."),
+			status:  http.StatusBadRequest,
+			errType: constants.ERR_TYPE_ARGUMENT_INVALID,
+			message: "Invalid `email` param.",
+		},
+		{
+			name:    "rate limit",
+			err:     utils.NewIamError(http.StatusTooManyRequests, "general_rate_limit_exceeded", "x"),
+			status:  http.StatusTooManyRequests,
+			errType: constants.ERR_TYPE_RATE_LIMITED,
+			message: constants.RateLimited,
+		},
+		{
+			name:    "provider down",
+			err:     utils.NewIamError(http.StatusServiceUnavailable, "", "Service Unavailable"),
+			status:  http.StatusServiceUnavailable,
+			errType: constants.ERR_TYPE_PROVIDER_DOWN,
+			message: constants.ProviderDown,
+		},
+		{
+			name:    "server error",
+			err:     utils.NewIamError(http.StatusInternalServerError, "general_server_error", "x"),
+			status:  http.StatusBadGateway,
+			errType: constants.ERR_TYPE_PROVIDER_ERROR,
+			message: constants.ProviderFailed,
+		},
+		{
+			name: "wrong api key",
+			err: utils.NewIamError(
+				http.StatusUnauthorized,
+				constants.ERR_TYPE_UNAUTHORIZED_SCOPE,
+				"x",
+			),
+			status:  http.StatusBadGateway,
+			errType: constants.ERR_TYPE_PROVIDER_ERROR,
+			message: constants.ProviderFailed,
+		},
+		{
+			name:    "unsupported",
+			err:     utils.NewIamError(http.StatusNotImplemented, constants.ERR_TYPE_UNSUPPORTED, "x"),
+			status:  http.StatusNotImplemented,
+			errType: constants.ERR_TYPE_UNSUPPORTED,
+			message: "x",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := providerError(context.Background(), tt.err)
+			assertIamError(t, err, tt.status, tt.errType)
+			assert.Equal(t, tt.message, err.Error())
+		})
+	}
+}
+
+func TestProviderError_ContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := providerError(ctx, tUserNotFound)
+	assertIamError(t, err, constants.STATUS_CLIENT_CLOSED_REQUEST, constants.ERR_TYPE_CANCELED)
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"net/http"
+
+	"gitea.slauson.io/slausonio/iam-ms/config"
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+	"gitea.slauson.io/slausonio/iam-ms/model"
+	"gitea.slauson.io/slausonio/iam-ms/provider"
+	"gitea.slauson.io/slausonio/iam-ms/utils"
+)
+
+type RoleService struct {
+	idp provider.IdentityProvider
+}
+
+//go:generate mockery --name IamRoleService
+type IamRoleService interface {
+	GetCaller(ctx context.Context, jwt string) (*model.Caller, error)
+	GetRoles(ctx context.Context, id string) (*model.RolesResponse, error)
+	UpdateRoles(
+		ctx context.Context,
+		id string,
+		r *model.UpdateRolesRequest,
+	) (*model.RolesResponse, error)
+}
+
+func NewRoleService(cfg *config.Config) *RoleService {
+	return &RoleService{
+		idp: provider.Default(cfg),
+	}
+}
+
+// GetCaller resolves the user owning the session JWT along with their roles.
+func (s *RoleService) GetCaller(ctx context.Context, jwt string) (*model.Caller, error) {
+	response, err := s.idp.GetSessionUser(ctx, jwt)
+	if err != nil {
+		// Any client error means the JWT did not resolve to an active user.
+		if status := providerStatus(err); status < 400 || status >= 500 {
+			return nil, providerError(ctx, err)
+		}
+		return nil, utils.NewIamError(
+			http.StatusUnauthorized,
+			constants.ERR_TYPE_INVALID_SESSION,
+			constants.InvalidUserSession,
+		)
+	}
+
+	return &model.Caller{ID: response.ID, Roles: rolesFromLabels(response.Labels)}, nil
+}
+
+func (s *RoleService) GetRoles(ctx context.Context, id string) (*model.RolesResponse, error) {
+	response, err := s.idp.GetLabels(ctx, id)
+	if err != nil {
+		return nil, providerError(ctx, err)
+	}
+
+	return &model.RolesResponse{ID: response.ID, Roles: rolesFromLabels(response.Labels)}, nil
+}
+
+// UpdateRoles replaces the user's role labels, keeping any other labels.
+func (s *RoleService) UpdateRoles(
+	ctx context.Context,
+	id string,
+	r *model.UpdateRolesRequest,
+) (*model.RolesResponse, error) {
+	current, err := s.idp.GetLabels(ctx, id)
+	if err != nil {
+		return nil, providerError(ctx, err)
+	}
+
+	labels := make([]string, 0, len(current.Labels)+len(r.Roles))
+	for _, l := range current.Labels {
+		if !isRole(l) {
+			labels = append(labels, l)
+		}
+	}
+	labels = append(labels, r.Roles...)
+
+	response, err := s.idp.UpdateLabels(ctx, id, labels)
+	if err != nil {
+		return nil, providerError(ctx, err)
+	}
+
+	return &model.RolesResponse{ID: response.ID, Roles: rolesFromLabels(response.Labels)}, nil
+}
+
+func rolesFromLabels(labels []string) []string {
+	roles := []string{}
+	for _, l := range labels {
+		if isRole(l) {
+			roles = append(roles, l)
+		}
+	}
+	return roles
+}
+
+func isRole(label string) bool {
+	for _, r := range constants.ROLES {
+		if r == label {
+			return true
+		}
+	}
+	return false
+}
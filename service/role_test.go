@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"gitea.slauson.io/slausonio/go-testing/siotest"
+	"gitea.slauson.io/slausonio/go-utils/sioerror"
+	"gitea.slauson.io/slausonio/iam-ms/config"
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+	"gitea.slauson.io/slausonio/iam-ms/model"
+	"gitea.slauson.io/slausonio/iam-ms/provider/mocks"
+)
+
+func initRoleServiceTest(t *testing.T) (*RoleService, *mocks.IdentityProvider) {
+	idp := mocks.NewIdentityProvider(t)
+	rs := &RoleService{
+		idp: idp,
+	}
+	return rs, idp
+}
+
+func TestNewRoleService(t *testing.T) {
+	rs := NewRoleService(config.Default())
+	assert.NotNil(t, rs)
+}
+
+func TestRoleService_GetCaller(t *testing.T) {
+	rs, idp := initRoleServiceTest(t)
+
+	idp.On("GetSessionUser", mock.Anything, "jwt").
+		Return(&model.UserLabels{ID: "a", Labels: []string{"admin", "beta"}}, nil)
+	actual, err := rs.GetCaller(context.Background(), "jwt")
+	assert.Emptyf(t, err, "err: %v", err)
+	assert.Equal(t, &model.Caller{ID: "a", Roles: []string{"admin"}}, actual)
+}
+
+func TestRoleService_GetCaller_Error(t *testing.T) {
+	rs, idp := initRoleServiceTest(t)
+
+	idp.On("GetSessionUser", mock.Anything, "jwt").Return(nil, tInvalidToken)
+	actual, err := rs.GetCaller(context.Background(), "jwt")
+	assert.Nilf(t, actual, "expected nil, actual: %v", actual)
+	assertIamError(t, err, http.StatusUnauthorized, constants.ERR_TYPE_INVALID_SESSION)
+	assert.Equal(t, constants.InvalidUserSession, err.Error())
+}
+
+func TestRoleService_GetRoles(t *testing.T) {
+	rs, idp := initRoleServiceTest(t)
+
+	idp.On("GetLabels", mock.Anything, "a").
+		Return(&model.UserLabels{ID: "a", Labels: []string{"author"}}, nil)
+	actual, err := rs.GetRoles(context.Background(), "a")
+	assert.Emptyf(t, err, "err: %v", err)
+	assert.Equal(t, &model.RolesResponse{ID: "a", Roles: []string{"author"}}, actual)
+}
+
+func TestRoleService_GetRoles_Error(t *testing.T) {
+	rs, idp := initRoleServiceTest(t)
+
+	idp.On("GetLabels", mock.Anything, "a").Return(nil, tUserNotFound)
+	actual, err := rs.GetRoles(context.Background(), "a")
+	assert.Nilf(t, actual, "expected nil, actual: %v", actual)
+	assert.Equal(t, sioerror.NewSioNotFoundError(constants.NoUserFound).Error(), err.Error())
+}
+
+func TestRoleService_UpdateRoles(t *testing.T) {
+	rs, idp := initRoleServiceTest(t)
+
+	idp.On("GetLabels", mock.Anything, "a").
+		Return(&model.UserLabels{ID: "a", Labels: []string{"reader", "beta"}}, nil)
+	idp.On("UpdateLabels", mock.Anything, "a", []string{"beta", "author", "admin"}).
+		Return(&model.UserLabels{ID: "a", Labels: []string{"beta", "author", "admin"}}, nil)
+
+	actual, err := rs.UpdateRoles(
+		context.Background(),
+		"a",
+		&model.UpdateRolesRequest{Roles: []string{"author", "admin"}},
+	)
+	assert.Emptyf(t, err, "err: %v", err)
+	assert.Equal(t, &model.RolesResponse{ID: "a", Roles: []string{"author", "admin"}}, actual)
+}
+
+func TestRoleService_UpdateRoles_Error(t *testing.T) {
+	rs, idp := initRoleServiceTest(t)
+
+	idp.On("GetLabels", mock.Anything, "a").Return(&model.UserLabels{ID: "a"}, nil)
+	idp.On("UpdateLabels", mock.Anything, "a", []string{"admin"}).Return(nil, siotest.TError)
+
+	actual, err := rs.UpdateRoles(
+		context.Background(),
+		"a",
+		&model.UpdateRolesRequest{Roles: []string{"admin"}},
+	)
+	assert.Nilf(t, actual, "expected nil, actual: %v", actual)
+	assertIamError(t, err, http.StatusBadGateway, constants.ERR_TYPE_PROVIDER_ERROR)
+}
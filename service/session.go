@@ -1,51 +1,141 @@
 package service
 
 import (
+	"context"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+
 	"gitea.slauson.io/slausonio/go-types/siogeneric"
-	"gitea.slauson.io/slausonio/go-utils/sioerror"
-	"gitea.slauson.io/slausonio/iam-ms/client"
+	"gitea.slauson.io/slausonio/iam-ms/config"
 	"gitea.slauson.io/slausonio/iam-ms/constants"
+	"gitea.slauson.io/slausonio/iam-ms/lockout"
+	"gitea.slauson.io/slausonio/iam-ms/model"
+	"gitea.slauson.io/slausonio/iam-ms/provider"
+	"gitea.slauson.io/slausonio/iam-ms/utils"
 )
 
 type SessionService struct {
-	awClient client.AppwriteClient
+	idp provider.IdentityProvider
+	// lockout throttles failing logins. Without one, logins are not limited.
+	lockout *lockout.Guard
 }
 
 //go:generate mockery --name IamSessionService
 type IamSessionService interface {
 	CreateEmailSession(
+		ctx context.Context,
 		r *siogeneric.AwEmailSessionRequest,
-	) (*siogeneric.AwSession, error)
-	DeleteSession(ID, sID string) (siogeneric.SuccessResponse, error)
+		ip string,
+	) (*model.Session, error)
+	ClearLockout(
+		ctx context.Context,
+		p *model.ClearLockoutParams,
+	) (siogeneric.SuccessResponse, error)
+	ListSessions(ctx context.Context, id string) (*model.SessionListResponse, error)
+	DeleteSession(ctx context.Context, ID, sID string) (siogeneric.SuccessResponse, error)
+	DeleteSessions(ctx context.Context, id string) (siogeneric.SuccessResponse, error)
 }
 
-func NewSessionService() *SessionService {
+func NewSessionService(cfg *config.Config) *SessionService {
 	return &SessionService{
-		awClient: client.NewAwClient(),
+		idp:     provider.Default(cfg),
+		lockout: lockout.Default(cfg),
 	}
 }
 
+// CreateEmailSession logs in with email and password from the client at ip.
+// Wrong credentials count towards locking out both the email and the ip, and
+// while either is locked out the provider is not asked at all.
 func (s *SessionService) CreateEmailSession(
+	ctx context.Context,
 	r *siogeneric.AwEmailSessionRequest,
-) (*siogeneric.AwSession, error) {
-	response, err := s.awClient.CreateEmailSession(r)
+	ip string,
+) (*model.Session, error) {
+	if s.lockout != nil {
+		if err := s.lockout.Check(ctx, r.Email, ip); err != nil {
+			return nil, err
+		}
+	}
+
+	response, err := s.idp.CreateEmailSession(ctx, r.Email, r.Password)
 	if err != nil {
-		return nil, sioerror.NewSioUnauthorizedError(err.Error())
+		err = providerError(ctx, err)
+		if s.lockout != nil && providerStatus(err) == http.StatusUnauthorized {
+			s.lockout.Fail(ctx, r.Email, ip)
+		}
+		return nil, err
+	}
+
+	if s.lockout != nil {
+		s.lockout.Succeed(ctx, r.Email)
 	}
 	return response, nil
 }
 
+// ClearLockout lifts the login lockout of p's email and IP.
+func (s *SessionService) ClearLockout(
+	ctx context.Context,
+	p *model.ClearLockoutParams,
+) (siogeneric.SuccessResponse, error) {
+	if s.lockout == nil {
+		return siogeneric.SuccessResponse{Success: true}, nil
+	}
+
+	if err := s.lockout.Clear(ctx, p.Email, p.IP); err != nil {
+		if cerr := utils.ContextError(ctx); cerr != nil {
+			return siogeneric.SuccessResponse{Success: false}, cerr
+		}
+		log.Errorf("login lockout not cleared: %v", err)
+		return siogeneric.SuccessResponse{Success: false}, utils.NewIamError(
+			http.StatusInternalServerError,
+			constants.ERR_TYPE_UNKNOWN,
+			constants.LockoutClearFailed,
+		)
+	}
+	return siogeneric.SuccessResponse{Success: true}, nil
+}
+
 func (s *SessionService) DeleteSession(
+	ctx context.Context,
 	ID string,
 	sID string,
 ) (siogeneric.SuccessResponse, error) {
-	err := s.awClient.DeleteSession(ID, sID)
+	err := s.idp.DeleteSession(ctx, ID, sID)
+	if err != nil {
+		return siogeneric.SuccessResponse{Success: false}, providerError(ctx, err)
+	}
+
+	return siogeneric.SuccessResponse{Success: true}, nil
+}
+
+func (s *SessionService) ListSessions(
+	ctx context.Context,
+	id string,
+) (*model.SessionListResponse, error) {
+	sessions, err := s.idp.ListSessions(ctx, id)
+	if err != nil {
+		return nil, providerError(ctx, err)
+	}
+
+	result := &model.SessionListResponse{
+		Total:    len(sessions),
+		Sessions: make([]model.SessionSummary, 0, len(sessions)),
+	}
+	for i := range sessions {
+		result.Sessions = append(result.Sessions, model.NewSessionSummary(&sessions[i]))
+	}
+
+	return result, nil
+}
+
+func (s *SessionService) DeleteSessions(
+	ctx context.Context,
+	id string,
+) (siogeneric.SuccessResponse, error) {
+	err := s.idp.DeleteSessions(ctx, id)
 	if err != nil {
-		return siogeneric.SuccessResponse{
-				Success: false,
-			}, sioerror.NewSioNotFoundError(
-				constants.NoUserFound,
-			)
+		return siogeneric.SuccessResponse{Success: false}, providerError(ctx, err)
 	}
 
 	return siogeneric.SuccessResponse{Success: true}, nil
@@ -1,16 +1,22 @@
 package service
 
 import (
+	"context"
+	"net/http"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
-	"gitea.slauson.io/slausonio/go-testing/siotest"
 	"gitea.slauson.io/slausonio/go-types/siogeneric"
 	"gitea.slauson.io/slausonio/go-utils/sioerror"
-	"gitea.slauson.io/slausonio/iam-ms/client/mocks"
+	"gitea.slauson.io/slausonio/iam-ms/config"
 	"gitea.slauson.io/slausonio/iam-ms/constants"
+	"gitea.slauson.io/slausonio/iam-ms/lockout"
+	"gitea.slauson.io/slausonio/iam-ms/model"
+	"gitea.slauson.io/slausonio/iam-ms/provider/mocks"
+	"gitea.slauson.io/slausonio/iam-ms/utils"
 )
 
 // Func TestNewUserService(t *testing.T) {
@@ -33,82 +39,73 @@ var (
 		Password: "test",
 	}
 
-	mUserSession = &siogeneric.AwSession{
-		ID:                        "blah",
-		CreatedAt:                 "blah",
-		AwClientCode:              "blah",
-		AwClientEngine:            "blah",
-		AwClientEngineVersion:     "blah",
-		AwClientName:              "blah",
-		AwClientType:              "blah",
-		AwClientVersion:           "blah",
-		CountryCode:               "blah",
-		CountryName:               "blah",
-		Current:                   true,
-		DeviceBrand:               "blah",
-		DeviceModel:               "blah",
-		DeviceName:                "blah",
-		Expire:                    "blah",
-		Ip:                        "blah",
-		OsCode:                    "blah",
-		OsName:                    "blah",
-		OsVersion:                 "blah",
-		Provider:                  "blah",
-		ProviderAccessToken:       "blah",
-		ProviderAccessTokenExpiry: "blah",
-		ProviderRefreshToken:      "blah",
-		ProviderUid:               "blah",
-		UserId:                    "blah",
+	mUserSession = &model.Session{
+		ID:            "blah",
+		CreatedAt:     "blah",
+		UserID:        "blah",
+		Expire:        "blah",
+		Current:       true,
+		Provider:      "blah",
+		ClientName:    "blah",
+		ClientVersion: "blah",
+		DeviceName:    "blah",
+		DeviceBrand:   "blah",
+		DeviceModel:   "blah",
+		OsName:        "blah",
+		OsVersion:     "blah",
+		Ip:            "blah",
+		CountryCode:   "blah",
+		CountryName:   "blah",
 	}
 )
 
-func initSessionServiceTest(t *testing.T) (*SessionService, *mocks.AppwriteClient) {
-	ac := mocks.NewAppwriteClient(t)
+func initSessionServiceTest(t *testing.T) (*SessionService, *mocks.IdentityProvider) {
+	idp := mocks.NewIdentityProvider(t)
 	ss := &SessionService{
-		awClient: ac,
+		idp: idp,
 	}
-	return ss, ac
+	return ss, idp
 }
 
 func TestNewSessionService(t *testing.T) {
-	ss := NewSessionService()
+	ss := NewSessionService(config.Default())
 	assert.NotNil(t, ss)
 }
 
 func TestSessionService_CreateUser(t *testing.T) {
-	ss, awClient := initSessionServiceTest(t)
+	ss, idp := initSessionServiceTest(t)
 
-	awClient.On("CreateEmailSession", mock.AnythingOfType("*siogeneric.AwEmailSessionRequest")).
+	idp.On("CreateEmailSession", mock.Anything, "test", "test").
 		Return(mUserSession, nil)
-	actual, err := ss.CreateEmailSession(sessionReq)
+	actual, err := ss.CreateEmailSession(context.Background(), sessionReq, "10.0.0.1")
 	assert.Equalf(t, mUserSession, actual, "actual: %v", actual)
 	assert.Emptyf(t, err, "err: %v", err)
 }
 
 func TestSessionService_CreateUser_Error(t *testing.T) {
-	ss, awClient := initSessionServiceTest(t)
+	ss, idp := initSessionServiceTest(t)
 
-	awClient.On("CreateEmailSession", mock.AnythingOfType("*siogeneric.AwEmailSessionRequest")).
-		Return(nil, siotest.TError)
-	actual, err := ss.CreateEmailSession(sessionReq)
+	idp.On("CreateEmailSession", mock.Anything, "test", "test").
+		Return(nil, tInvalidCredentials)
+	actual, err := ss.CreateEmailSession(context.Background(), sessionReq, "10.0.0.1")
 	assert.Nilf(t, actual, "expected nil, actual: %v", actual)
-	assert.Equalf(t, err.Error(), siotest.TUnauthorizedError.Error(), "error: %v", err.Error())
+	assertIamError(t, err, http.StatusUnauthorized, constants.ERR_TYPE_INVALID_CREDENTIALS)
 }
 
 func TestSessionService_DeleteSession(t *testing.T) {
-	ss, awClient := initSessionServiceTest(t)
+	ss, idp := initSessionServiceTest(t)
 
-	awClient.On("DeleteSession", "a", "a").Return(nil)
-	actual, err := ss.DeleteSession("a", "a")
+	idp.On("DeleteSession", mock.Anything, "a", "a").Return(nil)
+	actual, err := ss.DeleteSession(context.Background(), "a", "a")
 	assert.Truef(t, actual.Success, "actual.Success: %v", actual.Success)
 	assert.Emptyf(t, err, "err: %v", err)
 }
 
 func TestSessionService_DeleteSession_Error(t *testing.T) {
-	ss, awClient := initSessionServiceTest(t)
+	ss, idp := initSessionServiceTest(t)
 
-	awClient.On("DeleteSession", "a", "a").Return(siotest.TError)
-	actual, err := ss.DeleteSession("a", "a")
+	idp.On("DeleteSession", mock.Anything, "a", "a").Return(tUserNotFound)
+	actual, err := ss.DeleteSession(context.Background(), "a", "a")
 	assert.False(t, actual.Success)
 	assert.Equalf(
 		t,
@@ -118,3 +115,147 @@ func TestSessionService_DeleteSession_Error(t *testing.T) {
 		err.Error(),
 	)
 }
+
+func TestSessionService_ListSessions(t *testing.T) {
+	ss, idp := initSessionServiceTest(t)
+
+	idp.On("ListSessions", mock.Anything, "a").Return([]model.Session{*mUserSession}, nil)
+	actual, err := ss.ListSessions(context.Background(), "a")
+	assert.Emptyf(t, err, "err: %v", err)
+	assert.Equal(t, 1, actual.Total)
+	assert.Equal(t, model.NewSessionSummary(mUserSession), actual.Sessions[0])
+}
+
+func TestSessionService_ListSessions_Error(t *testing.T) {
+	ss, idp := initSessionServiceTest(t)
+
+	idp.On("ListSessions", mock.Anything, "a").Return(nil, tUserNotFound)
+	actual, err := ss.ListSessions(context.Background(), "a")
+	assert.Nilf(t, actual, "expected nil, actual: %v", actual)
+	assert.Equalf(
+		t,
+		err.Error(),
+		sioerror.NewSioNotFoundError(constants.NoUserFound).Error(),
+		"error: %v",
+		err.Error(),
+	)
+}
+
+func TestSessionService_DeleteSessions(t *testing.T) {
+	ss, idp := initSessionServiceTest(t)
+
+	idp.On("DeleteSessions", mock.Anything, "a").Return(nil)
+	actual, err := ss.DeleteSessions(context.Background(), "a")
+	assert.Truef(t, actual.Success, "actual.Success: %v", actual.Success)
+	assert.Emptyf(t, err, "err: %v", err)
+}
+
+func TestSessionService_DeleteSessions_Error(t *testing.T) {
+	ss, idp := initSessionServiceTest(t)
+
+	idp.On("DeleteSessions", mock.Anything, "a").Return(tUserNotFound)
+	actual, err := ss.DeleteSessions(context.Background(), "a")
+	assert.False(t, actual.Success)
+	assert.Equalf(
+		t,
+		err.Error(),
+		sioerror.NewSioNotFoundError(constants.NoUserFound).Error(),
+		"error: %v",
+		err.Error(),
+	)
+}
+
+func initLockoutSessionServiceTest(t *testing.T) (*SessionService, *mocks.IdentityProvider) {
+	ss, idp := initSessionServiceTest(t)
+	policy := lockout.Policy{
+		Threshold:   2,
+		Window:      time.Minute,
+		Duration:    time.Minute,
+		MaxDuration: time.Hour,
+	}
+	ss.lockout = lockout.New(lockout.NewMemoryStore(), policy, lockout.Policy{})
+	return ss, idp
+}
+
+func TestSessionService_CreateEmailSession_Lockout(t *testing.T) {
+	ss, idp := initLockoutSessionServiceTest(t)
+
+	idp.On("CreateEmailSession", mock.Anything, "test", "test").
+		Return(nil, tInvalidCredentials).
+		Times(2)
+	for i := 0; i < 2; i++ {
+		_, err := ss.CreateEmailSession(context.Background(), sessionReq, "10.0.0.1")
+		assertIamError(t, err, http.StatusUnauthorized, constants.ERR_TYPE_INVALID_CREDENTIALS)
+	}
+
+	actual, err := ss.CreateEmailSession(context.Background(), sessionReq, "10.0.0.1")
+	assert.Nil(t, actual)
+	assertIamError(t, err, http.StatusTooManyRequests, constants.ERR_TYPE_RATE_LIMITED)
+	var ie *utils.IamError
+	if assert.ErrorAs(t, err, &ie) {
+		assert.Equal(t, time.Minute, ie.RetryAfter.Round(time.Second))
+	}
+}
+
+func TestSessionService_CreateEmailSession_LockoutIgnoresOtherErrors(t *testing.T) {
+	ss, idp := initLockoutSessionServiceTest(t)
+
+	idp.On("CreateEmailSession", mock.Anything, "test", "test").
+		Return(nil, tUserNotFound).
+		Times(3)
+	for i := 0; i < 3; i++ {
+		_, err := ss.CreateEmailSession(context.Background(), sessionReq, "10.0.0.1")
+		assertIamError(t, err, http.StatusNotFound, constants.ERR_TYPE_USER_NOT_FOUND)
+	}
+}
+
+func TestSessionService_CreateEmailSession_SuccessResets(t *testing.T) {
+	ss, idp := initLockoutSessionServiceTest(t)
+
+	fail := idp.On("CreateEmailSession", mock.Anything, "test", "test").
+		Return(nil, tInvalidCredentials)
+	_, err := ss.CreateEmailSession(context.Background(), sessionReq, "10.0.0.1")
+	assertIamError(t, err, http.StatusUnauthorized, constants.ERR_TYPE_INVALID_CREDENTIALS)
+
+	fail.Unset()
+	idp.On("CreateEmailSession", mock.Anything, "test", "test").Return(mUserSession, nil).Once()
+	_, err = ss.CreateEmailSession(context.Background(), sessionReq, "10.0.0.1")
+	assert.Nil(t, err)
+
+	// Had the first failure still counted, the second of these would be refused.
+	idp.On("CreateEmailSession", mock.Anything, "test", "test").Return(nil, tInvalidCredentials)
+	for i := 0; i < 2; i++ {
+		_, err = ss.CreateEmailSession(context.Background(), sessionReq, "10.0.0.1")
+		assertIamError(t, err, http.StatusUnauthorized, constants.ERR_TYPE_INVALID_CREDENTIALS)
+	}
+}
+
+func TestSessionService_ClearLockout(t *testing.T) {
+	ss, idp := initLockoutSessionServiceTest(t)
+
+	idp.On("CreateEmailSession", mock.Anything, "test", "test").
+		Return(nil, tInvalidCredentials).
+		Times(2)
+	idp.On("CreateEmailSession", mock.Anything, "test", "test").
+		Return(mUserSession, nil).
+		Once()
+	for i := 0; i < 2; i++ {
+		_, _ = ss.CreateEmailSession(context.Background(), sessionReq, "10.0.0.1")
+	}
+
+	actual, err := ss.ClearLockout(context.Background(), &model.ClearLockoutParams{Email: "TEST"})
+	assert.Nil(t, err)
+	assert.True(t, actual.Success)
+
+	session, err := ss.CreateEmailSession(context.Background(), sessionReq, "10.0.0.1")
+	assert.Nil(t, err)
+	assert.Equal(t, mUserSession, session)
+}
+
+func TestSessionService_ClearLockout_Disabled(t *testing.T) {
+	ss, _ := initSessionServiceTest(t)
+
+	actual, err := ss.ClearLockout(context.Background(), &model.ClearLockoutParams{IP: "10.0.0.1"})
+	assert.Nil(t, err)
+	assert.True(t, actual.Success)
+}
@@ -1,104 +1,508 @@
 package service
 
 import (
+	"context"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
 	"gitea.slauson.io/slausonio/go-types/siogeneric"
-	"gitea.slauson.io/slausonio/go-utils/sioerror"
-	"gitea.slauson.io/slausonio/iam-ms/client"
+	"gitea.slauson.io/slausonio/iam-ms/config"
 	"gitea.slauson.io/slausonio/iam-ms/constants"
+	"gitea.slauson.io/slausonio/iam-ms/lockout"
+	"gitea.slauson.io/slausonio/iam-ms/model"
+	"gitea.slauson.io/slausonio/iam-ms/provider"
+	"gitea.slauson.io/slausonio/iam-ms/utils"
+	"gitea.slauson.io/slausonio/iam-ms/webhook"
 )
 
+// publishTimeout bounds queueing a webhook event.
+const publishTimeout = 5 * time.Second
+
 type UserService struct {
-	awClient client.AppwriteClient
+	idp provider.IdentityProvider
+	// webhooks tells subscribers about changes. Without one, nobody is told.
+	webhooks *webhook.Dispatcher
+	// lockout throttles failing password checks along with logins. Without
+	// one, they are not limited.
+	lockout *lockout.Guard
 }
 
 //go:generate mockery --name IamUserService
 type IamUserService interface {
-	ListUsers() (*siogeneric.AwlistResponse, error)
-	GetUserByID(id string) (*siogeneric.AwUser, error)
-	CreateUser(r *siogeneric.AwCreateUserRequest) (*siogeneric.AwUser, error)
-	UpdateEmail(id string, r *siogeneric.UpdateEmailRequest) (*siogeneric.AwUser, error)
-	UpdatePhone(id string, r *siogeneric.UpdatePhoneRequest) (*siogeneric.AwUser, error)
+	ListUsers(ctx context.Context, p *model.ListUsersParams) (*model.UserListResponse, error)
+	GetUserByID(ctx context.Context, id string) (*model.User, error)
+	CreateUser(ctx context.Context, r *siogeneric.AwCreateUserRequest) (*model.User, error)
+	UpdateEmail(
+		ctx context.Context,
+		id string,
+		r *siogeneric.UpdateEmailRequest,
+	) (*model.User, error)
+	UpdatePhone(
+		ctx context.Context,
+		id string,
+		r *siogeneric.UpdatePhoneRequest,
+	) (*model.User, error)
 	UpdatePassword(
+		ctx context.Context,
 		id string,
 		r *siogeneric.UpdatePasswordRequest,
-	) (*siogeneric.AwUser, error)
-	DeleteUser(id string) (siogeneric.SuccessResponse, error)
+	) (*model.User, error)
+	UpdateOwnPassword(
+		ctx context.Context,
+		id string,
+		r *model.UpdateOwnPasswordRequest,
+		ip string,
+	) (*model.User, error)
+	UpdateOwnEmail(
+		ctx context.Context,
+		id string,
+		r *model.UpdateOwnEmailRequest,
+		ip string,
+	) (*model.User, error)
+	UpdateName(ctx context.Context, id string, r *model.UpdateNameRequest) (*model.User, error)
+	UpdateStatus(ctx context.Context, id string, r *model.UpdateStatusRequest) (*model.User, error)
+	GetPrefs(ctx context.Context, id string) (model.Prefs, error)
+	UpdatePrefs(ctx context.Context, id string, r *model.UpdatePrefsRequest) (model.Prefs, error)
+	DeleteUser(ctx context.Context, id string) (siogeneric.SuccessResponse, error)
+	CreatePasswordRecovery(
+		ctx context.Context,
+		r *model.PasswordRecoveryRequest,
+	) (siogeneric.SuccessResponse, error)
+	ConfirmPasswordRecovery(
+		ctx context.Context,
+		r *model.PasswordRecoveryConfirmRequest,
+	) (siogeneric.SuccessResponse, error)
+	SendEmailVerification(ctx context.Context, jwt string) (siogeneric.SuccessResponse, error)
+	ConfirmEmailVerification(
+		ctx context.Context,
+		r *model.VerificationConfirmRequest,
+	) (siogeneric.SuccessResponse, error)
+	SendPhoneVerification(ctx context.Context, jwt string) (siogeneric.SuccessResponse, error)
+	ConfirmPhoneVerification(
+		ctx context.Context,
+		r *model.VerificationConfirmRequest,
+	) (siogeneric.SuccessResponse, error)
+	UpdateVerification(
+		ctx context.Context,
+		id string,
+		r *model.VerificationStatusRequest,
+	) (*model.User, error)
 }
 
-func NewUserService() *UserService {
+func NewUserService(cfg *config.Config) *UserService {
 	return &UserService{
-		awClient: client.NewAwClient(),
+		idp:      provider.Default(cfg),
+		webhooks: webhook.Default(cfg),
+		lockout:  lockout.Default(cfg),
 	}
 }
 
-func (s *UserService) ListUsers() (*siogeneric.AwlistResponse, error) {
-	response, err := s.awClient.ListUsers()
+// publish tells webhook subscribers that the user changed. The change has
+// been made by now, so failing to queue the event is logged, not returned.
+// Queueing gets its own deadline, as the request's may be all but spent.
+func (s *UserService) publish(eventType string, user model.WebhookUser) {
+	if s.webhooks == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
+	err := s.webhooks.Publish(ctx, model.WebhookEvent{Type: eventType, User: user})
 	if err != nil {
-		return nil, err
+		log.Errorf("%s webhook for %s not queued: %v", eventType, user.ID, err)
 	}
+}
 
-	return response, nil
+func (s *UserService) ListUsers(
+	ctx context.Context,
+	p *model.ListUsersParams,
+) (*model.UserListResponse, error) {
+	if p == nil {
+		p = new(model.ListUsersParams)
+	}
+	if p.Limit == 0 {
+		p.Limit = constants.DEFAULT_USER_LIST_LIMIT
+	}
+	// Numbers are filtered on as they are stored.
+	p.Phone = utils.NormalizePhone(p.Phone)
+
+	response, err := s.idp.ListUsers(ctx, p)
+	if err != nil {
+		return nil, providerError(ctx, err)
+	}
+
+	result := &model.UserListResponse{
+		Total:  response.Total,
+		Users:  response.Users,
+		Limit:  p.Limit,
+		Offset: p.Offset,
+	}
+	// A full page means there may be more users after the last one returned.
+	if len(response.Users) == p.Limit {
+		result.NextCursor = response.Users[len(response.Users)-1].ID
+	}
+
+	return result, nil
 }
 
-func (s *UserService) GetUserByID(id string) (*siogeneric.AwUser, error) {
-	response, err := s.awClient.GetUserByID(id)
+func (s *UserService) GetUserByID(ctx context.Context, id string) (*model.User, error) {
+	response, err := s.idp.GetUserByID(ctx, id)
 	if err != nil {
-		return nil, sioerror.NewSioNotFoundError(constants.NoUserFound)
+		return nil, providerError(ctx, err)
 	}
 
 	return response, nil
 }
 
 func (s *UserService) CreateUser(
+	ctx context.Context,
 	r *siogeneric.AwCreateUserRequest,
-) (*siogeneric.AwUser, error) {
-	response, err := s.awClient.CreateUser(r)
+) (*model.User, error) {
+	response, err := s.idp.CreateUser(ctx, &model.NewUser{
+		ID:       r.UserID,
+		Email:    r.Email,
+		Phone:    utils.NormalizePhone(r.Phone),
+		Password: r.Password,
+		Name:     r.Name,
+	})
 	if err != nil {
-		return nil, sioerror.NewSioBadRequestError(err.Error())
+		return nil, providerError(ctx, err)
 	}
 
+	s.publish(constants.WEBHOOK_EVENT_USER_CREATED, model.NewWebhookUser(response))
 	return response, nil
 }
 
 func (s *UserService) UpdateEmail(
+	ctx context.Context,
 	id string,
 	r *siogeneric.UpdateEmailRequest,
-) (*siogeneric.AwUser, error) {
-	response, err := s.awClient.UpdateEmail(id, r)
+) (*model.User, error) {
+	response, err := s.idp.UpdateEmail(ctx, id, r.Email)
 	if err != nil {
-		return nil, err
+		return nil, providerError(ctx, err)
+	}
+
+	// The new address has not been verified yet. It has changed whether or
+	// not resetting that succeeds, so subscribers are told either way.
+	unverified, err := s.idp.UpdateEmailVerification(ctx, id, false)
+	if err == nil {
+		response = unverified
+	}
+	s.publish(constants.WEBHOOK_EVENT_USER_EMAIL_CHANGED, model.NewWebhookUser(response))
+	if err != nil {
+		return nil, providerError(ctx, err)
 	}
 	return response, nil
 }
 
 func (s *UserService) UpdatePhone(
+	ctx context.Context,
 	id string,
 	r *siogeneric.UpdatePhoneRequest,
-) (*siogeneric.AwUser, error) {
-	r.Number = "+1" + r.Number
-	response, err := s.awClient.UpdatePhone(id, r)
+) (*model.User, error) {
+	response, err := s.idp.UpdatePhone(ctx, id, utils.NormalizePhone(r.Number))
 	if err != nil {
-		return nil, err
+		return nil, providerError(ctx, err)
+	}
+
+	// The new number has not been verified yet. It has changed whether or
+	// not resetting that succeeds, so subscribers are told either way.
+	unverified, err := s.idp.UpdatePhoneVerification(ctx, id, false)
+	if err == nil {
+		response = unverified
+	}
+	s.publish(constants.WEBHOOK_EVENT_USER_PHONE_CHANGED, model.NewWebhookUser(response))
+	if err != nil {
+		return nil, providerError(ctx, err)
 	}
 	return response, nil
 }
 
 func (s *UserService) UpdatePassword(
+	ctx context.Context,
 	id string,
 	r *siogeneric.UpdatePasswordRequest,
-) (*siogeneric.AwUser, error) {
-	response, err := s.awClient.UpdatePassword(id, r)
+) (*model.User, error) {
+	response, err := s.idp.UpdatePassword(ctx, id, r.Password)
+	if err != nil {
+		return nil, providerError(ctx, err)
+	}
+	return response, nil
+}
+
+// UpdateOwnPassword changes the caller's password once they have proven they
+// know the current one, so a stolen session alone cannot take over the account.
+// ip is the client's, counted towards the login lockout on a wrong password.
+func (s *UserService) UpdateOwnPassword(
+	ctx context.Context,
+	id string,
+	r *model.UpdateOwnPasswordRequest,
+	ip string,
+) (*model.User, error) {
+	if err := s.verifyPassword(ctx, id, r.OldPassword, ip); err != nil {
+		return nil, err
+	}
+
+	return s.UpdatePassword(ctx, id, &siogeneric.UpdatePasswordRequest{Password: r.Password})
+}
+
+// UpdateOwnEmail changes the caller's email once they have proven they know
+// their current password, the same way UpdateOwnPassword does.
+func (s *UserService) UpdateOwnEmail(
+	ctx context.Context,
+	id string,
+	r *model.UpdateOwnEmailRequest,
+	ip string,
+) (*model.User, error) {
+	if err := s.verifyPassword(ctx, id, r.OldPassword, ip); err != nil {
+		return nil, err
+	}
+
+	return s.UpdateEmail(ctx, id, &siogeneric.UpdateEmailRequest{Email: r.Email})
+}
+
+// verifyPassword checks password against the provider by opening a session
+// with it, then discards that session. It is a login like any other to the
+// lockout, so a session cannot be used to guess the password unthrottled.
+func (s *UserService) verifyPassword(
+	ctx context.Context,
+	id string,
+	password string,
+	ip string,
+) error {
+	user, err := s.idp.GetUserByID(ctx, id)
+	if err != nil {
+		return providerError(ctx, err)
+	}
+	if s.lockout != nil {
+		if err := s.lockout.Check(ctx, user.Email, ip); err != nil {
+			return err
+		}
+	}
+
+	session, err := s.idp.CreateEmailSession(ctx, user.Email, password)
+	if err != nil {
+		if providerStatus(err) != http.StatusUnauthorized {
+			return providerError(ctx, err)
+		}
+		if s.lockout != nil {
+			s.lockout.Fail(ctx, user.Email, ip)
+		}
+		return utils.NewIamError(
+			http.StatusUnauthorized,
+			constants.ERR_TYPE_INVALID_OLD_PASSWORD,
+			constants.InvalidOldPassword,
+		)
+	}
+
+	if s.lockout != nil {
+		s.lockout.Succeed(ctx, user.Email)
+	}
+	if err := s.idp.DeleteSession(ctx, id, session.ID); err != nil {
+		log.Warnf("could not delete password check session for %s: %v", id, err)
+	}
+	return nil
+}
+
+func (s *UserService) UpdateName(
+	ctx context.Context,
+	id string,
+	r *model.UpdateNameRequest,
+) (*model.User, error) {
+	response, err := s.idp.UpdateName(ctx, id, r.Name)
+	if err != nil {
+		return nil, providerError(ctx, err)
+	}
+
+	s.publish(constants.WEBHOOK_EVENT_USER_NAME_CHANGED, model.NewWebhookUser(response))
+	return response, nil
+}
+
+// UpdateStatus blocks or unblocks a user. Blocking also signs the user out of
+// every session so the block takes effect immediately. Subscribers are told
+// about a block even when signing out fails, which is reported as the error;
+// blocking again retries it.
+func (s *UserService) UpdateStatus(
+	ctx context.Context,
+	id string,
+	r *model.UpdateStatusRequest,
+) (*model.User, error) {
+	response, err := s.idp.UpdateStatus(ctx, id, *r.Status)
+	if err != nil {
+		return nil, providerError(ctx, err)
+	}
+
+	if *r.Status {
+		s.publish(constants.WEBHOOK_EVENT_USER_UNBLOCKED, model.NewWebhookUser(response))
+		return response, nil
+	}
+
+	s.publish(constants.WEBHOOK_EVENT_USER_BLOCKED, model.NewWebhookUser(response))
+	if err := s.idp.DeleteSessions(ctx, id); err != nil {
+		return nil, providerError(ctx, err)
+	}
+	return response, nil
+}
+
+func (s *UserService) GetPrefs(ctx context.Context, id string) (model.Prefs, error) {
+	response, err := s.idp.GetPrefs(ctx, id)
+	if err != nil {
+		return nil, providerError(ctx, err)
+	}
+
+	return response, nil
+}
+
+// UpdatePrefs merges the requested keys into the user's current prefs, since
+// providers only support replacing them wholesale.
+func (s *UserService) UpdatePrefs(
+	ctx context.Context,
+	id string,
+	r *model.UpdatePrefsRequest,
+) (model.Prefs, error) {
+	prefs, err := s.GetPrefs(ctx, id)
 	if err != nil {
 		return nil, err
 	}
+
+	for k, v := range r.Prefs {
+		if v == nil {
+			delete(prefs, k)
+			continue
+		}
+		prefs[k] = v
+	}
+
+	if err := utils.NewIamValidations().ValidatePrefs(prefs); err != nil {
+		return nil, err
+	}
+
+	response, err := s.idp.UpdatePrefs(ctx, id, prefs)
+	if err != nil {
+		return nil, providerError(ctx, err)
+	}
 	return response, nil
 }
 
-func (s *UserService) DeleteUser(id string) (siogeneric.SuccessResponse, error) {
-	err := s.awClient.DeleteUser(id)
+func (s *UserService) DeleteUser(
+	ctx context.Context,
+	id string,
+) (siogeneric.SuccessResponse, error) {
+	err := s.idp.DeleteUser(ctx, id)
+	if err != nil {
+		return siogeneric.SuccessResponse{Success: false}, providerError(ctx, err)
+	}
+
+	s.publish(constants.WEBHOOK_EVENT_USER_DELETED, model.WebhookUser{ID: id})
+	return siogeneric.SuccessResponse{Success: true}, nil
+}
+
+// CreatePasswordRecovery succeeds whether or not an account has the email, so
+// the endpoint cannot be used to find out which addresses are registered.
+func (s *UserService) CreatePasswordRecovery(
+	ctx context.Context,
+	r *model.PasswordRecoveryRequest,
+) (siogeneric.SuccessResponse, error) {
+	err := s.idp.CreateRecovery(ctx, r.Email)
+	if providerStatus(err) == http.StatusNotFound {
+		log.Debug("password recovery requested for an unknown email")
+		return siogeneric.SuccessResponse{Success: true}, nil
+	}
+	if err != nil {
+		return siogeneric.SuccessResponse{Success: false}, providerError(ctx, err)
+	}
+
+	return siogeneric.SuccessResponse{Success: true}, nil
+}
+
+func (s *UserService) ConfirmPasswordRecovery(
+	ctx context.Context,
+	r *model.PasswordRecoveryConfirmRequest,
+) (siogeneric.SuccessResponse, error) {
+	err := s.idp.ConfirmRecovery(ctx, r.UserID, r.Secret, r.Password)
+	if err != nil {
+		return siogeneric.SuccessResponse{Success: false}, providerError(ctx, err)
+	}
+
+	return siogeneric.SuccessResponse{Success: true}, nil
+}
+
+func (s *UserService) SendEmailVerification(
+	ctx context.Context,
+	jwt string,
+) (siogeneric.SuccessResponse, error) {
+	err := s.idp.SendEmailVerification(ctx, jwt)
+	if err != nil {
+		return siogeneric.SuccessResponse{Success: false}, providerError(ctx, err)
+	}
+
+	return siogeneric.SuccessResponse{Success: true}, nil
+}
+
+func (s *UserService) ConfirmEmailVerification(
+	ctx context.Context,
+	r *model.VerificationConfirmRequest,
+) (siogeneric.SuccessResponse, error) {
+	err := s.idp.ConfirmEmailVerification(ctx, r.UserID, r.Secret)
 	if err != nil {
-		return siogeneric.SuccessResponse{Success: false}, err
+		return siogeneric.SuccessResponse{Success: false}, providerError(ctx, err)
 	}
 
 	return siogeneric.SuccessResponse{Success: true}, nil
 }
+
+func (s *UserService) SendPhoneVerification(
+	ctx context.Context,
+	jwt string,
+) (siogeneric.SuccessResponse, error) {
+	err := s.idp.SendPhoneVerification(ctx, jwt)
+	if err != nil {
+		return siogeneric.SuccessResponse{Success: false}, providerError(ctx, err)
+	}
+
+	return siogeneric.SuccessResponse{Success: true}, nil
+}
+
+func (s *UserService) ConfirmPhoneVerification(
+	ctx context.Context,
+	r *model.VerificationConfirmRequest,
+) (siogeneric.SuccessResponse, error) {
+	err := s.idp.ConfirmPhoneVerification(ctx, r.UserID, r.Secret)
+	if err != nil {
+		return siogeneric.SuccessResponse{Success: false}, providerError(ctx, err)
+	}
+
+	return siogeneric.SuccessResponse{Success: true}, nil
+}
+
+// UpdateVerification is the admin override for a user's email and phone
+// verification flags.
+func (s *UserService) UpdateVerification(
+	ctx context.Context,
+	id string,
+	r *model.VerificationStatusRequest,
+) (*model.User, error) {
+	var (
+		response *model.User
+		err      error
+	)
+
+	if r.Email != nil {
+		response, err = s.idp.UpdateEmailVerification(ctx, id, *r.Email)
+		if err != nil {
+			return nil, providerError(ctx, err)
+		}
+	}
+
+	if r.Phone != nil {
+		response, err = s.idp.UpdatePhoneVerification(ctx, id, *r.Phone)
+		if err != nil {
+			return nil, providerError(ctx, err)
+		}
+	}
+
+	return response, nil
+}
@@ -1,27 +1,37 @@
 package service
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
-	"gitea.slauson.io/slausonio/go-testing/siotest"
 	"gitea.slauson.io/slausonio/go-types/siogeneric"
 	"gitea.slauson.io/slausonio/go-utils/sioerror"
-	"gitea.slauson.io/slausonio/iam-ms/client/mocks"
+	"gitea.slauson.io/slausonio/iam-ms/config"
 	"gitea.slauson.io/slausonio/iam-ms/constants"
+	"gitea.slauson.io/slausonio/iam-ms/lockout"
+	"gitea.slauson.io/slausonio/iam-ms/model"
+	"gitea.slauson.io/slausonio/iam-ms/provider/mocks"
+	"gitea.slauson.io/slausonio/iam-ms/utils"
+	"gitea.slauson.io/slausonio/iam-ms/webhook"
 )
 
 var (
-	mAwUser = siogeneric.AwUser{
+	mUser = model.User{
 		Email: "t@t.com",
 	}
-	mAwUserPtr = &mAwUser
-	mUserList  = &siogeneric.AwlistResponse{
+	mUserPtr  = &mUser
+	mUserList = &model.UserList{
 		Total: 1,
-		Users: []siogeneric.AwUser{mAwUser},
+		Users: []model.User{mUser},
 	}
 	mCreateReq = &siogeneric.AwCreateUserRequest{
 		Email:    "t@t.com",
@@ -35,186 +45,764 @@ var (
 	uPasswordReq = &siogeneric.UpdatePasswordRequest{Password: "1235"}
 )
 
-func initUserServiceTest(t *testing.T) (*UserService, *mocks.AppwriteClient) {
-	awClient := mocks.NewAppwriteClient(t)
+func initUserServiceTest(t *testing.T) (*UserService, *mocks.IdentityProvider) {
+	idp := mocks.NewIdentityProvider(t)
 	us := &UserService{
-		awClient: awClient,
+		idp: idp,
 	}
-	return us, awClient
+	return us, idp
 }
 
 func TestNewUserService(t *testing.T) {
-	us := NewUserService()
+	us := NewUserService(config.Default())
 	assert.NotNilf(t, us, "expected non-nil UserService")
 }
 
 func TestUserService_ListUsers(t *testing.T) {
-	us, awClient := initUserServiceTest(t)
+	us, idp := initUserServiceTest(t)
+
+	idp.On("ListUsers", mock.Anything, mock.AnythingOfType("*model.ListUsersParams")).
+		Return(mUserList, nil)
+	actual, err := us.ListUsers(context.Background(), nil)
+	assert.Equalf(t, mUserList.Users, actual.Users, "actual: %v", actual)
+	assert.Equalf(t, mUserList.Total, actual.Total, "actual: %v", actual)
+	assert.Equal(t, constants.DEFAULT_USER_LIST_LIMIT, actual.Limit)
+	assert.Empty(t, actual.NextCursor)
+	assert.Emptyf(t, err, "error should have been nil. err: %v", err)
+}
 
-	awClient.On("ListUsers").Return(mUserList, nil)
-	actual, err := us.ListUsers()
-	assert.Equalf(t, mUserList, actual, "actual: %v", actual)
+func TestUserService_ListUsers_Phone(t *testing.T) {
+	us, idp := initUserServiceTest(t)
+
+	idp.On("ListUsers", mock.Anything, &model.ListUsersParams{
+		Limit: constants.DEFAULT_USER_LIST_LIMIT,
+		Phone: "+12125551234",
+	}).Return(mUserList, nil)
+	_, err := us.ListUsers(context.Background(), &model.ListUsersParams{Phone: "2125551234"})
+	assert.Nil(t, err)
+}
+
+func TestUserService_ListUsers_NextCursor(t *testing.T) {
+	us, idp := initUserServiceTest(t)
+
+	page := &model.UserList{
+		Total: 3,
+		Users: []model.User{{ID: "a"}, {ID: "b"}},
+	}
+	idp.On("ListUsers", mock.Anything, mock.AnythingOfType("*model.ListUsersParams")).
+		Return(page, nil)
+	actual, err := us.ListUsers(context.Background(), &model.ListUsersParams{Limit: 2})
 	assert.Emptyf(t, err, "error should have been nil. err: %v", err)
+	assert.Equal(t, "b", actual.NextCursor)
+	assert.Equal(t, 2, actual.Limit)
 }
 
 func TestUserService_ListUsers_Error(t *testing.T) {
-	us, awClient := initUserServiceTest(t)
+	us, idp := initUserServiceTest(t)
 
-	te := sioerror.NewSioNotFoundError(constants.NoUserFound)
-
-	awClient.On("ListUsers").Return(nil, te)
-	actual, err := us.ListUsers()
+	idp.On("ListUsers", mock.Anything, mock.AnythingOfType("*model.ListUsersParams")).
+		Return(nil, tError)
+	actual, err := us.ListUsers(context.Background(), &model.ListUsersParams{})
 	assert.Nilf(t, actual, "expected nil, actual: %v", actual)
-	assert.Equalf(t, err.Error(), te.Error(), "actual error: %v", err.Error())
+	assertIamError(t, err, http.StatusBadGateway, constants.ERR_TYPE_PROVIDER_ERROR)
 }
 
 func TestUserService_GetUserByID(t *testing.T) {
-	us, awClient := initUserServiceTest(t)
+	us, idp := initUserServiceTest(t)
 
-	awClient.On("GetUserByID", "a").Return(mAwUserPtr, nil)
-	actual, err := us.GetUserByID("a")
-	assert.Equalf(t, mAwUserPtr, actual, "actual: %v", actual)
+	idp.On("GetUserByID", mock.Anything, "a").Return(mUserPtr, nil)
+	actual, err := us.GetUserByID(context.Background(), "a")
+	assert.Equalf(t, mUserPtr, actual, "actual: %v", actual)
 	assert.Emptyf(t, err, "error should have been nil. err: %v", err)
 }
 
 func TestUserService_GetUserByID_Error(t *testing.T) {
-	us, awClient := initUserServiceTest(t)
+	us, idp := initUserServiceTest(t)
 
-	awClient.On("GetUserByID", "a").Return(nil, tError)
-	actual, err := us.GetUserByID("a")
+	idp.On("GetUserByID", mock.Anything, "a").Return(nil, tUserNotFound)
+	actual, err := us.GetUserByID(context.Background(), "a")
 	assert.Nilf(t, actual, "expected nil, actual: %v", actual)
 	assert.Equal(t, err.Error(), sioerror.NewSioNotFoundError(constants.NoUserFound).Error())
 }
 
-func TestUserService_CreateUser(t *testing.T) {
-	us, awClient := initUserServiceTest(t)
+func TestUserService_GetUserByID_Timeout(t *testing.T) {
+	us, idp := initUserServiceTest(t)
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now())
+	defer cancel()
 
-	awClient.On("CreateUser", mock.AnythingOfType("*siogeneric.AwCreateUserRequest")).
-		Return(mAwUserPtr, nil)
-	actual, err := us.CreateUser(mCreateReq)
-	assert.Equalf(t, mAwUserPtr, actual, "actual: %v", actual)
+	idp.On("GetUserByID", mock.Anything, "a").Return(nil, tError)
+	actual, err := us.GetUserByID(ctx, "a")
+	assert.Nilf(t, actual, "expected nil, actual: %v", actual)
+	assert.Equal(t, constants.RequestTimeout, err.Error())
+}
+
+func TestUserService_CreateUser(t *testing.T) {
+	us, idp := initUserServiceTest(t)
+
+	idp.On("CreateUser", mock.Anything, mock.MatchedBy(func(u *model.NewUser) bool {
+		return u.Phone == "+1test_phone"
+	})).
+		Return(mUserPtr, nil)
+	actual, err := us.CreateUser(context.Background(), mCreateReq)
+	assert.Equalf(t, mUserPtr, actual, "actual: %v", actual)
 	assert.Emptyf(t, err, "error should have been nil. err: %v", err)
 }
 
 func TestUserService_CreateUser_Error(t *testing.T) {
-	us, awClient := initUserServiceTest(t)
+	us, idp := initUserServiceTest(t)
 
-	awClient.On("CreateUser", mock.AnythingOfType("*siogeneric.AwCreateUserRequest")).
-		Return(nil, tError)
-	actual, err := us.CreateUser(mCreateReq)
+	idp.On("CreateUser", mock.Anything, mock.AnythingOfType("*model.NewUser")).
+		Return(nil, tUserExists)
+	actual, err := us.CreateUser(context.Background(), mCreateReq)
 	assert.Nilf(t, actual, "expected nil, actual: %v", actual)
-	assert.Equalf(
-		t,
-		err.Error(),
-		siotest.TBadRequestError.Error(),
-		"error: %v wanted: %v",
-		err.Error(),
-		siotest.TBadRequestError.Error(),
-	)
+	assertIamError(t, err, http.StatusConflict, constants.ERR_TYPE_USER_ALREADY_EXISTS)
 }
 
 func TestUserService_UpdateEmail(t *testing.T) {
-	us, awClient := initUserServiceTest(t)
+	us, idp := initUserServiceTest(t)
 
-	awClient.On("UpdateEmail", "a", mock.AnythingOfType("*siogeneric.UpdateEmailRequest")).
-		Return(mAwUserPtr, nil)
-	actual, err := us.UpdateEmail("a", uEmailReq)
-	assert.Equalf(t, mAwUserPtr, actual, "actual: %v", actual)
+	idp.On("UpdateEmail", mock.Anything, "a", "test").
+		Return(mUserPtr, nil)
+	idp.On("UpdateEmailVerification", mock.Anything, "a", false).Return(mUserPtr, nil)
+	actual, err := us.UpdateEmail(context.Background(), "a", uEmailReq)
+	assert.Equalf(t, mUserPtr, actual, "actual: %v", actual)
 	assert.Emptyf(t, err, "error should have been nil. err: %v", err)
 }
 
 func TestUserService_UpdateEmail_Error(t *testing.T) {
-	us, awClient := initUserServiceTest(t)
+	us, idp := initUserServiceTest(t)
 
-	awClient.On("UpdateEmail", "a", mock.AnythingOfType("*siogeneric.UpdateEmailRequest")).
+	idp.On("UpdateEmail", mock.Anything, "a", "test").
 		Return(nil, tError)
-	actual, err := us.UpdateEmail("a", uEmailReq)
+	actual, err := us.UpdateEmail(context.Background(), "a", uEmailReq)
 	assert.Nilf(t, actual, "expected nil, actual: %v", actual)
-	assert.Equalf(
-		t,
-		err.Error(),
-		siotest.TBadRequestError.Error(),
-		"error: %v wanted: %v",
-		err.Error(),
-		siotest.TBadRequestError.Error(),
-	)
+	assertIamError(t, err, http.StatusBadGateway, constants.ERR_TYPE_PROVIDER_ERROR)
+}
+
+func TestUserService_UpdateEmail_VerificationError(t *testing.T) {
+	us, idp := initUserServiceTest(t)
+
+	idp.On("UpdateEmail", mock.Anything, "a", "test").
+		Return(mUserPtr, nil)
+	idp.On("UpdateEmailVerification", mock.Anything, "a", false).Return(nil, tError)
+	actual, err := us.UpdateEmail(context.Background(), "a", uEmailReq)
+	assert.Nilf(t, actual, "expected nil, actual: %v", actual)
+	assertIamError(t, err, http.StatusBadGateway, constants.ERR_TYPE_PROVIDER_ERROR)
 }
 
 func TestUserService_UpdatePhone(t *testing.T) {
-	us, awClient := initUserServiceTest(t)
+	us, idp := initUserServiceTest(t)
 
-	awClient.On("UpdatePhone", "a", mock.AnythingOfType("*siogeneric.UpdatePhoneRequest")).
-		Return(mAwUserPtr, nil)
-	actual, err := us.UpdatePhone("a", uPhoneReq)
-	assert.Equalf(t, mAwUserPtr, actual, "actual: %v", actual)
+	idp.On("UpdatePhone", mock.Anything, "a", "+11235").
+		Return(mUserPtr, nil)
+	idp.On("UpdatePhoneVerification", mock.Anything, "a", false).Return(mUserPtr, nil)
+	actual, err := us.UpdatePhone(context.Background(), "a", uPhoneReq)
+	assert.Equalf(t, mUserPtr, actual, "actual: %v", actual)
 	assert.Emptyf(t, err, "error should have been nil. err: %v", err)
 }
 
 func TestUserService_UpdatePhone_Error(t *testing.T) {
-	us, awClient := initUserServiceTest(t)
+	us, idp := initUserServiceTest(t)
 
-	awClient.On("UpdatePhone", "a", mock.AnythingOfType("*siogeneric.UpdatePhoneRequest")).
+	idp.On("UpdatePhone", mock.Anything, "a", mock.AnythingOfType("string")).
 		Return(nil, tError)
-	actual, err := us.UpdatePhone("a", uPhoneReq)
+	actual, err := us.UpdatePhone(context.Background(), "a", uPhoneReq)
 	assert.Nilf(t, actual, "expected nil, actual: %v", actual)
-	assert.Equalf(
+	assertIamError(t, err, http.StatusBadGateway, constants.ERR_TYPE_PROVIDER_ERROR)
+}
+
+func TestUserService_UpdatePhone_VerificationError(t *testing.T) {
+	us, idp := initUserServiceTest(t)
+
+	idp.On("UpdatePhone", mock.Anything, "a", "+11235").
+		Return(mUserPtr, nil)
+	idp.On("UpdatePhoneVerification", mock.Anything, "a", false).Return(nil, tError)
+	actual, err := us.UpdatePhone(context.Background(), "a", uPhoneReq)
+	assert.Nilf(t, actual, "expected nil, actual: %v", actual)
+	assertIamError(t, err, http.StatusBadGateway, constants.ERR_TYPE_PROVIDER_ERROR)
+}
+
+func TestUserService_UpdateOwnPassword(t *testing.T) {
+	us, idp := initUserServiceTest(t)
+
+	idp.On("GetUserByID", mock.Anything, "a").Return(mUserPtr, nil)
+	idp.On("CreateEmailSession", mock.Anything, mUserPtr.Email, "old").
+		Return(&model.Session{ID: "s"}, nil)
+	idp.On("DeleteSession", mock.Anything, "a", "s").Return(nil)
+	idp.On("UpdatePassword", mock.Anything, "a", "new").
+		Return(mUserPtr, nil)
+
+	actual, err := us.UpdateOwnPassword(
+		context.Background(),
+		"a",
+		&model.UpdateOwnPasswordRequest{OldPassword: "old", Password: "new"},
+		"10.0.0.1",
+	)
+	assert.Equalf(t, mUserPtr, actual, "actual: %v", actual)
+	assert.Emptyf(t, err, "error should have been nil. err: %v", err)
+}
+
+func TestUserService_UpdateOwnPassword_WrongOldPassword(t *testing.T) {
+	us, idp := initUserServiceTest(t)
+
+	idp.On("GetUserByID", mock.Anything, "a").Return(mUserPtr, nil)
+	idp.On("CreateEmailSession", mock.Anything, mUserPtr.Email, mock.AnythingOfType("string")).
+		Return(nil, tInvalidCredentials)
+
+	actual, err := us.UpdateOwnPassword(
+		context.Background(),
+		"a",
+		&model.UpdateOwnPasswordRequest{OldPassword: "wrong", Password: "new"},
+		"10.0.0.1",
+	)
+	assert.Nilf(t, actual, "expected nil, actual: %v", actual)
+	assert.Equal(
 		t,
+		utils.NewIamError(
+			http.StatusUnauthorized,
+			constants.ERR_TYPE_INVALID_OLD_PASSWORD,
+			constants.InvalidOldPassword,
+		).Error(),
 		err.Error(),
-		siotest.TBadRequestError.Error(),
-		"error: %v wanted: %v",
-		err.Error(),
-		siotest.TBadRequestError.Error(),
 	)
 }
 
+func TestUserService_UpdateOwnPassword_Lockout(t *testing.T) {
+	us, idp := initUserServiceTest(t)
+	us.lockout = lockout.New(lockout.NewMemoryStore(), lockout.Policy{
+		Threshold:   2,
+		Window:      time.Minute,
+		Duration:    time.Minute,
+		MaxDuration: time.Hour,
+	}, lockout.Policy{})
+
+	idp.On("GetUserByID", mock.Anything, "a").Return(mUserPtr, nil)
+	idp.On("CreateEmailSession", mock.Anything, mUserPtr.Email, "wrong").
+		Return(nil, tInvalidCredentials).
+		Times(2)
+	r := &model.UpdateOwnPasswordRequest{OldPassword: "wrong", Password: "new"}
+	for i := 0; i < 2; i++ {
+		_, err := us.UpdateOwnPassword(context.Background(), "a", r, "10.0.0.1")
+		assertIamError(t, err, http.StatusUnauthorized, constants.ERR_TYPE_INVALID_OLD_PASSWORD)
+	}
+
+	// Locked out, the provider is not asked again.
+	_, err := us.UpdateOwnPassword(context.Background(), "a", r, "10.0.0.1")
+	assertIamError(t, err, http.StatusTooManyRequests, constants.ERR_TYPE_RATE_LIMITED)
+}
+
+func TestUserService_UpdateOwnPassword_NoUser(t *testing.T) {
+	us, idp := initUserServiceTest(t)
+
+	idp.On("GetUserByID", mock.Anything, "a").Return(nil, tUserNotFound)
+
+	actual, err := us.UpdateOwnPassword(
+		context.Background(),
+		"a",
+		&model.UpdateOwnPasswordRequest{OldPassword: "old", Password: "new"},
+		"10.0.0.1",
+	)
+	assert.Nilf(t, actual, "expected nil, actual: %v", actual)
+	assert.Equal(t, sioerror.NewSioNotFoundError(constants.NoUserFound).Error(), err.Error())
+}
+
+func TestUserService_UpdateOwnEmail(t *testing.T) {
+	us, idp := initUserServiceTest(t)
+
+	idp.On("GetUserByID", mock.Anything, "a").Return(mUserPtr, nil)
+	idp.On("CreateEmailSession", mock.Anything, mUserPtr.Email, "old").
+		Return(&model.Session{ID: "s"}, nil)
+	// A leftover check session is not fatal.
+	idp.On("DeleteSession", mock.Anything, "a", "s").Return(tError)
+	idp.On("UpdateEmail", mock.Anything, "a", "n@t.com").
+		Return(mUserPtr, nil)
+	idp.On("UpdateEmailVerification", mock.Anything, "a", false).Return(mUserPtr, nil)
+
+	actual, err := us.UpdateOwnEmail(
+		context.Background(),
+		"a",
+		&model.UpdateOwnEmailRequest{OldPassword: "old", Email: "n@t.com"},
+		"10.0.0.1",
+	)
+	assert.Equalf(t, mUserPtr, actual, "actual: %v", actual)
+	assert.Emptyf(t, err, "error should have been nil. err: %v", err)
+}
+
+func TestUserService_UpdateOwnEmail_WrongOldPassword(t *testing.T) {
+	us, idp := initUserServiceTest(t)
+
+	idp.On("GetUserByID", mock.Anything, "a").Return(mUserPtr, nil)
+	idp.On("CreateEmailSession", mock.Anything, mUserPtr.Email, mock.AnythingOfType("string")).
+		Return(nil, tError)
+
+	actual, err := us.UpdateOwnEmail(
+		context.Background(),
+		"a",
+		&model.UpdateOwnEmailRequest{OldPassword: "wrong", Email: "n@t.com"},
+		"10.0.0.1",
+	)
+	assert.Nilf(t, actual, "expected nil, actual: %v", actual)
+	assert.NotNil(t, err)
+}
+
+func TestUserService_UpdateName(t *testing.T) {
+	us, idp := initUserServiceTest(t)
+
+	idp.On("UpdateName", mock.Anything, "a", "matt").
+		Return(mUserPtr, nil)
+	actual, err := us.UpdateName(context.Background(), "a", &model.UpdateNameRequest{Name: "matt"})
+	assert.Equalf(t, mUserPtr, actual, "actual: %v", actual)
+	assert.Emptyf(t, err, "error should have been nil. err: %v", err)
+}
+
+func TestUserService_UpdateName_Error(t *testing.T) {
+	us, idp := initUserServiceTest(t)
+
+	idp.On("UpdateName", mock.Anything, "a", "matt").
+		Return(nil, tError)
+	actual, err := us.UpdateName(context.Background(), "a", &model.UpdateNameRequest{Name: "matt"})
+	assert.Nilf(t, actual, "expected nil, actual: %v", actual)
+	assertIamError(t, err, http.StatusBadGateway, constants.ERR_TYPE_PROVIDER_ERROR)
+}
+
 func TestUserService_UpdatePassword(t *testing.T) {
-	us, awClient := initUserServiceTest(t)
+	us, idp := initUserServiceTest(t)
 
-	awClient.On("UpdatePassword", "a", mock.AnythingOfType("*siogeneric.UpdatePasswordRequest")).
-		Return(mAwUserPtr, nil)
-	actual, err := us.UpdatePassword("a", uPasswordReq)
-	assert.Equalf(t, mAwUserPtr, actual, "actual: %v", actual)
+	idp.On("UpdatePassword", mock.Anything, "a", "1235").
+		Return(mUserPtr, nil)
+	actual, err := us.UpdatePassword(context.Background(), "a", uPasswordReq)
+	assert.Equalf(t, mUserPtr, actual, "actual: %v", actual)
 	assert.Emptyf(t, err, "error should have been nil. err: %v", err)
 }
 
 func TestUserService_UpdatePassword_Error(t *testing.T) {
-	us, awClient := initUserServiceTest(t)
+	us, idp := initUserServiceTest(t)
 
-	awClient.On("UpdatePassword", "a", mock.AnythingOfType("*siogeneric.UpdatePasswordRequest")).
+	idp.On("UpdatePassword", mock.Anything, "a", "1235").
 		Return(nil, tError)
-	actual, err := us.UpdatePassword("a", uPasswordReq)
+	actual, err := us.UpdatePassword(context.Background(), "a", uPasswordReq)
 	assert.Nilf(t, actual, "expected nil, actual: %v", actual)
-	assert.Equalf(
-		t,
-		err.Error(),
-		siotest.TBadRequestError.Error(),
-		"error: %v wanted: %v",
-		err.Error(),
-		siotest.TBadRequestError.Error(),
-	)
+	assertIamError(t, err, http.StatusBadGateway, constants.ERR_TYPE_PROVIDER_ERROR)
 }
 
 func TestUserService_DeleteUser(t *testing.T) {
-	us, awClient := initUserServiceTest(t)
+	us, idp := initUserServiceTest(t)
 
-	awClient.On("DeleteUser", "a").Return(nil)
-	actual, err := us.DeleteUser("a")
+	idp.On("DeleteUser", mock.Anything, "a").Return(nil)
+	actual, err := us.DeleteUser(context.Background(), "a")
 	assert.Truef(t, actual.Success, "actual.Success: %v", actual.Success)
 	assert.Emptyf(t, err, "error should have been nil. err: %v", err)
 }
 
 func TestUserService_DeleteUser_Error(t *testing.T) {
-	us, awClient := initUserServiceTest(t)
+	us, idp := initUserServiceTest(t)
 
-	awClient.On("DeleteUser", "a").Return(tError)
-	actual, err := us.DeleteUser("a")
+	idp.On("DeleteUser", mock.Anything, "a").Return(tUserNotFound)
+	actual, err := us.DeleteUser(context.Background(), "a")
 	assert.False(t, actual.Success)
-	assert.Equalf(
-		t,
-		err.Error(),
-		siotest.TNotFoundError.Error(),
-		"error: %v wanted: %v",
-		err.Error(),
-		siotest.TNotFoundError.Error(),
+	assertIamError(t, err, http.StatusNotFound, constants.ERR_TYPE_USER_NOT_FOUND)
+}
+
+func TestUserService_CreatePasswordRecovery(t *testing.T) {
+	us, idp := initUserServiceTest(t)
+
+	idp.On("CreateRecovery", mock.Anything, "t@t.com").Return(nil)
+	actual, err := us.CreatePasswordRecovery(
+		context.Background(),
+		&model.PasswordRecoveryRequest{Email: "t@t.com"},
+	)
+	assert.Truef(t, actual.Success, "actual.Success: %v", actual.Success)
+	assert.Emptyf(t, err, "error should have been nil. err: %v", err)
+}
+
+func TestUserService_CreatePasswordRecovery_UnknownEmail(t *testing.T) {
+	us, idp := initUserServiceTest(t)
+
+	idp.On("CreateRecovery", mock.Anything, "t@t.com").Return(tUserNotFound)
+	actual, err := us.CreatePasswordRecovery(
+		context.Background(),
+		&model.PasswordRecoveryRequest{Email: "t@t.com"},
+	)
+	assert.True(t, actual.Success)
+	assert.Nil(t, err)
+}
+
+func TestUserService_CreatePasswordRecovery_Error(t *testing.T) {
+	us, idp := initUserServiceTest(t)
+
+	idp.On("CreateRecovery", mock.Anything, "t@t.com").Return(tError)
+	actual, err := us.CreatePasswordRecovery(
+		context.Background(),
+		&model.PasswordRecoveryRequest{Email: "t@t.com"},
+	)
+	assert.False(t, actual.Success)
+	assertIamError(t, err, http.StatusBadGateway, constants.ERR_TYPE_PROVIDER_ERROR)
+}
+
+func TestUserService_ConfirmPasswordRecovery(t *testing.T) {
+	us, idp := initUserServiceTest(t)
+
+	idp.On("ConfirmRecovery", mock.Anything, "a", "b", "Fake@123").Return(nil)
+	actual, err := us.ConfirmPasswordRecovery(
+		context.Background(),
+		&model.PasswordRecoveryConfirmRequest{UserID: "a", Secret: "b", Password: "Fake@123"},
+	)
+	assert.Truef(t, actual.Success, "actual.Success: %v", actual.Success)
+	assert.Emptyf(t, err, "error should have been nil. err: %v", err)
+}
+
+func TestUserService_ConfirmPasswordRecovery_Error(t *testing.T) {
+	us, idp := initUserServiceTest(t)
+
+	idp.On("ConfirmRecovery", mock.Anything, "a", "b", "Fake@123").Return(tInvalidToken)
+	actual, err := us.ConfirmPasswordRecovery(
+		context.Background(),
+		&model.PasswordRecoveryConfirmRequest{UserID: "a", Secret: "b", Password: "Fake@123"},
+	)
+	assert.False(t, actual.Success)
+	assertIamError(t, err, http.StatusUnauthorized, constants.ERR_TYPE_INVALID_TOKEN)
+}
+
+func TestUserService_SendEmailVerification(t *testing.T) {
+	us, idp := initUserServiceTest(t)
+
+	idp.On("SendEmailVerification", mock.Anything, "jwt").Return(nil)
+	actual, err := us.SendEmailVerification(context.Background(), "jwt")
+	assert.Truef(t, actual.Success, "actual.Success: %v", actual.Success)
+	assert.Emptyf(t, err, "error should have been nil. err: %v", err)
+}
+
+func TestUserService_SendEmailVerification_Error(t *testing.T) {
+	us, idp := initUserServiceTest(t)
+
+	idp.On("SendEmailVerification", mock.Anything, "jwt").Return(tError)
+	actual, err := us.SendEmailVerification(context.Background(), "jwt")
+	assert.False(t, actual.Success)
+	assertIamError(t, err, http.StatusBadGateway, constants.ERR_TYPE_PROVIDER_ERROR)
+}
+
+func TestUserService_ConfirmEmailVerification(t *testing.T) {
+	us, idp := initUserServiceTest(t)
+
+	r := &model.VerificationConfirmRequest{UserID: "a", Secret: "b"}
+	idp.On("ConfirmEmailVerification", mock.Anything, "a", "b").Return(nil)
+	actual, err := us.ConfirmEmailVerification(context.Background(), r)
+	assert.Truef(t, actual.Success, "actual.Success: %v", actual.Success)
+	assert.Emptyf(t, err, "error should have been nil. err: %v", err)
+}
+
+func TestUserService_ConfirmEmailVerification_Error(t *testing.T) {
+	us, idp := initUserServiceTest(t)
+
+	r := &model.VerificationConfirmRequest{UserID: "a", Secret: "b"}
+	idp.On("ConfirmEmailVerification", mock.Anything, "a", "b").Return(tInvalidToken)
+	actual, err := us.ConfirmEmailVerification(context.Background(), r)
+	assert.False(t, actual.Success)
+	assertIamError(t, err, http.StatusUnauthorized, constants.ERR_TYPE_INVALID_TOKEN)
+}
+
+func TestUserService_SendPhoneVerification(t *testing.T) {
+	us, idp := initUserServiceTest(t)
+
+	idp.On("SendPhoneVerification", mock.Anything, "jwt").Return(nil)
+	actual, err := us.SendPhoneVerification(context.Background(), "jwt")
+	assert.Truef(t, actual.Success, "actual.Success: %v", actual.Success)
+	assert.Emptyf(t, err, "error should have been nil. err: %v", err)
+}
+
+func TestUserService_ConfirmPhoneVerification_Error(t *testing.T) {
+	us, idp := initUserServiceTest(t)
+
+	r := &model.VerificationConfirmRequest{UserID: "a", Secret: "123456"}
+	idp.On("ConfirmPhoneVerification", mock.Anything, "a", "123456").Return(tInvalidToken)
+	actual, err := us.ConfirmPhoneVerification(context.Background(), r)
+	assert.False(t, actual.Success)
+	assertIamError(t, err, http.StatusUnauthorized, constants.ERR_TYPE_INVALID_TOKEN)
+}
+
+func TestUserService_UpdateVerification(t *testing.T) {
+	verified := true
+	us, idp := initUserServiceTest(t)
+
+	idp.On("UpdateEmailVerification", mock.Anything, "a", true).Return(mUserPtr, nil)
+	idp.On("UpdatePhoneVerification", mock.Anything, "a", true).Return(mUserPtr, nil)
+	actual, err := us.UpdateVerification(
+		context.Background(),
+		"a",
+		&model.VerificationStatusRequest{Email: &verified, Phone: &verified},
 	)
+	assert.Equalf(t, mUserPtr, actual, "actual: %v", actual)
+	assert.Emptyf(t, err, "error should have been nil. err: %v", err)
+}
+
+func TestUserService_UpdateVerification_Error(t *testing.T) {
+	verified := false
+	us, idp := initUserServiceTest(t)
+
+	idp.On("UpdatePhoneVerification", mock.Anything, "a", false).Return(nil, tError)
+	actual, err := us.UpdateVerification(
+		context.Background(),
+		"a",
+		&model.VerificationStatusRequest{Phone: &verified},
+	)
+	assert.Nilf(t, actual, "expected nil, actual: %v", actual)
+	assertIamError(t, err, http.StatusBadGateway, constants.ERR_TYPE_PROVIDER_ERROR)
+}
+
+func TestUserService_UpdateStatus(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       bool
+		statusErr    error
+		revoke       bool
+		revokeErr    error
+		wantErr      bool
+		expectResult bool
+	}{
+		{name: "enable", status: true, expectResult: true},
+		{name: "block revokes sessions", status: false, revoke: true, expectResult: true},
+		{name: "status error", status: false, statusErr: tError, wantErr: true},
+		{name: "revoke error", status: false, revoke: true, revokeErr: tError, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			us, idp := initUserServiceTest(t)
+
+			if tt.statusErr != nil {
+				idp.On("UpdateStatus", mock.Anything, "a", tt.status).Return(nil, tt.statusErr)
+			} else {
+				idp.On("UpdateStatus", mock.Anything, "a", tt.status).Return(mUserPtr, nil)
+			}
+			if tt.revoke {
+				idp.On("DeleteSessions", mock.Anything, "a").Return(tt.revokeErr)
+			}
+
+			actual, err := us.UpdateStatus(
+				context.Background(),
+				"a",
+				&model.UpdateStatusRequest{Status: &tt.status},
+			)
+			if tt.wantErr {
+				assertIamError(t, err, http.StatusBadGateway, constants.ERR_TYPE_PROVIDER_ERROR)
+			} else {
+				assert.Nil(t, err)
+			}
+			if tt.expectResult {
+				assert.Equalf(t, mUserPtr, actual, "actual: %v", actual)
+			} else {
+				assert.Nilf(t, actual, "expected nil, actual: %v", actual)
+			}
+		})
+	}
+}
+
+func TestUserService_GetPrefs(t *testing.T) {
+	us, idp := initUserServiceTest(t)
+
+	prefs := model.Prefs{"theme": "dark"}
+	idp.On("GetPrefs", mock.Anything, "a").Return(prefs, nil)
+	actual, err := us.GetPrefs(context.Background(), "a")
+	assert.Equal(t, prefs, actual)
+	assert.Emptyf(t, err, "error should have been nil. err: %v", err)
+}
+
+func TestUserService_GetPrefs_Error(t *testing.T) {
+	us, idp := initUserServiceTest(t)
+
+	idp.On("GetPrefs", mock.Anything, "a").Return(nil, tUserNotFound)
+	actual, err := us.GetPrefs(context.Background(), "a")
+	assert.Nilf(t, actual, "expected nil, actual: %v", actual)
+	assert.Equal(t, err.Error(), sioerror.NewSioNotFoundError(constants.NoUserFound).Error())
+}
+
+func TestUserService_UpdatePrefs(t *testing.T) {
+	us, idp := initUserServiceTest(t)
+
+	idp.On("GetPrefs", mock.Anything, "a").
+		Return(model.Prefs{"theme": "dark", "newsletter": true, "editor": "markdown"}, nil)
+	merged := model.Prefs{"theme": "light", "newsletter": true}
+	idp.On("UpdatePrefs", mock.Anything, "a", merged).Return(merged, nil)
+
+	actual, err := us.UpdatePrefs(context.Background(), "a", &model.UpdatePrefsRequest{
+		Prefs: model.Prefs{"theme": "light", "editor": nil},
+	})
+	assert.Equal(t, merged, actual)
+	assert.Emptyf(t, err, "error should have been nil. err: %v", err)
+}
+
+func TestUserService_UpdatePrefs_TooLarge(t *testing.T) {
+	us, idp := initUserServiceTest(t)
+
+	idp.On("GetPrefs", mock.Anything, "a").
+		Return(model.Prefs{"bio": strings.Repeat("a", constants.MAX_PREFS_BYTES)}, nil)
+
+	actual, err := us.UpdatePrefs(context.Background(), "a", &model.UpdatePrefsRequest{
+		Prefs: model.Prefs{"theme": "light"},
+	})
+	assert.Nilf(t, actual, "expected nil, actual: %v", actual)
+	assert.Error(t, err)
+}
+
+func TestUserService_UpdatePrefs_Error(t *testing.T) {
+	us, idp := initUserServiceTest(t)
+
+	idp.On("GetPrefs", mock.Anything, "a").Return(model.Prefs{}, nil)
+	idp.On("UpdatePrefs", mock.Anything, "a", model.Prefs{"theme": "light"}).Return(nil, tError)
+
+	actual, err := us.UpdatePrefs(context.Background(), "a", &model.UpdatePrefsRequest{
+		Prefs: model.Prefs{"theme": "light"},
+	})
+	assert.Nilf(t, actual, "expected nil, actual: %v", actual)
+	assertIamError(t, err, http.StatusBadGateway, constants.ERR_TYPE_PROVIDER_ERROR)
+}
+
+// initWebhookUserServiceTest is a UserService publishing to a dispatcher,
+// never started, with one subscription to every event.
+func initWebhookUserServiceTest(
+	t *testing.T,
+) (*UserService, *mocks.IdentityProvider, *webhook.Dispatcher) {
+	us, idp := initUserServiceTest(t)
+	store, err := webhook.NewBoltStore(filepath.Join(t.TempDir(), "webhooks.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	us.webhooks = webhook.New(store, config.Default().Webhooks)
+	t.Cleanup(func() { _ = us.webhooks.Close(context.Background()) })
+
+	_, err = us.webhooks.Subscribe(context.Background(), &model.CreateWebhookSubscriptionRequest{
+		URL: "http://example.com/hook",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return us, idp, us.webhooks
+}
+
+// publishedEvents is the events published to d, newest first.
+func publishedEvents(t *testing.T, d *webhook.Dispatcher) []model.WebhookEvent {
+	t.Helper()
+	list, err := d.Deliveries(context.Background(), &model.WebhookDeliveryParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := make([]model.WebhookEvent, len(list.Deliveries))
+	for i, delivery := range list.Deliveries {
+		if err := json.Unmarshal(delivery.Payload, &events[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return events
+}
+
+func TestUserService_CreateUser_Webhook(t *testing.T) {
+	us, idp, d := initWebhookUserServiceTest(t)
+
+	created := &model.User{ID: "a", Name: "test_name", Email: "t@t.com", Status: true}
+	idp.On("CreateUser", mock.Anything, mock.AnythingOfType("*model.NewUser")).
+		Return(created, nil)
+	_, err := us.CreateUser(context.Background(), mCreateReq)
+	assert.Nil(t, err)
+
+	events := publishedEvents(t, d)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, constants.WEBHOOK_EVENT_USER_CREATED, events[0].Type)
+		assert.Equal(t, model.NewWebhookUser(created), events[0].User)
+	}
+}
+
+func TestUserService_UpdateStatus_Webhook(t *testing.T) {
+	us, idp, d := initWebhookUserServiceTest(t)
+
+	blocked, active := false, true
+	idp.On("UpdateStatus", mock.Anything, "a", false).Return(&model.User{ID: "a"}, nil)
+	idp.On("DeleteSessions", mock.Anything, "a").Return(nil)
+	idp.On("UpdateStatus", mock.Anything, "a", true).
+		Return(&model.User{ID: "a", Status: true}, nil)
+	ctx := context.Background()
+	_, err := us.UpdateStatus(ctx, "a", &model.UpdateStatusRequest{Status: &blocked})
+	assert.Nil(t, err)
+	_, err = us.UpdateStatus(ctx, "a", &model.UpdateStatusRequest{Status: &active})
+	assert.Nil(t, err)
+
+	events := publishedEvents(t, d)
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, constants.WEBHOOK_EVENT_USER_UNBLOCKED, events[0].Type)
+		assert.Equal(t, constants.WEBHOOK_EVENT_USER_BLOCKED, events[1].Type)
+	}
+}
+
+func TestUserService_UpdateStatus_WebhookRevokeError(t *testing.T) {
+	us, idp, d := initWebhookUserServiceTest(t)
+
+	blocked := false
+	idp.On("UpdateStatus", mock.Anything, "a", false).Return(&model.User{ID: "a"}, nil)
+	idp.On("DeleteSessions", mock.Anything, "a").Return(tError)
+	_, err := us.UpdateStatus(
+		context.Background(),
+		"a",
+		&model.UpdateStatusRequest{Status: &blocked},
+	)
+	assertIamError(t, err, http.StatusBadGateway, constants.ERR_TYPE_PROVIDER_ERROR)
+
+	events := publishedEvents(t, d)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, constants.WEBHOOK_EVENT_USER_BLOCKED, events[0].Type)
+	}
+}
+
+func TestUserService_DeleteUser_Webhook(t *testing.T) {
+	us, idp, d := initWebhookUserServiceTest(t)
+
+	idp.On("DeleteUser", mock.Anything, "a").Return(nil)
+	_, err := us.DeleteUser(context.Background(), "a")
+	assert.Nil(t, err)
+
+	events := publishedEvents(t, d)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, constants.WEBHOOK_EVENT_USER_DELETED, events[0].Type)
+		assert.Equal(t, model.WebhookUser{ID: "a"}, events[0].User)
+	}
+}
+
+func TestUserService_UpdateEmail_Webhook(t *testing.T) {
+	us, idp, d := initWebhookUserServiceTest(t)
+
+	changed := &model.User{ID: "a", Email: "test", EmailVerification: true}
+	idp.On("UpdateEmail", mock.Anything, "a", "test").Return(changed, nil)
+	idp.On("UpdateEmailVerification", mock.Anything, "a", false).Return(nil, tError)
+	_, err := us.UpdateEmail(context.Background(), "a", uEmailReq)
+	assertIamError(t, err, http.StatusBadGateway, constants.ERR_TYPE_PROVIDER_ERROR)
+
+	events := publishedEvents(t, d)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, constants.WEBHOOK_EVENT_USER_EMAIL_CHANGED, events[0].Type)
+		assert.Equal(t, model.NewWebhookUser(changed), events[0].User)
+	}
+}
+
+func TestUserService_UpdatePhone_Webhook(t *testing.T) {
+	us, idp, d := initWebhookUserServiceTest(t)
+
+	changed := &model.User{ID: "a", Phone: "+11235"}
+	idp.On("UpdatePhone", mock.Anything, "a", "+11235").Return(changed, nil)
+	idp.On("UpdatePhoneVerification", mock.Anything, "a", false).Return(changed, nil)
+	_, err := us.UpdatePhone(context.Background(), "a", uPhoneReq)
+	assert.Nil(t, err)
+
+	events := publishedEvents(t, d)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, constants.WEBHOOK_EVENT_USER_PHONE_CHANGED, events[0].Type)
+		assert.Equal(t, "+11235", events[0].User.Phone)
+	}
+}
+
+func TestUserService_Webhook_NotOnError(t *testing.T) {
+	us, idp, d := initWebhookUserServiceTest(t)
+
+	idp.On("UpdateEmail", mock.Anything, "a", "test").Return(nil, tError)
+	idp.On("DeleteUser", mock.Anything, "a").Return(tError)
+	_, _ = us.UpdateEmail(context.Background(), "a", uEmailReq)
+	_, _ = us.DeleteUser(context.Background(), "a")
+
+	assert.Empty(t, publishedEvents(t, d))
 }
@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+)
+
+// ContextError reports why ctx is done: a 504 once its deadline has passed and
+// a 499 when the caller went away. It is nil while ctx is still live.
+func ContextError(ctx context.Context) error {
+	switch err := ctx.Err(); {
+	case errors.Is(err, context.DeadlineExceeded):
+		return NewIamError(
+			http.StatusGatewayTimeout,
+			constants.ERR_TYPE_TIMEOUT,
+			constants.RequestTimeout,
+		)
+	case errors.Is(err, context.Canceled):
+		return NewIamError(
+			constants.STATUS_CLIENT_CLOSED_REQUEST,
+			constants.ERR_TYPE_CANCELED,
+			constants.RequestCanceled,
+		)
+	}
+	return nil
+}
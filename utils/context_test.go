@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+)
+
+func TestContextError(t *testing.T) {
+	assert.Nil(t, ContextError(context.Background()))
+
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	err := ContextError(expired)
+	assert.NotNil(t, err)
+	assert.Equal(t, constants.RequestTimeout, err.Error())
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = ContextError(canceled)
+	assert.NotNil(t, err)
+	assert.Equal(t, constants.RequestCanceled, err.Error())
+}
@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"time"
+
+	"gitea.slauson.io/slausonio/go-types/siogeneric"
+	"gitea.slauson.io/slausonio/go-utils/sioerror"
+)
+
+// IamError is an error with an HTTP status and a stable machine readable
+// Type, e.g. user_already_exists, that API clients can branch on. Types follow
+// Appwrite's naming whichever identity provider is configured.
+//
+// middleware.ErrorCodes sends Type in the X-Error-Code header, and RetryAfter
+// when set in the Retry-After header, and hands siomw.ErrorHandler the
+// equivalent sioerror to render.
+type IamError struct {
+	Status  int
+	Type    string
+	Message string
+	// RetryAfter is how long a client should wait before trying again.
+	RetryAfter time.Duration
+}
+
+func (e *IamError) Error() string {
+	return e.Message
+}
+
+// SioError is e in the shape siomw.ErrorHandler renders.
+func (e *IamError) SioError() error {
+	return sioerror.NewSioIamError(&siogeneric.AppwriteError{
+		Code:    e.Status,
+		Type:    e.Type,
+		Message: e.Message,
+	})
+}
+
+// NewIamError builds an IamError. Use it for failures whose cause clients may
+// need to tell apart; sioerror constructors remain fine for plain validation
+// errors.
+func NewIamError(code int, errType string, message string) error {
+	return &IamError{
+		Status:  code,
+		Type:    errType,
+		Message: message,
+	}
+}
@@ -0,0 +1,25 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRandomBytes(t *testing.T) {
+	b, err := RandomBytes(10)
+	assert.Nil(t, err)
+	assert.Len(t, b, 10)
+
+	other, _ := RandomBytes(10)
+	assert.NotEqual(t, b, other)
+}
+
+func TestNewID(t *testing.T) {
+	id, err := NewID()
+	assert.Nil(t, err)
+	assert.Len(t, id, 32)
+
+	other, _ := NewID()
+	assert.NotEqual(t, id, other)
+}
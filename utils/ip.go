@@ -0,0 +1,16 @@
+package utils
+
+import "net"
+
+// PublicIP reports whether ip is reachable on the internet rather than being
+// private, loopback, link-local, multicast or unspecified, which requests made
+// on a client's behalf must not reach.
+func PublicIP(ip net.IP) bool {
+	return !(ip.IsPrivate() ||
+		ip.IsLoopback() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		ip.IsUnspecified())
+}
@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublicIP(t *testing.T) {
+	public := []string{"93.184.216.34", "2606:2800:220:1:248:1893:25c8:1946"}
+	for _, ip := range public {
+		assert.Truef(t, PublicIP(net.ParseIP(ip)), "%s is public", ip)
+	}
+
+	internal := []string{
+		"10.0.0.1",
+		"172.16.0.1",
+		"192.168.1.1",
+		"127.0.0.1",
+		"169.254.169.254",
+		"0.0.0.0",
+		"224.0.0.1",
+		"::1",
+		"fe80::1",
+		"fd00::1",
+		"::",
+	}
+	for _, ip := range internal {
+		assert.Falsef(t, PublicIP(net.ParseIP(ip)), "%s is not public", ip)
+	}
+}
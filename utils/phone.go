@@ -0,0 +1,12 @@
+package utils
+
+import "strings"
+
+// NormalizePhone is number as providers store it, in E.164 form. Numbers are
+// taken without a country code as US ones.
+func NormalizePhone(number string) string {
+	if number == "" || strings.HasPrefix(number, "+") {
+		return number
+	}
+	return "+1" + number
+}
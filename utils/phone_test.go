@@ -0,0 +1,14 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizePhone(t *testing.T) {
+	assert.Equal(t, "+12125551234", NormalizePhone("2125551234"))
+	assert.Equal(t, "+12125551234", NormalizePhone("+12125551234"))
+	assert.Equal(t, "+442071234567", NormalizePhone("+442071234567"))
+	assert.Equal(t, "", NormalizePhone(""))
+}
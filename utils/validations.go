@@ -1,8 +1,22 @@
 package utils
 
 import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"time"
+
 	"gitea.slauson.io/slausonio/go-types/siogeneric"
 	"gitea.slauson.io/slausonio/go-utils/sioUtils"
+	"gitea.slauson.io/slausonio/go-utils/sioerror"
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+	"gitea.slauson.io/slausonio/iam-ms/model"
+)
+
+var prefKeyRegex = regexp.MustCompile(
+	fmt.Sprintf(`^[a-zA-Z][a-zA-Z0-9_]{0,%d}$`, constants.MAX_PREF_KEY_CHARS-1),
 )
 
 type IamValidations struct {
@@ -51,6 +65,30 @@ func (v *IamValidations) ValidateUpdateEmailRequest(r *siogeneric.UpdateEmailReq
 	return nil
 }
 
+func (v *IamValidations) ValidateUpdateOwnPasswordRequest(r *model.UpdateOwnPasswordRequest) error {
+	if r.OldPassword == "" {
+		return sioerror.NewSioBadRequestError(constants.MissingOldPassword)
+	}
+
+	if err := v.validator.ValidatePassword(r.Password); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (v *IamValidations) ValidateUpdateOwnEmailRequest(r *model.UpdateOwnEmailRequest) error {
+	if r.OldPassword == "" {
+		return sioerror.NewSioBadRequestError(constants.MissingOldPassword)
+	}
+
+	if err := v.validator.ValidateEmail(r.Email); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (v *IamValidations) ValidateUpdatePhoneRequest(r *siogeneric.UpdatePhoneRequest) error {
 	if err := v.validator.ValidatePhone(r.Number); err != nil {
 		return err
@@ -58,3 +96,239 @@ func (v *IamValidations) ValidateUpdatePhoneRequest(r *siogeneric.UpdatePhoneReq
 
 	return nil
 }
+
+func (v *IamValidations) ValidateUpdateNameRequest(r *model.UpdateNameRequest) error {
+	if err := v.validator.ValidateName(r.Name); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (v *IamValidations) ValidateListUsersParams(p *model.ListUsersParams) error {
+	if p.Limit < 0 || p.Limit > constants.MAX_USER_LIST_LIMIT {
+		return sioerror.NewSioBadRequestError(
+			fmt.Sprintf("limit must be between 1 and %d", constants.MAX_USER_LIST_LIMIT),
+		)
+	}
+
+	if p.Offset < 0 {
+		return sioerror.NewSioBadRequestError("offset must not be negative")
+	}
+
+	if p.Email != "" {
+		if err := v.validator.ValidateEmail(p.Email); err != nil {
+			return err
+		}
+	}
+
+	if p.CreatedAfter != "" {
+		if _, err := time.Parse(time.RFC3339, p.CreatedAfter); err != nil {
+			return sioerror.NewSioBadRequestError("createdAfter must be an RFC3339 timestamp")
+		}
+	}
+
+	return nil
+}
+
+func (v *IamValidations) ValidateAuditQueryParams(p *model.AuditQueryParams) error {
+	if p.Limit < 0 || p.Limit > constants.MAX_AUDIT_LIST_LIMIT {
+		return sioerror.NewSioBadRequestError(
+			fmt.Sprintf("limit must be between 1 and %d", constants.MAX_AUDIT_LIST_LIMIT),
+		)
+	}
+
+	switch p.Outcome {
+	case "", constants.AUDIT_OUTCOME_SUCCESS, constants.AUDIT_OUTCOME_FAILURE:
+	default:
+		return sioerror.NewSioBadRequestError("outcome must be success or failure")
+	}
+
+	if p.Since != "" {
+		if _, err := time.Parse(time.RFC3339, p.Since); err != nil {
+			return sioerror.NewSioBadRequestError("since must be an RFC3339 timestamp")
+		}
+	}
+
+	return nil
+}
+
+func (v *IamValidations) ValidateClearLockoutParams(p *model.ClearLockoutParams) error {
+	if p.Email == "" && p.IP == "" {
+		return sioerror.NewSioBadRequestError("email or ip is required")
+	}
+
+	if p.Email != "" {
+		if err := v.validator.ValidateEmail(p.Email); err != nil {
+			return err
+		}
+	}
+
+	if p.IP != "" && net.ParseIP(p.IP) == nil {
+		return sioerror.NewSioBadRequestError("ip must be an IP address")
+	}
+
+	return nil
+}
+
+func (v *IamValidations) ValidateCreateWebhookSubscriptionRequest(
+	r *model.CreateWebhookSubscriptionRequest,
+) error {
+	u, err := url.Parse(r.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return sioerror.NewSioBadRequestError("url must be an absolute http or https URL")
+	}
+
+	seen := map[string]bool{}
+	for _, e := range r.Events {
+		if !contains(constants.WEBHOOK_EVENTS, e) {
+			return sioerror.NewSioBadRequestError(fmt.Sprintf("unknown event %q", e))
+		}
+		if seen[e] {
+			return sioerror.NewSioBadRequestError(fmt.Sprintf("duplicate event %q", e))
+		}
+		seen[e] = true
+	}
+
+	return nil
+}
+
+func (v *IamValidations) ValidateWebhookDeliveryParams(p *model.WebhookDeliveryParams) error {
+	if p.Limit < 0 || p.Limit > constants.MAX_WEBHOOK_DELIVERY_LIMIT {
+		return sioerror.NewSioBadRequestError(
+			fmt.Sprintf("limit must be between 1 and %d", constants.MAX_WEBHOOK_DELIVERY_LIMIT),
+		)
+	}
+
+	switch p.Status {
+	case "",
+		constants.WEBHOOK_STATUS_PENDING,
+		constants.WEBHOOK_STATUS_DELIVERED,
+		constants.WEBHOOK_STATUS_FAILED:
+	default:
+		return sioerror.NewSioBadRequestError("status must be pending, delivered or failed")
+	}
+
+	if p.Event != "" && !contains(constants.WEBHOOK_EVENTS, p.Event) {
+		return sioerror.NewSioBadRequestError(fmt.Sprintf("unknown event %q", p.Event))
+	}
+
+	return nil
+}
+
+func (v *IamValidations) ValidatePasswordRecoveryRequest(r *model.PasswordRecoveryRequest) error {
+	if err := v.validator.ValidateEmail(r.Email); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (v *IamValidations) ValidatePasswordRecoveryConfirmRequest(
+	r *model.PasswordRecoveryConfirmRequest,
+) error {
+	if r.UserID == "" || r.Secret == "" {
+		return sioerror.NewSioBadRequestError("userId and secret are required")
+	}
+
+	if err := v.validator.ValidatePassword(r.Password); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (v *IamValidations) ValidateVerificationConfirmRequest(
+	r *model.VerificationConfirmRequest,
+) error {
+	if r.UserID == "" || r.Secret == "" {
+		return sioerror.NewSioBadRequestError("userId and secret are required")
+	}
+
+	return nil
+}
+
+func (v *IamValidations) ValidateVerificationStatusRequest(
+	r *model.VerificationStatusRequest,
+) error {
+	if r.Email == nil && r.Phone == nil {
+		return sioerror.NewSioBadRequestError("email or phone is required")
+	}
+
+	return nil
+}
+
+func (v *IamValidations) ValidateUpdateStatusRequest(r *model.UpdateStatusRequest) error {
+	if r.Status == nil {
+		return sioerror.NewSioBadRequestError("status is required")
+	}
+
+	return nil
+}
+
+func (v *IamValidations) ValidateUpdatePrefsRequest(r *model.UpdatePrefsRequest) error {
+	if len(r.Prefs) == 0 {
+		return sioerror.NewSioBadRequestError("prefs must contain at least one key")
+	}
+
+	for k := range r.Prefs {
+		if !prefKeyRegex.MatchString(k) {
+			return sioerror.NewSioBadRequestError(fmt.Sprintf(
+				"invalid pref key %q: keys must start with a letter and contain only letters, numbers and underscores (max %d chars)",
+				k,
+				constants.MAX_PREF_KEY_CHARS,
+			))
+		}
+	}
+
+	return v.ValidatePrefs(r.Prefs)
+}
+
+// ValidatePrefs checks the serialized size of a full set of prefs.
+func (v *IamValidations) ValidatePrefs(p model.Prefs) error {
+	pJSON, err := json.Marshal(p)
+	if err != nil {
+		return sioerror.NewSioBadRequestError(err.Error())
+	}
+
+	if len(pJSON) > constants.MAX_PREFS_BYTES {
+		return sioerror.NewSioBadRequestError(
+			fmt.Sprintf("prefs must not exceed %d bytes", constants.MAX_PREFS_BYTES),
+		)
+	}
+
+	return nil
+}
+
+func (v *IamValidations) ValidateUpdateRolesRequest(r *model.UpdateRolesRequest) error {
+	seen := map[string]bool{}
+	for _, role := range r.Roles {
+		valid := false
+		for _, known := range constants.ROLES {
+			if role == known {
+				valid = true
+				break
+			}
+		}
+
+		if !valid {
+			return sioerror.NewSioBadRequestError(fmt.Sprintf("unknown role %q", role))
+		}
+
+		if seen[role] {
+			return sioerror.NewSioBadRequestError(fmt.Sprintf("duplicate role %q", role))
+		}
+		seen[role] = true
+	}
+
+	return nil
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
@@ -1,12 +1,15 @@
 package utils
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
 	"gitea.slauson.io/slausonio/go-types/siogeneric"
 	"gitea.slauson.io/slausonio/go-utils/sioerror"
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+	"gitea.slauson.io/slausonio/iam-ms/model"
 )
 
 func TestValidateCreateUserRequest(t *testing.T) {
@@ -317,3 +320,599 @@ func TestValidateUpdatePhone(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateUpdateOwnPassword(t *testing.T) {
+	tests := []struct {
+		name    string
+		request *model.UpdateOwnPasswordRequest
+		error   error
+	}{
+		{
+			name:    "Valid",
+			request: &model.UpdateOwnPasswordRequest{OldPassword: "old", Password: "Password123!"},
+			error:   nil,
+		},
+		{
+			name:    "missing old password",
+			request: &model.UpdateOwnPasswordRequest{Password: "Password123!"},
+			error:   sioerror.NewSioBadRequestError(constants.MissingOldPassword),
+		},
+		{
+			name:    "weak password",
+			request: &model.UpdateOwnPasswordRequest{OldPassword: "old", Password: "asdf"},
+			error: sioerror.NewSioBadRequestError(
+				"invalid password: Requirements are 8 char min, 1 upper, 1 special, and 1 numerical",
+			),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v := NewIamValidations()
+			err := v.ValidateUpdateOwnPasswordRequest(test.request)
+			if test.error == nil {
+				assert.Nilf(t, err, "Expected no error, got %v", err)
+			} else {
+				assert.Equal(t, test.error.Error(), err.Error())
+			}
+		})
+	}
+}
+
+func TestValidateUpdateOwnEmail(t *testing.T) {
+	tests := []struct {
+		name    string
+		request *model.UpdateOwnEmailRequest
+		wantErr bool
+	}{
+		{
+			name:    "Valid",
+			request: &model.UpdateOwnEmailRequest{OldPassword: "old", Email: "t@t.com"},
+		},
+		{
+			name:    "missing old password",
+			request: &model.UpdateOwnEmailRequest{Email: "t@t.com"},
+			wantErr: true,
+		},
+		{
+			name:    "bad email",
+			request: &model.UpdateOwnEmailRequest{OldPassword: "old", Email: "t.com"},
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v := NewIamValidations()
+			err := v.ValidateUpdateOwnEmailRequest(test.request)
+			assert.Equal(t, test.wantErr, err != nil, "err: %v", err)
+		})
+	}
+}
+
+func TestValidateUpdateName(t *testing.T) {
+	tests := []struct {
+		name    string
+		request *model.UpdateNameRequest
+		error   error
+	}{
+		{
+			name:    "Valid",
+			request: &model.UpdateNameRequest{Name: "Matt Slauson"},
+			error:   nil,
+		},
+		{
+			name:    "empty name",
+			request: &model.UpdateNameRequest{Name: ""},
+			error:   sioerror.NewSioBadRequestError("invalid name"),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v := NewIamValidations()
+			err := v.ValidateUpdateNameRequest(test.request)
+			if test.error == nil {
+				assert.Nilf(t, err, "Expected no error, got %v", err)
+			} else {
+				assert.Equalf(
+					t,
+					test.error.Error(),
+					err.Error(),
+					"Expected error %s, got %s",
+					test.error.Error(),
+					err.Error(),
+				)
+			}
+		})
+	}
+}
+
+func TestValidateListUsersParams(t *testing.T) {
+	tests := []struct {
+		name   string
+		params *model.ListUsersParams
+		error  error
+	}{
+		{
+			name:   "Valid",
+			params: &model.ListUsersParams{Limit: 25, Offset: 50, CreatedAfter: "2023-06-01T00:00:00Z"},
+			error:  nil,
+		},
+		{
+			name:   "Empty",
+			params: &model.ListUsersParams{},
+			error:  nil,
+		},
+		{
+			name:   "limit too large",
+			params: &model.ListUsersParams{Limit: 101},
+			error:  sioerror.NewSioBadRequestError("limit must be between 1 and 100"),
+		},
+		{
+			name:   "negative limit",
+			params: &model.ListUsersParams{Limit: -1},
+			error:  sioerror.NewSioBadRequestError("limit must be between 1 and 100"),
+		},
+		{
+			name:   "negative offset",
+			params: &model.ListUsersParams{Offset: -1},
+			error:  sioerror.NewSioBadRequestError("offset must not be negative"),
+		},
+		{
+			name:   "bad createdAfter",
+			params: &model.ListUsersParams{CreatedAfter: "06/01/2023"},
+			error:  sioerror.NewSioBadRequestError("createdAfter must be an RFC3339 timestamp"),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v := NewIamValidations()
+			err := v.ValidateListUsersParams(test.params)
+			if test.error == nil {
+				assert.Nilf(t, err, "Expected no error, got %v", err)
+			} else {
+				assert.Equalf(
+					t,
+					test.error.Error(),
+					err.Error(),
+					"Expected error %s, got %s",
+					test.error.Error(),
+					err.Error(),
+				)
+			}
+		})
+	}
+}
+
+func TestValidateAuditQueryParams(t *testing.T) {
+	tests := []struct {
+		name   string
+		params *model.AuditQueryParams
+		error  error
+	}{
+		{
+			name: "Valid",
+			params: &model.AuditQueryParams{
+				Limit:   500,
+				Outcome: "failure",
+				Since:   "2023-06-01T00:00:00Z",
+			},
+			error: nil,
+		},
+		{
+			name:   "Empty",
+			params: &model.AuditQueryParams{},
+			error:  nil,
+		},
+		{
+			name:   "limit too large",
+			params: &model.AuditQueryParams{Limit: 501},
+			error:  sioerror.NewSioBadRequestError("limit must be between 1 and 500"),
+		},
+		{
+			name:   "unknown outcome",
+			params: &model.AuditQueryParams{Outcome: "denied"},
+			error:  sioerror.NewSioBadRequestError("outcome must be success or failure"),
+		},
+		{
+			name:   "bad since",
+			params: &model.AuditQueryParams{Since: "06/01/2023"},
+			error:  sioerror.NewSioBadRequestError("since must be an RFC3339 timestamp"),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v := NewIamValidations()
+			err := v.ValidateAuditQueryParams(test.params)
+			if test.error == nil {
+				assert.Nilf(t, err, "Expected no error, got %v", err)
+			} else if assert.NotNil(t, err) {
+				assert.Equal(t, test.error.Error(), err.Error())
+			}
+		})
+	}
+}
+
+func TestValidateClearLockoutParams(t *testing.T) {
+	tests := []struct {
+		name   string
+		params *model.ClearLockoutParams
+		error  error
+	}{
+		{
+			name:   "Valid",
+			params: &model.ClearLockoutParams{Email: "t@t.com", IP: "2001:db8::1"},
+			error:  nil,
+		},
+		{
+			name:   "Email only",
+			params: &model.ClearLockoutParams{Email: "t@t.com"},
+			error:  nil,
+		},
+		{
+			name:   "Empty",
+			params: &model.ClearLockoutParams{},
+			error:  sioerror.NewSioBadRequestError("email or ip is required"),
+		},
+		{
+			name:   "bad ip",
+			params: &model.ClearLockoutParams{IP: "10.0.0"},
+			error:  sioerror.NewSioBadRequestError("ip must be an IP address"),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v := NewIamValidations()
+			err := v.ValidateClearLockoutParams(test.params)
+			if test.error == nil {
+				assert.Nilf(t, err, "Expected no error, got %v", err)
+			} else if assert.NotNil(t, err) {
+				assert.Equal(t, test.error.Error(), err.Error())
+			}
+		})
+	}
+}
+
+func TestValidateCreateWebhookSubscriptionRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		request *model.CreateWebhookSubscriptionRequest
+		error   error
+	}{
+		{
+			name: "Valid",
+			request: &model.CreateWebhookSubscriptionRequest{
+				URL: "https://blog.example.com/hooks/iam",
+				Events: []string{
+					constants.WEBHOOK_EVENT_USER_CREATED,
+					constants.WEBHOOK_EVENT_USER_DELETED,
+				},
+			},
+			error: nil,
+		},
+		{
+			name:    "All events",
+			request: &model.CreateWebhookSubscriptionRequest{URL: "http://blog:8080/hooks"},
+			error:   nil,
+		},
+		{
+			name:    "Relative url",
+			request: &model.CreateWebhookSubscriptionRequest{URL: "/hooks/iam"},
+			error:   sioerror.NewSioBadRequestError("url must be an absolute http or https URL"),
+		},
+		{
+			name:    "Bad scheme",
+			request: &model.CreateWebhookSubscriptionRequest{URL: "ftp://blog.example.com"},
+			error:   sioerror.NewSioBadRequestError("url must be an absolute http or https URL"),
+		},
+		{
+			name: "Unknown event",
+			request: &model.CreateWebhookSubscriptionRequest{
+				URL:    "https://blog.example.com",
+				Events: []string{"user.renamed"},
+			},
+			error: sioerror.NewSioBadRequestError(`unknown event "user.renamed"`),
+		},
+		{
+			name: "Duplicate event",
+			request: &model.CreateWebhookSubscriptionRequest{
+				URL: "https://blog.example.com",
+				Events: []string{
+					constants.WEBHOOK_EVENT_USER_BLOCKED,
+					constants.WEBHOOK_EVENT_USER_BLOCKED,
+				},
+			},
+			error: sioerror.NewSioBadRequestError(`duplicate event "user.blocked"`),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v := NewIamValidations()
+			err := v.ValidateCreateWebhookSubscriptionRequest(test.request)
+			if test.error == nil {
+				assert.Nilf(t, err, "Expected no error, got %v", err)
+			} else if assert.NotNil(t, err) {
+				assert.Equal(t, test.error.Error(), err.Error())
+			}
+		})
+	}
+}
+
+func TestValidateWebhookDeliveryParams(t *testing.T) {
+	tests := []struct {
+		name   string
+		params *model.WebhookDeliveryParams
+		error  error
+	}{
+		{
+			name: "Valid",
+			params: &model.WebhookDeliveryParams{
+				Subscription: "a",
+				Event:        constants.WEBHOOK_EVENT_USER_EMAIL_CHANGED,
+				Status:       constants.WEBHOOK_STATUS_FAILED,
+				Limit:        500,
+			},
+			error: nil,
+		},
+		{
+			name:   "Empty",
+			params: &model.WebhookDeliveryParams{},
+			error:  nil,
+		},
+		{
+			name:   "Limit too high",
+			params: &model.WebhookDeliveryParams{Limit: 501},
+			error:  sioerror.NewSioBadRequestError("limit must be between 1 and 500"),
+		},
+		{
+			name:   "Bad status",
+			params: &model.WebhookDeliveryParams{Status: "lost"},
+			error:  sioerror.NewSioBadRequestError("status must be pending, delivered or failed"),
+		},
+		{
+			name:   "Unknown event",
+			params: &model.WebhookDeliveryParams{Event: "user.renamed"},
+			error:  sioerror.NewSioBadRequestError(`unknown event "user.renamed"`),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v := NewIamValidations()
+			err := v.ValidateWebhookDeliveryParams(test.params)
+			if test.error == nil {
+				assert.Nilf(t, err, "Expected no error, got %v", err)
+			} else if assert.NotNil(t, err) {
+				assert.Equal(t, test.error.Error(), err.Error())
+			}
+		})
+	}
+}
+
+func TestValidatePasswordRecoveryConfirmRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		request *model.PasswordRecoveryConfirmRequest
+		error   error
+	}{
+		{
+			name: "Valid",
+			request: &model.PasswordRecoveryConfirmRequest{
+				UserID:   "10000069",
+				Secret:   "secret",
+				Password: "Fake@123",
+			},
+			error: nil,
+		},
+		{
+			name: "missing secret",
+			request: &model.PasswordRecoveryConfirmRequest{
+				UserID:   "10000069",
+				Password: "Fake@123",
+			},
+			error: sioerror.NewSioBadRequestError("userId and secret are required"),
+		},
+		{
+			name: "Short Password",
+			request: &model.PasswordRecoveryConfirmRequest{
+				UserID:   "10000069",
+				Secret:   "secret",
+				Password: "F@123",
+			},
+			error: sioerror.NewSioBadRequestError(
+				"invalid password: Requirements are 8 char min, 1 upper, 1 special, and 1 numerical",
+			),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v := NewIamValidations()
+			err := v.ValidatePasswordRecoveryConfirmRequest(test.request)
+			if test.error == nil {
+				assert.Nilf(t, err, "Expected no error, got %v", err)
+			} else {
+				assert.Equalf(
+					t,
+					test.error.Error(),
+					err.Error(),
+					"Expected error %s, got %s",
+					test.error.Error(),
+					err.Error(),
+				)
+			}
+		})
+	}
+}
+
+func TestValidateVerificationStatusRequest(t *testing.T) {
+	verified := true
+	tests := []struct {
+		name    string
+		request *model.VerificationStatusRequest
+		error   error
+	}{
+		{
+			name:    "Valid",
+			request: &model.VerificationStatusRequest{Email: &verified},
+			error:   nil,
+		},
+		{
+			name:    "Empty",
+			request: &model.VerificationStatusRequest{},
+			error:   sioerror.NewSioBadRequestError("email or phone is required"),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v := NewIamValidations()
+			err := v.ValidateVerificationStatusRequest(test.request)
+			if test.error == nil {
+				assert.Nilf(t, err, "Expected no error, got %v", err)
+			} else {
+				assert.Equalf(
+					t,
+					test.error.Error(),
+					err.Error(),
+					"Expected error %s, got %s",
+					test.error.Error(),
+					err.Error(),
+				)
+			}
+		})
+	}
+}
+
+func TestValidateUpdateStatusRequest(t *testing.T) {
+	blocked := false
+	tests := []struct {
+		name    string
+		request *model.UpdateStatusRequest
+		error   error
+	}{
+		{
+			name:    "Valid",
+			request: &model.UpdateStatusRequest{Status: &blocked},
+			error:   nil,
+		},
+		{
+			name:    "Missing Status",
+			request: &model.UpdateStatusRequest{},
+			error:   sioerror.NewSioBadRequestError("status is required"),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v := NewIamValidations()
+			err := v.ValidateUpdateStatusRequest(test.request)
+			if test.error == nil {
+				assert.Nilf(t, err, "Expected no error, got %v", err)
+			} else {
+				assert.Equalf(
+					t,
+					test.error.Error(),
+					err.Error(),
+					"Expected error %s, got %s",
+					test.error.Error(),
+					err.Error(),
+				)
+			}
+		})
+	}
+}
+
+func TestValidateUpdatePrefsRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		request *model.UpdatePrefsRequest
+		error   error
+	}{
+		{
+			name: "Valid",
+			request: &model.UpdatePrefsRequest{
+				Prefs: model.Prefs{"theme": "dark", "newsletter_opt_in": true},
+			},
+			error: nil,
+		},
+		{
+			name:    "Empty",
+			request: &model.UpdatePrefsRequest{Prefs: model.Prefs{}},
+			error:   sioerror.NewSioBadRequestError("prefs must contain at least one key"),
+		},
+		{
+			name:    "Bad Key",
+			request: &model.UpdatePrefsRequest{Prefs: model.Prefs{"1theme": "dark"}},
+			error: sioerror.NewSioBadRequestError(
+				`invalid pref key "1theme": keys must start with a letter and contain only letters, numbers and underscores (max 64 chars)`,
+			),
+		},
+		{
+			name: "Too Large",
+			request: &model.UpdatePrefsRequest{
+				Prefs: model.Prefs{"bio": strings.Repeat("a", 16*1024)},
+			},
+			error: sioerror.NewSioBadRequestError("prefs must not exceed 16384 bytes"),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v := NewIamValidations()
+			err := v.ValidateUpdatePrefsRequest(test.request)
+			if test.error == nil {
+				assert.Nilf(t, err, "Expected no error, got %v", err)
+			} else {
+				assert.Equalf(
+					t,
+					test.error.Error(),
+					err.Error(),
+					"Expected error %s, got %s",
+					test.error.Error(),
+					err.Error(),
+				)
+			}
+		})
+	}
+}
+
+func TestValidateUpdateRolesRequest(t *testing.T) {
+	tests := []struct {
+		name    string
+		request *model.UpdateRolesRequest
+		error   error
+	}{
+		{
+			name:    "Valid",
+			request: &model.UpdateRolesRequest{Roles: []string{"admin", "author"}},
+			error:   nil,
+		},
+		{
+			name:    "No Roles",
+			request: &model.UpdateRolesRequest{Roles: []string{}},
+			error:   nil,
+		},
+		{
+			name:    "Unknown Role",
+			request: &model.UpdateRolesRequest{Roles: []string{"owner"}},
+			error:   sioerror.NewSioBadRequestError(`unknown role "owner"`),
+		},
+		{
+			name:    "Duplicate Role",
+			request: &model.UpdateRolesRequest{Roles: []string{"reader", "reader"}},
+			error:   sioerror.NewSioBadRequestError(`duplicate role "reader"`),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v := NewIamValidations()
+			err := v.ValidateUpdateRolesRequest(test.request)
+			if test.error == nil {
+				assert.Nilf(t, err, "Expected no error, got %v", err)
+			} else {
+				assert.Equalf(
+					t,
+					test.error.Error(),
+					err.Error(),
+					"Expected error %s, got %s",
+					test.error.Error(),
+					err.Error(),
+				)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"gitea.slauson.io/slausonio/iam-ms/utils"
+)
+
+// errBlockedAddress is a subscriber address deliveries may not reach, which
+// keeps webhooks from being pointed at the services around this one.
+var errBlockedAddress = errors.New("address is not public or in an allowed network")
+
+// parseNetworks is the CIDRs in cidrs, which are expected to be valid.
+func parseNetworks(cidrs []string) []*net.IPNet {
+	var networks []*net.IPNet
+	for _, cidr := range cidrs {
+		if _, network, err := net.ParseCIDR(cidr); err == nil {
+			networks = append(networks, network)
+		}
+	}
+	return networks
+}
+
+// allowedIP reports whether deliveries may reach ip: a public address or one
+// in an allowed network.
+func (d *Dispatcher) allowedIP(ip net.IP) bool {
+	if utils.PublicIP(ip) {
+		return true
+	}
+	for _, network := range d.allowed {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedURL reports whether raw may be subscribed. Only localhost and IP
+// hosts can be judged here; names are checked again once resolved.
+func (d *Dispatcher) allowedURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return d.allowedIP(net.IPv4(127, 0, 0, 1))
+	}
+	ip := net.ParseIP(host)
+	return ip == nil || d.allowedIP(ip)
+}
+
+// newClient is the delivery client. It connects only to addresses allowed
+// reports true for, checked after resolving so a name cannot lead elsewhere,
+// and does not follow redirects, which a subscriber could use to the same end.
+func newClient(timeout time.Duration, allowed func(net.IP) bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   timeout,
+		KeepAlive: 30 * time.Second,
+		Control: func(_ string, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !allowed(ip) {
+				return fmt.Errorf("%s: %w", host, errBlockedAddress)
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// Through a proxy the dialer would only ever see the proxy's address.
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
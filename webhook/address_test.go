@@ -0,0 +1,104 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"gitea.slauson.io/slausonio/iam-ms/config"
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+	"gitea.slauson.io/slausonio/iam-ms/model"
+	"gitea.slauson.io/slausonio/iam-ms/utils"
+)
+
+func TestSubscribe_BlockedURL(t *testing.T) {
+	d := New(initBoltStoreTest(t), config.Default().Webhooks)
+
+	urls := []string{
+		"http://127.0.0.1:8080/hook",
+		"http://localhost/hook",
+		"http://api.localhost/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://10.0.0.5/hook",
+		"http://[::1]/hook",
+	}
+	for _, url := range urls {
+		t.Run(url, func(t *testing.T) {
+			_, err := d.Subscribe(context.Background(), &model.CreateWebhookSubscriptionRequest{
+				URL:    url,
+				Events: []string{constants.WEBHOOK_EVENT_USER_CREATED},
+			})
+
+			var ie *utils.IamError
+			if assert.True(t, errors.As(err, &ie)) {
+				assert.Equal(t, http.StatusBadRequest, ie.Status)
+				assert.Equal(t, constants.WebhookURLBlocked, ie.Message)
+			}
+		})
+	}
+}
+
+func TestAllowedURL(t *testing.T) {
+	cfg := config.Default().Webhooks
+	cfg.AllowedNetworks = []string{"10.0.0.0/8"}
+	d := New(initBoltStoreTest(t), cfg)
+
+	assert.True(t, d.allowedURL("https://hooks.example.com/iam"))
+	assert.True(t, d.allowedURL("http://blog:8080/hook"))
+	assert.True(t, d.allowedURL("http://10.1.2.3/hook"))
+	assert.True(t, d.allowedURL("https://93.184.216.34/hook"))
+	assert.False(t, d.allowedURL("http://192.168.1.1/hook"))
+	assert.False(t, d.allowedURL("http://LOCALHOST/hook"))
+	assert.False(t, d.allowedURL("http://[fe80::1]/hook"))
+}
+
+func TestAllowedIP(t *testing.T) {
+	d := New(initBoltStoreTest(t), config.Default().Webhooks)
+	d.allowed = parseNetworks([]string{"10.0.0.0/8", "not a network"})
+
+	assert.Len(t, d.allowed, 1)
+	assert.True(t, d.allowedIP(net.ParseIP("8.8.8.8")))
+	assert.True(t, d.allowedIP(net.ParseIP("10.20.30.40")))
+	assert.False(t, d.allowedIP(net.ParseIP("172.16.0.1")))
+	assert.False(t, d.allowedIP(net.ParseIP("127.0.0.1")))
+}
+
+func TestDeliver_BlockedAddress(t *testing.T) {
+	d, _, _, requests := initDeliverTest(t, http.StatusNoContent)
+	ctx := context.Background()
+	// The subscriber's name now resolves to an address no longer allowed.
+	d.allowed = nil
+
+	_ = d.Publish(ctx, model.WebhookEvent{Type: constants.WEBHOOK_EVENT_USER_CREATED})
+	d.deliverDue(ctx)
+
+	assert.Empty(t, requests)
+	delivery := onlyDelivery(t, d)
+	assert.Equal(t, constants.WEBHOOK_STATUS_FAILED, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.Contains(t, delivery.LastError, errBlockedAddress.Error())
+}
+
+func TestDeliver_NoRedirects(t *testing.T) {
+	d, _ := initDispatcherTest(t)
+	target := make(chan struct{}, 1)
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		target <- struct{}{}
+	}))
+	t.Cleanup(internal.Close)
+	srv := httptest.NewServer(http.RedirectHandler(internal.URL, http.StatusFound))
+	t.Cleanup(srv.Close)
+	subscribe(t, d, srv.URL)
+	ctx := context.Background()
+
+	_ = d.Publish(ctx, model.WebhookEvent{Type: constants.WEBHOOK_EVENT_USER_CREATED})
+	d.deliverDue(ctx)
+
+	assert.Empty(t, target)
+	assert.Equal(t, http.StatusFound, onlyDelivery(t, d).LastStatus)
+}
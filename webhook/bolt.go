@@ -0,0 +1,269 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+	"gitea.slauson.io/slausonio/iam-ms/model"
+)
+
+var (
+	boltSubscriptionsBucket = []byte("subscriptions")
+	// boltDeliveriesBucket is keyed by creation time and ID, so it iterates
+	// oldest first.
+	boltDeliveriesBucket = []byte("deliveries")
+	// boltDueBucket indexes pending deliveries by next attempt, each key being
+	// the time followed by the delivery's key.
+	boltDueBucket = []byte("due")
+)
+
+// BoltStore keeps webhooks in an embedded BoltDB file. The file is locked
+// while open, so only one replica can use it.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+func NewBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{
+			boltSubscriptionsBucket,
+			boltDeliveriesBucket,
+			boltDueBucket,
+		} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return &BoltStore{db: db}, nil
+}
+
+// Close releases the database file lock.
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *BoltStore) AddSubscription(_ context.Context, sub model.WebhookSubscription) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return putJSON(tx.Bucket(boltSubscriptionsBucket), []byte(sub.ID), sub)
+	})
+}
+
+func (s *BoltStore) GetSubscription(
+	_ context.Context,
+	id string,
+) (*model.WebhookSubscription, error) {
+	sub := new(model.WebhookSubscription)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(boltSubscriptionsBucket).Get([]byte(id))
+		if v == nil {
+			return ErrNotFound
+		}
+		return json.Unmarshal(v, sub)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+func (s *BoltStore) ListSubscriptions(_ context.Context) ([]model.WebhookSubscription, error) {
+	subs := []model.WebhookSubscription{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltSubscriptionsBucket).ForEach(func(_, v []byte) error {
+			var sub model.WebhookSubscription
+			if err := json.Unmarshal(v, &sub); err != nil {
+				return err
+			}
+			subs = append(subs, sub)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(subs, func(i, j int) bool {
+		return subs[i].CreatedAt.Before(subs[j].CreatedAt)
+	})
+	return subs, nil
+}
+
+func (s *BoltStore) DeleteSubscription(_ context.Context, id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(boltSubscriptionsBucket)
+		if b.Get([]byte(id)) == nil {
+			return ErrNotFound
+		}
+		return b.Delete([]byte(id))
+	})
+}
+
+func (s *BoltStore) AddDeliveries(_ context.Context, ds []model.WebhookDelivery) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		for i := range ds {
+			if err := putDelivery(tx, &ds[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *BoltStore) ClaimDeliveries(
+	_ context.Context,
+	now time.Time,
+	limit int,
+	lease time.Duration,
+) ([]model.WebhookDelivery, error) {
+	var claimed []model.WebhookDelivery
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		// Collect first: the bucket must not change under its cursor.
+		var keys [][]byte
+		until := timeKey(now)
+		c := tx.Bucket(boltDueBucket).Cursor()
+		for k, _ := c.First(); k != nil && len(keys) < limit; k, _ = c.Next() {
+			if bytes.Compare(k[:8], until) > 0 {
+				break
+			}
+			keys = append(keys, append([]byte(nil), k[8:]...))
+		}
+
+		for _, k := range keys {
+			var d model.WebhookDelivery
+			v := tx.Bucket(boltDeliveriesBucket).Get(k)
+			if err := json.Unmarshal(v, &d); err != nil {
+				return err
+			}
+			d.NextAttempt = now.Add(lease)
+			if err := putDelivery(tx, &d); err != nil {
+				return err
+			}
+			claimed = append(claimed, d)
+		}
+		return nil
+	})
+	return claimed, err
+}
+
+func (s *BoltStore) SaveDelivery(_ context.Context, d model.WebhookDelivery) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return putDelivery(tx, &d)
+	})
+}
+
+func (s *BoltStore) ListDeliveries(
+	_ context.Context,
+	p *model.WebhookDeliveryParams,
+) ([]model.WebhookDelivery, error) {
+	ds := []model.WebhookDelivery{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(boltDeliveriesBucket).Cursor()
+		for k, v := c.Last(); k != nil && len(ds) < p.Limit; k, v = c.Prev() {
+			var d model.WebhookDelivery
+			if err := json.Unmarshal(v, &d); err != nil {
+				return err
+			}
+			if p.Matches(&d) {
+				ds = append(ds, d)
+			}
+		}
+		return nil
+	})
+	return ds, err
+}
+
+func (s *BoltStore) PruneDeliveries(_ context.Context, before time.Time) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(boltDeliveriesBucket)
+		var keys [][]byte
+		err := b.ForEach(func(k, v []byte) error {
+			var d model.WebhookDelivery
+			if err := json.Unmarshal(v, &d); err != nil {
+				return err
+			}
+			if d.Status != constants.WEBHOOK_STATUS_PENDING && d.UpdatedAt.Before(before) {
+				keys = append(keys, k)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, k := range keys {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// putDelivery writes d and moves its entry in the due index to its next
+// attempt, or out of the index once it is finished.
+func putDelivery(tx *bolt.Tx, d *model.WebhookDelivery) error {
+	deliveries := tx.Bucket(boltDeliveriesBucket)
+	due := tx.Bucket(boltDueBucket)
+	key := deliveryKey(d)
+
+	if v := deliveries.Get(key); v != nil {
+		var old model.WebhookDelivery
+		if err := json.Unmarshal(v, &old); err != nil {
+			return err
+		}
+		if err := due.Delete(dueKey(&old)); err != nil {
+			return err
+		}
+	}
+
+	if d.Status == constants.WEBHOOK_STATUS_PENDING {
+		if err := due.Put(dueKey(d), []byte{}); err != nil {
+			return err
+		}
+	}
+	return putJSON(deliveries, key, d)
+}
+
+func deliveryKey(d *model.WebhookDelivery) []byte {
+	return append(timeKey(d.CreatedAt), d.ID...)
+}
+
+func dueKey(d *model.WebhookDelivery) []byte {
+	return append(timeKey(d.NextAttempt), deliveryKey(d)...)
+}
+
+// timeKey encodes t so that keys sort by time. Times before 1970 sort first.
+func timeKey(t time.Time) []byte {
+	k := make([]byte, 8)
+	n := t.UnixNano()
+	if n < 0 {
+		n = 0
+	}
+	binary.BigEndian.PutUint64(k, uint64(n))
+	return k
+}
+
+func putJSON(b *bolt.Bucket, key []byte, v any) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return b.Put(key, value)
+}
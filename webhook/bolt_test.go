@@ -0,0 +1,49 @@
+package webhook
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"gitea.slauson.io/slausonio/iam-ms/model"
+)
+
+func initBoltStoreTest(t *testing.T) *BoltStore {
+	s, err := NewBoltStore(filepath.Join(t.TempDir(), "webhooks.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func TestBoltStore_Subscriptions(t *testing.T) {
+	testStoreSubscriptions(t, initBoltStoreTest(t))
+}
+
+func TestBoltStore_Deliveries(t *testing.T) {
+	testStoreDeliveries(t, initBoltStoreTest(t))
+}
+
+func TestBoltStore_Reopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "webhooks.db")
+	s, err := NewBoltStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	assert.Nil(t, s.AddDeliveries(ctx, []model.WebhookDelivery{tDelivery("1", "a", tStart)}))
+	assert.Nil(t, s.Close())
+
+	s, err = NewBoltStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	claimed, err := s.ClaimDeliveries(ctx, tStart, 10, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1"}, ids(claimed))
+}
@@ -0,0 +1,238 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	log "github.com/sirupsen/logrus"
+
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+	"gitea.slauson.io/slausonio/iam-ms/model"
+)
+
+const (
+	// claimBatch is how many deliveries are sent at once.
+	claimBatch = 16
+	// pruneInterval is how often finished deliveries older than the history
+	// are dropped.
+	pruneInterval = time.Hour
+	userAgent     = "iam-ms-webhooks"
+)
+
+// deliveryAttempts is registered with the default Prometheus registry, which
+// is what sioprom serves on /metrics.
+var deliveryAttempts = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "iam_webhook_delivery_attempts_total",
+	Help: "Webhook delivery attempts by outcome: delivered, retrying or failed.",
+}, []string{"outcome"})
+
+// permanentError is a failed attempt that is not worth retrying.
+type permanentError struct {
+	error
+}
+
+// retryPolicy is when a failed delivery is tried again.
+type retryPolicy struct {
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+}
+
+// after is the wait following the nth failed attempt.
+func (p retryPolicy) after(n int) time.Duration {
+	d := p.backoff
+	for i := 1; i < n && d < p.maxBackoff; i++ {
+		d *= 2
+	}
+	if d > p.maxBackoff {
+		return p.maxBackoff
+	}
+	return d
+}
+
+// Start sends due deliveries in the background until Close.
+func (d *Dispatcher) Start() {
+	d.startOnce.Do(func() {
+		go d.run()
+	})
+}
+
+// Close stops sending, waiting up to ctx for the attempts under way, and
+// closes the store. Deliveries still pending are sent after the next start.
+func (d *Dispatcher) Close(ctx context.Context) error {
+	if d == nil {
+		return nil
+	}
+
+	d.stopOnce.Do(func() {
+		close(d.stop)
+	})
+	// Closing first also keeps the dispatcher from ever starting.
+	started := true
+	d.startOnce.Do(func() {
+		started = false
+	})
+	if started {
+		select {
+		case <-d.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return d.store.Close()
+}
+
+func (d *Dispatcher) run() {
+	defer close(d.done)
+
+	// Attempts under way are not cut short by Close, only bounded by the
+	// client timeout, so none is sent twice for want of a few seconds.
+	ctx := context.Background()
+	ticker := time.NewTicker(d.poll)
+	defer ticker.Stop()
+	var lastPrune time.Time
+	for {
+		select {
+		case <-d.stop:
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+
+		d.deliverDue(ctx)
+		if now := d.now(); now.Sub(lastPrune) >= pruneInterval {
+			lastPrune = now
+			if err := d.store.PruneDeliveries(ctx, now.Add(-d.history)); err != nil {
+				log.Errorf("webhook deliveries not pruned: %v", err)
+			}
+		}
+	}
+}
+
+// deliverDue sends every delivery due, a batch at a time, until Close.
+func (d *Dispatcher) deliverDue(ctx context.Context) {
+	for {
+		select {
+		case <-d.stop:
+			return
+		default:
+		}
+
+		ds, err := d.store.ClaimDeliveries(ctx, d.now(), claimBatch, d.lease)
+		if err != nil {
+			log.Errorf("webhook deliveries not claimed: %v", err)
+			return
+		}
+
+		var wg sync.WaitGroup
+		for i := range ds {
+			wg.Add(1)
+			go func(delivery model.WebhookDelivery) {
+				defer wg.Done()
+				d.deliver(ctx, delivery)
+			}(ds[i])
+		}
+		wg.Wait()
+
+		if len(ds) < claimBatch {
+			return
+		}
+	}
+}
+
+// deliver makes an attempt at delivery and records how it went.
+func (d *Dispatcher) deliver(ctx context.Context, delivery model.WebhookDelivery) {
+	status, err := d.attempt(ctx, &delivery)
+
+	now := d.now()
+	delivery.Attempts++
+	delivery.LastStatus = status
+	delivery.LastError = ""
+	delivery.UpdatedAt = now
+
+	var perm permanentError
+	outcome := "retrying"
+	switch {
+	case err == nil:
+		outcome = constants.WEBHOOK_STATUS_DELIVERED
+		delivery.Status = constants.WEBHOOK_STATUS_DELIVERED
+	case delivery.Attempts >= d.policy.maxAttempts, errors.As(err, &perm):
+		outcome = constants.WEBHOOK_STATUS_FAILED
+		delivery.Status = constants.WEBHOOK_STATUS_FAILED
+		delivery.LastError = err.Error()
+	default:
+		delivery.LastError = err.Error()
+		delivery.NextAttempt = now.Add(d.policy.after(delivery.Attempts))
+	}
+	deliveryAttempts.WithLabelValues(outcome).Inc()
+
+	if err != nil {
+		log.Warnf(
+			"webhook delivery %s of %s to %s failed, attempt %d: %v",
+			delivery.ID,
+			delivery.EventType,
+			delivery.URL,
+			delivery.Attempts,
+			err,
+		)
+	}
+	if err := d.store.SaveDelivery(ctx, delivery); err != nil {
+		log.Errorf("webhook delivery %s not saved: %v", delivery.ID, err)
+	}
+}
+
+// attempt POSTs delivery to its subscription. It returns the HTTP status, or
+// 0 when there was no response.
+func (d *Dispatcher) attempt(ctx context.Context, delivery *model.WebhookDelivery) (int, error) {
+	sub, err := d.store.GetSubscription(ctx, delivery.SubscriptionID)
+	if errors.Is(err, ErrNotFound) {
+		return 0, permanentError{err}
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		delivery.URL,
+		bytes.NewReader(delivery.Payload),
+	)
+	if err != nil {
+		return 0, permanentError{err}
+	}
+	timestamp := d.now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(constants.HEADER_WEBHOOK_ID, delivery.ID)
+	req.Header.Set(constants.HEADER_WEBHOOK_EVENT, delivery.EventType)
+	req.Header.Set(constants.HEADER_WEBHOOK_TIMESTAMP, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(
+		constants.HEADER_WEBHOOK_SIGNATURE,
+		Sign(sub.Secret, timestamp, delivery.Payload),
+	)
+
+	resp, err := d.client.Do(req)
+	if errors.Is(err, errBlockedAddress) {
+		return 0, permanentError{err}
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("subscriber responded %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
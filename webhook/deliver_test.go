@@ -0,0 +1,238 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+	"gitea.slauson.io/slausonio/iam-ms/model"
+)
+
+// received is a request the test server got, with its body.
+type received struct {
+	*http.Request
+	body []byte
+}
+
+// initDeliverTest is a dispatcher with a subscription to a server answering
+// with statuses in turn, and the requests the server got.
+func initDeliverTest(
+	t *testing.T,
+	statuses ...int,
+) (*Dispatcher, *time.Time, *model.WebhookSubscription, chan received) {
+	d, now := initDispatcherTest(t)
+	requests := make(chan received, len(statuses))
+	var n int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.WriteHeader(statuses[n])
+		n++
+		requests <- received{r, body}
+	}))
+	t.Cleanup(srv.Close)
+
+	sub := subscribe(t, d, srv.URL)
+	return d, now, sub, requests
+}
+
+func onlyDelivery(t *testing.T, d *Dispatcher) model.WebhookDelivery {
+	t.Helper()
+	ds, err := d.store.ListDeliveries(context.Background(), &model.WebhookDeliveryParams{Limit: 10})
+	if err != nil || !assert.Len(t, ds, 1) {
+		t.FailNow()
+	}
+	return ds[0]
+}
+
+func TestRetryPolicy_After(t *testing.T) {
+	p := retryPolicy{maxAttempts: 10, backoff: 10 * time.Second, maxBackoff: time.Minute}
+
+	tests := map[int]time.Duration{
+		1:  10 * time.Second,
+		2:  20 * time.Second,
+		3:  40 * time.Second,
+		4:  time.Minute,
+		9:  time.Minute,
+		64: time.Minute,
+	}
+	for n, want := range tests {
+		assert.Equalf(t, want, p.after(n), "after attempt %d", n)
+	}
+}
+
+func TestDeliver(t *testing.T) {
+	d, _, sub, requests := initDeliverTest(t, http.StatusNoContent)
+	ctx := context.Background()
+	delivered := testutil.ToFloat64(deliveryAttempts.WithLabelValues("delivered"))
+
+	err := d.Publish(ctx, model.WebhookEvent{
+		ID:   "e1",
+		Type: constants.WEBHOOK_EVENT_USER_EMAIL_CHANGED,
+		User: model.WebhookUser{ID: "u1", Email: "new@t.com"},
+	})
+	assert.Nil(t, err)
+	d.deliverDue(ctx)
+
+	r := <-requests
+	body := r.body
+	assert.Equal(t, http.MethodPost, r.Method)
+	assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+	assert.Equal(t, constants.WEBHOOK_EVENT_USER_EMAIL_CHANGED, r.Header.Get("X-Webhook-Event"))
+	assert.Equal(t, strconv.FormatInt(tStart.Unix(), 10), r.Header.Get("X-Webhook-Timestamp"))
+	assert.True(t, Verify(sub.Secret, tStart.Unix(), body, r.Header.Get("X-Webhook-Signature")))
+	assert.Contains(t, string(body), `"email":"new@t.com"`)
+
+	delivery := onlyDelivery(t, d)
+	assert.Equal(t, delivery.ID, r.Header.Get("X-Webhook-Id"))
+	assert.Equal(t, constants.WEBHOOK_STATUS_DELIVERED, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.Equal(t, http.StatusNoContent, delivery.LastStatus)
+	assert.Empty(t, delivery.LastError)
+	assert.Equal(
+		t,
+		delivered+1,
+		testutil.ToFloat64(deliveryAttempts.WithLabelValues("delivered")),
+	)
+
+	// A delivered event is not sent again.
+	d.deliverDue(ctx)
+	assert.Empty(t, requests)
+}
+
+func TestDeliver_Retry(t *testing.T) {
+	d, now, _, requests := initDeliverTest(
+		t,
+		http.StatusServiceUnavailable,
+		http.StatusInternalServerError,
+		http.StatusOK,
+	)
+	ctx := context.Background()
+
+	_ = d.Publish(ctx, model.WebhookEvent{Type: constants.WEBHOOK_EVENT_USER_CREATED})
+	d.deliverDue(ctx)
+	<-requests
+
+	delivery := onlyDelivery(t, d)
+	assert.Equal(t, constants.WEBHOOK_STATUS_PENDING, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.Equal(t, http.StatusServiceUnavailable, delivery.LastStatus)
+	assert.Equal(t, "subscriber responded 503 Service Unavailable", delivery.LastError)
+	assert.True(t, now.Add(d.policy.backoff).Equal(delivery.NextAttempt))
+
+	// Not yet due.
+	d.deliverDue(ctx)
+	assert.Empty(t, requests)
+
+	*now = delivery.NextAttempt
+	d.deliverDue(ctx)
+	<-requests
+	delivery = onlyDelivery(t, d)
+	assert.Equal(t, 2, delivery.Attempts)
+	assert.True(t, now.Add(2*d.policy.backoff).Equal(delivery.NextAttempt))
+
+	*now = delivery.NextAttempt
+	d.deliverDue(ctx)
+	<-requests
+	delivery = onlyDelivery(t, d)
+	assert.Equal(t, constants.WEBHOOK_STATUS_DELIVERED, delivery.Status)
+	assert.Equal(t, 3, delivery.Attempts)
+	assert.Equal(t, http.StatusOK, delivery.LastStatus)
+	assert.Empty(t, delivery.LastError)
+}
+
+func TestDeliver_AttemptsRunOut(t *testing.T) {
+	d, now, _, requests := initDeliverTest(t, http.StatusBadGateway, http.StatusBadGateway)
+	d.policy.maxAttempts = 2
+	ctx := context.Background()
+
+	_ = d.Publish(ctx, model.WebhookEvent{Type: constants.WEBHOOK_EVENT_USER_CREATED})
+	d.deliverDue(ctx)
+	<-requests
+	*now = onlyDelivery(t, d).NextAttempt
+	d.deliverDue(ctx)
+	<-requests
+
+	delivery := onlyDelivery(t, d)
+	assert.Equal(t, constants.WEBHOOK_STATUS_FAILED, delivery.Status)
+	assert.Equal(t, 2, delivery.Attempts)
+	assert.Equal(t, http.StatusBadGateway, delivery.LastStatus)
+
+	*now = now.Add(24 * time.Hour)
+	d.deliverDue(ctx)
+	assert.Empty(t, requests)
+}
+
+func TestDeliver_Unsubscribed(t *testing.T) {
+	d, _, sub, requests := initDeliverTest(t)
+	ctx := context.Background()
+
+	_ = d.Publish(ctx, model.WebhookEvent{Type: constants.WEBHOOK_EVENT_USER_CREATED})
+	assert.Nil(t, d.Unsubscribe(ctx, sub.ID))
+	d.deliverDue(ctx)
+
+	assert.Empty(t, requests)
+	delivery := onlyDelivery(t, d)
+	assert.Equal(t, constants.WEBHOOK_STATUS_FAILED, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.Zero(t, delivery.LastStatus)
+	assert.Equal(t, ErrNotFound.Error(), delivery.LastError)
+}
+
+func TestDeliver_Unreachable(t *testing.T) {
+	d, _ := initDispatcherTest(t)
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	subscribe(t, d, srv.URL)
+	ctx := context.Background()
+
+	_ = d.Publish(ctx, model.WebhookEvent{Type: constants.WEBHOOK_EVENT_USER_CREATED})
+	d.deliverDue(ctx)
+
+	delivery := onlyDelivery(t, d)
+	assert.Equal(t, constants.WEBHOOK_STATUS_PENDING, delivery.Status)
+	assert.Zero(t, delivery.LastStatus)
+	assert.NotEmpty(t, delivery.LastError)
+}
+
+func TestDispatcher_StartClose(t *testing.T) {
+	d, _, _, requests := initDeliverTest(t, http.StatusOK)
+	// Publishing wakes the worker without waiting for the poll.
+	d.poll = time.Hour
+	d.Start()
+
+	_ = d.Publish(context.Background(), model.WebhookEvent{
+		Type: constants.WEBHOOK_EVENT_USER_CREATED,
+	})
+	select {
+	case <-requests:
+	case <-time.After(5 * time.Second):
+		t.Fatal("delivery not sent")
+	}
+
+	assert.Nil(t, d.Close(context.Background()))
+	assert.Nil(t, d.Close(context.Background()))
+}
+
+func TestDispatcher_CloseUnstarted(t *testing.T) {
+	d, _ := initDispatcherTest(t)
+	assert.Nil(t, d.Close(context.Background()))
+
+	// Closed first, it never starts.
+	d.Start()
+	select {
+	case <-d.done:
+		t.Fatal("dispatcher started after Close")
+	default:
+	}
+
+	var nilDispatcher *Dispatcher
+	assert.Nil(t, nilDispatcher.Close(context.Background()))
+}
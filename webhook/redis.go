@@ -0,0 +1,307 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"gitea.slauson.io/slausonio/iam-ms/config"
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+	"gitea.slauson.io/slausonio/iam-ms/model"
+)
+
+const (
+	redisSubscriptionsKey = "iam:webhooks:subscriptions"
+	redisDeliveryPrefix   = "iam:webhooks:delivery:"
+	// redisDeliveriesKey is every delivery ID scored by creation time and
+	// redisDueKey every pending one by next attempt, both in milliseconds.
+	redisDeliveriesKey = "iam:webhooks:deliveries"
+	redisDueKey        = "iam:webhooks:due"
+	// redisListPage is how many deliveries are read at a time when listing.
+	redisListPage = 100
+)
+
+// redisClaim moves a delivery's next attempt, given in ARGV[1], to ARGV[3]
+// unless it is no longer due at ARGV[2], which means another replica has
+// claimed it.
+var redisClaim = redis.NewScript(`
+local score = redis.call('ZSCORE', KEYS[1], ARGV[1])
+if not score or tonumber(score) > tonumber(ARGV[2]) then
+	return 0
+end
+redis.call('ZADD', KEYS[1], ARGV[3], ARGV[1])
+return 1
+`)
+
+// RedisStore keeps webhooks in Redis, shared by every replica.
+type RedisStore struct {
+	client *redis.Client
+}
+
+func NewRedisStore(cfg *config.Config) *RedisStore {
+	return NewRedisStoreFor(redis.NewClient(&redis.Options{
+		Addr:     cfg.Redis.Addr,
+		Password: cfg.Redis.Password.Value(),
+		DB:       cfg.Redis.DB,
+	}))
+}
+
+func NewRedisStoreFor(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
+
+func (s *RedisStore) AddSubscription(ctx context.Context, sub model.WebhookSubscription) error {
+	value, err := json.Marshal(sub)
+	if err != nil {
+		return err
+	}
+	return s.client.HSet(ctx, redisSubscriptionsKey, sub.ID, value).Err()
+}
+
+func (s *RedisStore) GetSubscription(
+	ctx context.Context,
+	id string,
+) (*model.WebhookSubscription, error) {
+	value, err := s.client.HGet(ctx, redisSubscriptionsKey, id).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sub := new(model.WebhookSubscription)
+	if err := json.Unmarshal(value, sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+func (s *RedisStore) ListSubscriptions(ctx context.Context) ([]model.WebhookSubscription, error) {
+	values, err := s.client.HVals(ctx, redisSubscriptionsKey).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	subs := make([]model.WebhookSubscription, 0, len(values))
+	for _, v := range values {
+		var sub model.WebhookSubscription
+		if err := json.Unmarshal([]byte(v), &sub); err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	sort.SliceStable(subs, func(i, j int) bool {
+		return subs[i].CreatedAt.Before(subs[j].CreatedAt)
+	})
+	return subs, nil
+}
+
+func (s *RedisStore) DeleteSubscription(ctx context.Context, id string) error {
+	n, err := s.client.HDel(ctx, redisSubscriptionsKey, id).Result()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *RedisStore) AddDeliveries(ctx context.Context, ds []model.WebhookDelivery) error {
+	_, err := s.client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		for i := range ds {
+			p.ZAdd(ctx, redisDeliveriesKey, redis.Z{
+				Score:  score(ds[i].CreatedAt),
+				Member: ds[i].ID,
+			})
+			if err := putRedisDelivery(ctx, p, &ds[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return err
+}
+
+func (s *RedisStore) ClaimDeliveries(
+	ctx context.Context,
+	now time.Time,
+	limit int,
+	lease time.Duration,
+) ([]model.WebhookDelivery, error) {
+	ids, err := s.client.ZRangeByScore(ctx, redisDueKey, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatFloat(score(now), 'f', -1, 64),
+		Count: int64(limit),
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	var claimed []model.WebhookDelivery
+	next := now.Add(lease)
+	for _, id := range ids {
+		ok, err := redisClaim.Run(
+			ctx,
+			s.client,
+			[]string{redisDueKey},
+			id,
+			score(now),
+			score(next),
+		).Bool()
+		if err != nil {
+			return claimed, err
+		}
+		if !ok {
+			continue
+		}
+
+		d, err := s.getDelivery(ctx, id)
+		if err != nil {
+			return claimed, err
+		}
+		d.NextAttempt = next
+		if err := s.SaveDelivery(ctx, *d); err != nil {
+			return claimed, err
+		}
+		claimed = append(claimed, *d)
+	}
+	return claimed, nil
+}
+
+func (s *RedisStore) SaveDelivery(ctx context.Context, d model.WebhookDelivery) error {
+	_, err := s.client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		return putRedisDelivery(ctx, p, &d)
+	})
+	return err
+}
+
+func (s *RedisStore) ListDeliveries(
+	ctx context.Context,
+	p *model.WebhookDeliveryParams,
+) ([]model.WebhookDelivery, error) {
+	ds := []model.WebhookDelivery{}
+	for start := int64(0); len(ds) < p.Limit; start += redisListPage {
+		ids, err := s.client.ZRevRange(ctx, redisDeliveriesKey, start, start+redisListPage-1).
+			Result()
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			break
+		}
+
+		page, err := s.getDeliveries(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		for i := range page {
+			if p.Matches(&page[i]) && len(ds) < p.Limit {
+				ds = append(ds, page[i])
+			}
+		}
+	}
+	return ds, nil
+}
+
+func (s *RedisStore) PruneDeliveries(ctx context.Context, before time.Time) error {
+	// A delivery is last updated after it was created, so those created since
+	// before cannot be due for pruning.
+	ids, err := s.client.ZRangeByScore(ctx, redisDeliveriesKey, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: "(" + strconv.FormatFloat(score(before), 'f', -1, 64),
+	}).Result()
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+
+	ds, err := s.getDeliveries(ctx, ids)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		for _, d := range ds {
+			if d.Status != constants.WEBHOOK_STATUS_PENDING && d.UpdatedAt.Before(before) {
+				p.Del(ctx, redisDeliveryPrefix+d.ID)
+				p.ZRem(ctx, redisDeliveriesKey, d.ID)
+			}
+		}
+		return nil
+	})
+	return err
+}
+
+func (s *RedisStore) getDelivery(ctx context.Context, id string) (*model.WebhookDelivery, error) {
+	value, err := s.client.Get(ctx, redisDeliveryPrefix+id).Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	d := new(model.WebhookDelivery)
+	if err := json.Unmarshal(value, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// getDeliveries returns the deliveries of ids in order, skipping any that have
+// been pruned meanwhile.
+func (s *RedisStore) getDeliveries(
+	ctx context.Context,
+	ids []string,
+) ([]model.WebhookDelivery, error) {
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = redisDeliveryPrefix + id
+	}
+	values, err := s.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	ds := make([]model.WebhookDelivery, 0, len(values))
+	for _, v := range values {
+		raw, ok := v.(string)
+		if !ok {
+			continue
+		}
+		var d model.WebhookDelivery
+		if err := json.Unmarshal([]byte(raw), &d); err != nil {
+			return nil, err
+		}
+		ds = append(ds, d)
+	}
+	return ds, nil
+}
+
+// putRedisDelivery queues writing d on p and moving it in the due set to its
+// next attempt, or out of the set once it is finished.
+func putRedisDelivery(ctx context.Context, p redis.Pipeliner, d *model.WebhookDelivery) error {
+	value, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+
+	p.Set(ctx, redisDeliveryPrefix+d.ID, value, 0)
+	if d.Status == constants.WEBHOOK_STATUS_PENDING {
+		p.ZAdd(ctx, redisDueKey, redis.Z{Score: score(d.NextAttempt), Member: d.ID})
+	} else {
+		p.ZRem(ctx, redisDueKey, d.ID)
+	}
+	return nil
+}
+
+// score is t as a sorted set score, in milliseconds.
+func score(t time.Time) float64 {
+	return float64(t.UnixMilli())
+}
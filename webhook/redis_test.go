@@ -0,0 +1,53 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+
+	"gitea.slauson.io/slausonio/iam-ms/model"
+)
+
+func initRedisStoreTest(t *testing.T) (*RedisStore, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	return NewRedisStoreFor(redis.NewClient(&redis.Options{Addr: mr.Addr()})), mr
+}
+
+func TestRedisStore_Subscriptions(t *testing.T) {
+	s, _ := initRedisStoreTest(t)
+	testStoreSubscriptions(t, s)
+}
+
+func TestRedisStore_Deliveries(t *testing.T) {
+	s, mr := initRedisStoreTest(t)
+	testStoreDeliveries(t, s)
+
+	assert.False(t, mr.Exists(redisDeliveryPrefix+"1"))
+}
+
+func TestRedisStore_ListPages(t *testing.T) {
+	s, _ := initRedisStoreTest(t)
+	ctx := context.Background()
+
+	var ds []model.WebhookDelivery
+	for i := 0; i < redisListPage+10; i++ {
+		sub := "a"
+		if i%2 == 1 {
+			sub = "b"
+		}
+		ds = append(ds, tDelivery(fmt.Sprint(i), sub, tStart.Add(time.Duration(i)*time.Second)))
+	}
+	assert.Nil(t, s.AddDeliveries(ctx, ds))
+
+	listed, err := s.ListDeliveries(ctx, &model.WebhookDeliveryParams{Subscription: "b", Limit: 55})
+	assert.Nil(t, err)
+	if assert.Len(t, listed, 55) {
+		assert.Equal(t, fmt.Sprint(redisListPage+9), listed[0].ID)
+		assert.Equal(t, "1", listed[54].ID)
+	}
+}
@@ -0,0 +1,29 @@
+package webhook
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"type":"user.created"}`)
+
+	// echo -n '1685620800.{"type":"user.created"}' | openssl dgst -sha256 -hmac secret
+	assert.Equal(
+		t,
+		"sha256=d1b6cf57559c74cbc9b2b0447b1c735ea5d94b1106bb302a883c439508d600e8",
+		Sign("secret", 1685620800, body),
+	)
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"type":"user.created"}`)
+	signature := Sign("secret", 1685620800, body)
+
+	assert.True(t, Verify("secret", 1685620800, body, signature))
+	assert.False(t, Verify("other", 1685620800, body, signature))
+	assert.False(t, Verify("secret", 1685620801, body, signature))
+	assert.False(t, Verify("secret", 1685620800, []byte(`{}`), signature))
+	assert.False(t, Verify("secret", 1685620800, body, ""))
+}
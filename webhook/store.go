@@ -0,0 +1,45 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gitea.slauson.io/slausonio/iam-ms/model"
+)
+
+// ErrNotFound is returned for a subscription that does not exist.
+var ErrNotFound = errors.New("webhook subscription not found")
+
+// Store keeps subscriptions and the outbox of deliveries, so pending
+// deliveries survive a restart.
+type Store interface {
+	AddSubscription(ctx context.Context, s model.WebhookSubscription) error
+	GetSubscription(ctx context.Context, id string) (*model.WebhookSubscription, error)
+	// ListSubscriptions returns every subscription, oldest first.
+	ListSubscriptions(ctx context.Context) ([]model.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, id string) error
+
+	AddDeliveries(ctx context.Context, ds []model.WebhookDelivery) error
+	// ClaimDeliveries returns up to limit pending deliveries due at now and
+	// puts their next attempt off by lease, so no other worker picks them up
+	// while they are being sent. One whose worker dies is tried again once
+	// the lease is up.
+	ClaimDeliveries(
+		ctx context.Context,
+		now time.Time,
+		limit int,
+		lease time.Duration,
+	) ([]model.WebhookDelivery, error)
+	// SaveDelivery records the outcome of an attempt at d.
+	SaveDelivery(ctx context.Context, d model.WebhookDelivery) error
+	// ListDeliveries returns the deliveries matching p, newest first.
+	ListDeliveries(
+		ctx context.Context,
+		p *model.WebhookDeliveryParams,
+	) ([]model.WebhookDelivery, error)
+	// PruneDeliveries forgets finished deliveries last updated before before.
+	PruneDeliveries(ctx context.Context, before time.Time) error
+
+	Close() error
+}
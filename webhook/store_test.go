@@ -0,0 +1,155 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+	"gitea.slauson.io/slausonio/iam-ms/model"
+)
+
+var tStart = time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func tDelivery(id string, subscription string, created time.Time) model.WebhookDelivery {
+	return model.WebhookDelivery{
+		ID:             id,
+		SubscriptionID: subscription,
+		URL:            "http://example.com/hook",
+		EventID:        "e-" + id,
+		EventType:      constants.WEBHOOK_EVENT_USER_CREATED,
+		Payload:        []byte(`{"id":"e-` + id + `"}`),
+		Status:         constants.WEBHOOK_STATUS_PENDING,
+		NextAttempt:    created,
+		CreatedAt:      created,
+		UpdatedAt:      created,
+	}
+}
+
+func ids(ds []model.WebhookDelivery) []string {
+	out := make([]string, len(ds))
+	for i := range ds {
+		out[i] = ds[i].ID
+	}
+	return out
+}
+
+// testStoreSubscriptions is the subscription half of the Store contract.
+func testStoreSubscriptions(t *testing.T, s Store) {
+	ctx := context.Background()
+
+	subs, err := s.ListSubscriptions(ctx)
+	assert.Nil(t, err)
+	assert.Empty(t, subs)
+
+	_, err = s.GetSubscription(ctx, "a")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	a := model.WebhookSubscription{
+		ID:        "a",
+		URL:       "http://a.example.com",
+		Events:    []string{constants.WEBHOOK_EVENT_USER_DELETED},
+		Secret:    "s",
+		CreatedAt: tStart.Add(time.Second),
+	}
+	b := model.WebhookSubscription{
+		ID:        "b",
+		URL:       "http://b.example.com",
+		Events:    []string{},
+		Secret:    "t",
+		CreatedAt: tStart,
+	}
+	assert.Nil(t, s.AddSubscription(ctx, a))
+	assert.Nil(t, s.AddSubscription(ctx, b))
+
+	got, err := s.GetSubscription(ctx, "a")
+	assert.Nil(t, err)
+	if assert.NotNil(t, got) {
+		assert.Equal(t, a.URL, got.URL)
+		assert.Equal(t, a.Events, got.Events)
+		assert.Equal(t, "s", got.Secret)
+		assert.True(t, a.CreatedAt.Equal(got.CreatedAt))
+	}
+
+	subs, err = s.ListSubscriptions(ctx)
+	assert.Nil(t, err)
+	if assert.Len(t, subs, 2) {
+		assert.Equal(t, "b", subs[0].ID)
+		assert.Equal(t, "a", subs[1].ID)
+	}
+
+	assert.Nil(t, s.DeleteSubscription(ctx, "a"))
+	assert.ErrorIs(t, s.DeleteSubscription(ctx, "a"), ErrNotFound)
+	_, err = s.GetSubscription(ctx, "a")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+// testStoreDeliveries is the outbox half of the Store contract.
+func testStoreDeliveries(t *testing.T, s Store) {
+	ctx := context.Background()
+
+	ds := []model.WebhookDelivery{
+		tDelivery("1", "a", tStart),
+		tDelivery("2", "b", tStart.Add(time.Second)),
+		tDelivery("3", "a", tStart.Add(2*time.Second)),
+	}
+	ds[2].NextAttempt = tStart.Add(time.Hour)
+	assert.Nil(t, s.AddDeliveries(ctx, ds))
+
+	listed, err := s.ListDeliveries(ctx, &model.WebhookDeliveryParams{Limit: 10})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"3", "2", "1"}, ids(listed))
+
+	listed, _ = s.ListDeliveries(ctx, &model.WebhookDeliveryParams{Subscription: "a", Limit: 1})
+	assert.Equal(t, []string{"3"}, ids(listed))
+
+	// Only the first two are due, and claiming puts them off by the lease.
+	now := tStart.Add(time.Minute)
+	claimed, err := s.ClaimDeliveries(ctx, now, 10, time.Minute)
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{"1", "2"}, ids(claimed))
+	for _, d := range claimed {
+		assert.True(t, now.Add(time.Minute).Equal(d.NextAttempt))
+		assert.JSONEq(t, `{"id":"e-`+d.ID+`"}`, string(d.Payload))
+	}
+
+	claimed, err = s.ClaimDeliveries(ctx, now, 10, time.Minute)
+	assert.Nil(t, err)
+	assert.Empty(t, claimed)
+
+	// Once the lease is up an unsaved delivery is due again.
+	claimed, _ = s.ClaimDeliveries(ctx, now.Add(time.Minute), 1, time.Minute)
+	assert.Len(t, claimed, 1)
+
+	delivered := ds[0]
+	delivered.Status = constants.WEBHOOK_STATUS_DELIVERED
+	delivered.Attempts = 1
+	delivered.LastStatus = 204
+	delivered.UpdatedAt = now
+	assert.Nil(t, s.SaveDelivery(ctx, delivered))
+
+	// A finished delivery is never claimed again.
+	claimed, _ = s.ClaimDeliveries(ctx, tStart.Add(2*time.Hour), 10, time.Minute)
+	assert.ElementsMatch(t, []string{"2", "3"}, ids(claimed))
+
+	listed, _ = s.ListDeliveries(ctx, &model.WebhookDeliveryParams{
+		Status: constants.WEBHOOK_STATUS_DELIVERED,
+		Limit:  10,
+	})
+	if assert.Len(t, listed, 1) {
+		assert.Equal(t, "1", listed[0].ID)
+		assert.Equal(t, 1, listed[0].Attempts)
+		assert.Equal(t, 204, listed[0].LastStatus)
+	}
+
+	// Pruning keeps pending deliveries and those updated since the cutoff.
+	assert.Nil(t, s.PruneDeliveries(ctx, now))
+	listed, _ = s.ListDeliveries(ctx, &model.WebhookDeliveryParams{Limit: 10})
+	assert.Equal(t, []string{"3", "2", "1"}, ids(listed))
+
+	assert.Nil(t, s.PruneDeliveries(ctx, now.Add(time.Second)))
+	listed, _ = s.ListDeliveries(ctx, &model.WebhookDeliveryParams{Limit: 10})
+	assert.Equal(t, []string{"3", "2"}, ids(listed))
+}
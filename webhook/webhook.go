@@ -0,0 +1,258 @@
+// Package webhook tells other services about identity changes. Events are
+// written to a persistent outbox, one delivery per matching subscription, and
+// POSTed from there with an HMAC signature, retrying with backoff until the
+// subscriber accepts them or the attempts run out.
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"gitea.slauson.io/slausonio/iam-ms/config"
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+	"gitea.slauson.io/slausonio/iam-ms/model"
+	"gitea.slauson.io/slausonio/iam-ms/utils"
+)
+
+// Dispatcher queues events for subscribers and delivers them in the
+// background once started.
+type Dispatcher struct {
+	store  Store
+	client *http.Client
+	// allowed are the private networks subscribers may be in.
+	allowed []*net.IPNet
+	policy  retryPolicy
+	poll    time.Duration
+	// lease is how long a claimed delivery is left to its worker.
+	lease   time.Duration
+	history time.Duration
+	now     func() time.Time
+
+	wake      chan struct{}
+	stop      chan struct{}
+	done      chan struct{}
+	startOnce sync.Once
+	stopOnce  sync.Once
+}
+
+var (
+	defaultOnce       sync.Once
+	defaultDispatcher *Dispatcher
+)
+
+func New(store Store, cfg config.Webhooks) *Dispatcher {
+	d := &Dispatcher{
+		store:   store,
+		allowed: parseNetworks(cfg.AllowedNetworks),
+		policy: retryPolicy{
+			maxAttempts: cfg.MaxAttempts,
+			backoff:     cfg.Backoff,
+			maxBackoff:  cfg.MaxBackoff,
+		},
+		poll:    cfg.PollInterval,
+		lease:   2 * cfg.Timeout,
+		history: cfg.History,
+		now:     time.Now,
+		wake:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	d.client = newClient(cfg.Timeout, d.allowedIP)
+	return d
+}
+
+// FromConfig builds a Dispatcher over the configured store, or returns nil
+// when webhooks are off.
+func FromConfig(cfg *config.Config) (*Dispatcher, error) {
+	var store Store
+	switch cfg.Webhooks.Store {
+	case "":
+		return nil, nil
+	case constants.STORE_REDIS:
+		store = NewRedisStore(cfg)
+	default:
+		s, err := NewBoltStore(cfg.Webhooks.DB)
+		if err != nil {
+			return nil, err
+		}
+		store = s
+	}
+	return New(store, cfg.Webhooks), nil
+}
+
+// Default is the started Dispatcher shared by the user service and the
+// webhook admin routes, or nil when webhooks are off.
+func Default(cfg *config.Config) *Dispatcher {
+	defaultOnce.Do(func() {
+		d, err := FromConfig(cfg)
+		if err != nil {
+			log.Fatalf("error: webhook store: %v", err)
+		}
+		if d != nil {
+			d.Start()
+		}
+		defaultDispatcher = d
+	})
+	return defaultDispatcher
+}
+
+// Publish queues e for every subscription to its type, filling in its ID and
+// time when unset.
+func (d *Dispatcher) Publish(ctx context.Context, e model.WebhookEvent) error {
+	if e.ID == "" {
+		id, err := utils.NewID()
+		if err != nil {
+			return err
+		}
+		e.ID = id
+	}
+	if e.Time.IsZero() {
+		e.Time = d.now().UTC()
+	}
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	subs, err := d.store.ListSubscriptions(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := d.now()
+	var ds []model.WebhookDelivery
+	for i := range subs {
+		if !subs[i].Matches(e.Type) {
+			continue
+		}
+		id, err := utils.NewID()
+		if err != nil {
+			return err
+		}
+		ds = append(ds, model.WebhookDelivery{
+			ID:             id,
+			SubscriptionID: subs[i].ID,
+			URL:            subs[i].URL,
+			EventID:        e.ID,
+			EventType:      e.Type,
+			Payload:        payload,
+			Status:         constants.WEBHOOK_STATUS_PENDING,
+			NextAttempt:    now,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		})
+	}
+	if len(ds) == 0 {
+		return nil
+	}
+
+	if err := d.store.AddDeliveries(ctx, ds); err != nil {
+		return err
+	}
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Subscribe registers the subscription r asks for, with a new secret. Its URL
+// must not point at a private address outside the allowed networks.
+func (d *Dispatcher) Subscribe(
+	ctx context.Context,
+	r *model.CreateWebhookSubscriptionRequest,
+) (*model.WebhookSubscription, error) {
+	if !d.allowedURL(r.URL) {
+		return nil, utils.NewIamError(
+			http.StatusBadRequest,
+			constants.ERR_TYPE_ARGUMENT_INVALID,
+			constants.WebhookURLBlocked,
+		)
+	}
+	id, err := utils.NewID()
+	if err != nil {
+		return nil, err
+	}
+	secret, err := utils.NewID()
+	if err != nil {
+		return nil, err
+	}
+	sub := model.WebhookSubscription{
+		ID:        id,
+		URL:       r.URL,
+		Events:    r.Events,
+		Secret:    secret,
+		CreatedAt: d.now().UTC(),
+	}
+	if sub.Events == nil {
+		sub.Events = []string{}
+	}
+	if err := d.store.AddSubscription(ctx, sub); err != nil {
+		return nil, storeError(ctx, err)
+	}
+	return &sub, nil
+}
+
+// Subscriptions lists every subscription, oldest first, without secrets.
+func (d *Dispatcher) Subscriptions(ctx context.Context) (*model.WebhookSubscriptionList, error) {
+	subs, err := d.store.ListSubscriptions(ctx)
+	if err != nil {
+		return nil, storeError(ctx, err)
+	}
+	for i := range subs {
+		subs[i].Secret = ""
+	}
+	return &model.WebhookSubscriptionList{Subscriptions: subs}, nil
+}
+
+// Unsubscribe deletes subscription id. Its pending deliveries fail at their
+// next attempt.
+func (d *Dispatcher) Unsubscribe(ctx context.Context, id string) error {
+	if err := d.store.DeleteSubscription(ctx, id); err != nil {
+		return storeError(ctx, err)
+	}
+	return nil
+}
+
+// Deliveries lists the deliveries matching p, newest first.
+func (d *Dispatcher) Deliveries(
+	ctx context.Context,
+	p *model.WebhookDeliveryParams,
+) (*model.WebhookDeliveryList, error) {
+	if p.Limit == 0 {
+		p.Limit = constants.DEFAULT_WEBHOOK_DELIVERY_LIMIT
+	}
+	ds, err := d.store.ListDeliveries(ctx, p)
+	if err != nil {
+		return nil, storeError(ctx, err)
+	}
+	return &model.WebhookDeliveryList{Deliveries: ds}, nil
+}
+
+// storeError maps a failed store call to the error the API returns.
+func storeError(ctx context.Context, err error) error {
+	if cerr := utils.ContextError(ctx); cerr != nil {
+		return cerr
+	}
+	if errors.Is(err, ErrNotFound) {
+		return utils.NewIamError(
+			http.StatusNotFound,
+			constants.ERR_TYPE_NOT_FOUND,
+			constants.NoWebhookFound,
+		)
+	}
+
+	log.Errorf("webhook store call failed: %v", err)
+	return utils.NewIamError(
+		http.StatusInternalServerError,
+		constants.ERR_TYPE_UNKNOWN,
+		constants.WebhookStoreFailed,
+	)
+}
@@ -0,0 +1,216 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"gitea.slauson.io/slausonio/iam-ms/config"
+	"gitea.slauson.io/slausonio/iam-ms/constants"
+	"gitea.slauson.io/slausonio/iam-ms/model"
+	"gitea.slauson.io/slausonio/iam-ms/utils"
+)
+
+// initDispatcherTest is a Dispatcher over a fresh bolt store whose clock
+// reads *now.
+func initDispatcherTest(t *testing.T) (*Dispatcher, *time.Time) {
+	now := tStart
+	cfg := config.Default().Webhooks
+	cfg.AllowedNetworks = []string{"127.0.0.0/8", "::1/128"}
+	d := New(initBoltStoreTest(t), cfg)
+	d.now = func() time.Time { return now }
+	return d, &now
+}
+
+func subscribe(
+	t *testing.T,
+	d *Dispatcher,
+	url string,
+	events ...string,
+) *model.WebhookSubscription {
+	t.Helper()
+	sub, err := d.Subscribe(context.Background(), &model.CreateWebhookSubscriptionRequest{
+		URL:    url,
+		Events: events,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sub
+}
+
+func TestFromConfig(t *testing.T) {
+	cfg := config.Default()
+	d, err := FromConfig(cfg)
+	assert.Nil(t, err)
+	assert.Nil(t, d)
+
+	cfg.Webhooks.Store = constants.STORE_BOLT
+	cfg.Webhooks.DB = filepath.Join(t.TempDir(), "webhooks.db")
+	d, err = FromConfig(cfg)
+	assert.Nil(t, err)
+	if assert.NotNil(t, d) {
+		assert.IsType(t, &BoltStore{}, d.store)
+		assert.Nil(t, d.Close(context.Background()))
+	}
+
+	cfg.Webhooks.DB = filepath.Join(t.TempDir(), "missing", "webhooks.db")
+	_, err = FromConfig(cfg)
+	assert.NotNil(t, err)
+}
+
+func TestPublish(t *testing.T) {
+	d, now := initDispatcherTest(t)
+	all := subscribe(t, d, "http://all.example.com")
+	deleted := subscribe(t, d, "http://deleted.example.com", constants.WEBHOOK_EVENT_USER_DELETED)
+	ctx := context.Background()
+
+	err := d.Publish(ctx, model.WebhookEvent{
+		Type: constants.WEBHOOK_EVENT_USER_CREATED,
+		User: model.WebhookUser{ID: "u1", Email: "t@t.com"},
+	})
+	assert.Nil(t, err)
+
+	*now = now.Add(time.Second)
+	err = d.Publish(ctx, model.WebhookEvent{
+		ID:   "e2",
+		Type: constants.WEBHOOK_EVENT_USER_DELETED,
+		User: model.WebhookUser{ID: "u1"},
+	})
+	assert.Nil(t, err)
+
+	ds, err := d.store.ListDeliveries(ctx, &model.WebhookDeliveryParams{Limit: 10})
+	assert.Nil(t, err)
+	if !assert.Len(t, ds, 3) {
+		return
+	}
+	assert.Equal(t, constants.WEBHOOK_EVENT_USER_CREATED, ds[2].EventType)
+	assert.Equal(t, all.ID, ds[2].SubscriptionID)
+	assert.Equal(t, all.URL, ds[2].URL)
+	assert.Equal(t, constants.WEBHOOK_STATUS_PENDING, ds[2].Status)
+	assert.True(t, tStart.Equal(ds[2].NextAttempt))
+
+	var e model.WebhookEvent
+	assert.Nil(t, json.Unmarshal(ds[2].Payload, &e))
+	assert.NotEmpty(t, e.ID)
+	assert.Equal(t, ds[2].EventID, e.ID)
+	assert.True(t, tStart.Equal(e.Time))
+	assert.Equal(t, "t@t.com", e.User.Email)
+
+	for _, delivery := range ds[:2] {
+		assert.Equal(t, "e2", delivery.EventID)
+		assert.Equal(t, constants.WEBHOOK_EVENT_USER_DELETED, delivery.EventType)
+	}
+	assert.ElementsMatch(
+		t,
+		[]string{all.ID, deleted.ID},
+		[]string{ds[0].SubscriptionID, ds[1].SubscriptionID},
+	)
+	assert.Len(t, d.wake, 1)
+}
+
+func TestPublish_NoSubscribers(t *testing.T) {
+	d, _ := initDispatcherTest(t)
+	subscribe(t, d, "http://deleted.example.com", constants.WEBHOOK_EVENT_USER_DELETED)
+
+	err := d.Publish(context.Background(), model.WebhookEvent{
+		Type: constants.WEBHOOK_EVENT_USER_CREATED,
+	})
+	assert.Nil(t, err)
+
+	ds, _ := d.store.ListDeliveries(context.Background(), &model.WebhookDeliveryParams{Limit: 10})
+	assert.Empty(t, ds)
+	assert.Empty(t, d.wake)
+}
+
+func TestSubscriptions(t *testing.T) {
+	d, now := initDispatcherTest(t)
+	first := subscribe(t, d, "http://a.example.com")
+	*now = now.Add(time.Second)
+	second := subscribe(
+		t,
+		d,
+		"http://b.example.com",
+		constants.WEBHOOK_EVENT_USER_BLOCKED,
+		constants.WEBHOOK_EVENT_USER_UNBLOCKED,
+	)
+
+	assert.NotEmpty(t, first.Secret)
+	assert.NotEqual(t, first.Secret, second.Secret)
+	assert.Equal(t, []string{}, first.Events)
+
+	list, err := d.Subscriptions(context.Background())
+	assert.Nil(t, err)
+	if assert.Len(t, list.Subscriptions, 2) {
+		assert.Equal(t, first.ID, list.Subscriptions[0].ID)
+		assert.Equal(t, second.ID, list.Subscriptions[1].ID)
+		assert.Equal(t, second.Events, list.Subscriptions[1].Events)
+		for _, sub := range list.Subscriptions {
+			assert.Empty(t, sub.Secret)
+		}
+	}
+
+	// The stored secret is untouched.
+	sub, _ := d.store.GetSubscription(context.Background(), first.ID)
+	assert.Equal(t, first.Secret, sub.Secret)
+}
+
+func TestUnsubscribe(t *testing.T) {
+	d, _ := initDispatcherTest(t)
+	sub := subscribe(t, d, "http://a.example.com")
+	ctx := context.Background()
+
+	assert.Nil(t, d.Unsubscribe(ctx, sub.ID))
+
+	err := d.Unsubscribe(ctx, sub.ID)
+	var ie *utils.IamError
+	if assert.True(t, errors.As(err, &ie)) {
+		assert.Equal(t, http.StatusNotFound, ie.Status)
+		assert.Equal(t, constants.ERR_TYPE_NOT_FOUND, ie.Type)
+		assert.Equal(t, constants.NoWebhookFound, ie.Message)
+	}
+}
+
+func TestDeliveries(t *testing.T) {
+	d, now := initDispatcherTest(t)
+	sub := subscribe(t, d, "http://a.example.com")
+	ctx := context.Background()
+	for i := 0; i < constants.DEFAULT_WEBHOOK_DELIVERY_LIMIT+1; i++ {
+		*now = now.Add(time.Second)
+		_ = d.Publish(ctx, model.WebhookEvent{Type: constants.WEBHOOK_EVENT_USER_CREATED})
+	}
+
+	params := &model.WebhookDeliveryParams{}
+	list, err := d.Deliveries(ctx, params)
+	assert.Nil(t, err)
+	assert.Len(t, list.Deliveries, constants.DEFAULT_WEBHOOK_DELIVERY_LIMIT)
+	assert.Equal(t, constants.DEFAULT_WEBHOOK_DELIVERY_LIMIT, params.Limit)
+
+	list, _ = d.Deliveries(ctx, &model.WebhookDeliveryParams{Subscription: sub.ID, Limit: 2})
+	assert.Len(t, list.Deliveries, 2)
+
+	list, _ = d.Deliveries(ctx, &model.WebhookDeliveryParams{Subscription: "other"})
+	assert.Equal(t, []model.WebhookDelivery{}, list.Deliveries)
+}
+
+func TestStoreError(t *testing.T) {
+	var ie *utils.IamError
+	err := storeError(context.Background(), errors.New("disk full"))
+	if assert.True(t, errors.As(err, &ie)) {
+		assert.Equal(t, http.StatusInternalServerError, ie.Status)
+		assert.Equal(t, constants.WebhookStoreFailed, ie.Message)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = storeError(ctx, errors.New("canceled"))
+	if assert.True(t, errors.As(err, &ie)) {
+		assert.Equal(t, constants.ERR_TYPE_CANCELED, ie.Type)
+	}
+}